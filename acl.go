@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"myapp/module"
+)
+
+// 解析模块配置 acl：键为 "[METHOD ]path"（相对模块路由组，按 route_overrides 改写后实际注册的路径），
+// 值为允许访问的角色，字符串或列表，满足其一即可。例如 {"/order": ["admin"], "DELETE /order": "root"}
+func parseACL(cfg module.ModuleConfig) (map[string][]string, error) {
+	raw, _ := cfg["acl"].(map[string]any)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	rules := make(map[string][]string, len(raw))
+	for key, v := range raw {
+		if m, p, found := strings.Cut(key, " "); found {
+			key = strings.ToUpper(m) + " " + p
+		}
+		var roles []string
+		switch val := v.(type) {
+		case string:
+			roles = []string{val}
+		case []any:
+			for _, r := range val {
+				s, ok := r.(string)
+				if !ok {
+					return nil, fmt.Errorf("acl %q: roles must be strings", key)
+				}
+				roles = append(roles, s)
+			}
+		case []string:
+			roles = val
+		}
+		if len(roles) == 0 {
+			return nil, fmt.Errorf("acl %q: at least one role is required", key)
+		}
+		rules[key] = roles
+	}
+	return rules, nil
+}
+
+// 引用了模块未注册路由的规则：拼写错误会让路由失去保护，视为错误
+func unmatchedACL(routes *routeTable, name, prefix string, rules map[string][]string) []string {
+	var unmatched []string
+	for key := range rules {
+		methods, p := anyMethods, key
+		if m, rest, found := strings.Cut(key, " "); found {
+			methods, p = []string{m}, rest
+		}
+		if !slices.ContainsFunc(methods, func(m string) bool { return routes.owners[m+" "+prefix+p] == name }) {
+			unmatched = append(unmatched, key)
+		}
+	}
+	sort.Strings(unmatched)
+	return unmatched
+}
+
+// 按 acl 规则检查认证中间件记录的角色（module.Roles），不满足时返回 403；须排在依赖的认证中间件之后
+func aclMiddleware(prefix string, rules map[string][]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := strings.TrimPrefix(c.FullPath(), prefix)
+		roles, ok := rules[c.Request.Method+" "+p]
+		if !ok {
+			roles, ok = rules[p]
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+		have := module.Roles(c)
+		for _, r := range roles {
+			if slices.Contains(have, r) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "required_roles": roles})
+	}
+}
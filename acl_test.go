@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以 secret 签名的 HS256 令牌
+func signedToken(claims map[string]any, secret string) string {
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + enc(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRouteACL(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	token := func(claims map[string]any) string {
+		claims["exp"] = exp
+		return signedToken(claims, "s3cret")
+	}
+	tests := []struct {
+		name     string
+		acl      map[string]any
+		method   string
+		token    string
+		wantCode int
+	}{
+		{"right role", map[string]any{"/order": []any{"admin"}}, http.MethodGet, token(map[string]any{"roles": []any{"viewer", "admin"}}), http.StatusOK},
+		{"single role claim", map[string]any{"/order": "admin"}, http.MethodGet, token(map[string]any{"role": "admin"}), http.StatusOK},
+		{"wrong role", map[string]any{"/order": []any{"admin"}}, http.MethodGet, token(map[string]any{"roles": []any{"viewer"}}), http.StatusForbidden},
+		{"no roles", map[string]any{"/order": []any{"admin"}}, http.MethodGet, token(map[string]any{"sub": "u1"}), http.StatusForbidden},
+		{"any of several roles", map[string]any{"/order": []any{"admin", "ops"}}, http.MethodGet, token(map[string]any{"roles": []any{"ops"}}), http.StatusOK},
+		// 认证失败时在 acl 之前由 auth 返回 401
+		{"missing token", map[string]any{"/order": []any{"admin"}}, http.MethodGet, "", http.StatusUnauthorized},
+		// 方法名不区分大小写；"METHOD path" 规则优先于只写路径的规则
+		{"method-specific rule", map[string]any{"get /order": []any{"admin"}}, http.MethodGet, token(map[string]any{"roles": []any{"viewer"}}), http.StatusForbidden},
+		{"method rule wins over path rule", map[string]any{"/order": "viewer", "GET /order": "admin"}, http.MethodGet, token(map[string]any{"roles": []any{"viewer"}}), http.StatusForbidden},
+		{"no acl", nil, http.MethodGet, token(map[string]any{"sub": "u1"}), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{
+				Modules: []string{"order"},
+				Configs: map[string]map[string]any{"auth": {"secret": "s3cret"}, "order": {}},
+			}
+			if tt.acl != nil {
+				cfg.Configs["order"]["acl"] = tt.acl
+			}
+			var r *routerSet
+			var err error
+			captureStdout(t, func() { r, err = m.Update(context.Background(), cfg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(tt.method, "/order", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s /order = %d %s, want %d", tt.method, w.Code, w.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusForbidden && !strings.Contains(w.Body.String(), `"required_roles":["admin"]`) {
+				t.Errorf("body = %s, want the required roles", w.Body)
+			}
+		})
+	}
+}
+
+func TestRouteACLConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		acl     any
+		wantErr string
+	}{
+		{"unregistered route", map[string]any{"/orders": "admin"}, "acl references unregistered routes: /orders"},
+		{"unregistered method", map[string]any{"DELETE /order": "admin"}, "acl references unregistered routes: DELETE /order"},
+		{"empty roles", map[string]any{"/order": []any{}}, `acl "/order": at least one role is required`},
+		{"non-string role", map[string]any{"/order": []any{1}}, `acl "/order": roles must be strings`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"order"}, Configs: map[string]map[string]any{"order": {"acl": tt.acl}}}
+			var err error
+			captureStdout(t, func() { _, err = m.Update(context.Background(), cfg) })
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Update error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 管理端点由 manager 在每次重建路由时注册；未配置 server.admin_auth 与 server.admin_token 时不启用
+func registerAdminRoutes(g *gin.RouterGroup, routes *routeTable, cfg Config) {
+	// 配置已在 Update 中校验
+	auth, _ := newAdminAuthenticator(cfg.Server)
+	if auth == nil {
+		return
+	}
+	admin := routes.router("manager", g.Group("/admin", adminAuthMiddleware(auth)))
+
+	// 重新读取配置（含环境变量与 ${file:...} 的重新展开）并重建路由，与文件监听、SIGHUP 共用同一把重载锁
+	admin.POST("/reload", func(c *gin.Context) {
+		if err := reloadConfig(context.Background()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"modules": manager.ActiveModules()})
+	})
+
+	admin.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.Stats())
+	})
+
+	admin.GET("/introspect", func(c *gin.Context) {
+		c.JSON(http.StatusOK, introspect(manager.ActiveModules()))
+	})
+
+	admin.GET("/reloads", func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.ReloadHistory())
+	})
+
+	admin.GET("/modules", func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.ModuleStates())
+	})
+
+	// 本次构建登记的全部路由，按注册它的模块分组；处理请求时路由表已构建完成，不再变化
+	admin.GET("/routes", func(c *gin.Context) {
+		c.JSON(http.StatusOK, routes.byModule())
+	})
+
+	// 由模块通过 module.Route 描述的路由生成的 OpenAPI 3 文档
+	admin.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, routes.openAPI())
+	})
+
+	// 当前生效的配置（已展开环境变量并合并 include / profile），键名匹配 redact_pattern 的值被隐藏
+	redact := redactPattern(cfg.Server.RedactPattern)
+	admin.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, redactConfig(manager.EffectiveConfig(), redact))
+	})
+
+	// 完整（不隐藏敏感值）的运行状态快照，供新进程以 -source snapshot:<URL> 接管
+	admin.GET("/state", func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.Snapshot())
+	})
+
+	registerCanaryRoutes(admin)
+
+	// 运行时替换单个模块的配置并重新初始化该模块，其他模块实例保持不变；
+	// ?cascade=true 时同时重新初始化依赖它的模块。下次从文件重载时以文件内容为准。
+	// 配置先按模块的 ConfigSchema 校验，不符合时返回 422 与问题列表；模块 Init 拒绝新配置时回滚并同样返回 422
+	admin.PUT("/modules/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		var values map[string]any
+		if err := c.ShouldBindJSON(&values); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		mod, ok := manager.activeModule(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "module not active: " + name})
+			return
+		}
+		if problems := validateModuleConfig(mod, values); len(problems) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid config for module " + name, "problems": problems})
+			return
+		}
+		oldCfg := manager.appliedConfig()
+		newCfg, ok := manager.patchedConfig(name, values)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "module not active: " + name})
+			return
+		}
+		reinit := []string{name}
+		if c.Query("cascade") == "true" {
+			reinit = append(reinit, manager.dependents(name)...)
+		}
+		if err := rebuildRouter(context.Background(), newCfg, reinit...); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, module.ErrModuleInit) {
+				code = http.StatusUnprocessableEntity
+			}
+			c.JSON(code, gin.H{"error": err.Error()})
+			return
+		}
+		// 默认策略下初始化失败的模块被跳过而重建仍然成功，此时回滚到原配置
+		if _, ok := manager.activeModule(name); !ok {
+			initErr := manager.initError()
+			if err := rebuildRouter(context.Background(), oldCfg, reinit...); err != nil {
+				fmt.Println("Failed to roll back config of module", name+":", err)
+			}
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("module %s rejected the config: %v", name, initErr)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"module": name, "modules": manager.ActiveModules()})
+	})
+}
+
+// 校验 Bearer Token 的中间件，pprof 使用
+func adminAuth(token string) gin.HandlerFunc {
+	return adminAuthMiddleware(tokenAuth{token: token})
+}
+
+const defaultRedactPattern = `(?i)password|secret|token`
+
+func redactPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = defaultRedactPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Println("Invalid server.redact_pattern, using default:", err)
+		re = regexp.MustCompile(defaultRedactPattern)
+	}
+	return re
+}
+
+// 转为通用的 map 结构后递归隐藏敏感键的值
+func redactConfig(cfg Config, re *regexp.Regexp) any {
+	// basic 认证的密码以用户名为键，无法按键名匹配，单独隐藏
+	if a := cfg.Server.AdminAuth; a != nil && len(a.Users) > 0 {
+		masked := *a
+		masked.Users = make(map[string]string, len(a.Users))
+		for user := range a.Users {
+			masked.Users[user] = "******"
+		}
+		cfg.Server.AdminAuth = &masked
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return gin.H{"error": err.Error()}
+	}
+	var tree any
+	json.Unmarshal(data, &tree)
+	return redactValue(tree, re)
+}
+
+func redactValue(v any, re *regexp.Regexp) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, v2 := range val {
+			if re.MatchString(k) {
+				if v2 != nil && v2 != "" {
+					val[k] = "******"
+				}
+				continue
+			}
+			val[k] = redactValue(v2, re)
+		}
+	case []any:
+		for i, v2 := range val {
+			val[i] = redactValue(v2, re)
+		}
+	}
+	return v
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"myapp/module"
+)
+
+// 带 schema 的测试模块，覆盖 validateModuleConfig 支持的约束
+type schemaTestModule struct{ module.Base }
+
+func (schemaTestModule) Defaults() module.ModuleConfig { return module.ModuleConfig{"mode": "fast"} }
+
+func (schemaTestModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"dsn", "mode"},
+		"properties": map[string]any{
+			"dsn":     map[string]any{"type": "string"},
+			"mode":    map[string]any{"type": "string", "enum": []string{"fast", "safe"}},
+			"workers": map[string]any{"type": "integer", "minimum": 1},
+			"ttl":     durationSchema("ttl"),
+			"hosts":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"weights": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer", "minimum": 1}},
+		},
+	}
+}
+
+func TestValidateModuleConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mod    module.Module
+		values string // JSON，与管理端点收到的请求体相同
+		want   []string
+	}{
+		{"valid", &schemaTestModule{}, `{"dsn": "x", "workers": 2, "ttl": "1m", "hosts": ["a"], "weights": {"a": 3}}`, nil},
+		{"duration as seconds", &schemaTestModule{}, `{"dsn": "x", "ttl": 30}`, nil},
+		{"default satisfies required", &schemaTestModule{}, `{"dsn": "x"}`, nil},
+		{"managed keys allowed", &schemaTestModule{}, `{"dsn": "x", "init_retries": 2, "log_level": "debug"}`, nil},
+		{"missing required", &schemaTestModule{}, `{}`, []string{"dsn: required"}},
+		{"unknown key", &schemaTestModule{}, `{"dsn": "x", "dns": "y"}`, []string{"dns: unknown key"}},
+		{"wrong type", &schemaTestModule{}, `{"dsn": 5}`, []string{"dsn: expected string, got float64"}},
+		{"not an integer", &schemaTestModule{}, `{"dsn": "x", "workers": 1.5}`, []string{"workers: expected integer, got float64"}},
+		{"below minimum", &schemaTestModule{}, `{"dsn": "x", "workers": 0}`, []string{"workers: must be >= 1"}},
+		{"not in enum", &schemaTestModule{}, `{"dsn": "x", "mode": "slow"}`, []string{"mode: must be one of [fast safe]"}},
+		{"bad array item", &schemaTestModule{}, `{"dsn": "x", "hosts": ["a", 1]}`, []string{"hosts[1]: expected string, got float64"}},
+		{"bad object value", &schemaTestModule{}, `{"dsn": "x", "weights": {"a": 0}}`, []string{"weights.a: must be >= 1"}},
+		{"bad managed key", &schemaTestModule{}, `{"dsn": "x", "init_retries": "3"}`, []string{"init_retries: expected integer, got string"}},
+		{"no schema: unknown keys allowed", &module.Base{}, `{"anything": 1, "enabled": "yes"}`, []string{"enabled: expected boolean, got string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var values map[string]any
+			if err := json.Unmarshal([]byte(tt.values), &values); err != nil {
+				t.Fatal(err)
+			}
+			if got := validateModuleConfig(tt.mod, values); !slices.Equal(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPutModuleConfig(t *testing.T) {
+	useGlobalRouter(t)
+	cfg := Config{
+		Modules: []string{"auth", "order"},
+		Configs: map[string]map[string]any{"order": {"dsn": "memory://initial"}},
+	}
+	cfg.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		front.ServeHTTP(w, req)
+		return w
+	}
+	auth, _ := manager.activeModule("auth")
+
+	tests := []struct {
+		name     string
+		module   string
+		body     string
+		wantCode int
+		wantBody string // 响应中应包含的片段
+		wantDSN  string // 之后 /order 返回的 DSN
+	}{
+		{"change order dsn", "order", `{"dsn": "memory://patched"}`, http.StatusOK, `"module":"order"`, "memory://patched"},
+		{"wrong type", "order", `{"dsn": 42}`, http.StatusUnprocessableEntity, "dsn: expected string", "memory://patched"},
+		{"unknown key", "order", `{"dsn": "memory://x", "dns": "typo"}`, http.StatusUnprocessableEntity, "dns: unknown key", "memory://patched"},
+		{"rejected by Init", "auth", `{"algorithm": "RS256", "public_key": "garbage"}`, http.StatusUnprocessableEntity, "public_key", "memory://patched"},
+		{"rolled back", "order", `{"dsn": "memory://patched"}`, http.StatusOK, `"modules":["auth","order"]`, "memory://patched"},
+		{"malformed body", "order", `{"dsn":`, http.StatusBadRequest, "error", "memory://patched"},
+		{"inactive module", "cache", `{}`, http.StatusNotFound, "module not active", "memory://patched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(http.MethodPut, "/admin/modules/"+tt.module, tt.body)
+			if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("PUT = %d %s, want %d containing %q", w.Code, w.Body, tt.wantCode, tt.wantBody)
+			}
+			w = do(http.MethodGet, "/order", "")
+			if !strings.Contains(w.Body.String(), tt.wantDSN) {
+				t.Errorf("/order = %s, want DSN %s", w.Body, tt.wantDSN)
+			}
+			// 只重新初始化被修改的模块；被拒绝的 auth 配置回滚时会重建 auth
+			cur, _ := manager.activeModule("auth")
+			if tt.module != "auth" && cur != auth {
+				t.Error("auth was re-initialized")
+			}
+			auth = cur
+		})
+	}
+}
+
+func TestAdminReload(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"t_a"}}
+	cfg.Server.AdminToken = "secret"
+	src := &mutableSource{cfg: cfg}
+	source = src
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+
+	tests := []struct {
+		name     string
+		modules  []string // 触发前写入配置来源的模块列表
+		token    string
+		wantCode int
+		want     []string // 之后激活的模块
+	}{
+		{"no token", []string{"t_a", "t_b"}, "", http.StatusUnauthorized, []string{"t_a"}},
+		{"wrong token", []string{"t_a", "t_b"}, "guess", http.StatusUnauthorized, []string{"t_a"}},
+		{"reload", []string{"t_a", "t_b"}, "secret", http.StatusOK, []string{"t_a", "t_b"}},
+		{"re-reads the source", []string{"t_b"}, "secret", http.StatusOK, []string{"t_b"}},
+		{"failed reload keeps current modules", []string{"t_b", "t_missing"}, "secret", http.StatusInternalServerError, []string{"t_b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := cfg
+			next.Modules = tt.modules
+			src.set(next)
+			req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("POST /admin/reload = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+			if got := manager.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"auth", "order"}}
+	cfg.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+
+	tests := []struct {
+		name string
+		hits int
+		want float64 // 累计的 orders_served
+	}{
+		{"no orders yet", 0, 0},
+		{"three orders", 3, 3},
+		{"two more", 2, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.hits; i++ {
+				w := httptest.NewRecorder()
+				front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order", nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET /order = %d", w.Code)
+				}
+			}
+			req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, req)
+			var stats map[string]map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+				t.Fatalf("GET /admin/stats = %d %s: %v", w.Code, w.Body, err)
+			}
+			if got := stats["order"]["orders_served"]; got != tt.want {
+				t.Errorf("orders_served = %v, want %v", got, tt.want)
+			}
+			if _, ok := stats["auth"]; ok {
+				t.Error("auth reports stats but does not implement Stats")
+			}
+		})
+	}
+}
+
+func TestAdminConfigRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		module  map[string]any
+		want    map[string]any // 返回的 order 配置中各键的值
+	}{
+		{
+			name:   "default pattern",
+			module: map[string]any{"dsn": "postgres://db/orders", "password": "hunter2", "db_secret": "s", "api_token": ""},
+			want:   map[string]any{"dsn": "postgres://db/orders", "password": "******", "db_secret": "******", "api_token": ""},
+		},
+		{
+			name:    "custom pattern",
+			pattern: "(?i)dsn",
+			module:  map[string]any{"dsn": "postgres://db/orders", "password": "hunter2"},
+			want:    map[string]any{"dsn": "******", "password": "hunter2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"order"}, Configs: map[string]map[string]any{"order": tt.module}}
+			cfg.Server.AdminToken = "admin-secret"
+			cfg.Server.RedactPattern = tt.pattern
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			front := frontHandler(cfg.Server, false, "")
+
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("GET /admin/config without token = %d, want 401", w.Code)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			req.Header.Set("Authorization", "Bearer admin-secret")
+			w = httptest.NewRecorder()
+			front.ServeHTTP(w, req)
+			var got struct {
+				Configs map[string]map[string]any
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET /admin/config = %d %s: %v", w.Code, w.Body, err)
+			}
+			for k, want := range tt.want {
+				if v := got.Configs["order"][k]; v != want {
+					t.Errorf("order.%s = %v, want %v", k, v, want)
+				}
+			}
+			// 默认模式同样隐藏 server.admin_token
+			if tt.pattern == "" && strings.Contains(w.Body.String(), "admin-secret") {
+				t.Error("admin token is not redacted")
+			}
+		})
+	}
+}
+
+func TestAdminModuleStates(t *testing.T) {
+	useGlobalRouter(t)
+	type state struct {
+		InitCount int    `json:"init_count"`
+		FailCount int    `json:"fail_count"`
+		LastError string `json:"last_error"`
+		Active    bool   `json:"active"`
+	}
+	// 依次应用的 order 配置，每次都使 order 重新初始化
+	tests := []struct {
+		name  string
+		order map[string]any
+		want  state
+	}{
+		{"first load", map[string]any{"dsn": "memory://a"}, state{InitCount: 1, Active: true}},
+		{"reload with new dsn", map[string]any{"dsn": "memory://b"}, state{InitCount: 2, Active: true}},
+		{"forced failure", map[string]any{"dsn": map[string]any{"bad": true}}, state{InitCount: 2, FailCount: 1}},
+		{"recovered", map[string]any{"dsn": "memory://c"}, state{InitCount: 3, FailCount: 1, Active: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"order"}, Configs: map[string]map[string]any{"order": tt.order}}
+			cfg.Server.AdminToken = "secret"
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/admin/modules", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			frontHandler(cfg.Server, false, "").ServeHTTP(w, req)
+			var states map[string]state
+			if err := json.Unmarshal(w.Body.Bytes(), &states); err != nil {
+				t.Fatalf("GET /admin/modules = %d %s: %v", w.Code, w.Body, err)
+			}
+			got := states["order"]
+			if got.InitCount != tt.want.InitCount || got.FailCount != tt.want.FailCount || got.Active != tt.want.Active {
+				t.Errorf("order state = %+v, want %+v", got, tt.want)
+			}
+			// 失败后保留最近一次错误，直到下一次失败
+			if wantErr := tt.want.FailCount > 0; (got.LastError != "") != wantErr {
+				t.Errorf("last_error = %q, want set = %v", got.LastError, wantErr)
+			}
+		})
+	}
+}
+
+func TestAdminPrefix(t *testing.T) {
+	// 模块自己的 /healthz 在管理端点移走后不再冲突
+	registerRouteModules(t, map[string][2]string{"t_health": {"/healthz", "module health"}})
+	disabled := false
+	endpoints := []string{"/healthz", "/readyz", "/version", "/metrics", "/admin/stats"}
+	tests := []struct {
+		name    string
+		prefix  string
+		enabled *bool
+		modules []string
+		mounted string // 管理端点应在的前缀，禁用时忽略
+		absent  string // 管理端点不应出现的前缀
+	}{
+		{"default root", "", nil, []string{"order"}, "", "/ops"},
+		{"under prefix", "/ops", nil, []string{"order", "t_health"}, "/ops", ""},
+		{"prefix normalized", "ops/", nil, []string{"order", "t_health"}, "/ops", ""},
+		{"disabled", "/ops", &disabled, []string{"order"}, "", "/ops"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: tt.modules}
+			cfg.Server.AdminToken = "secret"
+			cfg.Server.AdminPrefix = tt.prefix
+			cfg.Server.AdminEnabled = tt.enabled
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			get := func(path string) int {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Authorization", "Bearer secret")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+			for _, ep := range endpoints {
+				// /readyz 在未就绪时返回 503，这里只关心端点是否注册
+				if tt.enabled == nil {
+					if code := get(tt.mounted + ep); code == http.StatusNotFound {
+						t.Errorf("GET %s%s = 404, want it registered", tt.mounted, ep)
+					}
+				}
+				// 根路径上的 /healthz 由 t_health 模块提供
+				if tt.absent == "" && ep == "/healthz" {
+					continue
+				}
+				if code := get(tt.absent + ep); code != http.StatusNotFound {
+					t.Errorf("GET %s%s = %d, want 404", tt.absent, ep, code)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminReloadHistory(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+	useGlobalRouter(t)
+	var cfg Config
+	for _, modules := range [][]string{{"t_a"}, {"t_a", "t_b"}, {"t_missing"}, {"t_b"}, {"t_b"}} {
+		cfg = Config{Modules: modules}
+		cfg.Server.AdminToken = "secret"
+		cfg.Server.ReloadHistory = 3
+		rebuildRouter(context.Background(), cfg)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/reloads", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	frontHandler(cfg.Server, false, "").ServeHTTP(w, req)
+	var got []ReloadEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /admin/reloads = %d %s: %v", w.Code, w.Body, err)
+	}
+
+	// 只保留最近 3 次，从旧到新
+	want := []struct {
+		success bool
+		started []string
+		stopped []string
+		err     string
+	}{
+		{false, nil, nil, "unknown module: t_missing"},
+		{true, nil, []string{"t_a"}, ""},
+		{true, nil, nil, ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d reload events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, tt := range want {
+		ev := got[i]
+		if ev.Success != tt.success || !slices.Equal(ev.Started, tt.started) || !slices.Equal(ev.Stopped, tt.stopped) || !strings.Contains(ev.Error, tt.err) {
+			t.Errorf("event %d = %+v, want %+v", i, ev, tt)
+		}
+		if i > 0 && ev.Time.Before(got[i-1].Time) {
+			t.Errorf("event %d is older than event %d", i, i-1)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理端点的认证后端：返回调用方身份（写入 gin.Context 的 admin_identity），失败时返回错误
+type AdminAuthenticator interface {
+	Authenticate(r *http.Request) (identity string, err error)
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// 管理端点认证配置，type 为 token | basic | ip：
+//
+//	admin_auth:
+//	  type: basic
+//	  users:
+//	    ops: "${ADMIN_PASSWORD}"
+type AdminAuthConfig struct {
+	Type  string            `yaml:"type"`
+	Token string            `yaml:"token"` // type: token，缺省时使用 server.admin_token
+	Users map[string]string `yaml:"users"` // type: basic，用户名 -> 密码
+	Allow []string          `yaml:"allow"` // type: ip，允许的来源 IP 或 CIDR
+}
+
+// 按 server.admin_auth 创建认证后端；未配置时沿用 server.admin_token，两者都未配置时返回 nil（不启用管理端点）
+func newAdminAuthenticator(s ServerConfig) (AdminAuthenticator, error) {
+	if s.AdminAuth == nil {
+		if s.AdminToken == "" {
+			return nil, nil
+		}
+		return tokenAuth{token: s.AdminToken}, nil
+	}
+	a := s.AdminAuth
+	switch a.Type {
+	case "token", "":
+		token := a.Token
+		if token == "" {
+			token = s.AdminToken
+		}
+		if token == "" {
+			return nil, fmt.Errorf("server.admin_auth: token is required")
+		}
+		return tokenAuth{token: token}, nil
+	case "basic":
+		if len(a.Users) == 0 {
+			return nil, fmt.Errorf("server.admin_auth: basic requires users")
+		}
+		return basicAuth{users: a.Users}, nil
+	case "ip":
+		return newIPAllowlist(a.Allow)
+	default:
+		return nil, fmt.Errorf("server.admin_auth: unknown type %q", a.Type)
+	}
+}
+
+// Authorization: Bearer <token>
+type tokenAuth struct {
+	token string
+}
+
+func (t tokenAuth) Authenticate(r *http.Request) (string, error) {
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(t.token)) != 1 {
+		return "", errUnauthorized
+	}
+	return "token", nil
+}
+
+// HTTP Basic 认证，身份为用户名
+type basicAuth struct {
+	users map[string]string
+}
+
+func (b basicAuth) Authenticate(r *http.Request) (string, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return "", errUnauthorized
+	}
+	want, exists := b.users[user]
+	// 用户不存在时同样做一次比较，避免通过耗时区分
+	if subtle.ConstantTimeCompare([]byte(pass), []byte(want)) != 1 || !exists {
+		return "", errUnauthorized
+	}
+	return user, nil
+}
+
+// 来源 IP 白名单，身份为来源 IP；只看直连地址（RemoteAddr），不信任 X-Forwarded-For
+type ipAllowlist struct {
+	nets []*net.IPNet
+}
+
+func newIPAllowlist(allow []string) (ipAllowlist, error) {
+	if len(allow) == 0 {
+		return ipAllowlist{}, fmt.Errorf("server.admin_auth: ip requires allow")
+	}
+	var l ipAllowlist
+	for _, a := range allow {
+		if ip := net.ParseIP(a); ip != nil {
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			l.nets = append(l.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			return ipAllowlist{}, fmt.Errorf("server.admin_auth: invalid IP or CIDR %q", a)
+		}
+		l.nets = append(l.nets, n)
+	}
+	return l, nil
+}
+
+func (l ipAllowlist) Authenticate(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	for _, n := range l.nets {
+		if ip != nil && n.Contains(ip) {
+			return ip.String(), nil
+		}
+	}
+	return "", errUnauthorized
+}
+
+// 认证中间件：失败返回 401（basic 附带 WWW-Authenticate 以便浏览器弹出登录框）
+func adminAuthMiddleware(a AdminAuthenticator) gin.HandlerFunc {
+	_, basic := a.(basicAuth)
+	return func(c *gin.Context) {
+		id, err := a.Authenticate(c.Request)
+		if err != nil {
+			if basic {
+				c.Header("WWW-Authenticate", `Basic realm="admin"`)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set("admin_identity", id)
+		c.Next()
+	}
+}
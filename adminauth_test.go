@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuthenticators(t *testing.T) {
+	token := func(v string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+v) }
+	}
+	basic := func(user, pass string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+	}
+	from := func(addr string) func(*http.Request) {
+		return func(r *http.Request) { r.RemoteAddr = addr }
+	}
+	users := map[string]string{"ops": "s3cret"}
+	tests := []struct {
+		name     string
+		server   ServerConfig
+		req      func(*http.Request)
+		wantCode int
+		wantID   string
+	}{
+		{"legacy token ok", ServerConfig{AdminToken: "t0k"}, token("t0k"), http.StatusOK, "token"},
+		{"legacy token wrong", ServerConfig{AdminToken: "t0k"}, token("nope"), http.StatusUnauthorized, ""},
+		{"token ok", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "token", Token: "t0k"}}, token("t0k"), http.StatusOK, "token"},
+		{"token falls back to admin_token", ServerConfig{AdminToken: "t0k", AdminAuth: &AdminAuthConfig{Type: "token"}}, token("t0k"), http.StatusOK, "token"},
+		{"token missing", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "token", Token: "t0k"}}, func(*http.Request) {}, http.StatusUnauthorized, ""},
+		{"basic ok", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "basic", Users: users}}, basic("ops", "s3cret"), http.StatusOK, "ops"},
+		{"basic wrong password", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "basic", Users: users}}, basic("ops", "guess"), http.StatusUnauthorized, ""},
+		{"basic unknown user", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "basic", Users: users}}, basic("root", ""), http.StatusUnauthorized, ""},
+		{"basic rejects bearer", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "basic", Users: users}}, token("s3cret"), http.StatusUnauthorized, ""},
+		{"ip exact ok", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "ip", Allow: []string{"10.0.0.7"}}}, from("10.0.0.7:4000"), http.StatusOK, "10.0.0.7"},
+		{"ip cidr ok", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "ip", Allow: []string{"192.168.0.0/16"}}}, from("192.168.3.4:4000"), http.StatusOK, "192.168.3.4"},
+		{"ip denied", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "ip", Allow: []string{"10.0.0.7"}}}, from("10.0.0.8:4000"), http.StatusUnauthorized, ""},
+		// 只看直连地址，伪造的 X-Forwarded-For 无效
+		{"ip ignores forwarded", ServerConfig{AdminAuth: &AdminAuthConfig{Type: "ip", Allow: []string{"10.0.0.7"}}}, func(r *http.Request) {
+			r.RemoteAddr = "203.0.113.1:4000"
+			r.Header.Set("X-Forwarded-For", "10.0.0.7")
+		}, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := newAdminAuthenticator(tt.server)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			r.GET("/admin", adminAuthMiddleware(auth), func(c *gin.Context) { c.String(http.StatusOK, c.GetString("admin_identity")) })
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			tt.req(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && w.Body.String() != tt.wantID {
+				t.Errorf("identity = %q, want %q", w.Body, tt.wantID)
+			}
+			// basic 认证失败时提示浏览器弹出登录框
+			_, isBasic := auth.(basicAuth)
+			if got := w.Header().Get("WWW-Authenticate") != ""; got != (isBasic && tt.wantCode != http.StatusOK) {
+				t.Errorf("WWW-Authenticate = %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAdminAuthConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    AdminAuthConfig
+		wantErr string
+	}{
+		{"token without value", AdminAuthConfig{Type: "token"}, "token is required"},
+		{"basic without users", AdminAuthConfig{Type: "basic"}, "basic requires users"},
+		{"ip without allow", AdminAuthConfig{Type: "ip"}, "ip requires allow"},
+		{"ip invalid", AdminAuthConfig{Type: "ip", Allow: []string{"10.0.0.300"}}, "invalid IP or CIDR"},
+		{"unknown type", AdminAuthConfig{Type: "oauth"}, "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth
+			_, err := newAdminAuthenticator(ServerConfig{AdminAuth: &auth})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// App 是在本机随机端口上运行的完整应用（管理器、模块路由、管理端点与 HTTP server），供集成测试使用：
+//
+//	app, err := StartApp(Config{Modules: []string{"user"}})
+//	if err != nil { t.Fatal(err) }
+//	defer app.Close()
+//	resp, err := app.Client.Get(app.URL + "/user")
+//
+// 启动过程与 main 相同（见 Run），只是配置来自内存且不监听配置变化。
+// main 包无法被其他包导入，因此放在这里，由本包的测试使用
+type App struct {
+	URL       string            // 如 http://127.0.0.1:54321
+	Listeners map[string]string // server.listeners 中各具名监听的 URL，地址可写作 127.0.0.1:0
+	Client    *http.Client
+
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// 启动时的配置固定不变的配置来源，/admin/reload 等重新读取配置时得到同一份配置
+type staticSource struct {
+	cfg Config
+}
+
+func (s *staticSource) Load() (Config, error)        { return s.cfg, nil }
+func (s *staticSource) Watch(ch chan<- Config) error { return nil }
+
+// StartApp 以 cfg 启动应用并在开始监听后返回；server.unix_socket 与 server.tls 被忽略，始终监听 127.0.0.1 上的明文 HTTP
+func StartApp(cfg Config) (*App, error) {
+	cfg.Server.UnixSocket, cfg.Server.TLS = "", nil
+	source = &staticSource{cfg: cfg}
+	ctx, cancel := context.WithCancel(context.Background())
+	listening := make(chan []net.Addr, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, cfg, RunOptions{
+			Addrs:     []string{"127.0.0.1:0"},
+			Listening: func(addrs []net.Addr) { listening <- addrs },
+		})
+	}()
+	select {
+	case addrs := <-listening:
+		app := &App{
+			URL:       "http://" + addrs[0].String(),
+			Listeners: make(map[string]string, len(cfg.Server.Listeners)),
+			Client:    &http.Client{Timeout: 10 * time.Second},
+			cancel:    cancel,
+			done:      done,
+		}
+		// 具名监听排在默认监听之后，按名称排序
+		for i, name := range listenerNames(cfg.Server) {
+			app.Listeners[name] = "http://" + addrs[1+i].String()
+		}
+		return app, nil
+	case err := <-done:
+		cancel()
+		return nil, err
+	}
+}
+
+// Close 优雅关闭应用：停止监听、等待在途请求，再关闭全部模块
+func (a *App) Close() error {
+	a.cancel()
+	return <-a.done
+}
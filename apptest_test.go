@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestStartApp(t *testing.T) {
+	tests := []struct {
+		name     string
+		modules  []string
+		path     string
+		wantCode int
+	}{
+		{"user module", []string{"user"}, "/user", http.StatusOK},
+		{"module not enabled", []string{"user"}, "/order", http.StatusNotFound},
+		{"dependent modules", []string{"auth", "order"}, "/order", http.StatusOK},
+		{"health endpoint", []string{"user"}, "/healthz", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			app, err := StartApp(Config{Modules: tt.modules})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := app.Client.Get(app.URL + tt.path)
+			if err != nil {
+				app.Close()
+				t.Fatal(err)
+			}
+			var body map[string]any
+			json.NewDecoder(resp.Body).Decode(&body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("GET %s = %d %v, want %d", tt.path, resp.StatusCode, body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && len(body) == 0 {
+				t.Errorf("GET %s returned an empty body", tt.path)
+			}
+			if err := app.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			// 关闭后不再监听
+			if resp, err := app.Client.Get(app.URL + tt.path); err == nil {
+				resp.Body.Close()
+				t.Errorf("GET %s after Close = %d, want a connection error", tt.path, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 金丝雀：用独立的 ModuleManager 按新配置构建第二套路由，与当前路由并存，
+// 按 percent 把一部分请求交给它；观察两边的错误数后由 /admin/canary/promote 或 /rollback 收尾
+type canaryRouter struct {
+	manager *ModuleManager
+	routers *routerSet
+	refs    *inflightCounter
+	cfg     Config
+	admin   string // 管理端点前缀，这些请求总是交给当前路由
+	started time.Time
+	percent atomic.Int64
+	traffic trafficStats
+}
+
+// 请求数与 5xx 响应数
+type trafficStats struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+}
+
+func (s *trafficStats) record(status int) {
+	s.requests.Add(1)
+	if status >= http.StatusInternalServerError {
+		s.errors.Add(1)
+	}
+}
+
+func (s *trafficStats) snapshot() gin.H {
+	return gin.H{"requests": s.requests.Load(), "errors": s.errors.Load()}
+}
+
+var (
+	canary        *canaryRouter // 受 globalRouter 保护
+	stableTraffic trafficStats  // 金丝雀期间当前路由的流量，开始新的金丝雀时清零
+)
+
+var (
+	errCanaryActive = errors.New("a canary is already active")
+	errNoCanary     = errors.New("no canary is active")
+)
+
+// 本次请求是否交给金丝雀；管理请求（包括 promote / rollback 本身）不分流，
+// 否则摘下金丝雀时会等待处理该请求的自身
+func (c *canaryRouter) pick(r *http.Request) bool {
+	p := c.percent.Load()
+	if p <= 0 || strings.HasPrefix(r.URL.Path, c.admin) {
+		return false
+	}
+	return rand.Int64N(100) < p
+}
+
+// 取得金丝雀路由；refs 与 routers 一起在 globalRouter 内读取，leadership 变化重建后请求仍释放到取得时的计数
+func (c *canaryRouter) acquire(listener string) (*gin.Engine, func(status int)) {
+	refs := c.refs
+	refs.acquire()
+	return c.routers.forListener(listener), func(status int) {
+		c.traffic.record(status)
+		refs.release()
+	}
+}
+
+// 以 leadership 重建金丝雀路由，与 applyLeadership 对当前路由的处理相同；调用方持有 reloadLock
+func (c *canaryRouter) applyLeadership(ctx context.Context, leader bool) {
+	if c.manager.leader.Swap(leader) == leader {
+		return
+	}
+	routers, err := c.manager.Update(ctx, c.cfg)
+	if err != nil {
+		fmt.Println("Canary rebuild failed, keeping previous canary router:", err)
+		return
+	}
+	globalRouter.Lock()
+	c.routers = routers
+	oldRefs := c.refs
+	c.refs = &inflightCounter{}
+	globalRouter.Unlock()
+	deadline := time.Now().Add(c.cfg.Server.drainTimeout())
+	if !oldRefs.wait(deadline) {
+		fmt.Println("Drain timeout, previous canary router still has in-flight requests")
+	}
+	c.manager.StopRetired(time.Until(deadline))
+}
+
+// 按 cfg 构建金丝雀路由并开始分流；构建失败时不影响当前路由。
+// 金丝雀与当前路由使用相同的 leadership，本实例不是 leader 时同样不启动 ClusterSingleton 模块
+func startCanary(ctx context.Context, cfg Config, percent int) error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	globalRouter.Lock()
+	active := canary != nil
+	globalRouter.Unlock()
+	if active {
+		return errCanaryActive
+	}
+	m := NewModuleManager()
+	m.leader.Store(manager.leader.Load())
+	routers, err := m.Update(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("build canary: %w", err)
+	}
+	c := &canaryRouter{manager: m, routers: routers, refs: &inflightCounter{}, cfg: cfg, started: time.Now()}
+	c.admin = manager.EffectiveConfig().Server.adminPrefix() + "/admin/"
+	c.percent.Store(int64(percent))
+
+	globalRouter.Lock()
+	if canary != nil {
+		globalRouter.Unlock()
+		m.ShutdownAll(0)
+		return errCanaryActive
+	}
+	canary = c
+	stableTraffic.requests.Store(0)
+	stableTraffic.errors.Store(0)
+	globalRouter.Unlock()
+	fmt.Printf("Canary started with %d%% of traffic\n", percent)
+	return nil
+}
+
+// 摘下金丝雀：不再分流，等待已分给它的请求结束后关闭其模块
+func stopCanary(drainTimeout time.Duration) *canaryRouter {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	globalRouter.Lock()
+	c := canary
+	canary = nil
+	var refs *inflightCounter
+	if c != nil {
+		refs = c.refs
+	}
+	globalRouter.Unlock()
+	if c == nil {
+		return nil
+	}
+	deadline := time.Now().Add(drainTimeout)
+	if !refs.wait(deadline) {
+		fmt.Println("Drain timeout, canary router still has in-flight requests")
+	}
+	c.manager.ShutdownAll(time.Until(deadline))
+	return c
+}
+
+// 以金丝雀的配置重建当前路由（未变化的模块实例照常复用），成功后摘下金丝雀；
+// 失败时金丝雀保持不变
+func promoteCanary(ctx context.Context) error {
+	globalRouter.Lock()
+	c := canary
+	globalRouter.Unlock()
+	if c == nil {
+		return errNoCanary
+	}
+	if err := rebuildRouter(ctx, c.cfg); err != nil {
+		return err
+	}
+	stopCanary(c.cfg.Server.drainTimeout())
+	fmt.Println("Canary promoted")
+	return nil
+}
+
+func canaryStatus() gin.H {
+	globalRouter.Lock()
+	c := canary
+	globalRouter.Unlock()
+	if c == nil {
+		return gin.H{"active": false}
+	}
+	return gin.H{
+		"active":  true,
+		"percent": c.percent.Load(),
+		"started": c.started,
+		"modules": c.manager.ActiveModules(),
+		"canary":  c.traffic.snapshot(),
+		"stable":  stableTraffic.snapshot(),
+	}
+}
+
+// /admin/canary：POST 开始（{"percent": 10, "config": "canary.yaml"}，未给 config 时重新读取当前配置来源）、
+// PUT 调整比例、GET 查看两边的请求与错误数、POST /promote 与 /rollback 收尾
+func registerCanaryRoutes(admin gin.IRouter) {
+	admin.GET("/canary", func(c *gin.Context) {
+		c.JSON(http.StatusOK, canaryStatus())
+	})
+
+	admin.POST("/canary", func(c *gin.Context) {
+		var req struct {
+			Percent int    `json:"percent"`
+			Config  string `json:"config"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Percent < 0 || req.Percent > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "percent must be between 0 and 100"})
+			return
+		}
+		var cfg Config
+		var err error
+		if req.Config != "" {
+			cfg, err = loadConfigFrom(req.Config)
+		} else {
+			cfg, err = source.Load()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := startCanary(context.Background(), cfg, req.Percent); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, errCanaryActive) {
+				code = http.StatusConflict
+			}
+			c.JSON(code, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, canaryStatus())
+	})
+
+	admin.PUT("/canary", func(c *gin.Context) {
+		var req struct {
+			Percent *int `json:"percent"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Percent == nil || *req.Percent < 0 || *req.Percent > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "percent must be between 0 and 100"})
+			return
+		}
+		globalRouter.Lock()
+		cr := canary
+		globalRouter.Unlock()
+		if cr == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errNoCanary.Error()})
+			return
+		}
+		cr.percent.Store(int64(*req.Percent))
+		c.JSON(http.StatusOK, canaryStatus())
+	})
+
+	admin.POST("/canary/promote", func(c *gin.Context) {
+		if err := promoteCanary(context.Background()); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, errNoCanary) {
+				code = http.StatusNotFound
+			}
+			c.JSON(code, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"modules": manager.ActiveModules()})
+	})
+
+	admin.POST("/canary/rollback", func(c *gin.Context) {
+		cr := stopCanary(manager.EffectiveConfig().Server.drainTimeout())
+		if cr == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errNoCanary.Error()})
+			return
+		}
+		fmt.Println("Canary rolled back")
+		c.JSON(http.StatusOK, gin.H{"canary": cr.traffic.snapshot(), "stable": stableTraffic.snapshot()})
+	})
+}
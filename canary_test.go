@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 当前路由与金丝雀路由在同一路径 /which 上返回不同内容，管理端点使用 token
+func startTestCanary(t *testing.T, percent int) (http.Handler, func(method, path, body string) *httptest.ResponseRecorder) {
+	t.Helper()
+	registerRouteModules(t, map[string][2]string{"t_stable": {"/which", "stable"}, "t_canary": {"/which", "canary"}})
+	useGlobalRouter(t)
+	t.Cleanup(func() { stopCanary(0) })
+	stable := Config{Modules: []string{"t_stable"}}
+	stable.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), stable); err != nil {
+		t.Fatal(err)
+	}
+	next := Config{Modules: []string{"t_canary"}, Server: stable.Server}
+	source = &staticSource{cfg: next}
+
+	front := frontHandler(stable.Server, false, "")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		front.ServeHTTP(w, req)
+		return w
+	}
+	var w *httptest.ResponseRecorder
+	captureStdout(t, func() { w = do(http.MethodPost, "/admin/canary", `{"percent": `+strconv.Itoa(percent)+`}`) })
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /admin/canary = %d %s", w.Code, w.Body)
+	}
+	return front, do
+}
+
+func TestCanarySplit(t *testing.T) {
+	_, do := startTestCanary(t, 10)
+	const requests = 2000
+	tests := []struct {
+		percent  int
+		min, max int // 命中金丝雀的请求数范围
+	}{
+		{10, 140, 260},
+		{0, 0, 0},
+		{50, 880, 1120},
+		{100, requests, requests},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.percent)+"%", func(t *testing.T) {
+			if tt.percent != 10 {
+				if w := do(http.MethodPut, "/admin/canary", `{"percent": `+strconv.Itoa(tt.percent)+`}`); w.Code != http.StatusOK {
+					t.Fatalf("PUT /admin/canary = %d %s", w.Code, w.Body)
+				}
+			}
+			var before struct {
+				Canary struct{ Requests int } `json:"canary"`
+			}
+			json.Unmarshal(do(http.MethodGet, "/admin/canary", "").Body.Bytes(), &before)
+
+			hits := 0
+			for i := 0; i < requests; i++ {
+				switch body := do(http.MethodGet, "/which", "").Body.String(); body {
+				case "canary":
+					hits++
+				case "stable":
+				default:
+					t.Fatalf("GET /which = %q", body)
+				}
+			}
+			if hits < tt.min || hits > tt.max {
+				t.Errorf("%d of %d requests hit the canary at %d%%, want %d-%d", hits, requests, tt.percent, tt.min, tt.max)
+			}
+
+			// 管理请求不分流，且金丝雀统计与实际命中数一致
+			var after struct {
+				Percent int                    `json:"percent"`
+				Canary  struct{ Requests int } `json:"canary"`
+			}
+			w := do(http.MethodGet, "/admin/canary", "")
+			if err := json.Unmarshal(w.Body.Bytes(), &after); err != nil {
+				t.Fatalf("GET /admin/canary = %d %s", w.Code, w.Body)
+			}
+			if after.Percent != tt.percent || after.Canary.Requests-before.Canary.Requests != hits {
+				t.Errorf("status = %s, want percent %d and %d canary requests", w.Body, tt.percent, hits)
+			}
+		})
+	}
+}
+
+func TestCanaryPromoteRollback(t *testing.T) {
+	tests := []struct {
+		action string
+		want   string // 收尾后 /which 的响应
+	}{
+		{"promote", "canary"},
+		{"rollback", "stable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			_, do := startTestCanary(t, 50)
+			var w *httptest.ResponseRecorder
+			captureStdout(t, func() { w = do(http.MethodPost, "/admin/canary/"+tt.action, "") })
+			if w.Code != http.StatusOK {
+				t.Fatalf("POST /admin/canary/%s = %d %s", tt.action, w.Code, w.Body)
+			}
+			for i := 0; i < 50; i++ {
+				if body := do(http.MethodGet, "/which", "").Body.String(); body != tt.want {
+					t.Fatalf("GET /which after %s = %q, want %q", tt.action, body, tt.want)
+				}
+			}
+			if w := do(http.MethodGet, "/admin/canary", ""); !strings.Contains(w.Body.String(), `"active":false`) {
+				t.Errorf("GET /admin/canary after %s = %s", tt.action, w.Body)
+			}
+			// 没有金丝雀时再次收尾返回 404
+			if w := do(http.MethodPost, "/admin/canary/"+tt.action, ""); w.Code != http.StatusNotFound {
+				t.Errorf("second %s = %d, want 404", tt.action, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 按模块配置 use 列出的中间件链（顶层 chains 中定义，值为提供中间件的模块名）依次收集中间件，
+// 返回中间件与所用的模块。模块已通过依赖获得的中间件不再重复添加；
+// 引用未定义的链、未启用或不提供中间件的模块时返回错误，避免本应受保护的路由静默失去保护
+func chainMiddlewares(chains map[string][]string, mod module.Module, uses []string, instances map[string]module.Module) ([]gin.HandlerFunc, []string, error) {
+	deps := depNames(mod)
+	var handlers []gin.HandlerFunc
+	var providers []string
+	for _, chain := range uses {
+		members, ok := chains[chain]
+		if !ok {
+			return nil, nil, fmt.Errorf("use: unknown middleware chain %q", chain)
+		}
+		for _, name := range members {
+			if slices.Contains(deps, name) || slices.Contains(providers, name) {
+				continue
+			}
+			inst, ok := instances[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("chain %q: module %q is not enabled", chain, name)
+			}
+			p, ok := module.CapabilityOf[module.MiddlewareProvider](inst, module.CapMiddleware)
+			if !ok {
+				return nil, nil, fmt.Errorf("chain %q: module %q provides no middleware", chain, name)
+			}
+			handlers = append(handlers, p.Middlewares()...)
+			providers = append(providers, name)
+		}
+	}
+	return handlers, providers, nil
+}
+
+// 校验 chains 与各模块的 use：链中的模块须已注册，use 引用的链须已定义
+func (c Config) chainProblems() []error {
+	var problems []error
+	chains := make([]string, 0, len(c.Chains))
+	for chain := range c.Chains {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	for _, chain := range chains {
+		for _, name := range c.Chains[chain] {
+			if _, ok := registry.Factory(name); !ok {
+				problems = append(problems, fmt.Errorf("chain %q: %w", chain, &module.UnknownModuleError{Name: name}))
+			}
+		}
+	}
+	names := make([]string, 0, len(c.Configs))
+	for name := range c.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, chain := range module.ModuleConfig(c.Configs[name]).GetStringSlice("use") {
+			if _, ok := c.Chains[chain]; !ok {
+				problems = append(problems, fmt.Errorf("module %q uses unknown middleware chain %q", name, chain))
+			}
+		}
+	}
+	return problems
+}
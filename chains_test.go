@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 中间件在响应头 X-Chain 中追加模块名，用于观察执行顺序
+type chainMWModule struct {
+	module.Base
+	name string
+}
+
+func (m *chainMWModule) Middlewares() []gin.HandlerFunc {
+	return []gin.HandlerFunc{func(c *gin.Context) { c.Writer.Header().Add("X-Chain", m.name) }}
+}
+
+func TestMiddlewareChains(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{"t_chained": {"/chained", "ok"}, "t_plain": {"/plain", "ok"}})
+	for _, name := range []string{"t_mwauth", "t_mwlimit"} {
+		registry.Modules[name] = func() module.Module { return &chainMWModule{name: name} }
+	}
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_mwauth")
+		delete(registry.Modules, "t_mwlimit")
+	})
+	tests := []struct {
+		name    string
+		chains  map[string][]string
+		use     []string // t_chained 的 use
+		want    []string // GET /chained 的 X-Chain
+		wantErr string
+	}{
+		{"protected chain in order", map[string][]string{"protected": {"t_mwauth", "t_mwlimit"}}, []string{"protected"},
+			[]string{"t_mwauth", "t_mwlimit"}, ""},
+		{"chain order is kept", map[string][]string{"protected": {"t_mwlimit", "t_mwauth"}}, []string{"protected"},
+			[]string{"t_mwlimit", "t_mwauth"}, ""},
+		{"several chains", map[string][]string{"auth": {"t_mwauth"}, "limit": {"t_mwlimit"}}, []string{"limit", "auth"},
+			[]string{"t_mwlimit", "t_mwauth"}, ""},
+		// 多个链包含同一模块时只应用一次
+		{"shared member applied once", map[string][]string{"auth": {"t_mwauth"}, "protected": {"t_mwauth", "t_mwlimit"}}, []string{"auth", "protected"},
+			[]string{"t_mwauth", "t_mwlimit"}, ""},
+		{"no use", map[string][]string{"protected": {"t_mwauth", "t_mwlimit"}}, nil, nil, ""},
+		{"unknown chain", nil, []string{"missing"}, nil, `unknown middleware chain "missing"`},
+		{"member without middleware", map[string][]string{"protected": {"t_plain"}}, []string{"protected"}, nil,
+			`chain "protected": module "t_plain" provides no middleware`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_chained", "t_plain", "t_mwauth", "t_mwlimit"}, Chains: tt.chains}
+			if tt.use != nil {
+				cfg.Configs = map[string]map[string]any{"t_chained": {"use": tt.use}}
+			}
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Update error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chained", nil))
+			if got := w.Header().Values("X-Chain"); w.Code != http.StatusOK || !slices.Equal(got, tt.want) {
+				t.Errorf("GET /chained = %d with X-Chain %v, want 200 with %v", w.Code, got, tt.want)
+			}
+			// 未配置 use 的模块不受链影响
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain", nil))
+			if got := w.Header().Values("X-Chain"); len(got) > 0 {
+				t.Errorf("GET /plain X-Chain = %v, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 响应压缩配置：响应体不小于 min_size 且内容类型未被排除时使用 gzip
+type CompressionConfig struct {
+	Enabled              bool     `yaml:"enabled"`
+	MinSize              int      `yaml:"min_size"`               // 默认 1024 字节
+	Level                int      `yaml:"level"`                  // 1-9，默认 gzip.DefaultCompression
+	ExcludedContentTypes []string `yaml:"excluded_content_types"` // 按前缀匹配，如 image/
+}
+
+const defaultCompressionMinSize = 1024
+
+// 已压缩或流式的内容默认不再压缩
+var defaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "application/zip", "application/gzip", "text/event-stream",
+}
+
+func gzipMiddleware(cfg *CompressionConfig) gin.HandlerFunc {
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressionMinSize
+	}
+	level := cfg.Level
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression || level == 0 {
+		level = gzip.DefaultCompression
+	}
+	excluded := cfg.ExcludedContentTypes
+	if excluded == nil {
+		excluded = defaultExcludedContentTypes
+	}
+
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") || c.Request.Method == "HEAD" {
+			c.Next()
+			return
+		}
+		w := &gzipWriter{ResponseWriter: c.Writer, minSize: minSize, level: level, excluded: excluded}
+		c.Writer = w
+		defer w.finish()
+		c.Next()
+	}
+}
+
+// 先缓冲响应体，达到 minSize 后才决定是否压缩，因此小响应不会被压缩
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize  int
+	level    int
+	excluded []string
+
+	buf     bytes.Buffer
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// 流式输出时不再等待阈值，按未压缩输出
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// 供 http.ResponseController 找到底层连接（如长连接流式响应调用 SetWriteDeadline）
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipWriter) decide(large bool) error {
+	w.decided = true
+	h := w.Header()
+	if large && h.Get("Content-Encoding") == "" && !w.isExcluded(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *gzipWriter) isExcluded(contentType string) bool {
+	for _, prefix := range w.excluded {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 处理结束：未达到阈值的响应原样输出，压缩流写入结尾
+func (w *gzipWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
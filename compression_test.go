@@ -0,0 +1,87 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// GET /body?n=<字节数>&type=<内容类型> 返回 n 个字节
+type sizedBodyModule struct{ module.Base }
+
+func (m *sizedBodyModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/body", func(c *gin.Context) {
+		n, _ := strconv.Atoi(c.Query("n"))
+		c.Data(http.StatusOK, c.DefaultQuery("type", "application/json"), []byte(strings.Repeat("a", n)))
+	})
+}
+
+func TestCompression(t *testing.T) {
+	registry.Modules["t_body"] = func() module.Module { return &sizedBodyModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_body") })
+	tests := []struct {
+		name     string
+		cfg      CompressionConfig
+		accept   string
+		size     int
+		ctype    string
+		wantGzip bool
+	}{
+		{"above default threshold", CompressionConfig{Enabled: true}, "gzip", 2048, "", true},
+		{"below default threshold", CompressionConfig{Enabled: true}, "gzip", 100, "", false},
+		{"custom threshold", CompressionConfig{Enabled: true, MinSize: 50}, "gzip, deflate", 100, "", true},
+		{"client without gzip", CompressionConfig{Enabled: true}, "", 2048, "", false},
+		{"default excluded type", CompressionConfig{Enabled: true}, "gzip", 2048, "image/png", false},
+		{"custom excluded type", CompressionConfig{Enabled: true, ExcludedContentTypes: []string{"application/json"}}, "gzip", 2048, "", false},
+		{"disabled by default", CompressionConfig{}, "gzip", 2048, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_body"}}
+			cfg.Server.Compression = tt.cfg
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := "/body?n=" + strconv.Itoa(tt.size)
+			if tt.ctype != "" {
+				target += "&type=" + tt.ctype
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if gzipped := w.Header().Get("Content-Encoding") == "gzip"; gzipped != tt.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip = %v", w.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+			body := io.Reader(w.Body)
+			if tt.wantGzip {
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != tt.size {
+				t.Errorf("decoded body is %d bytes, want %d", len(data), tt.size)
+			}
+		})
+	}
+}
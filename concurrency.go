@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"myapp/module"
+)
+
+const defaultQueueTimeout = time.Second
+
+// 并发上限：max 个请求同时处理，超出的最多 queue 个排队等待空位，最长 timeout；
+// 队列已满或等待超时返回 503
+type concurrencyLimits struct {
+	max     int
+	queue   int
+	timeout time.Duration
+}
+
+// 全局上限取 server.max_inflight / max_queue / queue_timeout
+func serverLimits(s ServerConfig) concurrencyLimits {
+	return newLimits(s.MaxInflight, s.MaxQueue, s.QueueTimeout)
+}
+
+// 模块上限取模块配置中的同名键
+func moduleLimits(cfg module.ModuleConfig) concurrencyLimits {
+	return newLimits(cfg.GetInt("max_inflight", 0), cfg.GetInt("max_queue", 0), cfg.GetDuration("queue_timeout", 0))
+}
+
+// 未设置队列长度时与并发上限相同
+func newLimits(max, queue int, timeout time.Duration) concurrencyLimits {
+	if queue <= 0 {
+		queue = max
+	}
+	if timeout <= 0 {
+		timeout = defaultQueueTimeout
+	}
+	return concurrencyLimits{max: max, queue: queue, timeout: timeout}
+}
+
+type concurrencyLimiter struct {
+	limits   concurrencyLimits
+	slots    chan struct{}
+	waiting  atomic.Int64
+	rejected atomic.Int64
+}
+
+// 返回 limits 对应的限流器：与 prev 的设置相同时沿用 prev，使重载前后的在途请求共用同一组名额；
+// 未设置上限时返回 nil
+func limiterFor(prev *concurrencyLimiter, limits concurrencyLimits) *concurrencyLimiter {
+	if limits.max <= 0 {
+		return nil
+	}
+	if prev != nil && prev.limits == limits {
+		return prev
+	}
+	return &concurrencyLimiter{limits: limits, slots: make(chan struct{}, limits.max)}
+}
+
+func (l *concurrencyLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.acquire(c) {
+			return
+		}
+		defer func() { <-l.slots }()
+		c.Next()
+	}
+}
+
+// 取得一个名额；失败时已写出 503（客户端断开时直接中止），返回 false
+func (l *concurrencyLimiter) acquire(c *gin.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.waiting.Add(1) > int64(l.limits.queue) {
+		l.waiting.Add(-1)
+		l.reject(c, "too many requests in flight")
+		return false
+	}
+	defer l.waiting.Add(-1)
+	timer := time.NewTimer(l.limits.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		l.reject(c, "timed out waiting for a free request slot")
+	case <-c.Request.Context().Done():
+		c.Abort()
+	}
+	return false
+}
+
+func (l *concurrencyLimiter) reject(c *gin.Context, msg string) {
+	l.rejected.Add(1)
+	c.Header("Retry-After", strconv.Itoa(int(l.limits.timeout.Round(time.Second)/time.Second)+1))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": msg})
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"myapp/module"
+)
+
+// 模块配置 condition：为假时该模块不加载，与 enabled: false 相同。表达式形如
+//
+//	APP_ENV == dev
+//	profile != prod && (REGION == "eu" || FEATURE_X)
+//
+// == / != 左边为环境变量名（profile 表示当前配置 profile，即 -profile 参数或 APP_ENV），右边为字面值，可加引号；
+// 单独的变量名在其值非空时为真；支持 !、&&、|| 与括号。每次加载配置时按当前环境求值
+type condExpr func(lookup func(string) string) bool
+
+func parseCondition(s string) (condExpr, error) {
+	toks, err := condTokens(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return e, nil
+}
+
+// 条件中的变量取值
+func conditionLookup(name string) string {
+	if name == "profile" {
+		return activeProfile
+	}
+	return os.Getenv(name)
+}
+
+// 返回模块的 condition 是否成立，未配置时为真；表达式无效时返回错误
+func (c Config) moduleCondition(name string) (bool, error) {
+	s := module.ModuleConfig(c.Configs[name]).GetString("condition", "")
+	if strings.TrimSpace(s) == "" {
+		return true, nil
+	}
+	e, err := parseCondition(s)
+	if err != nil {
+		return false, fmt.Errorf("module %q: invalid condition %q: %w", name, s, err)
+	}
+	return e(conditionLookup), nil
+}
+
+// 校验各模块的 condition 表达式
+func (c Config) conditionProblems() []error {
+	var problems []error
+	names := make([]string, 0, len(c.Configs))
+	for name := range c.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := c.moduleCondition(name); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+type condToken struct {
+	text   string
+	quoted bool // 带引号的字面值，不能作为运算符或变量名
+}
+
+func condTokens(s string) ([]condToken, error) {
+	var toks []condToken
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')':
+			toks = append(toks, condToken{text: s[i : i+1]})
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||") || strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!="):
+			toks = append(toks, condToken{text: s[i : i+2]})
+			i += 2
+		case ch == '!':
+			toks = append(toks, condToken{text: "!"})
+			i++
+		case ch == '"' || ch == '\'':
+			end := strings.IndexByte(s[i+1:], ch)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, condToken{text: s[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		default:
+			j := i
+			for j < len(s) && isCondWordChar(rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", ch, i)
+			}
+			toks = append(toks, condToken{text: s[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func isCondWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:/", r)
+}
+
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) peek(op string) bool {
+	return p.pos < len(p.toks) && !p.toks[p.pos].quoted && p.toks[p.pos].text == op
+}
+
+func (p *condParser) or() (condExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) string) bool { return l(lookup) || right(lookup) }
+	}
+	return left, nil
+}
+
+func (p *condParser) and() (condExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(lookup func(string) string) bool { return l(lookup) && right(lookup) }
+	}
+	return left, nil
+}
+
+func (p *condParser) unary() (condExpr, error) {
+	if p.peek("!") {
+		p.pos++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(lookup func(string) string) bool { return !e(lookup) }, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+	}
+	name, err := p.word(false)
+	if err != nil {
+		return nil, err
+	}
+	if !p.peek("==") && !p.peek("!=") {
+		return func(lookup func(string) string) bool { return lookup(name) != "" }, nil
+	}
+	negate := p.peek("!=")
+	p.pos++
+	value, err := p.word(true)
+	if err != nil {
+		return nil, err
+	}
+	return func(lookup func(string) string) bool { return (lookup(name) == value) != negate }, nil
+}
+
+// 读取变量名（literal 为 false）或字面值
+func (p *condParser) word(literal bool) (string, error) {
+	if p.pos >= len(p.toks) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	t := p.toks[p.pos]
+	if !t.quoted && !isCondWord(t.text) {
+		return "", fmt.Errorf("unexpected %q", t.text)
+	}
+	if t.quoted && !literal {
+		return "", fmt.Errorf("expected a variable name, got %q", t.text)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func isCondWord(s string) bool {
+	for _, r := range s {
+		if !isCondWordChar(r) {
+			return false
+		}
+	}
+	return s != ""
+}
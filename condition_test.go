@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	env := map[string]string{"APP_ENV": "dev", "REGION": "eu", "FEATURE_X": "1", "EMPTY": ""}
+	lookup := func(name string) string { return env[name] }
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr string
+	}{
+		{"APP_ENV == dev", true, ""},
+		{"APP_ENV == prod", false, ""},
+		{"APP_ENV != prod", true, ""},
+		{`REGION == "eu"`, true, ""},
+		{"REGION == 'us'", false, ""},
+		{"FEATURE_X", true, ""},
+		{"EMPTY", false, ""},
+		{"UNSET", false, ""},
+		{"!UNSET", true, ""},
+		{"APP_ENV == dev && REGION == us", false, ""},
+		{"APP_ENV == prod || FEATURE_X", true, ""},
+		{"APP_ENV != prod && (REGION == us || FEATURE_X)", true, ""},
+		{"!(APP_ENV == dev)", false, ""},
+		{"APP_ENV ==", false, "unexpected end of expression"},
+		{"(APP_ENV == dev", false, "missing )"},
+		{"APP_ENV == dev)", false, `unexpected ")"`},
+		{`REGION == "eu`, false, "unterminated string"},
+		{"APP_ENV = dev", false, "unexpected character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := parseCondition(tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseCondition(%q) error = %v, want %q", tt.expr, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e(lookup); got != tt.want {
+				t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionalModules(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_dbg": nil, "t_dbgui": {"t_dbg"}, "t_user": {"t_dbg"}})
+	dev := map[string]any{"condition": "APP_ENV == dev"}
+	tests := []struct {
+		name    string
+		env     string
+		modules []string
+		configs map[string]map[string]any
+		want    []string
+		wantErr string
+	}{
+		{"present in dev", "dev", []string{"t_a", "t_dbg"}, map[string]map[string]any{"t_dbg": dev}, []string{"t_a", "t_dbg"}, ""},
+		{"absent in prod", "prod", []string{"t_a", "t_dbg"}, map[string]map[string]any{"t_dbg": dev}, []string{"t_a"}, ""},
+		{"absent when unset", "", []string{"t_a", "t_dbg"}, map[string]map[string]any{"t_dbg": dev}, []string{"t_a"}, ""},
+		// 依赖方带同样的条件时一并排除，不报错
+		{"conditional dependent excluded", "prod", []string{"t_a", "t_dbg", "t_dbgui"},
+			map[string]map[string]any{"t_dbg": dev, "t_dbgui": dev}, []string{"t_a"}, ""},
+		{"conditional dependent in dev", "dev", []string{"t_a", "t_dbg", "t_dbgui"},
+			map[string]map[string]any{"t_dbg": dev, "t_dbgui": dev}, []string{"t_a", "t_dbg", "t_dbgui"}, ""},
+		{"unconditional dependent", "prod", []string{"t_a", "t_dbg", "t_user"},
+			map[string]map[string]any{"t_dbg": dev}, nil, `"t_dbg" is disabled but required by "t_user"`},
+		{"invalid condition", "dev", []string{"t_a", "t_dbg"},
+			map[string]map[string]any{"t_dbg": {"condition": "APP_ENV = dev"}}, nil, `module "t_dbg": invalid condition "APP_ENV = dev"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.env)
+			cfg := Config{Modules: tt.modules, Configs: tt.configs}
+			var problems []error
+			captureStdout(t, func() { problems = cfg.Validate() })
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			var err error
+			captureStdout(t, func() { _, err = m.Update(context.Background(), cfg) })
+			if tt.wantErr != "" {
+				if err == nil && len(problems) == 0 {
+					t.Fatalf("config accepted, want error %q", tt.wantErr)
+				}
+				if err == nil {
+					err = problems[0]
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) > 0 {
+				t.Errorf("Validate() = %v, want no problems", problems)
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+
+	"myapp/module"
+	"myapp/registry"
+	"myapp/utils"
+)
+
+const configFile = "config.yaml"
+
+type Config struct {
+	Include []string                  `yaml:"include"` // 引入其他配置片段，支持通配符，相对路径基于当前文件所在目录
+	Modules []string                  `yaml:"modules"`
+	Configs map[string]map[string]any `yaml:"configs"`
+	Server  ServerConfig              `yaml:"server"`
+	Logging LoggingConfig             `yaml:"logging"`
+
+	// 具名中间件链：链名 -> 提供中间件的模块，模块配置中以 use: [链名] 引用，无需声明依赖
+	Chains map[string][]string `yaml:"chains"`
+
+	// 按环境区分的模块集合，由 -profile 参数或 APP_ENV 选择
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// 为 true 时 Validate 发现的问题作为加载错误，否则只打印警告
+	Strict bool `yaml:"strict"`
+
+	// 模块配置目录，其中的 <模块名>.yaml 覆盖合并到 configs.<模块名>；相对路径基于主配置文件所在目录，默认 config.d
+	ModuleConfigDir string `yaml:"module_config_dir"`
+
+	// 启动时在任何模块 Init 之前依次执行一次的钩子（如数据库迁移），任一失败则启动中止；默认无
+	StartupHooks []StartupHook `yaml:"startup_hooks"`
+}
+
+const defaultModuleConfigDir = "config.d"
+
+// Profile 在基础配置之上追加模块并覆盖配置项
+type Profile struct {
+	Modules []string                  `yaml:"modules"`
+	Configs map[string]map[string]any `yaml:"configs"`
+}
+
+// 单个配置文件的最大字节数与模块配置的最大嵌套层数，可通过环境变量调整
+var (
+	maxConfigSize  = envInt64("CONFIG_MAX_BYTES", 10<<20)
+	maxConfigDepth = int(envInt64("CONFIG_MAX_DEPTH", 64))
+)
+
+func envInt64(key string, def int64) int64 {
+	if n, err := strconv.ParseInt(os.Getenv(key), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// 当前选择的 profile；main 中可由 -profile 参数覆盖，默认取 APP_ENV
+var activeProfile = os.Getenv("APP_ENV")
+
+// 未配置 server.trusted_proxies 时只信任本机回环地址
+var defaultTrustedProxies = []string{"127.0.0.1", "::1"}
+
+// 服务器配置
+type ServerConfig struct {
+	Addr string `yaml:"addr"` // 监听地址，默认 :8080
+	// 多个监听地址（如内网与外网网卡各一个，IPv6 写作 [::1]:8080），设置后忽略 addr
+	Listen []string `yaml:"listen"`
+	// 设置后改为监听 Unix socket（忽略 addr），unix_socket_mode 为八进制权限如 "0660"
+	UnixSocket     string            `yaml:"unix_socket"`
+	UnixSocketMode string            `yaml:"unix_socket_mode"`
+	TLS            *TLSConfig        `yaml:"tls"`
+	Watch          []string          `yaml:"watch"`          // 额外监听的文件、目录或通配符
+	WatchDebounce  time.Duration     `yaml:"watch_debounce"` // 变更事件合并窗口，默认 200ms
+	WatchEnabled   *bool             `yaml:"watch_enabled"`  // 为 false 时不监听配置变更（如只读的不可变部署），默认开启
+	AdminToken     string            `yaml:"admin_token"`    // 管理端点的 Bearer Token，为空则不启用
+	DrainTimeout   time.Duration     `yaml:"drain_timeout"`  // 移除模块时等待在途请求的最长时间，默认 5s
+	CORS           *CORSConfig       `yaml:"cors"`
+	Compression    CompressionConfig `yaml:"compression"`
+	// 不启用 TLS 时通过 h2c 提供明文 HTTP/2（如前置代理使用 h2c 回源）
+	HTTP2Cleartext bool   `yaml:"http2_cleartext"`
+	Pprof          bool   `yaml:"pprof"`       // 非开发模式下也启用 /debug/pprof，启动时生效
+	PprofToken     string `yaml:"pprof_token"` // 访问 pprof 所需的 Bearer Token，为空则不校验
+	// 信任其 X-Forwarded-For / X-Real-IP 头的代理（IP 或 CIDR），未设置时只信任本机
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// GET /admin/config 中需要隐藏值的键名正则，默认 (?i)password|secret|token
+	RedactPattern string `yaml:"redact_pattern"`
+	// 请求头最大字节数（默认 1MB）与请求体最大字节数（超出返回 413，0 表示不限制）
+	MaxHeaderBytes int   `yaml:"max_header_bytes"`
+	MaxBodyBytes   int64 `yaml:"max_body_bytes"`
+	// 管理端点（/healthz、/readyz、/version、/admin/*、/debug/pprof）的公共前缀，如 /ops；
+	// admin_enabled 为 false 时不注册这些端点，默认开启
+	AdminPrefix  string `yaml:"admin_prefix"`
+	AdminEnabled *bool  `yaml:"admin_enabled"`
+	// panic 的 JSON 错误响应中是否附带调用栈，默认仅在开发模式（gin DebugMode）下附带
+	ErrorStack *bool `yaml:"error_stack"`
+	// 收到 SIGINT / SIGTERM 后等待在途请求结束的最长时间，超时后强制关闭连接，默认 15s
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// gin 运行模式 debug | release | test，优先于 APP_ENV，启动时生效
+	GinMode string `yaml:"gin_mode"`
+	// /admin/reloads 保留的最近重载记录条数，默认 20
+	ReloadHistory int `yaml:"reload_history"`
+	// 为 true 时配置文件中的未知字段（顶层、server 等）以及模块 schema 之外的模块配置键视为错误
+	StrictConfig bool `yaml:"strict_config"`
+	// 管理端点的认证后端（token / basic / ip），未配置时使用 admin_token
+	AdminAuth *AdminAuthConfig `yaml:"admin_auth"`
+	// 覆盖 GOMAXPROCS，重载时生效；0 表示使用启动时的默认值
+	MaxProcs int `yaml:"max_procs"`
+	// 404 响应中是否列出已注册的路由，默认仅在开发模式（gin DebugMode）下列出
+	ListRoutesOn404 *bool `yaml:"list_routes_on_404"`
+	// http.Server 的超时，0 表示不限制。与 max_header_bytes、tls 一样可在重载时变更：
+	// 新配置在原有监听上换用新 server 生效，端口不会释放
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// 是否把 /user/ 重定向到 /user（默认开启），以及是否按大小写不敏感、清理后的路径重定向（默认关闭），与 gin 默认一致
+	RedirectTrailingSlash *bool `yaml:"redirect_trailing_slash"`
+	RedirectFixedPath     *bool `yaml:"redirect_fixed_path"`
+	// 受保护的模块：重载时若会移除其中处于激活状态的模块则拒绝该次重载，防止误删配置下线生产模块
+	ProtectModules []string `yaml:"protect_modules"`
+	// 所有模块路由合计的并发上限（0 表示不限制）；超出时最多 max_queue 个请求（默认等于 max_inflight）
+	// 排队等待 queue_timeout（默认 1s），队列已满或等待超时返回 503。模块配置中的同名键设置单个模块的上限
+	MaxInflight  int           `yaml:"max_inflight"`
+	MaxQueue     int           `yaml:"max_queue"`
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+	// 后台自愈：每隔 self_heal_interval 重试初始化已配置但未激活（初始化失败）的模块，
+	// 仍有失败时间隔翻倍，最长 5 分钟；0 表示关闭
+	SelfHealInterval time.Duration `yaml:"self_heal_interval"`
+	// 一次关闭多个模块（退出或重载移除）时各模块 Shutdown 共享的总时长，默认 30s；
+	// 每个模块至少可用其中的 1/n，前面模块未用完的时间顺延给后面的模块
+	ModuleShutdownBudget time.Duration `yaml:"module_shutdown_budget"`
+	// 请求直接交给模块路由而不经过外层 gin 引擎（pprof 除外），减少每个请求一次路由匹配；启动时生效
+	DirectRouting bool `yaml:"direct_routing"`
+	// 配置监听触发的两次重载之间的最小间隔（在 watch_debounce 之外），期间的变更合并为一次重载；0 表示不限制
+	MinReloadInterval time.Duration `yaml:"min_reload_interval"`
+	// 重载后激活的模块数不得少于该值，否则拒绝重载并保留当前状态（防止配置文件被截断）；0 表示不限制
+	MinActiveModules int `yaml:"min_active_modules"`
+	// 具名监听：名称 -> 地址，在 listen / addr 之外另行监听；模块配置 listener 后只在该监听上可访问。变更需重启生效
+	Listeners map[string]string `yaml:"listeners"`
+	// 同时交给模块路由处理的请求上限（含管理端点），超出时立即返回 503；0 表示不限制
+	MaxDispatch int `yaml:"max_dispatch"`
+	// leader 选举的锁文件：取得该文件排他锁的副本为 leader，只有 leader 启动 ClusterSingleton 模块；为空时本实例始终为 leader。变更需重启生效
+	LeaderLock string `yaml:"leader_lock"`
+}
+
+func (s ServerConfig) watchEnabled() bool {
+	return s.WatchEnabled == nil || *s.WatchEnabled
+}
+
+func (s ServerConfig) redirectTrailingSlash() bool {
+	return s.RedirectTrailingSlash == nil || *s.RedirectTrailingSlash
+}
+
+func (s ServerConfig) adminEnabled() bool {
+	return s.AdminEnabled == nil || *s.AdminEnabled
+}
+
+// 规范化 admin_prefix：以 / 开头、不以 / 结尾，未配置或为 / 时返回空串
+func (s ServerConfig) adminPrefix() string {
+	if s.AdminPrefix == "" {
+		return ""
+	}
+	p := path.Clean("/" + s.AdminPrefix)
+	if p == "/" {
+		return ""
+	}
+	return p
+}
+
+func (s ServerConfig) errorStack() bool {
+	if s.ErrorStack != nil {
+		return *s.ErrorStack
+	}
+	return gin.IsDebugging()
+}
+
+func (s ServerConfig) listRoutesOn404() bool {
+	if s.ListRoutesOn404 != nil {
+		return *s.ListRoutesOn404
+	}
+	return gin.IsDebugging()
+}
+
+func (s ServerConfig) shutdownTimeout() time.Duration {
+	if s.ShutdownTimeout <= 0 {
+		return 15 * time.Second
+	}
+	return s.ShutdownTimeout
+}
+
+func (s ServerConfig) moduleShutdownBudget() time.Duration {
+	if s.ModuleShutdownBudget <= 0 {
+		return defaultModuleShutdownBudget
+	}
+	return s.ModuleShutdownBudget
+}
+
+// 移除模块时等待在途请求的最长时间，未配置时为 5s
+func (s ServerConfig) drainTimeout() time.Duration {
+	if s.DrainTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return s.DrainTimeout
+}
+
+// TLS 配置：设置后使用 HTTPS 监听，证书文件变更时自动热加载
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// 模块是否启用：配置块中 enabled: false 表示暂时禁用，缺省为启用
+// enabled 为 false 或 condition 不成立（含表达式无效）时模块不加载
+func (c Config) moduleEnabled(name string) bool {
+	if !module.ModuleConfig(c.Configs[name]).GetBool("enabled", true) {
+		return false
+	}
+	ok, _ := c.moduleCondition(name)
+	return ok
+}
+
+// 返回 Modules 中启用的模块
+func (c Config) enabledModules() []string {
+	names := make([]string, 0, len(c.Modules))
+	for _, name := range c.Modules {
+		switch ok, err := c.moduleCondition(name); {
+		case !module.ModuleConfig(c.Configs[name]).GetBool("enabled", true):
+			fmt.Println("Module disabled by config:", name)
+		case err != nil:
+			fmt.Println("Module disabled:", err)
+		case !ok:
+			fmt.Printf("Module %s not loaded, condition is false: %s\n", name, c.Configs[name]["condition"])
+		default:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// 依赖解析会自动引入依赖模块；若被引入的模块已被禁用则报错，而不是让依赖方缺少依赖。
+// instances 为依赖解析时取得的实例，用于读取依赖方的 Deps()
+func (c Config) checkDisabledDeps(ordered []string, instances map[string]module.Module) error {
+	for _, name := range ordered {
+		if c.moduleEnabled(name) {
+			continue
+		}
+		for _, other := range ordered {
+			for _, dep := range instances[other].Deps() {
+				if module.DepName(dep) == name {
+					return fmt.Errorf("module %q is disabled but required by %q", name, other)
+				}
+			}
+		}
+		return fmt.Errorf("module %q is disabled but required by another module", name)
+	}
+	return nil
+}
+
+func loadConfig() (Config, error) {
+	return loadConfigFrom(configFile)
+}
+
+func loadConfigFrom(path string) (Config, error) {
+	return loadConfigFS(osFS{}, path)
+}
+
+// 从任意文件系统读取配置（含 include、profile、环境变量展开与检查），测试时可传入 fstest.MapFS
+func loadConfigFS(fsys fs.FS, path string) (Config, error) {
+	cfg, err := readRawConfigFS(fsys, path)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg, err = expandConfig(cfg); err != nil {
+		return Config{}, err
+	}
+	// 检查用的模块实例（读取依赖与 schema），两项检查共用，每个模块只创建一次
+	probes := make(map[string]module.Module)
+	if cfg.Server.StrictConfig {
+		if unknown := cfg.unknownModuleKeys(probes); len(unknown) > 0 {
+			return Config{}, fmt.Errorf("%s: %w", path, errors.Join(unknown...))
+		}
+	}
+	if problems := cfg.validate(probes); len(problems) > 0 {
+		if cfg.Strict {
+			return Config{}, fmt.Errorf("%s: %w", path, errors.Join(problems...))
+		}
+		// 输出到 stderr，不影响 dump 的标准输出
+		reportErrors(problems, true)
+	}
+	return cfg, nil
+}
+
+// Validate 检查配置的整体一致性：modules 中的重复项与未知模块、不会被加载的模块配置块、
+// 缺少模块 ConfigSchema 中 required 的配置项
+func (c Config) Validate() []error {
+	return c.validate(make(map[string]module.Module))
+}
+
+// probes 中已有的实例直接用于读取依赖与 schema，缺少的模块创建一次并加入 probes
+func (c Config) validate(probes map[string]module.Module) []error {
+	var problems []error
+	known := make([]string, 0, len(c.Modules))
+	listed := make(map[string]bool, len(c.Modules))
+	for _, name := range c.Modules {
+		// 重复项在依赖解析时会被合并，这里显式提示，避免掩盖拼写或合并错误
+		if listed[name] {
+			problems = append(problems, fmt.Errorf("module %q is listed more than once", name))
+			continue
+		}
+		listed[name] = true
+		if _, ok := registry.Factory(name); !ok {
+			problems = append(problems, &module.UnknownModuleError{Name: name})
+			continue
+		}
+		known = append(known, name)
+	}
+
+	// 依赖自动引入的模块也可以有配置块
+	loaded := make(map[string]bool)
+	if ordered, created, err := resolveModules(known, probes); err == nil {
+		maps.Copy(probes, created)
+		for _, name := range ordered {
+			loaded[name] = true
+		}
+		if _, err := modulePrefixes(ordered, c.Configs); err != nil {
+			problems = append(problems, err)
+		}
+	} else {
+		problems = append(problems, err)
+	}
+
+	names := make([]string, 0, len(c.Configs))
+	for name := range c.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !loaded[name] {
+			problems = append(problems, fmt.Errorf("config block for %q has no effect: module is not in modules", name))
+		}
+	}
+
+	problems = append(problems, c.chainProblems()...)
+	problems = append(problems, c.listenerProblems()...)
+	problems = append(problems, c.hookProblems()...)
+	problems = append(problems, c.conditionProblems()...)
+	return append(problems, c.missingRequired(known, probes)...)
+}
+
+// 检查模块 ConfigSchema 中 required 的配置项是否都已设置；instances 中没有的模块临时创建实例读取 schema
+func (c Config) missingRequired(names []string, instances map[string]module.Module) []error {
+	var problems []error
+	for _, name := range names {
+		mod, ok := instances[name]
+		if !ok {
+			mod = factory(name)()
+		}
+		sp, ok := mod.(module.SchemaProvider)
+		if !ok {
+			continue
+		}
+		// 模块声明了默认值的键视为已提供
+		effective := module.WithDefaults(mod, c.Configs[name])
+		required, _ := sp.ConfigSchema()["required"].([]string)
+		for _, key := range required {
+			if _, ok := effective[key]; !ok {
+				problems = append(problems, fmt.Errorf("module %q requires config key %q", name, key))
+			}
+		}
+	}
+	return problems
+}
+
+// 模块配置块中既不在模块 schema 也不是管理器通用配置项的键；未提供 schema 的模块不检查。
+// probes 中没有的模块创建实例读取 schema 并加入 probes
+func (c Config) unknownModuleKeys(probes map[string]module.Module) []error {
+	names := make([]string, 0, len(c.Configs))
+	for name := range c.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []error
+	for _, name := range names {
+		mod, ok := probes[name]
+		if !ok {
+			f := factory(name)
+			if f == nil {
+				continue
+			}
+			mod = f()
+			probes[name] = mod
+		}
+		sp, ok := mod.(module.SchemaProvider)
+		if !ok {
+			continue
+		}
+		props, _ := sp.ConfigSchema()["properties"].(map[string]any)
+		keys := make([]string, 0, len(c.Configs[name]))
+		for key := range c.Configs[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := props[key]; ok {
+				continue
+			}
+			if _, ok := managedConfigSchema[key]; ok {
+				continue
+			}
+			problems = append(problems, fmt.Errorf("configs.%s: unknown key %q", name, key))
+		}
+	}
+	return problems
+}
+
+// 读取配置、合并 include 并应用 profile，不展开环境变量；dump --raw 用它排查变量未生效的问题
+func loadRawConfig() (Config, error) {
+	return readRawConfig(configFile)
+}
+
+func readRawConfig(path string) (Config, error) {
+	return readRawConfigFS(osFS{}, path)
+}
+
+func readRawConfigFS(fsys fs.FS, path string) (Config, error) {
+	cfg, err := readConfigFile(fsys, path, map[string]bool{}, false)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := applyModuleFiles(fsys, path, &cfg); err != nil {
+		return Config{}, err
+	}
+	applyProfile(&cfg, activeProfile)
+	return cfg, nil
+}
+
+// 主配置文件 path 对应的模块配置目录
+func moduleConfigDir(path string, cfg Config) string {
+	dir := cfg.ModuleConfigDir
+	if dir == "" {
+		dir = defaultModuleConfigDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(path), dir)
+	}
+	return dir
+}
+
+// 读取模块配置目录中的 <模块名>.yaml，逐键覆盖主配置中的 configs.<模块名>；目录不存在时不做改动。
+// 文件内容只包含该模块的配置项，与主配置一样在之后展开环境变量，profile 仍在其后生效
+func applyModuleFiles(fsys fs.FS, path string, cfg *Config) error {
+	matches, err := fs.Glob(fsys, filepath.Join(moduleConfigDir(path, *cfg), "*.yaml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for _, f := range matches {
+		data, err := readLimited(fsys, f, maxConfigSize)
+		if err != nil {
+			return err
+		}
+		var values map[string]any
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return newConfigParseError(f, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".yaml")
+		if cfg.Configs == nil {
+			cfg.Configs = map[string]map[string]any{}
+		}
+		merged := make(map[string]any, len(cfg.Configs[name])+len(values))
+		for k, v := range cfg.Configs[name] {
+			merged[k] = v
+		}
+		for k, v := range values {
+			merged[k] = v
+		}
+		cfg.Configs[name] = merged
+	}
+	return nil
+}
+
+// 应用 profile：追加其中未出现过的模块，并逐键覆盖模块配置；未定义该 profile 时不做改动
+func applyProfile(cfg *Config, name string) {
+	profile, ok := cfg.Profiles[name]
+	if name == "" || !ok {
+		return
+	}
+	seen := make(map[string]bool, len(cfg.Modules))
+	for _, m := range cfg.Modules {
+		seen[m] = true
+	}
+	for _, m := range profile.Modules {
+		if !seen[m] {
+			cfg.Modules = append(cfg.Modules, m)
+			seen[m] = true
+		}
+	}
+	if cfg.Configs == nil {
+		cfg.Configs = map[string]map[string]any{}
+	}
+	for mod, values := range profile.Configs {
+		merged := make(map[string]any, len(cfg.Configs[mod])+len(values))
+		for k, v := range cfg.Configs[mod] {
+			merged[k] = v
+		}
+		for k, v := range values {
+			merged[k] = v
+		}
+		cfg.Configs[mod] = merged
+	}
+}
+
+// 展开配置中的 ${VAR} / ${file:...} 引用，再解析 ${config.<module>.<key>} 模块间引用
+func expandConfig(cfg Config) (Config, error) {
+	newCfg := cfg
+	newCfg.Configs = map[string]map[string]any{}
+	newCfg.Server.AdminToken = utils.ExpandEnv(cfg.Server.AdminToken)
+	newCfg.Server.PprofToken = utils.ExpandEnv(cfg.Server.PprofToken)
+	if a := cfg.Server.AdminAuth; a != nil {
+		expanded := *a
+		expanded.Token = utils.ExpandEnv(a.Token)
+		expanded.Users = make(map[string]string, len(a.Users))
+		for user, pass := range a.Users {
+			expanded.Users[user] = utils.ExpandEnv(pass)
+		}
+		newCfg.Server.AdminAuth = &expanded
+	}
+	for k, v := range cfg.Configs {
+		expanded, err := utils.ExpandConfigDepth(v, maxConfigDepth)
+		if err != nil {
+			return Config{}, fmt.Errorf("configs.%s: %w", k, err)
+		}
+		if m, ok := expanded.(map[string]any); ok {
+			newCfg.Configs[k] = m
+		}
+	}
+	// 环境变量展开后再解析模块间的 ${config.<module>.<key>} 引用
+	if err := utils.ExpandRefs(newCfg.Configs); err != nil {
+		return Config{}, err
+	}
+	return newCfg, nil
+}
+
+// 读取配置文件并处理 include；stack 记录当前引入链，用于检测循环引入。
+// strict 为 true（或本文件设置了 server.strict_config）时，本文件及其引入的文件中出现未知字段即报错
+func readConfigFile(fsys fs.FS, path string, stack map[string]bool, strict bool) (Config, error) {
+	key, err := fsKey(fsys, path)
+	if err != nil {
+		return Config{}, err
+	}
+	if stack[key] {
+		return Config{}, fmt.Errorf("include cycle detected at %s", path)
+	}
+	stack[key] = true
+	defer delete(stack, key)
+
+	data, err := readLimited(fsys, path, maxConfigSize)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("%w: %w", ErrConfigNotFound, err)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	if isTemplated(path) {
+		if data, err = renderConfigTemplate(path, data); err != nil {
+			return Config{}, err
+		}
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, newConfigParseError(path, err)
+	}
+	strict = strict || cfg.Server.StrictConfig
+	if strict {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&Config{}); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, newConfigParseError(path, err)
+		}
+	}
+
+	base := filepath.Dir(path)
+	for _, pattern := range cfg.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(base, pattern)
+		}
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return Config{}, fmt.Errorf("include %q: %w", pattern, err)
+		}
+		sort.Strings(matches)
+		for _, f := range matches {
+			frag, err := readConfigFile(fsys, f, stack, strict)
+			if err != nil {
+				return Config{}, err
+			}
+			mergeFragment(&cfg, frag)
+		}
+	}
+	return cfg, nil
+}
+
+// 配置文件读取失败的类别，可用 errors.Is 区分文件不存在与内容无法解析
+var (
+	ErrConfigNotFound = errors.New("config file not found")
+	ErrConfigParse    = errors.New("config parse error")
+)
+
+// ConfigParseError 为 YAML 解析失败的详情；Line、Column 取自 yaml 的错误信息，未给出时为 0
+type ConfigParseError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ConfigParseError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *ConfigParseError) Unwrap() []error { return []error{ErrConfigParse, e.Err} }
+
+var yamlPosPattern = regexp.MustCompile(`line (\d+)(?:, column (\d+))?`)
+
+func newConfigParseError(path string, err error) error {
+	pe := &ConfigParseError{Path: path, Err: err}
+	if m := yamlPosPattern.FindStringSubmatch(err.Error()); m != nil {
+		pe.Line, _ = strconv.Atoi(m[1])
+		pe.Column, _ = strconv.Atoi(m[2])
+	}
+	return pe
+}
+
+// 读取文件，超过 limit 字节时报错而不是整个读入内存
+func readLimited(fsys fs.FS, path string, limit int64) ([]byte, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s: config file exceeds %d bytes (CONFIG_MAX_BYTES)", path, limit)
+	}
+	return data, nil
+}
+
+// 本地文件系统：与 os.DirFS 不同，接受绝对路径和 ".." 开头的相对路径
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)     { return os.Open(name) }
+func (osFS) ReadFile(name string) ([]byte, error)  { return os.ReadFile(name) }
+func (osFS) Glob(pattern string) ([]string, error) { return filepath.Glob(pattern) }
+
+// 检测循环引入用的文件标识：本地文件取绝对路径，其他文件系统取规范化路径
+func fsKey(fsys fs.FS, path string) (string, error) {
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Abs(path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// 合并配置片段：追加未出现过的模块；片段中的配置项只补充当前文件未设置的键
+func mergeFragment(cfg *Config, frag Config) {
+	seen := make(map[string]bool, len(cfg.Modules))
+	for _, name := range cfg.Modules {
+		seen[name] = true
+	}
+	for _, name := range frag.Modules {
+		if !seen[name] {
+			cfg.Modules = append(cfg.Modules, name)
+			seen[name] = true
+		}
+	}
+
+	if cfg.Configs == nil {
+		cfg.Configs = map[string]map[string]any{}
+	}
+	for name, values := range frag.Configs {
+		dst, ok := cfg.Configs[name]
+		if !ok {
+			dst = map[string]any{}
+			cfg.Configs[name] = dst
+		}
+		for k, v := range values {
+			if _, exists := dst[k]; !exists {
+				dst[k] = v
+			}
+		}
+	}
+}
+
+func (s ServerConfig) trustedProxies() []string {
+	if s.TrustedProxies == nil {
+		return defaultTrustedProxies
+	}
+	return s.TrustedProxies
+}
+
+// 校验 trusted_proxies 中的每一项都是 IP 或 CIDR
+func (s ServerConfig) validateTrustedProxies() error {
+	for _, p := range s.TrustedProxies {
+		if net.ParseIP(p) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(p); err != nil {
+			return fmt.Errorf("server.trusted_proxies: invalid IP or CIDR %q", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,754 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"myapp/utils"
+)
+
+// 每个模块名出现的次数
+func countEach(names []string) map[string]int {
+	counts := make(map[string]int)
+	for _, name := range names {
+		counts[name]++
+	}
+	return counts
+}
+
+func TestConfigChecksBuildEachModuleOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want map[string]int // 加载配置期间各模块的创建次数
+	}{
+		{
+			name: "listed modules",
+			yaml: "modules: [t_a, t_b]\n",
+			want: map[string]int{"t_a": 1, "t_b": 1},
+		},
+		{
+			name: "dependency pulled in",
+			yaml: "modules: [t_c]\n",
+			want: map[string]int{"t_a": 1, "t_c": 1},
+		},
+		{
+			name: "strict config with config blocks",
+			yaml: "modules: [t_c]\nserver:\n  strict_config: true\nconfigs:\n  t_c:\n    enabled: true\n  t_a:\n    enabled: true\n",
+			want: map[string]int{"t_a": 1, "t_c": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_c": {"t_a"}})
+			fsys := fstest.MapFS{"config.yaml": {Data: []byte(tt.yaml)}}
+			cfg, err := loadConfigFS(fsys, "config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := countEach(events.with("build "))
+			if len(got) != len(tt.want) {
+				t.Fatalf("built during load = %v, want %v", got, tt.want)
+			}
+			for name, n := range tt.want {
+				if got[name] != n {
+					t.Errorf("%s built %d times during load, want %d", name, got[name], n)
+				}
+			}
+
+			// 首次构建只为每个模块创建一个实例（即被初始化的那个），相同配置的重载复用实例
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			for i := 0; i < 2; i++ {
+				if _, err := m.Update(context.Background(), cfg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got = countEach(events.with("build "))
+			for name, n := range tt.want {
+				if got[name] != n+1 {
+					t.Errorf("%s built %d times after load and two updates, want %d", name, got[name], n+1)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsDisabledDependency(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_c": {"t_a"}})
+	cfg := Config{Modules: []string{"t_c"}, Configs: map[string]map[string]any{"t_a": {"enabled": false}}}
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	_, err := m.Update(context.Background(), cfg)
+	if err == nil || err.Error() != `module "t_a" is disabled but required by "t_c"` {
+		t.Fatalf("Update error = %v", err)
+	}
+}
+
+func TestEnabledFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []string
+		configs map[string]map[string]any
+		want    []string // 激活的模块
+		wantErr string
+	}{
+		{"enabled by default", []string{"t_a", "t_leaf"}, nil, []string{"t_a", "t_leaf"}, ""},
+		{"explicitly enabled", []string{"t_a", "t_leaf"}, map[string]map[string]any{"t_leaf": {"enabled": true}}, []string{"t_a", "t_leaf"}, ""},
+		{"disabled leaf", []string{"t_a", "t_leaf"}, map[string]map[string]any{"t_leaf": {"enabled": false}}, []string{"t_a"}, ""},
+		{"disabled as string", []string{"t_a", "t_leaf"}, map[string]map[string]any{"t_leaf": {"enabled": "false"}}, []string{"t_a"}, ""},
+		{"disabled dependent", []string{"t_a", "t_b"}, map[string]map[string]any{"t_b": {"enabled": false}}, []string{"t_a"}, ""},
+		{"disabled required dependency", []string{"t_a", "t_b"}, map[string]map[string]any{"t_a": {"enabled": false}}, nil, `module "t_a" is disabled but required by "t_b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerTestModules(t, map[string][]string{"t_a": nil, "t_b": {"t_a"}, "t_leaf": nil})
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), Config{Modules: tt.modules, Configs: tt.configs})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Update error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+			// 禁用模块的路由不注册
+			for _, name := range []string{"t_a", "t_b", "t_leaf"} {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+				if served := w.Code == http.StatusOK; served != slices.Contains(tt.want, name) {
+					t.Errorf("GET /%s = %d, active = %v", name, w.Code, slices.Contains(tt.want, name))
+				}
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	const yaml = `modules: [auth, order]
+configs:
+  order:
+    dsn: memory://base
+profiles:
+  dev:
+    modules: [debug]
+    configs:
+      order:
+        dsn: memory://dev
+  prod:
+    modules: [cache, order]
+`
+	tests := []struct {
+		profile string
+		want    []string // 激活的模块（按启动顺序）
+		wantDSN string
+	}{
+		{"", []string{"auth", "order"}, "memory://base"},
+		{"dev", []string{"auth", "debug", "order"}, "memory://dev"},
+		{"prod", []string{"auth", "cache", "order"}, "memory://base"},
+		{"missing", []string{"auth", "order"}, "memory://base"},
+	}
+	for _, tt := range tests {
+		t.Run("profile="+tt.profile, func(t *testing.T) {
+			useGlobalRouter(t)
+			old := activeProfile
+			activeProfile = tt.profile
+			t.Cleanup(func() { activeProfile = old })
+			cfg, err := loadConfigFS(fstest.MapFS{"config.yaml": {Data: []byte(yaml)}}, "config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dsn := cfg.Configs["order"]["dsn"]; dsn != tt.wantDSN {
+				t.Errorf("order dsn = %v, want %s", dsn, tt.wantDSN)
+			}
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			if got := manager.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantModules []string
+		wantDSN     string
+		wantErr     string
+	}{
+		{
+			name: "fragment provides the order dsn",
+			files: map[string]string{
+				"config.yaml":        "modules: [order]\ninclude: [modules/*.yaml]\n",
+				"modules/order.yaml": "configs:\n  order:\n    dsn: memory://fragment\n",
+			},
+			wantModules: []string{"order"},
+			wantDSN:     "memory://fragment",
+		},
+		{
+			name: "main file wins over fragments",
+			files: map[string]string{
+				"config.yaml":        "modules: [order]\ninclude: [modules/*.yaml]\nconfigs:\n  order:\n    dsn: memory://main\n",
+				"modules/order.yaml": "configs:\n  order:\n    dsn: memory://fragment\n",
+			},
+			wantModules: []string{"order"},
+			wantDSN:     "memory://main",
+		},
+		{
+			name: "fragments add modules in sorted order",
+			files: map[string]string{
+				"config.yaml":    "modules: [order]\ninclude: [modules/*.yaml]\n",
+				"modules/b.yaml": "modules: [cache]\nconfigs:\n  order:\n    dsn: memory://b\n",
+				"modules/a.yaml": "modules: [user, order]\nconfigs:\n  order:\n    dsn: memory://a\n",
+			},
+			wantModules: []string{"order", "user", "cache"},
+			wantDSN:     "memory://a",
+		},
+		{
+			name: "nested include resolves against the fragment's directory",
+			files: map[string]string{
+				"config.yaml":          "modules: [order]\ninclude: [modules/base.yaml]\n",
+				"modules/base.yaml":    "include: [extra/*.yaml]\n",
+				"modules/extra/x.yaml": "configs:\n  order:\n    dsn: memory://nested\n",
+			},
+			wantModules: []string{"order"},
+			wantDSN:     "memory://nested",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"config.yaml":    "modules: [order]\ninclude: [modules/a.yaml]\n",
+				"modules/a.yaml": "include: [../config.yaml]\n",
+			},
+			wantErr: "include cycle detected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			cfg, err := loadConfigFS(fsys, "config.yaml")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Modules, tt.wantModules) {
+				t.Errorf("modules = %v, want %v", cfg.Modules, tt.wantModules)
+			}
+			if dsn := cfg.Configs["order"]["dsn"]; dsn != tt.wantDSN {
+				t.Errorf("order dsn = %v, want %s", dsn, tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string // Validate 报告的问题
+	}{
+		{
+			name: "consistent",
+			yaml: "modules: [t_c]\nconfigs:\n  t_c: {}\n  t_a: {}\n",
+		},
+		{
+			name: "orphan config block",
+			yaml: "modules: [t_a]\nconfigs:\n  t_b: {}\n",
+			want: []string{`config block for "t_b" has no effect: module is not in modules`},
+		},
+		{
+			name: "unknown module",
+			yaml: "modules: [t_a, t_nope]\n",
+			want: []string{"unknown module: t_nope"},
+		},
+		{
+			name: "listed twice",
+			yaml: "modules: [t_a, t_a]\n",
+			want: []string{`module "t_a" is listed more than once`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_c": {"t_a"}})
+			fsys := fstest.MapFS{"config.yaml": {Data: []byte(tt.yaml)}}
+			cfg, err := loadConfigFS(fsys, "config.yaml")
+			if err != nil {
+				t.Fatalf("non-strict load failed: %v", err)
+			}
+			var got []string
+			for _, p := range cfg.Validate() {
+				got = append(got, p.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+
+			// strict: true 时这些问题使加载失败
+			fsys["config.yaml"].Data = []byte("strict: true\n" + tt.yaml)
+			_, err = loadConfigFS(fsys, "config.yaml")
+			if failed := err != nil; failed != (len(tt.want) > 0) {
+				t.Errorf("strict load error = %v, want failure = %v", err, len(tt.want) > 0)
+			}
+			for _, want := range tt.want {
+				if err != nil && !strings.Contains(err.Error(), want) {
+					t.Errorf("strict load error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateModuleNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"warns by default", "modules: [t_a, t_b, t_a]\n", false},
+		{"fails in strict mode", "strict: true\nmodules: [t_a, t_b, t_a]\n", true},
+	}
+	const problem = `module "t_a" is listed more than once`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+			fsys := fstest.MapFS{"config.yaml": {Data: []byte(tt.yaml)}}
+			var cfg Config
+			var err error
+			stderr := captureStderr(t, func() { cfg, err = loadConfigFS(fsys, "config.yaml") })
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), problem) {
+					t.Fatalf("load error = %v, want %q", err, problem)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(stderr, "Config warning: "+problem) {
+				t.Errorf("stderr = %q, want a warning %q", stderr, problem)
+			}
+			// 重复项仍被合并，模块只初始化一次
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			if _, err := m.Update(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			if got := countEach(events.with("init ")); got["t_a"] != 1 || got["t_b"] != 1 {
+				t.Errorf("init counts = %v, want each module once", got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFS(t *testing.T) {
+	t.Setenv("T_DSN", "postgres://db/orders")
+	t.Setenv("T_LIMIT", "25")
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantErr  error  // 与 errors.Is 比较
+		errText  string // 错误信息应包含的内容
+		wantDSN  any
+		wantKeys map[string]any // configs.order 中其他键的期望值
+	}{
+		{
+			name:     "env expansion",
+			files:    map[string]string{"config.yaml": "modules: [order]\nconfigs:\n  order:\n    dsn: ${T_DSN}\n    limit: ${T_LIMIT:int}\n"},
+			wantDSN:  "postgres://db/orders",
+			wantKeys: map[string]any{"limit": 25},
+		},
+		{
+			name:    "env default",
+			files:   map[string]string{"config.yaml": "modules: [order]\nconfigs:\n  order:\n    dsn: ${T_UNSET_DSN:memory://fallback}\n"},
+			wantDSN: "memory://fallback",
+		},
+		{
+			name: "module config dir overrides main file",
+			files: map[string]string{
+				"config.yaml":         "modules: [order]\nconfigs:\n  order:\n    dsn: memory://main\n    pool: 4\n",
+				"config.d/order.yaml": "dsn: ${T_DSN}\n",
+			},
+			wantDSN:  "postgres://db/orders",
+			wantKeys: map[string]any{"pool": 4},
+		},
+		{
+			name:    "invalid yaml",
+			files:   map[string]string{"config.yaml": "modules: [order\n"},
+			errText: "config.yaml",
+		},
+		{
+			name:    "missing file",
+			files:   map[string]string{},
+			wantErr: ErrConfigNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			cfg, err := loadConfigFS(fsys, "config.yaml")
+			if tt.wantErr != nil || tt.errText != "" {
+				if err == nil {
+					t.Fatal("load succeeded, want an error")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := cfg.Configs["order"]
+			if order["dsn"] != tt.wantDSN {
+				t.Errorf("dsn = %v, want %v", order["dsn"], tt.wantDSN)
+			}
+			for k, want := range tt.wantKeys {
+				if order[k] != want {
+					t.Errorf("%s = %#v, want %#v", k, order[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestModuleConfigFiles(t *testing.T) {
+	t.Setenv("T_SIDECAR_DSN", "postgres://db/sidecar")
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    map[string]any // configs.order
+		errText string
+	}{
+		{"sidecar provides dsn", map[string]string{
+			"config.yaml":         "modules: [order]\n",
+			"config.d/order.yaml": "dsn: memory://sidecar\n",
+		}, map[string]any{"dsn": "memory://sidecar"}, ""},
+		{"merged over inline config", map[string]string{
+			"config.yaml":         "modules: [order]\nconfigs:\n  order:\n    dsn: memory://inline\n    pool: 4\n",
+			"config.d/order.yaml": "dsn: memory://sidecar\n",
+		}, map[string]any{"dsn": "memory://sidecar", "pool": 4}, ""},
+		{"env expanded", map[string]string{
+			"config.yaml":         "modules: [order]\n",
+			"config.d/order.yaml": "dsn: ${T_SIDECAR_DSN}\n",
+		}, map[string]any{"dsn": "postgres://db/sidecar"}, ""},
+		{"custom directory", map[string]string{
+			"config.yaml":          "modules: [order]\nmodule_config_dir: modules.d\n",
+			"modules.d/order.yaml": "dsn: memory://custom\n",
+			"config.d/order.yaml":  "dsn: memory://ignored\n",
+		}, map[string]any{"dsn": "memory://custom"}, ""},
+		{"no directory", map[string]string{
+			"config.yaml": "modules: [order]\nconfigs:\n  order:\n    dsn: memory://inline\n",
+		}, map[string]any{"dsn": "memory://inline"}, ""},
+		{"invalid sidecar", map[string]string{
+			"config.yaml":         "modules: [order]\n",
+			"config.d/order.yaml": "dsn: [memory\n",
+		}, nil, "config.d/order.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			cfg, err := loadConfigFS(fsys, "config.yaml")
+			if tt.errText != "" {
+				if err == nil || !errors.Is(err, ErrConfigParse) || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("err = %v, want a parse error mentioning %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Configs["order"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configs.order = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// configs.order 下嵌套 depth 层 map 的配置
+func nestedConfig(depth int) string {
+	var b strings.Builder
+	b.WriteString("modules: [order]\nconfigs:\n  order:\n")
+	for i := 0; i < depth; i++ {
+		b.WriteString(strings.Repeat("  ", i+2) + "n:\n")
+	}
+	b.WriteString(strings.Repeat("  ", depth+2) + "leaf: 1\n")
+	return b.String()
+}
+
+func TestConfigLimits(t *testing.T) {
+	oldSize, oldDepth := maxConfigSize, maxConfigDepth
+	maxConfigSize, maxConfigDepth = 4096, 8
+	t.Cleanup(func() { maxConfigSize, maxConfigDepth = oldSize, oldDepth })
+
+	large := "modules: [order]\n#" + strings.Repeat("x", 4096) + "\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr error
+		errText string
+	}{
+		{"within depth limit", map[string]string{"config.yaml": nestedConfig(6)}, nil, ""},
+		{"nesting beyond depth limit", map[string]string{"config.yaml": nestedConfig(20)}, utils.ErrMaxDepth, "configs.order: n.n.n.n.n.n.n"},
+		{"file beyond size limit", map[string]string{"config.yaml": large}, nil, "config.yaml: config file exceeds 4096 bytes (CONFIG_MAX_BYTES)"},
+		{
+			"included file beyond size limit",
+			map[string]string{"config.yaml": "modules: [order]\ninclude: [big.yaml]\n", "big.yaml": large},
+			nil, "big.yaml: config file exceeds 4096 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			_, err := loadConfigFS(fsys, "config.yaml")
+			if tt.wantErr == nil && tt.errText == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("load succeeded, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.errText)
+			}
+		})
+	}
+}
+
+func TestStrictConfig(t *testing.T) {
+	const strict = "server:\n  strict_config: true\n"
+	tests := []struct {
+		name        string
+		files       map[string]string
+		errText     string // 为空表示加载成功
+		wantModules []string
+	}{
+		{
+			name:  "misspelled top-level key ignored when lenient",
+			files: map[string]string{"config.yaml": "module: [order]\n"},
+		},
+		{
+			name:    "misspelled top-level key rejected when strict",
+			files:   map[string]string{"config.yaml": strict + "module: [order]\n"},
+			errText: "field module not found",
+		},
+		{
+			name:        "known keys accepted when strict",
+			files:       map[string]string{"config.yaml": strict + "modules: [order]\nconfigs:\n  order:\n    dsn: memory://x\n    init_retries: 2\n"},
+			wantModules: []string{"order"},
+		},
+		{
+			name:        "unknown module key ignored when lenient",
+			files:       map[string]string{"config.yaml": "modules: [order]\nconfigs:\n  order:\n    dsnn: memory://x\n"},
+			wantModules: []string{"order"},
+		},
+		{
+			name:    "unknown module key rejected when strict",
+			files:   map[string]string{"config.yaml": strict + "modules: [order]\nconfigs:\n  order:\n    dsnn: memory://x\n"},
+			errText: `configs.order: unknown key "dsnn"`,
+		},
+		{
+			// 主文件开启的严格模式同样作用于 include 的文件
+			name: "strict applies to includes",
+			files: map[string]string{
+				"config.yaml": strict + "include: [extra.yaml]\nmodules: [order]\n",
+				"extra.yaml":  "modulez: [user]\n",
+			},
+			errText: "extra.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			var cfg Config
+			var err error
+			captureStderr(t, func() { cfg, err = loadConfigFS(fsys, "config.yaml") })
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Modules, tt.wantModules) {
+				t.Errorf("modules = %v, want %v", cfg.Modules, tt.wantModules)
+			}
+		})
+	}
+}
+
+func TestConfigTemplate(t *testing.T) {
+	t.Setenv("T_WORKERS", "3")
+	t.Setenv("T_BAD", "three")
+	const ranged = `modules:
+{{- range seq (env "T_WORKERS" | atoi) }}
+  - order@w{{ . }}
+{{- end }}
+configs:
+{{- range seq (env "T_WORKERS" | atoi) }}
+  order@w{{ . }}:
+    dsn: memory://w{{ add . 1 }}
+{{- end }}
+`
+	tests := []struct {
+		name        string
+		path        string
+		data        string
+		errText     string
+		wantModules []string
+		wantDSN     map[string]any // 实例 -> configs 中的 dsn
+	}{
+		{
+			name:        "ranged module configs",
+			path:        "config.yaml.tmpl",
+			data:        ranged,
+			wantModules: []string{"order@w0", "order@w1", "order@w2"},
+			wantDSN:     map[string]any{"order@w0": "memory://w1", "order@w1": "memory://w2", "order@w2": "memory://w3"},
+		},
+		{
+			name:        "default helper and env map",
+			path:        "config.yaml.tmpl",
+			data:        "modules: [{{ env \"T_UNSET_MODULE\" | default \"order\" }}]\nconfigs:\n  order:\n    dsn: memory://{{ .Env.T_WORKERS }}\n",
+			wantModules: []string{"order"},
+			wantDSN:     map[string]any{"order": "memory://3"},
+		},
+		{
+			// 非模板文件不做渲染，{{ 原样保留
+			name:        "plain yaml is not rendered",
+			path:        "config.yaml",
+			data:        "modules: [order]\nconfigs:\n  order:\n    dsn: \"memory://{{x}}\"\n",
+			wantModules: []string{"order"},
+			wantDSN:     map[string]any{"order": "memory://{{x}}"},
+		},
+		{
+			name:    "parse error",
+			path:    "config.yaml.tmpl",
+			data:    "modules: [{{ range }]\n",
+			errText: "config.yaml.tmpl: config template: ",
+		},
+		{
+			name:    "missing key",
+			path:    "config.yaml.tmpl",
+			data:    "modules: [{{ .Missing }}]\n",
+			errText: "config.yaml.tmpl: config template: ",
+		},
+		{
+			name:    "helper error",
+			path:    "config.yaml.tmpl",
+			data:    "{{ range seq (env \"T_BAD\" | atoi) }}{{ end }}\n",
+			errText: `invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{tt.path: {Data: []byte(tt.data)}}
+			var cfg Config
+			var err error
+			captureStderr(t, func() { cfg, err = loadConfigFS(fsys, tt.path) })
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Modules, tt.wantModules) {
+				t.Errorf("modules = %v, want %v", cfg.Modules, tt.wantModules)
+			}
+			for name, want := range tt.wantDSN {
+				if got := cfg.Configs[name]["dsn"]; got != want {
+					t.Errorf("configs.%s.dsn = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantErr  error
+		wantPath string // ConfigParseError 的 Path，为空表示不应是解析错误
+		wantLine int
+	}{
+		{"missing file", map[string]string{}, ErrConfigNotFound, "", 0},
+		{"unterminated flow sequence", map[string]string{"config.yaml": "modules: [order\nserver:\n  addr: :80\n"}, ErrConfigParse, "config.yaml", 1},
+		{"tab indentation", map[string]string{"config.yaml": "modules:\n\t- order\n"}, ErrConfigParse, "config.yaml", 2},
+		{"wrong type", map[string]string{"config.yaml": "server:\n  addr: :80\nmodules: {order: 1}\n"}, ErrConfigParse, "config.yaml", 3},
+		// 引入的文件解析失败时报告该文件
+		{"broken include", map[string]string{"config.yaml": "include: [extra.yaml]\n", "extra.yaml": "modules: [order\n"}, ErrConfigParse, "extra.yaml", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			_, err := loadConfigFS(fsys, "config.yaml")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var pe *ConfigParseError
+			if isParse := errors.As(err, &pe); isParse != (tt.wantPath != "") {
+				t.Fatalf("err = %v, want parse error = %v", err, tt.wantPath != "")
+			}
+			if pe == nil {
+				if errors.Is(err, ErrConfigParse) {
+					t.Errorf("missing file also matches ErrConfigParse: %v", err)
+				}
+				return
+			}
+			if pe.Path != tt.wantPath || pe.Line != tt.wantLine {
+				t.Errorf("parse error at %s:%d, want %s:%d (%v)", pe.Path, pe.Line, tt.wantPath, tt.wantLine, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 两次配置之间的差异，随重载记录在 /admin/reloads 中返回
+type ConfigDiff struct {
+	AddedModules   []string       `json:"added_modules,omitempty"`
+	RemovedModules []string       `json:"removed_modules,omitempty"`
+	Changes        []ConfigChange `json:"changes,omitempty"`
+}
+
+// 单个键的变化；Path 使用配置文件中的键名，如 configs.order.dsn、server.read_timeout。
+// 新增的键没有 Old，删除的键没有 New；键名匹配 redact_pattern 的值以 ****** 代替
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+func (d ConfigDiff) empty() bool {
+	return len(d.AddedModules) == 0 && len(d.RemovedModules) == 0 && len(d.Changes) == 0
+}
+
+// 比较新旧配置：启用的模块集合，以及按 YAML 键名展开后的全部键（modules 列表本身除外）
+func diffConfig(old, cfg Config, redact *regexp.Regexp) ConfigDiff {
+	var d ConfigDiff
+	oldMods, newMods := old.enabledModules(), cfg.enabledModules()
+	for _, name := range newMods {
+		if !slices.Contains(oldMods, name) {
+			d.AddedModules = append(d.AddedModules, name)
+		}
+	}
+	for _, name := range oldMods {
+		if !slices.Contains(newMods, name) {
+			d.RemovedModules = append(d.RemovedModules, name)
+		}
+	}
+	a, b := configTree(old), configTree(cfg)
+	delete(a, "modules")
+	delete(b, "modules")
+	diffValues(&d, "", a, b, redact)
+	return d
+}
+
+// 经 YAML 编解码转为通用结构，键名与配置文件一致，时长等类型以配置文件中的写法呈现
+func configTree(cfg Config) map[string]any {
+	tree := map[string]any{}
+	if data, err := yaml.Marshal(cfg); err == nil {
+		yaml.Unmarshal(data, &tree)
+	}
+	return tree
+}
+
+func diffValues(d *ConfigDiff, path string, old, cur any, redact *regexp.Regexp) {
+	om, oldIsMap := old.(map[string]any)
+	nm, newIsMap := cur.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(om)+len(nm))
+		for k := range om {
+			keys = append(keys, k)
+		}
+		for k := range nm {
+			if _, ok := om[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffValues(d, joinPath(path, k), om[k], nm[k], redact)
+		}
+		return
+	}
+	if reflect.DeepEqual(old, cur) {
+		return
+	}
+	change := ConfigChange{Path: path, Old: old, New: cur}
+	if sensitivePath(path, redact) {
+		change.Old, change.New = maskValue(old), maskValue(cur)
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// 路径中任一段匹配 redact_pattern 即视为敏感；basic 认证的密码以用户名为键，同样隐藏
+func sensitivePath(path string, redact *regexp.Regexp) bool {
+	if strings.HasPrefix(path, "server.admin_auth.users.") {
+		return true
+	}
+	for _, seg := range strings.Split(path, ".") {
+		if redact.MatchString(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskValue(v any) any {
+	if v == nil || v == "" {
+		return v
+	}
+	return "******"
+}
+
+// 打印差异，每个变化一行
+func (d ConfigDiff) log() {
+	if len(d.AddedModules) > 0 {
+		fmt.Println("Config diff: added modules", d.AddedModules)
+	}
+	if len(d.RemovedModules) > 0 {
+		fmt.Println("Config diff: removed modules", d.RemovedModules)
+	}
+	for _, c := range d.Changes {
+		switch {
+		case c.Old == nil:
+			fmt.Printf("Config diff: %s added: %v\n", c.Path, c.New)
+		case c.New == nil:
+			fmt.Printf("Config diff: %s removed (was %v)\n", c.Path, c.Old)
+		default:
+			fmt.Printf("Config diff: %s: %v -> %v\n", c.Path, c.Old, c.New)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReloadConfigDiff(t *testing.T) {
+	base := func() Config {
+		return Config{
+			Modules: []string{"order"},
+			Configs: map[string]map[string]any{"order": {"dsn": "memory://a", "db_password": "p1"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		change  func(*Config)
+		want    ConfigDiff
+		wantLog string
+		hidden  string // 不应出现在日志中的值
+	}{
+		{
+			name:    "dsn changed",
+			change:  func(c *Config) { c.Configs["order"]["dsn"] = "memory://b" },
+			want:    ConfigDiff{Changes: []ConfigChange{{Path: "configs.order.dsn", Old: "memory://a", New: "memory://b"}}},
+			wantLog: "Config diff: configs.order.dsn: memory://a -> memory://b",
+		},
+		{
+			name:    "secret changed is redacted",
+			change:  func(c *Config) { c.Configs["order"]["db_password"] = "p2" },
+			want:    ConfigDiff{Changes: []ConfigChange{{Path: "configs.order.db_password", Old: "******", New: "******"}}},
+			wantLog: "Config diff: configs.order.db_password: ****** -> ******",
+			hidden:  "p2",
+		},
+		{
+			name:    "key added",
+			change:  func(c *Config) { c.Configs["order"]["pool"] = 4 },
+			want:    ConfigDiff{Changes: []ConfigChange{{Path: "configs.order.pool", New: 4}}},
+			wantLog: "Config diff: configs.order.pool added: 4",
+		},
+		{
+			name:    "module added",
+			change:  func(c *Config) { c.Modules = append(c.Modules, "user") },
+			want:    ConfigDiff{AddedModules: []string{"user"}},
+			wantLog: "Config diff: added modules [user]",
+		},
+		{
+			name:    "no change",
+			change:  func(*Config) {},
+			want:    ConfigDiff{},
+			wantLog: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			var err error
+			captureStdout(t, func() { _, err = m.Update(context.Background(), base()) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := base()
+			next.Configs = map[string]map[string]any{"order": maps.Clone(next.Configs["order"])}
+			tt.change(&next)
+			out := captureStdout(t, func() { _, err = m.Update(context.Background(), next) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantLog != "" && !strings.Contains(out, tt.wantLog) {
+				t.Errorf("output = %q, want %q", out, tt.wantLog)
+			}
+			if tt.wantLog == "" && strings.Contains(out, "Config diff:") {
+				t.Errorf("output = %q, want no diff", out)
+			}
+			if tt.hidden != "" && strings.Contains(out, tt.hidden) {
+				t.Errorf("output leaks %q: %q", tt.hidden, out)
+			}
+
+			// 同样记录在重载历史中
+			history := m.ReloadHistory()
+			got := history[len(history)-1].Diff
+			if tt.want.empty() {
+				if got != nil && !got.empty() {
+					t.Errorf("diff = %+v, want none", *got)
+				}
+				return
+			}
+			if got == nil || !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("diff = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
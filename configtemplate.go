@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 以 .tmpl 结尾的配置文件（或设置 CONFIG_TEMPLATE=true 时的全部配置文件）在 YAML 解析前
+// 先按 text/template 渲染，可用 range / if 生成重复的模块配置；其余文件只做 ${VAR} 展开
+var templateAll, _ = strconv.ParseBool(os.Getenv("CONFIG_TEMPLATE"))
+
+func isTemplated(path string) bool {
+	return templateAll || strings.HasSuffix(path, ".tmpl")
+}
+
+// 模板中可用的辅助函数
+var configTemplateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	// default 值在前，便于管道写法：{{ env "N" | default "3" }}
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	// seq 3 得到 0 1 2
+	"seq": func(n int) []int {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		return s
+	},
+	"atoi": func(s string) (int, error) {
+		return strconv.Atoi(strings.TrimSpace(s))
+	},
+	"add":   func(a, b int) int { return a + b },
+	"quote": strconv.Quote,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// 模板数据：.Env 为环境变量，.Profile 为当前选择的 profile
+type configTemplateData struct {
+	Env     map[string]string
+	Profile string
+}
+
+// 渲染配置模板；解析与执行错误都带上文件名和行号，渲染结果同样受 CONFIG_MAX_BYTES 限制
+func renderConfigTemplate(path string, data []byte) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(configTemplateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: config template: %w", path, err)
+	}
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, configTemplateData{Env: env, Profile: activeProfile}); err != nil {
+		return nil, fmt.Errorf("%s: config template: %w", path, err)
+	}
+	if int64(buf.Len()) > maxConfigSize {
+		return nil, fmt.Errorf("%s: rendered config exceeds %d bytes (CONFIG_MAX_BYTES)", path, maxConfigSize)
+	}
+	return buf.Bytes(), nil
+}
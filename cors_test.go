@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := &CORSConfig{
+		AllowedOrigins:   []string{"https://app.example"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	tests := []struct {
+		name    string
+		cors    *CORSConfig
+		method  string
+		headers map[string]string
+		code    int
+		want    map[string]string // 空值表示该响应头不应出现
+	}{
+		{
+			name:    "preflight",
+			cors:    cfg,
+			method:  http.MethodOptions,
+			headers: map[string]string{"Origin": "https://app.example", "Access-Control-Request-Method": "POST"},
+			code:    http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:    "simple request",
+			cors:    cfg,
+			method:  http.MethodGet,
+			headers: map[string]string{"Origin": "https://app.example"},
+			code:    http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example",
+				"Access-Control-Expose-Headers": "X-Request-ID",
+				"Access-Control-Allow-Methods":  "",
+			},
+		},
+		{
+			name:    "origin not allowed",
+			cors:    cfg,
+			method:  http.MethodOptions,
+			headers: map[string]string{"Origin": "https://evil.example", "Access-Control-Request-Method": "POST"},
+			code:    http.StatusNotFound,
+			want:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:    "wildcard echoes request headers",
+			cors:    &CORSConfig{AllowedOrigins: []string{"*"}},
+			method:  http.MethodOptions,
+			headers: map[string]string{"Origin": "https://any.example", "Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "X-Custom"},
+			code:    http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Headers":     "X-Custom",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, HEAD",
+				"Access-Control-Allow-Credentials": "",
+				"Vary":                             "",
+			},
+		},
+		{
+			name:    "disabled by default",
+			cors:    nil,
+			method:  http.MethodGet,
+			headers: map[string]string{"Origin": "https://app.example"},
+			code:    http.StatusOK,
+			want:    map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			c.Server.CORS = tt.cors
+			r := newEngine(c)
+			r.GET("/items", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+			req := httptest.NewRequest(tt.method, "/items", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Errorf("status = %d, want %d", w.Code, tt.code)
+			}
+			for k, want := range tt.want {
+				if got := w.Header().Get(k); got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSRejectsCredentialsWithWildcard(t *testing.T) {
+	tests := []struct {
+		cors    *CORSConfig
+		wantErr bool
+	}{
+		{nil, false},
+		{&CORSConfig{AllowedOrigins: []string{"*"}}, false},
+		{&CORSConfig{AllowedOrigins: []string{"https://app.example"}, AllowCredentials: true}, false},
+		{&CORSConfig{AllowedOrigins: []string{"https://app.example", "*"}, AllowCredentials: true}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cors.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) = %v, wantErr %v", tt.cors, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+const defaultDebugIOMaxBytes = 4096
+
+// 模块配置 debug_io: true 时记录该模块每个请求的请求体与响应体，用于排查问题，默认关闭。
+// 每一方最多记录 debug_io_max_bytes 字节（默认 4096）；JSON 与表单中键名匹配 server.redact_pattern 的值以 ****** 代替，
+// 非文本内容只记录类型与大小。请求体只预读记录所需的部分，其余原样交给处理器，不会整体读入内存
+func debugIO(name string, maxBytes int, redact *regexp.Regexp) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = defaultDebugIOMaxBytes
+	}
+	return func(c *gin.Context) {
+		req := c.Request
+		var reqBody []byte
+		reqTruncated := false
+		if req.Body != nil && req.Body != http.NoBody {
+			// 读取出错时 head 为已读到的部分，错误由处理器继续读取时得到
+			head, _ := io.ReadAll(io.LimitReader(req.Body, int64(maxBytes)+1))
+			reqBody, reqTruncated = head, len(head) > maxBytes
+			if reqTruncated {
+				reqBody = head[:maxBytes]
+			}
+			req.Body = readCloser{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+		}
+		w := &captureWriter{ResponseWriter: c.Writer, max: maxBytes}
+		c.Writer = w
+		c.Next()
+
+		prefix := fmt.Sprintf("[%s] debug_io [%s] %s %s", name, module.RequestID(c), req.Method, req.URL.RequestURI())
+		fmt.Printf("%s request: %s\n", prefix, describeBody(req.Header.Get("Content-Type"), reqBody, reqTruncated, redact))
+		fmt.Printf("%s response %d: %s\n", prefix, w.Status(), describeBody(w.Header().Get("Content-Type"), w.buf.Bytes(), w.truncated, redact))
+	}
+}
+
+// 请求体：先读出已预读的部分，再读原始 body 的剩余部分；Close 关闭原始 body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// 记录写出的前 max 字节，写入照常到达客户端；Flush、Hijack 等由内嵌的 gin.ResponseWriter 提供
+type captureWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.capture(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) capture(p []byte) {
+	if room := w.max - w.buf.Len(); room < len(p) {
+		w.truncated = true
+		p = p[:max(room, 0)]
+	}
+	w.buf.Write(p)
+}
+
+// 截断后的 JSON 无法解析，按 "键": "值" 的形式逐个隐藏
+var jsonPairPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+func describeBody(contentType string, body []byte, truncated bool, redact *regexp.Regexp) string {
+	if len(body) == 0 {
+		return "(empty)"
+	}
+	media, _, _ := mime.ParseMediaType(contentType)
+	text := string(body)
+	switch {
+	case media == "application/json" || strings.HasSuffix(media, "+json"):
+		var tree any
+		if !truncated && json.Unmarshal(body, &tree) == nil {
+			data, _ := json.Marshal(redactValue(tree, redact))
+			text = string(data)
+		} else {
+			text = jsonPairPattern.ReplaceAllStringFunc(text, func(pair string) string {
+				m := jsonPairPattern.FindStringSubmatch(pair)
+				if redact.MatchString(m[1]) {
+					return `"` + m[1] + `"` + m[2] + `"******"`
+				}
+				return pair
+			})
+		}
+	case media == "application/x-www-form-urlencoded":
+		// 截断的表单同样逐个字段处理，最后一个字段可能不完整
+		pairs := strings.Split(text, "&")
+		for i, pair := range pairs {
+			key, _, _ := strings.Cut(pair, "=")
+			if k, err := url.QueryUnescape(key); err == nil && redact.MatchString(k) {
+				pairs[i] = key + "=******"
+			}
+		}
+		text = strings.Join(pairs, "&")
+	case media == "" || strings.HasPrefix(media, "text/") || strings.HasSuffix(media, "xml"):
+	default:
+		return fmt.Sprintf("<%d+ bytes of %s>", len(body), media)
+	}
+	if truncated {
+		return fmt.Sprintf("%q (truncated at %d bytes)", text, len(body))
+	}
+	return fmt.Sprintf("%q", text)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+func TestDebugIO(t *testing.T) {
+	registry.Modules["t_echo"] = func() module.Module { return &echoModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_echo") })
+	tests := []struct {
+		name        string
+		config      map[string]any
+		contentType string
+		body        string
+		want        []string // 日志中应出现的内容
+		absent      []string
+	}{
+		{"disabled by default", nil, "text/plain", "hello", nil, []string{"debug_io"}},
+		{"text body", map[string]any{"debug_io": true}, "text/plain", "hello",
+			[]string{`[t_echo] debug_io`, `POST /echo request: "hello"`, `response 200: "hello"`}, nil},
+		{"json redacted", map[string]any{"debug_io": true}, "application/json", `{"user":"ann","password":"hunter2"}`,
+			[]string{`request: "{\"password\":\"******\",\"user\":\"ann\"}"`}, []string{`request: "{\"password\":\"hunter2\"`}},
+		{"form redacted", map[string]any{"debug_io": true}, "application/x-www-form-urlencoded", "token=abc&x=1",
+			[]string{`request: "token=******&x=1"`}, nil},
+		{"truncated", map[string]any{"debug_io": true, "debug_io_max_bytes": 4}, "text/plain", "hello world",
+			[]string{`request: "hell" (truncated at 4 bytes)`}, nil},
+		{"binary body", map[string]any{"debug_io": true}, "application/octet-stream", "\x00\x01\x02",
+			[]string{"request: <3+ bytes of application/octet-stream>"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_echo"}}
+			if tt.config != nil {
+				cfg.Configs = map[string]map[string]any{"t_echo": tt.config}
+			}
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			out := captureStdout(t, func() { r.ServeHTTP(w, req) })
+			// 记录日志不影响处理器读取完整的请求体
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Errorf("POST /echo = %d %q, want 200 %q", w.Code, w.Body, tt.body)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("log is missing %s:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(out, s) {
+					t.Errorf("log contains %s:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// server.max_dispatch 对应的限流：同时交给模块路由处理的请求（含管理端点）上限，在取得当前路由之前检查，
+// 超出时立即返回 503 而不排队，请求洪峰下不会有越来越多的请求压进模块路由。
+// 连接的 goroutine 仍由 net/http 创建，被拒绝的请求只占用很短的时间。未设置时为 nil
+var dispatchSlots atomic.Pointer[dispatchLimiter]
+
+// 因 max_dispatch 被拒绝的请求数，跨重载累计，在 /metrics 中输出
+var dispatchShed atomic.Int64
+
+type dispatchLimiter struct {
+	slots chan struct{}
+}
+
+// 重载时应用 max_dispatch：上限不变时沿用当前的限流器，在途请求继续占用原有名额；
+// 替换后旧限流器上的请求仍释放到旧限流器
+func applyDispatchLimit(server ServerConfig) {
+	cur := dispatchSlots.Load()
+	switch {
+	case server.MaxDispatch <= 0:
+		if cur != nil {
+			fmt.Println("Dispatch limit disabled")
+		}
+		dispatchSlots.Store(nil)
+	case cur == nil || cap(cur.slots) != server.MaxDispatch:
+		fmt.Println("Dispatch limit set to", server.MaxDispatch)
+		dispatchSlots.Store(&dispatchLimiter{slots: make(chan struct{}, server.MaxDispatch)})
+	}
+}
+
+// 取得一个分发名额，未设置上限时总是成功；失败时已写出 503
+func acquireDispatch(w http.ResponseWriter) (release func(), ok bool) {
+	l := dispatchSlots.Load()
+	if l == nil {
+		return func() {}, true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	default:
+	}
+	dispatchShed.Add(1)
+	w.Header().Set("Retry-After", "1")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": "server is overloaded"})
+	return nil, false
+}
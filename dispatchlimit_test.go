@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// GET /t_slow 阻塞到 gate 关闭，记录同时处理中的请求数及其峰值
+type slowModule struct {
+	module.Base
+	gate           chan struct{}
+	inflight, peak atomic.Int64
+}
+
+func (m *slowModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/t_slow", func(c *gin.Context) {
+		n := m.inflight.Add(1)
+		for p := m.peak.Load(); n > p && !m.peak.CompareAndSwap(p, n); p = m.peak.Load() {
+		}
+		<-m.gate
+		m.inflight.Add(-1)
+		c.String(http.StatusOK, "done")
+	})
+}
+
+func TestDispatchLimitShedsExcessRequests(t *testing.T) {
+	const capacity, clients = 4, 24
+	for _, direct := range []bool{false, true} {
+		name := "gin"
+		if direct {
+			name = "direct"
+		}
+		t.Run(name, func(t *testing.T) {
+			slow := &slowModule{gate: make(chan struct{})}
+			registry.Modules["t_slow"] = func() module.Module { return slow }
+			t.Cleanup(func() { delete(registry.Modules, "t_slow") })
+			useGlobalRouter(t)
+			t.Cleanup(func() { dispatchSlots.Store(nil) })
+
+			cfg := Config{Modules: []string{"t_slow"}}
+			cfg.Server.MaxDispatch = capacity
+			cfg.Server.DirectRouting = direct
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			front := frontHandler(cfg.Server, false, "")
+			shedBefore := dispatchShed.Load()
+
+			var wg sync.WaitGroup
+			codes := make(chan int, clients)
+			for i := 0; i < clients; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					w := httptest.NewRecorder()
+					front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t_slow", nil))
+					codes <- w.Code
+				}()
+			}
+			// 名额占满后，其余请求应立即被拒绝而不是排队
+			deadline := time.Now().Add(5 * time.Second)
+			for slow.inflight.Load() < capacity || dispatchShed.Load()-shedBefore < clients-capacity {
+				if time.Now().After(deadline) {
+					t.Fatalf("inflight = %d, shed = %d", slow.inflight.Load(), dispatchShed.Load()-shedBefore)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			close(slow.gate)
+			wg.Wait()
+			close(codes)
+
+			count := map[int]int{}
+			for code := range codes {
+				count[code]++
+			}
+			if count[http.StatusOK] != capacity || count[http.StatusServiceUnavailable] != clients-capacity {
+				t.Errorf("status counts = %v, want %d×200 and %d×503", count, capacity, clients-capacity)
+			}
+			if p := slow.peak.Load(); p != capacity {
+				t.Errorf("peak concurrency = %d, want %d", p, capacity)
+			}
+		})
+	}
+}
+
+func TestApplyDispatchLimit(t *testing.T) {
+	t.Cleanup(func() { dispatchSlots.Store(nil) })
+	tests := []struct {
+		name string
+		max  int
+		keep bool // 是否沿用上一步的限流器
+		want int  // 0 表示不限制
+	}{
+		{"enable", 2, false, 2},
+		{"same capacity keeps the limiter", 2, true, 2},
+		{"resize", 3, false, 3},
+		{"disable", 0, false, 0},
+	}
+	for _, tt := range tests {
+		prev := dispatchSlots.Load()
+		applyDispatchLimit(ServerConfig{MaxDispatch: tt.max})
+		cur := dispatchSlots.Load()
+		got := 0
+		if cur != nil {
+			got = cap(cur.slots)
+		}
+		if got != tt.want {
+			t.Errorf("%s: capacity = %d, want %d", tt.name, got, tt.want)
+		}
+		if (cur == prev) != tt.keep {
+			t.Errorf("%s: limiter reused = %v, want %v", tt.name, cur == prev, tt.keep)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 在途请求计数：移除模块时先等待请求处理完成再调用 Shutdown；
+// 同样用于路由引擎，保证已取得旧引擎的请求结束后才关闭其中的模块
+type inflightCounter struct {
+	n atomic.Int64
+}
+
+func (c *inflightCounter) acquire() { c.n.Add(1) }
+
+func (c *inflightCounter) release() { c.n.Add(-1) }
+
+func (c *inflightCounter) middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c.acquire()
+		defer c.release()
+		ctx.Next()
+	}
+}
+
+// 等待在途请求归零，超过 deadline 返回 false
+func (c *inflightCounter) wait(deadline time.Time) bool {
+	for c.n.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// /slow 阻塞到 release 关闭，记录 Shutdown 的时间
+type blockingModule struct {
+	module.Base
+	entered    chan struct{}
+	release    chan struct{}
+	shutdownAt atomic.Pointer[time.Time]
+}
+
+func (m *blockingModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/slow", func(c *gin.Context) {
+		close(m.entered)
+		<-m.release
+		c.String(http.StatusOK, "done")
+	})
+}
+
+func (m *blockingModule) Shutdown() error {
+	now := time.Now()
+	m.shutdownAt.Store(&now)
+	return nil
+}
+
+func TestRemovedModuleDrainsBeforeShutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		drainTimeout time.Duration
+		finishAfter  time.Duration // 移除开始后多久结束慢请求
+		wantDrained  bool          // Shutdown 是否在请求结束之后
+	}{
+		{"request finishes within the drain timeout", 2 * time.Second, 150 * time.Millisecond, true},
+		{"drain timeout elapses first", 150 * time.Millisecond, time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slow := &blockingModule{entered: make(chan struct{}), release: make(chan struct{})}
+			registry.Modules["t_slow"] = func() module.Module { return slow }
+			t.Cleanup(func() { delete(registry.Modules, "t_slow") })
+			registerTestModules(t, map[string][]string{"t_a": nil})
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"t_a", "t_slow"}}
+			cfg.Server.DrainTimeout = tt.drainTimeout
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			front := frontHandler(cfg.Server, false, "")
+
+			done := make(chan int)
+			var finishedAt time.Time
+			go func() {
+				w := httptest.NewRecorder()
+				front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+				finishedAt = time.Now()
+				done <- w.Code
+			}()
+			<-slow.entered
+
+			start := time.Now()
+			go func() {
+				time.Sleep(tt.finishAfter)
+				close(slow.release)
+			}()
+			next := cfg
+			next.Modules = []string{"t_a"}
+			if err := rebuildRouter(context.Background(), next); err != nil {
+				t.Fatal(err)
+			}
+			if code := <-done; code != http.StatusOK {
+				t.Errorf("in-flight request = %d, want 200", code)
+			}
+			at := slow.shutdownAt.Load()
+			if at == nil {
+				t.Fatal("removed module was not shut down")
+			}
+			if drained := !at.Before(finishedAt); drained != tt.wantDrained {
+				t.Errorf("shutdown after request finished = %v, want %v", drained, tt.wantDrained)
+			}
+			if waited := at.Sub(start); waited < min(tt.drainTimeout, tt.finishAfter) {
+				t.Errorf("shutdown after %v, want it delayed by at least %v", waited, min(tt.drainTimeout, tt.finishAfter))
+			}
+
+			// 移除后的新请求不再路由到该模块
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("GET /slow after removal = %d, want 404", w.Code)
+			}
+		})
+	}
+}
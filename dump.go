@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"myapp/module"
+)
+
+// dump 子命令：--format=json / yaml 输出展开后的配置（--raw 输出展开前的原始值），dot / mermaid 输出模块依赖图，
+// explain 输出初始化顺序及每个模块位置的原因。--format=json 时错误与配置警告同样以 JSON 写到 stderr（见 reportErrors）
+func runDump(args []string) {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	format := fs.String("format", "json", "output format: json, yaml, dot, mermaid, explain")
+	raw := fs.Bool("raw", false, "print config without env expansion")
+	fs.StringVar(&activeProfile, "profile", activeProfile, "config profile to apply (defaults to APP_ENV)")
+	fs.Parse(args)
+	jsonErrors = *format == "json"
+
+	load := loadConfig
+	if *raw {
+		load = loadRawConfig
+	}
+	cfg, err := load()
+	if err != nil {
+		if jsonErrors {
+			reportErrors(splitErrors(err), false)
+			os.Exit(1)
+		}
+		log.Fatal("Failed to load config:", err)
+	}
+
+	switch *format {
+	case "json":
+		data, _ := json.MarshalIndent(cfg, "", "  ")
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := dumpYAML(cfg)
+		if err != nil {
+			log.Fatal("Failed to encode config:", err)
+		}
+		fmt.Print(string(data))
+	case "dot", "mermaid":
+		nodes, edges, err := moduleGraph(cfg.enabledModules())
+		if err != nil {
+			log.Fatal("Failed to resolve dependencies:", err)
+		}
+		if *format == "dot" {
+			fmt.Print(graphDot(nodes, edges))
+		} else {
+			fmt.Print(graphMermaid(nodes, edges))
+		}
+	case "explain":
+		out, err := explainOrder(cfg.enabledModules())
+		if err != nil {
+			log.Fatal("Failed to resolve dependencies:", err)
+		}
+		fmt.Print(out)
+	default:
+		log.Fatalf("unknown dump format: %s", *format)
+	}
+}
+
+// 以 YAML 输出配置，可直接粘贴回 config.yaml：省略取零值的字段（重新解析后仍是零值），
+// 显式设置的指针字段（如 admin_enabled: false）保留，configs 下的模块配置原样保留；yaml.v3 按键名排序输出 map，便于 diff
+func dumpYAML(cfg Config) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, err
+	}
+	pruneZero(&node, reflect.ValueOf(cfg))
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 按编码前的值删除 mapping 中取零值的结构体字段：零值按 Go 值判断，指向 false / 0 的指针不是零值；
+// 名为 configs 的键下是用户的模块配置，不做处理
+func pruneZero(n *yaml.Node, v reflect.Value) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(v.Type())
+		kept := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			if idx, ok := fields[k.Value]; ok {
+				fv := v.Field(idx)
+				if fv.IsZero() {
+					continue
+				}
+				if k.Value != "configs" {
+					pruneZero(val, fv)
+				}
+			}
+			kept = append(kept, k, val)
+		}
+		n.Content = kept
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode || v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if elem := v.MapIndex(reflect.ValueOf(n.Content[i].Value).Convert(v.Type().Key())); elem.IsValid() {
+				pruneZero(n.Content[i+1], elem)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, elem := range n.Content {
+			if i < v.Len() {
+				pruneZero(elem, v.Index(i))
+			}
+		}
+	}
+}
+
+// 结构体的 YAML 键名 -> 字段下标，规则与 yaml.v3 相同：未写 tag 时为小写的字段名
+func yamlFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+type graphEdge struct {
+	from, to string
+	optional bool
+}
+
+// 按解析后的依赖顺序收集节点与边；可选依赖只在对方被启用时输出
+func moduleGraph(modNames []string) ([]string, []graphEdge, error) {
+	ordered, err := resolveDependencies(modNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	included := make(map[string]bool, len(ordered))
+	for _, name := range ordered {
+		included[name] = true
+	}
+
+	var edges []graphEdge
+	for _, name := range ordered {
+		mod := factory(name)()
+		for _, dep := range mod.Deps() {
+			edges = append(edges, graphEdge{from: name, to: module.DepName(dep)})
+		}
+		if opt, ok := mod.(module.OptionalDeps); ok {
+			for _, dep := range opt.Optional() {
+				if dep = module.DepName(dep); included[dep] {
+					edges = append(edges, graphEdge{from: name, to: dep, optional: true})
+				}
+			}
+		}
+	}
+	return ordered, edges, nil
+}
+
+func graphDot(nodes []string, edges []graphEdge) string {
+	var b strings.Builder
+	b.WriteString("digraph modules {\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %q;\n", n)
+	}
+	for _, e := range edges {
+		if e.optional {
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed];\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.from, e.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func graphMermaid(nodes []string, edges []graphEdge) string {
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %s\n", n)
+	}
+	for _, e := range edges {
+		if e.optional {
+			fmt.Fprintf(&b, "  %s -.-> %s\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", e.from, e.to)
+		}
+	}
+	return b.String()
+}
+
+// 解释 resolveDependencies 给出的初始化顺序：每个模块为何被加载（列在 modules 中或被依赖引入）、
+// 排在哪些依赖之后、决定其层级的最长依赖链，以及可选依赖是否生效
+func explainOrder(modNames []string) (string, error) {
+	ordered, err := resolveDependencies(modNames)
+	if err != nil {
+		return "", err
+	}
+	configured := make(map[string]bool, len(modNames))
+	for _, name := range modNames {
+		configured[name] = true
+	}
+	mods := make(map[string]module.Module, len(ordered))
+	requiredBy := make(map[string][]string)
+	for _, name := range ordered {
+		mods[name] = factory(name)()
+		for _, dep := range mods[name].Deps() {
+			requiredBy[module.DepName(dep)] = append(requiredBy[module.DepName(dep)], name)
+		}
+	}
+
+	// 生效的依赖：必需依赖与已启用的可选依赖，与 resolveDependencies 的判断一致
+	deps := func(name string) []string {
+		var list []string
+		for _, dep := range mods[name].Deps() {
+			list = append(list, module.DepName(dep))
+		}
+		if opt, ok := mods[name].(module.OptionalDeps); ok {
+			for _, dep := range opt.Optional() {
+				if configured[module.DepName(dep)] {
+					list = append(list, module.DepName(dep))
+				}
+			}
+		}
+		return list
+	}
+	chains := make(map[string][]string, len(ordered))
+	var chain func(string) []string
+	chain = func(name string) []string {
+		if c, ok := chains[name]; ok {
+			return c
+		}
+		var longest []string
+		for _, dep := range deps(name) {
+			if c := chain(dep); len(c) > len(longest) {
+				longest = c
+			}
+		}
+		chains[name] = append([]string{name}, longest...)
+		return chains[name]
+	}
+
+	var b strings.Builder
+	b.WriteString("Init order:\n")
+	for i, name := range ordered {
+		mod := mods[name]
+		fmt.Fprintf(&b, "%d. %s\n", i+1, name)
+		if configured[name] {
+			b.WriteString("   listed in modules\n")
+		} else {
+			fmt.Fprintf(&b, "   pulled in as a dependency of %s\n", strings.Join(requiredBy[name], ", "))
+		}
+		for _, dep := range mod.Deps() {
+			fmt.Fprintf(&b, "   after %s (requires %s)\n", module.DepName(dep), dep)
+		}
+		if opt, ok := mod.(module.OptionalDeps); ok {
+			for _, dep := range opt.Optional() {
+				if configured[module.DepName(dep)] {
+					fmt.Fprintf(&b, "   after %s (optional, enabled)\n", module.DepName(dep))
+				} else {
+					fmt.Fprintf(&b, "   optional %s not enabled, ignored\n", module.DepName(dep))
+				}
+			}
+		}
+		if c := chain(name); len(c) > 1 {
+			fmt.Fprintf(&b, "   level %d via %s\n", len(c)-1, strings.Join(c, " -> "))
+		}
+		if p, ok := mod.(module.Prioritized); ok {
+			fmt.Fprintf(&b, "   priority %d within its level\n", p.Priority())
+		}
+	}
+	return b.String(), nil
+}
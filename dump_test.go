@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io/fs"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestModuleGraphOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []string
+		format  string
+		want    []string // 输出中应包含的行
+		notWant []string
+	}{
+		{
+			name:    "dot required edge",
+			modules: []string{"order"},
+			format:  "dot",
+			want:    []string{`digraph modules {`, `  "auth";`, `  "order";`, `  "order" -> "auth";`},
+			notWant: []string{`"order" -> "cache"`},
+		},
+		{
+			name:    "dot optional edge when enabled",
+			modules: []string{"order", "cache"},
+			format:  "dot",
+			want:    []string{`  "order" -> "auth";`, `  "order" -> "cache" [style=dashed];`},
+		},
+		{
+			name:    "mermaid",
+			modules: []string{"order", "cache"},
+			format:  "mermaid",
+			want:    []string{"graph TD", "  order --> auth", "  order -.-> cache"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, edges, err := moduleGraph(tt.modules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := graphMermaid(nodes, edges)
+			if tt.format == "dot" {
+				out = graphDot(nodes, edges)
+			}
+			lines := strings.Split(out, "\n")
+			for _, want := range tt.want {
+				if !slices.Contains(lines, want) {
+					t.Errorf("output missing line %q:\n%s", want, out)
+				}
+			}
+			for _, bad := range tt.notWant {
+				if strings.Contains(out, bad) {
+					t.Errorf("output contains %q:\n%s", bad, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpRawKeepsReferences(t *testing.T) {
+	t.Setenv("T_DSN", "postgres://db/app")
+	const yaml = "modules: [order]\nconfigs:\n  order:\n    dsn: ${T_DSN}\n    label: ${T_UNSET:fallback}\n"
+	tests := []struct {
+		name    string
+		load    func(fs.FS, string) (Config, error)
+		want    []string
+		notWant []string
+	}{
+		{"raw", readRawConfigFS, []string{"dsn: ${T_DSN}", "label: ${T_UNSET:fallback}"}, []string{"postgres://db/app"}},
+		{"expanded", loadConfigFS, []string{"dsn: postgres://db/app", "label: fallback"}, []string{"${"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.load(fstest.MapFS{"config.yaml": {Data: []byte(yaml)}}, "config.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := dumpYAML(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := string(data)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("dump missing %q:\n%s", want, out)
+				}
+			}
+			for _, bad := range tt.notWant {
+				if strings.Contains(out, bad) {
+					t.Errorf("dump contains %q:\n%s", bad, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpYAMLRoundTrip(t *testing.T) {
+	off := false
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"empty", Config{}},
+		{"modules and configs", Config{
+			Modules: []string{"order", "user"},
+			Configs: map[string]map[string]any{
+				"order": {"dsn": "memory://x", "pool": 4, "tags": map[string]any{"tier": "edge"}},
+				"user":  {"greeting": "hi", "enabled": false, "hosts": []any{"a", "b"}},
+			},
+		}},
+		{"server settings", Config{
+			Modules: []string{"user"},
+			Server: ServerConfig{
+				AdminPrefix:     "/ops",
+				AdminEnabled:    &off,
+				ShutdownTimeout: 3 * time.Second,
+				TrustedProxies:  []string{"10.0.0.0/8"},
+				Listeners:       map[string]string{"internal": ":9090", "partner": ":9091"},
+			},
+			Logging: LoggingConfig{Level: "debug"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := dumpYAML(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Config
+			if err := yaml.Unmarshal(data, &got); err != nil {
+				t.Fatalf("dump does not re-parse: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, tt.cfg) {
+				t.Errorf("round trip = %+v, want %+v\n%s", got, tt.cfg, data)
+			}
+			// map 按键名排序，多次输出一致
+			again, _ := dumpYAML(tt.cfg)
+			if string(again) != string(data) {
+				t.Errorf("dump output is not stable:\n%s\n---\n%s", data, again)
+			}
+		})
+	}
+}
+
+func TestExplainOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []string
+		want    []string // 按顺序出现在输出中的片段
+		wantErr string
+	}{
+		{
+			name:    "order after auth",
+			modules: []string{"order"},
+			want: []string{
+				"1. auth", "pulled in as a dependency of order",
+				"2. order", "listed in modules", "after auth (requires auth>=1.0.0)",
+				"optional cache not enabled, ignored", "optional ratelimit not enabled, ignored",
+				"level 1 via order -> auth",
+			},
+		},
+		{
+			name:    "enabled optional dependency",
+			modules: []string{"order", "cache"},
+			want: []string{
+				"1. auth", "2. cache", "listed in modules",
+				"3. order", "after auth (requires auth>=1.0.0)", "after cache (optional, enabled)",
+				"optional ratelimit not enabled, ignored",
+			},
+		},
+		{
+			name:    "no dependencies",
+			modules: []string{"user"},
+			want:    []string{"1. user", "listed in modules"},
+		},
+		{
+			name:    "unknown module",
+			modules: []string{"t_missing"},
+			wantErr: "t_missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := explainOrder(tt.modules)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			rest := out
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("output missing %q after the previous parts:\n%s", want, out)
+				}
+				rest = rest[i+len(want):]
+			}
+		})
+	}
+}
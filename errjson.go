@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"myapp/module"
+)
+
+// 为 true 时（validate / dump 的 --format=json）错误与配置警告以 JSON 写到 stderr，每行一个对象，便于 CI 解析
+var jsonErrors bool
+
+// 按错误类型生成 JSON 对象：error 为类别，message 为完整的错误文本，其余字段取自结构化的错误类型，如
+// {"error":"cycle","path":["a","b","a"],"message":"..."}
+func errorJSON(err error) map[string]any {
+	out := map[string]any{"error": "error", "message": err.Error()}
+	var (
+		cycle   *module.DependencyCycleError
+		unknown *module.UnknownModuleError
+		version *module.VersionConstraintError
+		scope   *module.UnsupportedScopeError
+		parse   *ConfigParseError
+	)
+	switch {
+	case errors.As(err, &cycle):
+		out["error"], out["path"] = "cycle", cycle.Path
+	case errors.As(err, &unknown):
+		out["error"], out["module"] = "unknown_module", unknown.Name
+	case errors.As(err, &version):
+		out["error"], out["module"], out["dep"], out["constraint"] = "version_constraint", version.Module, version.Dep, version.Constraint
+		if version.Version != "" {
+			out["version"] = version.Version
+		}
+	case errors.As(err, &scope):
+		out["error"], out["module"], out["scope"] = "unsupported_scope", scope.Module, scope.Scope.String()
+	case errors.As(err, &parse):
+		out["error"], out["path"] = "config_parse", parse.Path
+		if parse.Line > 0 {
+			out["line"] = parse.Line
+		}
+		if parse.Column > 0 {
+			out["column"] = parse.Column
+		}
+	case errors.Is(err, ErrConfigNotFound):
+		out["error"] = "config_not_found"
+	}
+	return out
+}
+
+// 把 errors.Join 合并的错误拆开，每个问题单独输出；解析错误与文件不存在同样实现了多个 Unwrap，但作为一个整体
+func splitErrors(err error) []error {
+	var parse *ConfigParseError
+	if errors.As(err, &parse) || errors.Is(err, ErrConfigNotFound) {
+		return []error{err}
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var out []error
+		for _, inner := range e.Unwrap() {
+			out = append(out, splitErrors(inner)...)
+		}
+		return out
+	case interface{ Unwrap() error }:
+		// 如 "config.yaml: " 前缀包裹的合并错误
+		if inner, ok := e.Unwrap().(interface{ Unwrap() []error }); ok {
+			return splitErrors(inner.(error))
+		}
+	}
+	return []error{err}
+}
+
+// 把错误写到 stderr：文本形式每行一个，配置警告带 "Config warning:" 前缀；
+// jsonErrors 时每行一个 errorJSON 对象，警告另带 "warning": true
+func reportErrors(errs []error, warning bool) {
+	enc := json.NewEncoder(os.Stderr)
+	enc.SetEscapeHTML(false)
+	for _, err := range errs {
+		switch {
+		case jsonErrors:
+			obj := errorJSON(err)
+			if warning {
+				obj["warning"] = true
+			}
+			enc.Encode(obj)
+		case warning:
+			fmt.Fprintln(os.Stderr, "Config warning:", err)
+		default:
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReportErrorsJSON(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_x": {"t_y"}, "t_y": {"t_x"}, "t_ok": nil})
+	parseErr := func() []error {
+		_, err := loadConfigFS(fstest.MapFS{"config.yaml": {Data: []byte("modules: [t_ok\n")}}, "config.yaml")
+		return splitErrors(err)
+	}
+	tests := []struct {
+		name    string
+		errs    []error
+		warning bool
+		want    []map[string]any // 每行一个对象，不含 message
+	}{
+		{"cycle", Config{Modules: []string{"t_x", "t_y"}}.Validate(), false,
+			[]map[string]any{{"error": "cycle", "path": []any{"t_x", "t_y", "t_x"}}}},
+		{"unknown module", Config{Modules: []string{"t_ok", "t_missing"}}.Validate(), false,
+			[]map[string]any{{"error": "unknown_module", "module": "t_missing"}}},
+		{"parse error", parseErr(), false,
+			[]map[string]any{{"error": "config_parse", "path": "config.yaml", "line": 1.0}}},
+		{"warning", Config{Modules: []string{"t_missing"}}.Validate(), true,
+			[]map[string]any{{"error": "unknown_module", "module": "t_missing", "warning": true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonErrors = true
+			defer func() { jsonErrors = false }()
+			out := captureStderr(t, func() { reportErrors(tt.errs, tt.warning) })
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("stderr = %q, want %d JSON lines", out, len(tt.want))
+			}
+			for i, line := range lines {
+				var got map[string]any
+				if err := json.Unmarshal([]byte(line), &got); err != nil {
+					t.Fatalf("line %q: %v", line, err)
+				}
+				if msg, _ := got["message"].(string); msg != tt.errs[i].Error() {
+					t.Errorf("message = %q, want %q", msg, tt.errs[i].Error())
+				}
+				delete(got, "message")
+				delete(got, "column")
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("line %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
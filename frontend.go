@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
+)
+
+// 监听上的入口 handler。默认是一个外层 gin 引擎：pprof 挂在其上，其余请求经 NoRoute 转交当前模块路由；
+// server.direct_routing 为 true 时请求直接交给当前模块路由，省去外层引擎的一次路由匹配与上下文分配，
+// 只有 pprof 前缀下的请求仍经过外层引擎。listener 为该 handler 所服务的监听名称，请求交给该监听的路由
+func frontHandler(server ServerConfig, enablePprof bool, listener string) http.Handler {
+	ginEngine := gin.New()
+	pprofPrefix := ""
+
+	// 开发模式、-pprof 参数或 server.pprof 任一开启时启用 pprof，挂在 admin_prefix 下；配置了 pprof_token 时需 Bearer Token 访问
+	if enablePprof && server.adminEnabled() {
+		group := ginEngine.Group(server.adminPrefix())
+		if token := server.PprofToken; token != "" {
+			group.Use(adminAuth(token))
+		}
+		pprof.RouteRegister(group)
+		pprofPrefix = strings.TrimSuffix(group.BasePath(), "/") + "/debug/pprof"
+		fmt.Println("pprof enabled at", pprofPrefix)
+	}
+
+	// 其余请求交给当前模块路由；用 NoRoute 而非 "/*path" 通配，避免与 /debug/pprof 前缀冲突
+	// 原样传入 c.Writer，Flush / Hijack / http.ResponseController 都能到达底层连接，SSE 等流式响应不会被缓冲
+	ginEngine.NoRoute(func(c *gin.Context) {
+		done, ok := acquireDispatch(c.Writer)
+		if !ok {
+			c.Abort()
+			return
+		}
+		defer done()
+		h, release := acquireHandler(c.Request, listener)
+		defer func() { release(c.Writer.Status()) }()
+		if h == nil {
+			c.Header("Retry-After", strconv.Itoa(startupRetryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is starting"})
+			return
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	})
+
+	if !server.DirectRouting {
+		return ginEngine
+	}
+	fmt.Println("Direct routing enabled, requests are served by the module router without the outer engine")
+	return &directHandler{outer: ginEngine, pprofPrefix: pprofPrefix, listener: listener}
+}
+
+// 直接分发到当前模块路由；每个请求在 acquireHandler 中取得当时的路由，重载时的切换与外层引擎方式相同
+type directHandler struct {
+	outer       http.Handler
+	pprofPrefix string
+	listener    string
+}
+
+func (d *directHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if d.pprofPrefix != "" && (r.URL.Path == d.pprofPrefix || strings.HasPrefix(r.URL.Path, d.pprofPrefix+"/")) {
+		d.outer.ServeHTTP(w, r)
+		return
+	}
+	done, ok := acquireDispatch(w)
+	if !ok {
+		return
+	}
+	defer done()
+	h, release := acquireHandler(r, d.listener)
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	defer func() { release(sw.status) }()
+	if h == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(startupRetryAfter))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		sw.status = http.StatusServiceUnavailable
+		json.NewEncoder(w).Encode(gin.H{"error": "server is starting"})
+		return
+	}
+	h.ServeHTTP(sw, r)
+}
+
+// 记录响应状态码供 release 使用；Flush、Hijack 与 Unwrap 透传到底层连接，流式响应与 WebSocket 不受影响
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
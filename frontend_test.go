@@ -0,0 +1,240 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 以 cfg 构建包级路由并返回入口 handler，路由中有一个 GET /bench 的模块
+func benchFrontend(b *testing.B, direct bool) http.Handler {
+	b.Helper()
+	registerRouteModules(b, map[string][2]string{"t_bench": {"/bench", "ok"}})
+	useGlobalRouter(b)
+	cfg := Config{Modules: []string{"t_bench"}}
+	cfg.Server.DirectRouting = direct
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		b.Fatal(err)
+	}
+	return frontHandler(cfg.Server, false, "")
+}
+
+func benchmarkFrontend(b *testing.B, direct bool) {
+	h := benchFrontend(b, direct)
+	req := httptest.NewRequest(http.MethodGet, "/bench", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("status = %d", w.Code)
+		}
+	}
+}
+
+// server.direct_routing: true，请求直接交给模块路由
+func BenchmarkDirectHandler(b *testing.B) { benchmarkFrontend(b, true) }
+
+// 默认方式，请求先经外层 gin 引擎的 NoRoute 再交给模块路由
+func BenchmarkGinHandler(b *testing.B) { benchmarkFrontend(b, false) }
+
+func TestFrontHandlerModes(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{"t_front": {"/front", "front"}})
+	tests := []struct {
+		name   string
+		direct bool
+		path   string
+		status int
+	}{
+		{"gin module route", false, "/front", http.StatusOK},
+		{"gin unknown route", false, "/missing", http.StatusNotFound},
+		{"direct module route", true, "/front", http.StatusOK},
+		{"direct unknown route", true, "/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"t_front"}}
+			cfg.Server.DirectRouting = tt.direct
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			frontHandler(cfg.Server, false, "").ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestPprofToggle(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{"t_front": {"/front", "front"}})
+	tests := []struct {
+		name   string
+		pprof  bool
+		direct bool
+		token  string
+		auth   string
+		path   string
+		status int
+	}{
+		{"enabled", true, false, "", "", "/debug/pprof/", http.StatusOK},
+		{"disabled", false, false, "", "", "/debug/pprof/", http.StatusNotFound},
+		{"enabled with direct routing", true, true, "", "", "/debug/pprof/", http.StatusOK},
+		{"disabled with direct routing", false, true, "", "", "/debug/pprof/", http.StatusNotFound},
+		{"token missing", true, false, "s3cret", "", "/debug/pprof/", http.StatusUnauthorized},
+		{"token given", true, false, "s3cret", "Bearer s3cret", "/debug/pprof/", http.StatusOK},
+		{"module routes unaffected", true, false, "s3cret", "", "/front", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"t_front"}}
+			cfg.Server.DirectRouting = tt.direct
+			cfg.Server.PprofToken = tt.token
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			frontHandler(cfg.Server, tt.pprof, "").ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+// GET /stream 逐块输出 chunks，每块之后 Flush 并等待 next 放行下一块
+type streamModule struct {
+	module.Base
+	chunks []string
+	next   chan struct{}
+}
+
+func (m *streamModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i, chunk := range m.chunks {
+			if i > 0 {
+				select {
+				case <-m.next:
+				case <-c.Request.Context().Done():
+					return
+				}
+			}
+			c.Writer.WriteString(chunk)
+			c.Writer.Flush()
+		}
+	})
+}
+
+func TestStreamingFlushes(t *testing.T) {
+	chunks := []string{"data: 1\n\n", "data: 2\n\n", "data: 3\n\n"}
+	tests := []struct {
+		name   string
+		server func(*ServerConfig)
+	}{
+		{"gin catch-all", func(*ServerConfig) {}},
+		{"direct routing", func(s *ServerConfig) { s.DirectRouting = true }},
+		// 压缩中间件在 Flush 时不再等待 min_size
+		{"compression enabled", func(s *ServerConfig) { s.Compression = CompressionConfig{Enabled: true} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := &streamModule{chunks: chunks, next: make(chan struct{})}
+			registry.Modules["t_stream"] = func() module.Module { return mod }
+			t.Cleanup(func() { delete(registry.Modules, "t_stream") })
+			cfg := Config{Modules: []string{"t_stream"}}
+			tt.server(&cfg.Server)
+			app, err := StartApp(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer app.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, app.URL+"/stream", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			resp, err := app.Client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body := io.Reader(resp.Body)
+			if resp.Header.Get("Content-Encoding") == "gzip" {
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 服务端放行下一块之前，客户端就应读到当前块
+			for i, want := range chunks {
+				got := make([]byte, len(want))
+				if _, err := io.ReadFull(body, got); err != nil {
+					t.Fatalf("chunk %d: %v", i, err)
+				}
+				if string(got) != want {
+					t.Fatalf("chunk %d = %q, want %q", i, got, want)
+				}
+				if i < len(chunks)-1 {
+					mod.next <- struct{}{}
+				}
+			}
+		})
+	}
+}
+
+func TestRequestsBeforeFirstBuild(t *testing.T) {
+	tests := []struct {
+		name   string
+		direct bool
+		method string
+		path   string
+	}{
+		{"gin module route", false, http.MethodGet, "/user"},
+		{"gin admin route", false, http.MethodGet, "/admin/modules"},
+		{"gin post", false, http.MethodPost, "/order"},
+		{"direct module route", true, http.MethodGet, "/user"},
+		{"direct admin route", true, http.MethodGet, "/admin/modules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.DirectRouting = tt.direct
+			var front http.Handler
+			captureStdout(t, func() { front = frontHandler(cfg.Server, false, "") })
+
+			// 首次构建完成之前返回 503 而不是 panic
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+				t.Fatalf("%s %s before first build = %d (Retry-After %q), want 503 with Retry-After 1", tt.method, tt.path, w.Code, w.Header().Get("Retry-After"))
+			}
+			if !strings.Contains(w.Body.String(), "server is starting") {
+				t.Errorf("body = %s", w.Body)
+			}
+
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			w = httptest.NewRecorder()
+			front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET /user after first build = %d, want 200", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 存活与就绪探针，由 manager 在每次重建路由时注册；
+// /healthz 汇总具备 health 能力（见 module.HasCapability）的模块，任一失败返回 503；
+// modules 字段按依赖图给出每个模块的自身与有效状态，依赖失败的模块显示为 degraded；
+// /readyz 在首次构建完成且所有模块初始化成功后返回 200，重载进行中返回 503
+func registerHealthRoutes(g *gin.RouterGroup, routes *routeTable, m *ModuleManager) {
+	ops := routes.router("manager", g)
+	ops.GET("/healthz", func(c *gin.Context) {
+		checks, report := m.HealthReport()
+		if len(checks) == 0 {
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
+		status, code := "ok", http.StatusOK
+		for _, v := range checks {
+			if v != "ok" {
+				status, code = "unhealthy", http.StatusServiceUnavailable
+			}
+		}
+		c.JSON(code, gin.H{"status": status, "checks": checks, "modules": report})
+	})
+	ops.GET("/readyz", func(c *gin.Context) {
+		if !m.ready.Load() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	})
+}
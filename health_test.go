@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// Init 阻塞到 gate 关闭，fail 为 true 时返回错误
+type gatedModule struct {
+	module.Base
+	entered chan struct{}
+	gate    chan struct{}
+	fail    bool
+}
+
+func (m *gatedModule) Init(cfg module.ModuleConfig) error {
+	if m.gate != nil {
+		close(m.entered)
+		<-m.gate
+	}
+	if m.fail {
+		return errors.New("init failed")
+	}
+	return nil
+}
+
+func TestReadyz(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	var next *gatedModule
+	registry.Modules["t_gated"] = func() module.Module { return next }
+	t.Cleanup(func() { delete(registry.Modules, "t_gated") })
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"t_a"}}
+	front := frontHandler(cfg.Server, false, "")
+	readyz := func() int {
+		w := httptest.NewRecorder()
+		front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Errorf("before the first build: /readyz = %d, want 503", code)
+	}
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		module *gatedModule // 本次重载新增的 t_gated 实例
+		during int          // 重载阻塞期间的 /readyz，0 表示重载不阻塞
+		after  int
+	}{
+		{"reload in progress", &gatedModule{entered: make(chan struct{}), gate: make(chan struct{})}, http.StatusServiceUnavailable, http.StatusOK},
+		{"module init failed", &gatedModule{fail: true}, 0, http.StatusServiceUnavailable},
+		{"recovered", &gatedModule{}, 0, http.StatusOK},
+	}
+	if code := readyz(); code != http.StatusOK {
+		t.Fatalf("after the first build: /readyz = %d, want 200", code)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next = tt.module
+			reloadCfg := Config{Modules: []string{"t_a", "t_gated"}}
+			errs := make(chan error, 1)
+			// reinit 使 t_gated 每次都创建新实例
+			go func() { errs <- rebuildRouter(context.Background(), reloadCfg, "t_gated") }()
+			if tt.during != 0 {
+				<-tt.module.entered
+				if code := readyz(); code != tt.during {
+					t.Errorf("during reload: /readyz = %d, want %d", code, tt.during)
+				}
+				close(tt.module.gate)
+			}
+			if err := <-errs; err != nil {
+				t.Fatalf("reload error = %v", err)
+			}
+			if code := readyz(); code != tt.after {
+				t.Errorf("after reload: /readyz = %d, want %d", code, tt.after)
+			}
+		})
+	}
+}
+
+// 不声明能力、按接口推断的健康检查模块
+type capModule struct {
+	module.Base
+	err error
+}
+
+func (m *capModule) Health() error { return m.err }
+
+// 通过 Capabilities 显式声明能力的健康检查模块
+type declaredCapModule struct {
+	capModule
+	caps []string
+}
+
+func (m *declaredCapModule) Capabilities() []string { return m.caps }
+
+// 声明了 health 能力却没实现 HealthChecker
+type claimingModule struct{ module.Base }
+
+func (m *claimingModule) Capabilities() []string { return []string{module.CapHealth} }
+
+func TestHealthzUsesCapabilities(t *testing.T) {
+	errDown := errors.New("down")
+	factories := map[string]func() module.Module{
+		"t_declared":   func() module.Module { return &declaredCapModule{caps: []string{module.CapHealth}} },
+		"t_undeclared": func() module.Module { return &declaredCapModule{capModule{err: errDown}, []string{module.CapStats}} },
+		"t_inferred":   func() module.Module { return &capModule{} },
+		"t_claiming":   func() module.Module { return &claimingModule{} },
+	}
+	for name, f := range factories {
+		registry.Modules[name] = f
+	}
+	t.Cleanup(func() {
+		for name := range factories {
+			delete(registry.Modules, name)
+		}
+	})
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), Config{Modules: []string{"t_declared", "t_undeclared", "t_inferred", "t_claiming"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	// t_undeclared 的 Health 会失败，但它没有声明 health 能力，不参与检查
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /healthz = %d %s, want 200", w.Code, w.Body)
+	}
+	var body struct {
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		module   string
+		included bool
+	}{
+		{"t_declared", true},
+		{"t_inferred", true},
+		{"t_undeclared", false},
+		{"t_claiming", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			if _, ok := body.Checks[tt.module]; ok != tt.included {
+				t.Errorf("%s in /healthz checks = %v, want %v (checks: %v)", tt.module, ok, tt.included, body.Checks)
+			}
+		})
+	}
+}
+
+// 预热在 gate 关闭前阻塞（gate 为 nil 时立即结束），返回 err
+type warmModule struct {
+	module.Base
+	events *testEvents
+	gate   chan struct{}
+	err    error
+}
+
+func (m *warmModule) Init(module.ModuleConfig) error { m.events.add("init"); return nil }
+
+func (m *warmModule) RegisterRoutes(r gin.IRouter) { m.events.add("routes") }
+
+func (m *warmModule) Warmup(ctx context.Context) error {
+	m.events.add("warmup")
+	if m.gate != nil {
+		select {
+		case <-m.gate:
+		case <-ctx.Done():
+			m.events.add("warmup cancelled")
+			return ctx.Err()
+		}
+	}
+	return m.err
+}
+
+func TestWarmupBeforeReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    string // warmup_timeout
+		release    bool   // 是否在检查就绪状态后放行预热
+		err        error
+		wantOutput string
+		wantEvents []string
+	}{
+		{"warmup succeeds", "5s", true, nil, "Module t_warm warmed up in", []string{"init", "routes", "warmup"}},
+		{"warmup fails", "5s", true, errors.New("cache unreachable"), "Warning: warmup of module t_warm failed: cache unreachable", []string{"init", "routes", "warmup"}},
+		{"warmup times out", "100ms", false, nil, "Warning: warmup of module t_warm failed: context deadline exceeded", []string{"init", "routes", "warmup", "warmup cancelled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerTestModules(t, map[string][]string{"t_a": nil})
+			events := &testEvents{}
+			mod := &warmModule{events: events, gate: make(chan struct{}), err: tt.err}
+			registry.Modules["t_warm"] = func() module.Module { return mod }
+			t.Cleanup(func() { delete(registry.Modules, "t_warm") })
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"t_a"}}
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			front := frontHandler(cfg.Server, false, "")
+			readyz := func() int {
+				w := httptest.NewRecorder()
+				front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+				return w.Code
+			}
+			if code := readyz(); code != http.StatusOK {
+				t.Fatalf("/readyz before reload = %d", code)
+			}
+
+			cfg.Modules = append(cfg.Modules, "t_warm")
+			cfg.Configs = map[string]map[string]any{"t_warm": {"warmup_timeout": tt.timeout}}
+			done := make(chan error, 1)
+			var out string
+			go func() {
+				var err error
+				out = captureStdout(t, func() { err = rebuildRouter(context.Background(), cfg) })
+				done <- err
+			}()
+			eventually(t, time.Second, "warmup start", func() bool { return slices.Contains(events.with(""), "warmup") })
+			// 预热进行中保持未就绪
+			if code := readyz(); code != http.StatusServiceUnavailable {
+				t.Errorf("/readyz during warmup = %d, want 503", code)
+			}
+			if tt.release {
+				close(mod.gate)
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if code := readyz(); code != http.StatusOK {
+				t.Errorf("/readyz after warmup = %d, want 200", code)
+			}
+			if !strings.Contains(out, tt.wantOutput) {
+				t.Errorf("output = %q, want %q", out, tt.wantOutput)
+			}
+			if got := events.with(""); !slices.Equal(got, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+// 带依赖的健康检查模块
+type depHealthModule struct {
+	capModule
+	deps []string
+}
+
+func (m *depHealthModule) Deps() []string { return m.deps }
+
+func TestHealthzDependencyAware(t *testing.T) {
+	errDown := errors.New("down")
+	// t_hview 依赖 t_horder，t_horder 依赖 t_hauth；t_hview 没有健康检查
+	tests := []struct {
+		name     string
+		down     []string
+		wantCode int
+		want     map[string]moduleHealth
+	}{
+		{"all healthy", nil, http.StatusOK, map[string]moduleHealth{
+			"t_hauth":  {Direct: "ok", Effective: "ok"},
+			"t_horder": {Direct: "ok", Effective: "ok"},
+			"t_hview":  {Direct: "ok", Effective: "ok"},
+		}},
+		{"auth unhealthy", []string{"t_hauth"}, http.StatusServiceUnavailable, map[string]moduleHealth{
+			"t_hauth":  {Direct: "unhealthy", Effective: "unhealthy"},
+			"t_horder": {Direct: "ok", Effective: "degraded", DegradedBy: []string{"t_hauth"}},
+			"t_hview":  {Direct: "ok", Effective: "degraded", DegradedBy: []string{"t_horder"}},
+		}},
+		{"order unhealthy", []string{"t_horder"}, http.StatusServiceUnavailable, map[string]moduleHealth{
+			"t_hauth":  {Direct: "ok", Effective: "ok"},
+			"t_horder": {Direct: "unhealthy", Effective: "unhealthy"},
+			"t_hview":  {Direct: "ok", Effective: "degraded", DegradedBy: []string{"t_horder"}},
+		}},
+		// 自身失败时有效状态为 unhealthy，不因依赖降为 degraded
+		{"both unhealthy", []string{"t_hauth", "t_horder"}, http.StatusServiceUnavailable, map[string]moduleHealth{
+			"t_hauth":  {Direct: "unhealthy", Effective: "unhealthy"},
+			"t_horder": {Direct: "unhealthy", Effective: "unhealthy", DegradedBy: []string{"t_hauth"}},
+			"t_hview":  {Direct: "ok", Effective: "degraded", DegradedBy: []string{"t_horder"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry.Modules["t_hauth"] = func() module.Module { return &depHealthModule{} }
+			registry.Modules["t_horder"] = func() module.Module { return &depHealthModule{deps: []string{"t_hauth"}} }
+			registry.Modules["t_hview"] = func() module.Module {
+				return &testModule{name: "t_hview", deps: []string{"t_horder"}, events: &testEvents{}}
+			}
+			defer func() {
+				for _, name := range []string{"t_hauth", "t_horder", "t_hview"} {
+					delete(registry.Modules, name)
+				}
+			}()
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), Config{Modules: []string{"t_hview", "t_horder", "t_hauth"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 重载自检通过后再让模块失败
+			m.mu.RLock()
+			for _, name := range tt.down {
+				m.active[name].(*depHealthModule).err = errDown
+			}
+			m.mu.RUnlock()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if w.Code != tt.wantCode {
+				t.Errorf("GET /healthz = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+			var body struct {
+				Modules map[string]moduleHealth `json:"modules"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				got := body.Modules[name]
+				if got.Direct != want.Direct || got.Effective != want.Effective || !slices.Equal(got.DegradedBy, want.DegradedBy) {
+					t.Errorf("%s health = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
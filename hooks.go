@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"myapp/registry"
+)
+
+// 启动钩子：name 引用 registry.Hooks 中注册的 Go 函数，command 为直接执行的命令（argv 形式，不经 shell），二者取其一
+type StartupHook struct {
+	Name    string        `yaml:"name"`
+	Command []string      `yaml:"command"`
+	Timeout time.Duration `yaml:"timeout"` // 0 表示不限时
+}
+
+func (h StartupHook) String() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return strings.Join(h.Command, " ")
+}
+
+// 在首次构建路由之前按顺序执行全部启动钩子，任一失败（命令退出码非 0）即返回错误，启动中止
+func runStartupHooks(ctx context.Context, hooks []StartupHook) error {
+	for i, h := range hooks {
+		fmt.Printf("Running startup hook %d/%d: %s\n", i+1, len(hooks), h)
+		start := time.Now()
+		if err := runStartupHook(ctx, h); err != nil {
+			return fmt.Errorf("startup hook %s: %w", h, err)
+		}
+		fmt.Printf("Startup hook %s finished in %s\n", h, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func runStartupHook(ctx context.Context, h StartupHook) error {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	if h.Name != "" {
+		fn, ok := registry.Hooks[h.Name]
+		if !ok {
+			return errors.New("no such registered hook")
+		}
+		return fn(ctx)
+	}
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// 校验 startup_hooks：每个钩子须恰好设置 name 与 command 之一，name 须已注册
+func (c Config) hookProblems() []error {
+	var problems []error
+	for i, h := range c.StartupHooks {
+		switch {
+		case (h.Name == "") == (len(h.Command) == 0):
+			problems = append(problems, fmt.Errorf("startup_hooks[%d]: set exactly one of name and command", i))
+		case h.Name != "":
+			if _, ok := registry.Hooks[h.Name]; !ok {
+				problems = append(problems, fmt.Errorf("startup_hooks[%d]: no registered hook named %q", i, h.Name))
+			}
+		case h.Command[0] == "":
+			problems = append(problems, fmt.Errorf("startup_hooks[%d]: command is empty", i))
+		}
+	}
+	return problems
+}
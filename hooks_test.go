@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/registry"
+)
+
+func TestStartupHooks(t *testing.T) {
+	events := registerTestModules(t, map[string][]string{"t_a": nil})
+	registry.Hooks["t_migrate"] = func(ctx context.Context) error {
+		events.add("hook t_migrate")
+		return nil
+	}
+	registry.Hooks["t_failing"] = func(ctx context.Context) error { return errors.New("migration failed") }
+	registry.Hooks["t_slow"] = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	t.Cleanup(func() {
+		for _, name := range []string{"t_migrate", "t_failing", "t_slow"} {
+			delete(registry.Hooks, name)
+		}
+	})
+	_, lookErr := exec.LookPath("false")
+	tests := []struct {
+		name    string
+		hooks   []StartupHook
+		command bool   // 需要系统中的 true / false 命令
+		wantErr string // 为空表示应正常启动
+		want    []string
+	}{
+		{"no hooks", nil, false, "", []string{"init t_a"}},
+		{"go hook before init", []StartupHook{{Name: "t_migrate"}}, false, "", []string{"hook t_migrate", "init t_a"}},
+		{"failing hook aborts startup", []StartupHook{{Name: "t_failing"}, {Name: "t_migrate"}}, false,
+			"startup failed: startup hook t_failing: migration failed", nil},
+		{"hook timeout", []StartupHook{{Name: "t_slow", Timeout: 50 * time.Millisecond}}, false,
+			"startup hook t_slow: context deadline exceeded", nil},
+		{"command succeeds", []StartupHook{{Command: []string{"true"}}, {Name: "t_migrate"}}, true, "", []string{"hook t_migrate", "init t_a"}},
+		{"command exits non-zero", []StartupHook{{Command: []string{"false"}}}, true, "startup hook false: exit status 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.command && lookErr != nil {
+				t.Skip("false command not available")
+			}
+			useGlobalRouter(t)
+			events.mu.Lock()
+			events.events = nil
+			events.mu.Unlock()
+			app, err := StartApp(Config{Modules: []string{"t_a"}, StartupHooks: tt.hooks})
+			if tt.wantErr != "" {
+				if err == nil {
+					app.Close()
+					t.Fatalf("startup succeeded, want error %q", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				// 钩子只在启动时执行一次，重载时不再执行
+				err = reloadConfig(context.Background())
+				app.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := slices.DeleteFunc(events.with(""), func(ev string) bool { return !strings.HasPrefix(ev, "hook ") && !strings.HasPrefix(ev, "init ") })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartupHookProblems(t *testing.T) {
+	registry.Hooks["t_migrate"] = func(ctx context.Context) error { return nil }
+	t.Cleanup(func() { delete(registry.Hooks, "t_migrate") })
+	tests := []struct {
+		name string
+		hook StartupHook
+		want string // 为空表示没有问题
+	}{
+		{"registered name", StartupHook{Name: "t_migrate"}, ""},
+		{"command", StartupHook{Command: []string{"migrate", "up"}}, ""},
+		{"unknown name", StartupHook{Name: "t_missing"}, `startup_hooks[0]: no registered hook named "t_missing"`},
+		{"neither", StartupHook{}, "startup_hooks[0]: set exactly one of name and command"},
+		{"both", StartupHook{Name: "t_migrate", Command: []string{"true"}}, "startup_hooks[0]: set exactly one of name and command"},
+		{"empty command", StartupHook{Command: []string{""}}, "startup_hooks[0]: command is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Config{StartupHooks: []StartupHook{tt.hook}}.hookProblems()
+			if tt.want == "" {
+				if len(problems) > 0 {
+					t.Errorf("problems = %v, want none", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Error() != tt.want {
+				t.Errorf("problems = %v, want [%s]", problems, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 已注册模块的元数据
+type moduleInfo struct {
+	Version      string         `json:"version,omitempty"`
+	Deps         []string       `json:"deps"`
+	Optional     []string       `json:"optional,omitempty"`
+	Priority     int            `json:"priority,omitempty"`
+	Deprecated   string         `json:"deprecated,omitempty"`
+	Capabilities []string       `json:"capabilities,omitempty"`
+	Schema       map[string]any `json:"schema"`
+	Instances    []string       `json:"instances"` // 当前激活的实例（含 name@suffix 别名），按启动顺序
+}
+
+// GET /admin/introspect 的内容：已注册模块的版本、依赖、配置 schema 与当前激活的实例；
+// 元数据来自临时创建的实例，不影响正在运行的模块
+func introspect(active []string) map[string]any {
+	names := make([]string, 0, len(registry.Modules))
+	for name := range registry.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	modules := make(map[string]moduleInfo, len(names))
+	for _, name := range names {
+		mod := registry.Modules[name]()
+		info := moduleInfo{
+			Deps:       append([]string{}, mod.Deps()...),
+			Deprecated: module.DeprecationOf(mod),
+			Schema:     moduleSchema(mod),
+			Instances:  []string{},
+		}
+		if v, ok := mod.(module.Versioned); ok {
+			info.Version = v.Version()
+		}
+		if opt, ok := mod.(module.OptionalDeps); ok {
+			info.Optional = opt.Optional()
+		}
+		if p, ok := mod.(module.Prioritized); ok {
+			info.Priority = p.Priority()
+		}
+		for _, c := range []string{module.CapHealth, module.CapStats, module.CapWorkers, module.CapMiddleware, module.CapWarmup, module.CapServices} {
+			if module.HasCapability(mod, c) {
+				info.Capabilities = append(info.Capabilities, c)
+			}
+		}
+		for _, inst := range active {
+			if typ, _, _ := strings.Cut(inst, "@"); typ == name {
+				info.Instances = append(info.Instances, inst)
+			}
+		}
+		modules[name] = info
+	}
+	return map[string]any{
+		"version": map[string]string{"version": Version, "commit": Commit, "build_time": BuildTime},
+		"active":  active,
+		"modules": modules,
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAdminIntrospect(t *testing.T) {
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"order", "order@primary", "user"}}
+	cfg.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/introspect", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	frontHandler(cfg.Server, false, "").ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /admin/introspect = %d %s", w.Code, w.Body)
+	}
+	var got struct {
+		Version map[string]string `json:"version"`
+		Active  []string          `json:"active"`
+		Modules map[string]struct {
+			Deps      []string       `json:"deps"`
+			Optional  []string       `json:"optional"`
+			Schema    map[string]any `json:"schema"`
+			Instances []string       `json:"instances"`
+		} `json:"modules"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version["version"] != Version {
+		t.Errorf("version = %v, want %q", got.Version, Version)
+	}
+	if !slices.Contains(got.Active, "order@primary") {
+		t.Errorf("active = %v, want order@primary", got.Active)
+	}
+
+	tests := []struct {
+		module    string
+		deps      []string
+		optional  []string
+		required  []any
+		instances []string
+	}{
+		{"order", []string{"auth>=1.0.0"}, []string{"cache", "ratelimit"}, []any{"dsn"}, []string{"order", "order@primary"}},
+		{"auth", []string{}, nil, nil, []string{"auth"}},
+		// 未启用的模块同样列出元数据
+		{"cache", []string{}, nil, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			info, ok := got.Modules[tt.module]
+			if !ok {
+				t.Fatalf("module %s missing from %v", tt.module, got.Modules)
+			}
+			if !slices.Equal(info.Deps, tt.deps) || !slices.Equal(info.Optional, tt.optional) {
+				t.Errorf("deps = %v optional = %v, want %v %v", info.Deps, info.Optional, tt.deps, tt.optional)
+			}
+			if !slices.Equal(info.Instances, tt.instances) {
+				t.Errorf("instances = %v, want %v", info.Instances, tt.instances)
+			}
+			if info.Schema == nil {
+				t.Fatal("schema missing")
+			}
+			if tt.required != nil {
+				req, _ := info.Schema["required"].([]any)
+				if !slices.Equal(req, tt.required) {
+					t.Errorf("schema = %v, want required %v", info.Schema, tt.required)
+				}
+			}
+		})
+	}
+
+	// 未认证时拒绝
+	w = httptest.NewRecorder()
+	frontHandler(cfg.Server, false, "").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/introspect", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated GET /admin/introspect = %d, want 401", w.Code)
+	}
+}
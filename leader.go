@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 启动时等待选举首次结果的最长时间，超时后先按非 leader 构建，成为 leader 后再启动 leader-only 模块
+const leaderWait = time.Second
+
+// 按 registry.LeaderElector 或 server.leader_lock 参与 leader 选举，直到 ctx 取消；均未设置时不选举，本实例始终为 leader。
+// leadership 变化时以上次应用的配置重建路由：成为 leader 后启动 ClusterSingleton 模块，失去后关闭它们
+func startLeaderElection(ctx context.Context, server ServerConfig) {
+	elector := registry.LeaderElector
+	if elector == nil && server.LeaderLock != "" {
+		elector = &module.FileLockElector{Path: server.LeaderLock}
+	}
+	if elector == nil {
+		return
+	}
+	manager.leader.Store(false)
+	first := make(chan struct{})
+	var once sync.Once
+	go elector.Run(ctx, func(leader bool) {
+		changed := manager.leader.Swap(leader) != leader
+		fmt.Println("Leader election: this instance is leader:", leader)
+		once.Do(func() { close(first) })
+		if changed {
+			go applyLeadership(ctx)
+		}
+	})
+	select {
+	case <-first:
+	case <-time.After(leaderWait):
+		fmt.Println("Leader election: no result yet, starting as a follower")
+	}
+}
+
+// 以当前 leadership 重建路由与金丝雀路由；首次构建尚未完成时跳过，首次构建读取的已是最新状态
+func applyLeadership(ctx context.Context) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	globalRouter.Lock()
+	built, c := router != nil, canary
+	globalRouter.Unlock()
+	if !built {
+		return
+	}
+	rebuildLocked(ctx, manager.appliedConfig())
+	if c != nil {
+		c.applyLeadership(ctx, manager.leader.Load())
+	}
+}
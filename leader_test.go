@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 由测试驱动的选举：每次从 states 收到值时以其调用 onChange
+type fakeElector struct {
+	states chan bool
+}
+
+func (e *fakeElector) Run(ctx context.Context, onChange func(leader bool)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case leader := <-e.states:
+			onChange(leader)
+		}
+	}
+}
+
+// 只在 leader 上运行的测试模块
+type singletonModule struct{ testModule }
+
+func (m *singletonModule) SingletonAcrossCluster() bool { return true }
+
+func TestLeaderOnlyModulesFollowElection(t *testing.T) {
+	events := registerTestModules(t, map[string][]string{"t_plain": nil})
+	registry.Modules["t_single"] = func() module.Module {
+		return &singletonModule{testModule{name: "t_single", events: events}}
+	}
+	elector := &fakeElector{states: make(chan bool, 1)}
+	prevElector := registry.LeaderElector
+	registry.LeaderElector = elector
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_single")
+		registry.LeaderElector = prevElector
+	})
+	useGlobalRouter(t)
+	t.Cleanup(func() { stopCanary(0) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	elector.states <- false
+	startLeaderElection(ctx, ServerConfig{})
+
+	cfg := Config{Modules: []string{"t_plain", "t_single"}}
+	if err := rebuildRouter(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := startCanary(ctx, cfg, 10); err != nil {
+		t.Fatal(err)
+	}
+	canaryModules := func() []string {
+		globalRouter.Lock()
+		defer globalRouter.Unlock()
+		return canary.manager.ActiveModules()
+	}
+
+	steps := []struct {
+		name   string
+		leader *bool // nil 表示不改变选举结果
+		want   []string
+	}{
+		{"follower skips singletons", nil, []string{"t_plain"}},
+		{"takeover starts them", ptr(true), []string{"t_plain", "t_single"}},
+		{"losing leadership stops them", ptr(false), []string{"t_plain"}},
+	}
+	for _, step := range steps {
+		if step.leader != nil {
+			elector.states <- *step.leader
+		}
+		eventually(t, 5*time.Second, step.name+": stable router", func() bool {
+			return slices.Equal(manager.ActiveModules(), step.want)
+		})
+		eventually(t, 5*time.Second, step.name+": canary router", func() bool {
+			return slices.Equal(canaryModules(), step.want)
+		})
+	}
+	// 两套路由各启动、关闭一次 t_single
+	want := []string{"t_single", "t_single"}
+	eventually(t, 5*time.Second, "singleton shut down", func() bool {
+		return slices.Equal(events.with("shutdown "), want)
+	})
+	got := events.with("init ")
+	slices.Sort(got)
+	if want := []string{"t_plain", "t_plain", "t_single", "t_single"}; !slices.Equal(got, want) {
+		t.Errorf("init events = %v, want %v", got, want)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
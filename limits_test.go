@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// POST /echo 读取整个请求体并原样返回
+type echoModule struct{ module.Base }
+
+func (m *echoModule) RegisterRoutes(r gin.IRouter) {
+	r.POST("/echo", func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return
+		}
+		c.Data(http.StatusOK, "text/plain", data)
+	})
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	registry.Modules["t_echo"] = func() module.Module { return &echoModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_echo") })
+	tests := []struct {
+		name    string
+		limit   int64
+		size    int
+		chunked bool // 不带 Content-Length，只能在读取时发现超限
+		status  int
+	}{
+		{"within limit", 64, 64, false, http.StatusOK},
+		{"content length over limit", 64, 65, false, http.StatusRequestEntityTooLarge},
+		{"chunked body over limit", 64, 1000, true, http.StatusRequestEntityTooLarge},
+		{"no limit configured", 0, 1 << 16, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_echo"}}
+			cfg.Server.MaxBodyBytes = tt.limit
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body io.Reader = strings.NewReader(strings.Repeat("a", tt.size))
+			if tt.chunked {
+				body = io.MultiReader(body) // 隐藏长度，httptest 不设置 Content-Length
+			}
+			req := httptest.NewRequest(http.MethodPost, "/echo", body)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.Len() != tt.size {
+				t.Errorf("echoed %d bytes, want %d", w.Body.Len(), tt.size)
+			}
+		})
+	}
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		header int // 请求中额外 header 的字节数
+		status int
+	}{
+		{"default limit", 0, 8 << 10, http.StatusOK},
+		// net/http 在 max_header_bytes 之外还留有 4096 字节余量
+		{"small limit", 1024, 8 << 10, http.StatusRequestHeaderFieldsTooLarge},
+		{"small header under small limit", 1024, 512, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.MaxHeaderBytes = tt.limit
+			app, err := StartApp(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer app.Close()
+			req, _ := http.NewRequest(http.MethodGet, app.URL+"/user", nil)
+			req.Header.Set("X-Padding", strings.Repeat("p", tt.header))
+			resp, err := app.Client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+// GET /delay?ms=<毫秒> 等待后返回 200；uncooperative=1 时不理会请求的 context
+type delayModule struct{ module.Base }
+
+func (m *delayModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/delay", func(c *gin.Context) {
+		ms, _ := strconv.Atoi(c.Query("ms"))
+		if c.Query("uncooperative") == "1" {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		} else {
+			select {
+			case <-time.After(time.Duration(ms) * time.Millisecond):
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+		c.String(http.StatusOK, "done")
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	registry.Modules["t_delay"] = func() module.Module { return &delayModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_delay") })
+	tests := []struct {
+		name     string
+		timeout  any // 模块配置 request_timeout，nil 表示不配置
+		query    string
+		wantCode int
+		maxTime  time.Duration // 响应前的最长耗时
+	}{
+		{"fast handler", "200ms", "ms=0", http.StatusOK, time.Second},
+		{"slow handler times out", "50ms", "ms=5000", http.StatusGatewayTimeout, time.Second},
+		{"no timeout configured", nil, "ms=100", http.StatusOK, time.Second},
+		// 不理会 context 的处理函数超时后才写出响应，以其响应为准
+		{"uncooperative handler", "50ms", "ms=150&uncooperative=1", http.StatusOK, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"t_delay"}}
+			if tt.timeout != nil {
+				cfg.Configs = map[string]map[string]any{"t_delay": {"request_timeout": tt.timeout}}
+			}
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/delay?"+tt.query, nil))
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Errorf("request took %v, want at most %v", elapsed, tt.maxTime)
+			}
+			if w.Code != tt.wantCode {
+				t.Fatalf("GET /delay?%s = %d %s, want %d", tt.query, w.Code, w.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusGatewayTimeout && !strings.Contains(w.Body.String(), "request timed out after 50ms") {
+				t.Errorf("body = %s, want the timeout error", w.Body)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	registry.Modules["t_delay"] = func() module.Module { return &delayModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_delay") })
+	tests := []struct {
+		name     string
+		server   ServerConfig
+		module   map[string]any // t_delay 的模块配置
+		requests int
+		ms       int // 每个请求的处理耗时
+		want     map[int]int
+		rejected int // 队列已满直接返回 503 的请求数，其余 503 均为等待超时
+	}{
+		{"no limit", ServerConfig{}, nil, 5, 200, map[int]int{200: 5}, 0},
+		{"global limit saturated", ServerConfig{MaxInflight: 2, MaxQueue: 1, QueueTimeout: 100 * time.Millisecond}, nil, 4, 500,
+			map[int]int{200: 2, 503: 2}, 1},
+		{"queued requests get a slot", ServerConfig{MaxInflight: 1, MaxQueue: 2, QueueTimeout: 2 * time.Second}, nil, 3, 100,
+			map[int]int{200: 3}, 0},
+		{"module limit saturated", ServerConfig{}, map[string]any{"max_inflight": 1, "max_queue": 1, "queue_timeout": "50ms"}, 3, 500,
+			map[int]int{200: 1, 503: 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"t_delay"}, Server: tt.server}
+			if tt.module != nil {
+				cfg.Configs = map[string]map[string]any{"t_delay": tt.module}
+			}
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var mu sync.Mutex
+			got := map[int]int{}
+			rejected := 0
+			var wg sync.WaitGroup
+			for range tt.requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/delay?ms="+strconv.Itoa(tt.ms), nil))
+					mu.Lock()
+					defer mu.Unlock()
+					got[w.Code]++
+					if strings.Contains(w.Body.String(), "too many requests in flight") {
+						rejected++
+					}
+					if w.Code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+						t.Error("503 response without Retry-After")
+					}
+				}()
+			}
+			wg.Wait()
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("status counts = %v, want %v", got, tt.want)
+			}
+			if rejected != tt.rejected {
+				t.Errorf("rejected with full queue = %d, want %d", rejected, tt.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 一次构建得到的全部路由：内嵌的 Engine 服务 listen / addr 上的请求，server.listeners 中的每个监听另有独立的路由，
+// 包含未绑定监听的模块与绑定到该监听的模块。不同监听上的模块可以注册相同的路径，404 / 405 也各自处理
+type routerSet struct {
+	*gin.Engine
+	listeners map[string]*gin.Engine
+}
+
+// 监听 name 上的请求使用的路由；name 为空（listen / addr 的监听）时为默认路由
+func (s *routerSet) forListener(name string) *gin.Engine {
+	if e, ok := s.listeners[name]; ok {
+		return e
+	}
+	return s.Engine
+}
+
+type listenerKey struct{}
+
+// 具名监听的路由在请求 context 中记录监听名称，进程内转发据此回到同一监听的路由
+func tagListener(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), listenerKey{}, name))
+	}
+}
+
+// 把请求交给接收它的监听的路由重新匹配，作为 Dispatcher 的 handle：
+// 绑定到某个监听的模块只能被同一监听上的转发访问到
+func (s *routerSet) handleContext(c *gin.Context) {
+	name, _ := c.Request.Context().Value(listenerKey{}).(string)
+	s.forListener(name).HandleContext(c)
+}
+
+// 构建中的单个路由及其路由表，name 为监听名称，默认路由为空
+type listenerRouter struct {
+	name   string
+	engine *gin.Engine
+	routes *routeTable
+}
+
+// 具名监听按名称排序，监听顺序与 Listening 回调中的地址顺序一致
+func listenerNames(server ServerConfig) []string {
+	names := make([]string, 0, len(server.Listeners))
+	for name := range server.Listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 校验 server.listeners 与各模块的 listener：名称与地址不能为空，listener 引用的监听须已定义
+func (c Config) listenerProblems() []error {
+	var problems []error
+	for _, name := range listenerNames(c.Server) {
+		if name == "" || c.Server.Listeners[name] == "" {
+			problems = append(problems, fmt.Errorf("server.listeners: listener %q needs a name and an address", name))
+		}
+	}
+	names := make([]string, 0, len(c.Configs))
+	for name := range c.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		listener := module.ModuleConfig(c.Configs[name]).GetString("listener", "")
+		if _, ok := c.Server.Listeners[listener]; listener != "" && !ok {
+			problems = append(problems, fmt.Errorf("module %q uses unknown listener %q", name, listener))
+		}
+	}
+	return problems
+}
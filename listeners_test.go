@@ -0,0 +1,171 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 在 path 上返回 body 的测试模块
+type routeModule struct {
+	module.Base
+	path, body string
+}
+
+func (m *routeModule) RegisterRoutes(r gin.IRouter) {
+	r.GET(m.path, func(c *gin.Context) { c.String(http.StatusOK, m.body) })
+}
+
+func registerRouteModules(t testing.TB, mods map[string][2]string) {
+	t.Helper()
+	for name, route := range mods {
+		route := route
+		registry.Modules[name] = func() module.Module { return &routeModule{path: route[0], body: route[1]} }
+	}
+	t.Cleanup(func() {
+		for name := range mods {
+			delete(registry.Modules, name)
+		}
+	})
+}
+
+// 在 path 上把请求转发到 target 的测试模块
+type dispatchModule struct {
+	module.Base
+	path, target string
+	dispatch     module.Dispatcher
+}
+
+func (m *dispatchModule) Provide(s *module.ServiceRegistry) {
+	m.dispatch, _ = module.Lookup[module.Dispatcher](s, module.DispatcherService)
+}
+
+func (m *dispatchModule) RegisterRoutes(r gin.IRouter) {
+	r.GET(m.path, func(c *gin.Context) { m.dispatch(c, m.target) })
+}
+
+func TestDispatchStaysOnListener(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{
+		"t_panel":   {"/panel", "panel"},
+		"t_status1": {"/status", "internal status"},
+		"t_status2": {"/status", "partner status"},
+	})
+	registry.Modules["t_fwd"] = func() module.Module { return &dispatchModule{path: "/fwd/panel", target: "/panel"} }
+	registry.Modules["t_fwd_status"] = func() module.Module { return &dispatchModule{path: "/fwd/status", target: "/status"} }
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_fwd")
+		delete(registry.Modules, "t_fwd_status")
+	})
+	cfg := Config{
+		Modules: []string{"t_panel", "t_status1", "t_status2", "t_fwd", "t_fwd_status"},
+		Configs: map[string]map[string]any{
+			"t_panel":      {"listener": "internal"},
+			"t_status1":    {"listener": "internal"},
+			"t_status2":    {"listener": "partner"},
+			"t_fwd_status": {"listener": "partner"},
+		},
+	}
+	cfg.Server.Listeners = map[string]string{"internal": "127.0.0.1:0", "partner": "127.0.0.1:0"}
+	app, err := StartApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	tests := []struct {
+		listener string
+		path     string
+		status   int
+		body     string
+	}{
+		{"", "/fwd/panel", http.StatusNotFound, ""},
+		{"internal", "/fwd/panel", http.StatusOK, "panel"},
+		{"partner", "/fwd/panel", http.StatusNotFound, ""},
+		{"", "/fwd/status", http.StatusNotFound, ""},
+		{"partner", "/fwd/status", http.StatusOK, "partner status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listener+tt.path, func(t *testing.T) {
+			base := app.URL
+			if tt.listener != "" {
+				base = app.Listeners[tt.listener]
+			}
+			resp, err := app.Client.Get(base + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.body != "" && string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestModulesBoundToListeners(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{
+		"t_public":  {"/pub", "public"},
+		"t_panel":   {"/panel", "panel"},
+		"t_status1": {"/status", "internal status"},
+		"t_status2": {"/status", "partner status"},
+	})
+	cfg := Config{
+		Modules: []string{"t_public", "t_panel", "t_status1", "t_status2"},
+		Configs: map[string]map[string]any{
+			"t_panel":   {"listener": "internal"},
+			"t_status1": {"listener": "internal"},
+			"t_status2": {"listener": "partner"},
+		},
+	}
+	cfg.Server.Listeners = map[string]string{"internal": "127.0.0.1:0", "partner": "127.0.0.1:0"}
+	app, err := StartApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	tests := []struct {
+		listener string // 空表示 listen / addr 的默认监听
+		path     string
+		status   int
+		body     string
+	}{
+		{"", "/pub", http.StatusOK, "public"},
+		{"", "/panel", http.StatusNotFound, ""},
+		{"", "/status", http.StatusNotFound, ""},
+		{"internal", "/pub", http.StatusOK, "public"},
+		{"internal", "/panel", http.StatusOK, "panel"},
+		{"internal", "/status", http.StatusOK, "internal status"},
+		{"partner", "/pub", http.StatusOK, "public"},
+		{"partner", "/panel", http.StatusNotFound, ""},
+		{"partner", "/status", http.StatusOK, "partner status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listener+tt.path, func(t *testing.T) {
+			base := app.URL
+			if tt.listener != "" {
+				base = app.Listeners[tt.listener]
+			}
+			resp, err := app.Client.Get(base + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.body != "" && string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
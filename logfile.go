@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// 访问日志与模块日志的输出目标：配置了 logging.file 时写入该文件，否则写标准输出。
+// logrotate 移走文件后发送 SIGHUP，reopen 在原路径重新创建文件，后续日志写入新文件
+type logWriter struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+var logOutput = &logWriter{}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.Stdout.Write(p)
+	}
+	return w.f.Write(p)
+}
+
+// 切换到 path（为空时写标准输出）；路径未变时保持当前文件。打开失败时沿用原输出
+func (w *logWriter) setPath(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if path == w.path {
+		return nil
+	}
+	return w.openLocked(path)
+}
+
+// 按当前路径重新打开文件
+func (w *logWriter) reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.path == "" {
+		return nil
+	}
+	return w.openLocked(w.path)
+}
+
+func (w *logWriter) openLocked(path string) error {
+	var f *os.File
+	if path != "" {
+		var err error
+		if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+	}
+	if w.f != nil {
+		w.f.Close()
+	}
+	w.path, w.f = path, f
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 访问日志配置
+type LoggingConfig struct {
+	AccessLog *bool  `yaml:"access_log"` // 是否记录访问日志，默认开启
+	Format    string `yaml:"format"`     // common | combined | json，为空时使用 gin 默认格式
+	Level     string `yaml:"level"`      // 模块日志的全局级别 debug | info | warn | error，默认 info
+	File      string `yaml:"file"`       // 访问日志与模块日志写入该文件而不是标准输出，收到 SIGHUP 时重新打开
+}
+
+func (l LoggingConfig) accessLogEnabled() bool {
+	return l.AccessLog == nil || *l.AccessLog
+}
+
+// 解析日志级别，为空或无法识别时返回 def
+func parseLogLevel(s string, def slog.Level) slog.Level {
+	var l slog.Level
+	if s == "" || l.UnmarshalText([]byte(strings.ToUpper(s))) != nil {
+		return def
+	}
+	return l
+}
+
+// 为模块创建子 logger，级别与格式取自 state，随重载更新而无需替换交给模块的 logger
+func moduleLogger(name string, state *logState) *slog.Logger {
+	return slog.New(&switchHandler{state: state}).With("module", name)
+}
+
+// 单个模块实例的日志设置：模块配置的 log_level 优先，否则继承 logging.level；
+// logging.format 为 json 时输出 JSON，否则输出 key=value 文本
+type logState struct {
+	level slog.LevelVar
+	base  atomic.Pointer[logBase]
+}
+
+type logBase struct {
+	format string
+	h      slog.Handler
+}
+
+// 按配置更新级别；格式变化时换上新的底层 handler，正在输出的记录不受影响
+func (s *logState) apply(cfg Config, modCfg module.ModuleConfig) {
+	level := parseLogLevel(cfg.Logging.Level, slog.LevelInfo)
+	s.level.Set(parseLogLevel(modCfg.GetString("log_level", ""), level))
+	format := "text"
+	if cfg.Logging.Format == "json" {
+		format = "json"
+	}
+	if b := s.base.Load(); b != nil && b.format == format {
+		return
+	}
+	opts := &slog.HandlerOptions{Level: &s.level}
+	var h slog.Handler
+	if format == "json" {
+		h = slog.NewJSONHandler(logOutput, opts)
+	} else {
+		h = slog.NewTextHandler(logOutput, opts)
+	}
+	s.base.Store(&logBase{format: format, h: h})
+}
+
+// 每条记录交给 state 当前的底层 handler；WithAttrs / WithGroup 记录下来在底层 handler 上重放，
+// 重放结果缓存到底层 handler 被替换为止
+type switchHandler struct {
+	state  *logState
+	ops    []func(slog.Handler) slog.Handler
+	cached atomic.Pointer[derivedHandler]
+}
+
+type derivedHandler struct {
+	base *logBase
+	h    slog.Handler
+}
+
+func (h *switchHandler) current() slog.Handler {
+	b := h.state.base.Load()
+	if d := h.cached.Load(); d != nil && d.base == b {
+		return d.h
+	}
+	out := b.h
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	h.cached.Store(&derivedHandler{base: b, h: out})
+	return out
+}
+
+func (h *switchHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.state.level.Level()
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.current().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithAttrs(attrs) })
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithGroup(name) })
+}
+
+func (h *switchHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	return &switchHandler{state: h.state, ops: append(slices.Clip(h.ops), op)}
+}
+
+// 按配置创建根路由：gin.New() + 可信代理 + 请求 ID + 访问日志 + JSON 错误恢复 + 请求体限制 + CORS + 响应压缩
+func newEngine(cfg Config) *gin.Engine {
+	r := gin.New()
+	r.RedirectTrailingSlash = cfg.Server.redirectTrailingSlash()
+	r.RedirectFixedPath = cfg.Server.RedirectFixedPath != nil && *cfg.Server.RedirectFixedPath
+	// 格式已在 Update 中校验
+	if err := r.SetTrustedProxies(cfg.Server.trustedProxies()); err != nil {
+		fmt.Println("Invalid trusted proxies, trusting none:", err)
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(requestIDMiddleware())
+	if cfg.Logging.accessLogEnabled() {
+		r.Use(accessLogger(cfg.Logging.Format, cfg.Logging.File != ""))
+	}
+	r.Use(recoveryMiddleware(cfg.Server.errorStack()))
+	if cfg.Server.MaxBodyBytes > 0 {
+		r.Use(bodyLimitMiddleware(cfg.Server.MaxBodyBytes))
+	}
+	if cfg.Server.CORS != nil {
+		r.Use(corsMiddleware(cfg.Server.CORS))
+	}
+	if cfg.Server.Compression.Enabled {
+		r.Use(gzipMiddleware(&cfg.Server.Compression))
+	}
+	return r
+}
+
+// toFile 为 false 时直接写标准输出，gin 默认格式在终端中保留颜色
+func accessLogger(format string, toFile bool) gin.HandlerFunc {
+	var out io.Writer = os.Stdout
+	if toFile {
+		out = logOutput
+	}
+	switch format {
+	case "common":
+		return gin.LoggerWithConfig(gin.LoggerConfig{Formatter: commonLogFormat, Output: out})
+	case "combined":
+		return gin.LoggerWithConfig(gin.LoggerConfig{Output: out, Formatter: func(p gin.LogFormatterParams) string {
+			line := commonLogFormat(p)
+			return fmt.Sprintf("%s \"%s\" \"%s\" %s\n", line[:len(line)-1], p.Request.Referer(), p.Request.UserAgent(), requestIDField(p))
+		}})
+	case "json":
+		return gin.LoggerWithConfig(gin.LoggerConfig{Formatter: jsonLogFormat, Output: out})
+	default:
+		return gin.LoggerWithWriter(out)
+	}
+}
+
+// Common Log Format: host - - [time] "METHOD path PROTO" status size
+func commonLogFormat(p gin.LogFormatterParams) string {
+	return fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d\n",
+		p.ClientIP,
+		p.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		p.Method,
+		p.Path,
+		p.Request.Proto,
+		p.StatusCode,
+		p.BodySize,
+	)
+}
+
+// 访问日志中的请求 ID，缺失时输出 "-"
+func requestIDField(p gin.LogFormatterParams) string {
+	if id, ok := p.Keys[module.RequestIDKey].(string); ok && id != "" {
+		return id
+	}
+	return "-"
+}
+
+func jsonLogFormat(p gin.LogFormatterParams) string {
+	entry := map[string]any{
+		"time":       p.TimeStamp.Format("2006-01-02T15:04:05.000Z07:00"),
+		"method":     p.Method,
+		"path":       p.Path,
+		"status":     p.StatusCode,
+		"latency_ms": float64(p.Latency.Microseconds()) / 1000,
+		"client_ip":  p.ClientIP,
+		"size":       p.BodySize,
+		"request_id": requestIDField(p),
+	}
+	if p.ErrorMessage != "" {
+		entry["error"] = p.ErrorMessage
+	}
+	data, _ := json.Marshal(entry)
+	return string(data) + "\n"
+}
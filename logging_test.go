@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 把访问日志写入临时文件，返回读取全部内容的函数
+func captureLogOutput(t *testing.T) func() string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := logOutput.setPath(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logOutput.setPath("") })
+	return func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   *regexp.Regexp // 匹配整行（不含换行）
+	}{
+		{"common", regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "GET /items HTTP/1\.1" 201 2$`)},
+		{"combined", regexp.MustCompile(`^192\.0\.2\.1 - - \[[^\]]+\] "GET /items HTTP/1\.1" 201 2 "http://ref/" "test-agent" req-1$`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			read := captureLogOutput(t)
+			serveLogged(tt.format)
+			if line := strings.TrimSuffix(read(), "\n"); !tt.want.MatchString(line) {
+				t.Errorf("log line = %q, want match %s", line, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONAccessLog(t *testing.T) {
+	read := captureLogOutput(t)
+	serveLogged("json")
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(read()), &entry); err != nil {
+		t.Fatalf("access log is not one JSON object: %v", err)
+	}
+	tests := []struct {
+		field string
+		want  any
+	}{
+		{"method", "GET"},
+		{"path", "/items"},
+		{"status", 201.0},
+		{"client_ip", "192.0.2.1"},
+		{"size", 2.0},
+		{"request_id", "req-1"},
+	}
+	for _, tt := range tests {
+		if entry[tt.field] != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, entry[tt.field], tt.want)
+		}
+	}
+	for _, field := range []string{"time", "latency_ms"} {
+		if _, ok := entry[field]; !ok {
+			t.Errorf("missing field %s", field)
+		}
+	}
+}
+
+// 经过请求 ID 与访问日志中间件处理一个请求
+func serveLogged(format string) {
+	r := gin.New()
+	r.Use(requestIDMiddleware(), accessLogger(format, true))
+	r.GET("/items", func(c *gin.Context) { c.String(http.StatusCreated, "ok") })
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Referer", "http://ref/")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-ID", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// Init 时在各级别各输出一条日志
+type chattyModule struct{ module.Base }
+
+func (m *chattyModule) Init(cfg module.ModuleConfig) error {
+	m.Logger().Debug("debug line")
+	m.Logger().Info("info line")
+	m.Logger().Error("error line")
+	return nil
+}
+
+func TestModuleLogLevels(t *testing.T) {
+	for _, name := range []string{"t_quiet", "t_loud", "t_default"} {
+		registry.Modules[name] = func() module.Module { return &chattyModule{} }
+	}
+	t.Cleanup(func() {
+		for _, name := range []string{"t_quiet", "t_loud", "t_default"} {
+			delete(registry.Modules, name)
+		}
+	})
+	read := captureLogOutput(t)
+	cfg := Config{
+		Modules: []string{"t_quiet", "t_loud", "t_default"},
+		Configs: map[string]map[string]any{
+			"t_quiet": {"log_level": "error"},
+			"t_loud":  {"log_level": "debug"},
+		},
+		Logging: LoggingConfig{Level: "info"},
+	}
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	if _, err := m.Update(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := read()
+
+	tests := []struct {
+		module string
+		want   []string // 应输出的级别
+		absent []string
+	}{
+		{"t_quiet", []string{"error"}, []string{"info", "debug"}},
+		{"t_loud", []string{"debug", "info", "error"}, nil},
+		{"t_default", []string{"info", "error"}, []string{"debug"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			for _, level := range tt.want {
+				if line := fmt.Sprintf(`msg="%s line" module=%s`, level, tt.module); !strings.Contains(out, line) {
+					t.Errorf("missing %s log:\n%s", level, out)
+				}
+			}
+			for _, level := range tt.absent {
+				if line := fmt.Sprintf(`msg="%s line" module=%s`, level, tt.module); strings.Contains(out, line) {
+					t.Errorf("%s log not suppressed", level)
+				}
+			}
+		})
+	}
+}
+
+// GET /ip 返回 gin 解析出的客户端 IP
+type clientIPModule struct{ module.Base }
+
+func (m *clientIPModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+}
+
+func TestTrustedProxies(t *testing.T) {
+	registry.Modules["t_ip"] = func() module.Module { return &clientIPModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_ip") })
+	tests := []struct {
+		name    string
+		proxies []string
+		peer    string
+		want    string
+		wantErr bool
+	}{
+		{"loopback trusted by default", nil, "127.0.0.1:4000", "203.0.113.9", false},
+		{"other peers untrusted by default", nil, "10.0.0.5:4000", "10.0.0.5", false},
+		{"configured CIDR trusted", []string{"10.0.0.0/8"}, "10.0.0.5:4000", "203.0.113.9", false},
+		{"configured list replaces default", []string{"10.0.0.0/8"}, "127.0.0.1:4000", "127.0.0.1", false},
+		{"empty list trusts none", []string{}, "127.0.0.1:4000", "127.0.0.1", false},
+		{"invalid entry rejected", []string{"not-an-ip"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_ip"}}
+			cfg.Server.TrustedProxies = tt.proxies
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Update accepted an invalid trusted proxy")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.peer
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// GET /log 在处理请求时输出 debug 与 info 日志；inits 统计 Init 次数
+type requestLogModule struct {
+	module.Base
+	inits *atomic.Int32
+}
+
+func (m *requestLogModule) Init(cfg module.ModuleConfig) error {
+	m.inits.Add(1)
+	return nil
+}
+
+func (m *requestLogModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/log", func(c *gin.Context) {
+		m.Logger().Debug("request debug")
+		m.Logger().Info("request info")
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func TestLoggingHotReload(t *testing.T) {
+	var inits atomic.Int32
+	registry.Modules["t_reqlog"] = func() module.Module { return &requestLogModule{inits: &inits} }
+	t.Cleanup(func() { delete(registry.Modules, "t_reqlog") })
+	read := captureLogOutput(t)
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+
+	// 依次重载，每步只检查该步请求产生的日志
+	tests := []struct {
+		name    string
+		logging LoggingConfig
+		want    []string
+		absent  []string
+	}{
+		{"info", LoggingConfig{Level: "info"}, []string{`msg="request info"`}, []string{"request debug"}},
+		{"debug enabled by reload", LoggingConfig{Level: "debug"}, []string{`msg="request debug"`, `msg="request info"`}, nil},
+		{"switch to json", LoggingConfig{Level: "debug", Format: "json"}, []string{`"msg":"request debug"`, `"module":"t_reqlog"`}, []string{`msg="request`}},
+		{"warn suppresses both", LoggingConfig{Level: "warn", Format: "json"}, nil, []string{"request debug", "request info"}},
+		{"back to text", LoggingConfig{Level: "info"}, []string{`msg="request info" module=t_reqlog`}, []string{"request debug", `"msg"`}},
+	}
+	var offset int
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.logging.AccessLog = new(bool) // 只看模块日志
+			r, err := m.Update(context.Background(), Config{Modules: []string{"t_reqlog"}, Logging: tt.logging})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/log", nil))
+			all := read()
+			out := all[offset:]
+			offset = len(all)
+			for _, s := range tt.want {
+				if !strings.Contains(out, s) {
+					t.Errorf("log is missing %s:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(out, s) {
+					t.Errorf("log contains %s:\n%s", s, out)
+				}
+			}
+			// 日志设置的变化不会重新初始化模块
+			if n := inits.Load(); n != 1 {
+				t.Errorf("t_reqlog initialized %d times, want 1", n)
+			}
+		})
+	}
+}
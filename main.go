@@ -1,238 +1,418 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
-	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
-	"myapp/module"
 	"myapp/registry"
-	"myapp/utils"
 )
 
-type Config struct {
-	Modules []string                  `yaml:"modules"`
-	Configs map[string]map[string]any `yaml:"configs"`
-}
-
-type ModuleManager struct {
-	active map[string]module.Module
-	lock   sync.Mutex
-}
+var (
+	router       *routerSet
+	routerRefs   = &inflightCounter{} // 持有当前 router 的在途请求数
+	routerAdmin  string               // 当前 router 的管理端点路径前缀，这些请求不登记在 routerRefs 中
+	manager      = NewModuleManager()
+	globalRouter sync.Mutex
+	reloadLock   sync.Mutex // 串行化整个重建过程（文件监听、管理端点）
+	source       ConfigSource
+)
 
-func NewModuleManager() *ModuleManager {
-	return &ModuleManager{active: make(map[string]module.Module)}
+func rebuildRouter(ctx context.Context, cfg Config, reinit ...string) error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	return rebuildLocked(ctx, cfg, reinit...)
 }
 
-func resolveDependencies(modNames []string) ([]string, error) {
-	visited := make(map[string]bool)
-	result := []string{}
-	var visit func(string) error
+// 调用方持有 reloadLock
+func rebuildLocked(ctx context.Context, cfg Config, reinit ...string) error {
+	if err := logOutput.setPath(cfg.Logging.File); err != nil {
+		fmt.Println("Cannot open log file, keeping current log output:", err)
+	}
+	// 重载期间 /readyz 返回 503；失败时恢复为重载前的状态
+	wasReady := manager.ready.Swap(false)
 
-	visit = func(name string) error {
-		if visited[name] {
-			return nil
-		}
-		factory, ok := registry.Modules[name]
-		if !ok {
-			return fmt.Errorf("unknown module: %s", name)
-		}
-		tmp := factory() // 创建临时实例来获取依赖
-		for _, dep := range tmp.Deps() {
-			if err := visit(dep); err != nil {
-				return err
-			}
+	// Update 可能因重试退避耗时较长，构建期间不持有 globalRouter，避免阻塞正在服务的请求
+	r, err := manager.Update(ctx, cfg, reinit...)
+	globalRouter.Lock()
+	if err != nil {
+		fmt.Println("Reload failed, keeping previous router:", err)
+		if router == nil {
+			router, routerAdmin = &routerSet{Engine: newEngine(cfg)}, cfg.Server.adminPrefix()+"/admin/"
 		}
-		visited[name] = true
-		result = append(result, name)
-		return nil
+		globalRouter.Unlock()
+		manager.ready.Store(wasReady)
+		return err
 	}
+	router, routerAdmin = r, cfg.Server.adminPrefix()+"/admin/"
+	oldRefs := routerRefs
+	routerRefs = &inflightCounter{}
+	globalRouter.Unlock()
+	applyMaxProcs(cfg.Server)
+	applyDispatchLimit(cfg.Server)
+	if g := httpServers.Load(); g != nil {
+		g.apply(cfg.Server)
+	}
+	manager.ready.Store(manager.allInitialized())
 
-	for _, m := range modNames {
-		if err := visit(m); err != nil {
-			return nil, err
-		}
+	// 先等待所有取得旧引擎的请求结束（它们可能尚未进入模块的计数中间件），再关闭被移除的模块
+	deadline := time.Now().Add(cfg.Server.drainTimeout())
+	if !oldRefs.wait(deadline) {
+		fmt.Println("Drain timeout, previous router still has in-flight requests")
 	}
-	return result, nil
+	manager.StopRetired(time.Until(deadline))
+	return nil
 }
 
-func (m *ModuleManager) Update(cfg Config) *gin.Engine {
-	m.lock.Lock()
-	defer m.lock.Unlock()
-
-	ordered, err := resolveDependencies(cfg.Modules)
+// 从配置来源重新读取并重建路由
+func reloadConfig(ctx context.Context) error {
+	cfg, err := source.Load()
 	if err != nil {
-		fmt.Println("Dependency resolution error:", err)
-		return gin.Default()
-	}
-
-	newActive := make(map[string]module.Module)
-	r := gin.Default()
-
-	// 启动新模块
-	for _, name := range ordered {
-		if old, exists := m.active[name]; exists {
-			// 已存在，保留
-			newActive[name] = old
-			old.RegisterRoutes(r)
-		} else if newFn, ok := registry.Modules[name]; ok {
-			mod := newFn()
-			modCfg := module.ModuleConfig(cfg.Configs[name])
-			if err := mod.Init(modCfg); err != nil {
-				fmt.Println("Failed to init module:", name, err)
-				continue
-			}
-			mod.RegisterRoutes(r)
-			newActive[name] = mod
-			fmt.Println("Started module:", name)
-		}
-	}
-
-	// 停止不再需要的模块（逆序）
-	for i := len(ordered) - 1; i >= 0; i-- {
-		name := ordered[i]
-		if _, stillActive := newActive[name]; stillActive {
-			continue
-		}
-		if old, exists := m.active[name]; exists {
-			if err := old.Shutdown(); err != nil {
-				fmt.Println("Error shutting down module:", name, err)
-			} else {
-				fmt.Println("Stopped module:", name)
-			}
-		}
+		fmt.Println("Error loading config:", err)
+		return err
 	}
-
-	m.active = newActive
-	return r
+	return rebuildRouter(ctx, cfg)
 }
 
-func loadConfig() (Config, error) {
-	data, err := os.ReadFile("config.yaml")
-	if err != nil {
-		return Config{}, err
+// 监听地址优先级：-addr 参数 > PORT / ADDR 环境变量 > server.listen > server.addr > :8080
+func listenAddrs(flagAddr string, server ServerConfig) []string {
+	if flagAddr != "" {
+		return []string{flagAddr}
 	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return Config{}, err
+	if port := os.Getenv("PORT"); port != "" {
+		return []string{":" + port}
 	}
-	
-	newCfg := Config{
-		Modules: cfg.Modules,
-		Configs: map[string]map[string]any{},
+	if addr := os.Getenv("ADDR"); addr != "" {
+		return []string{addr}
 	}
-	for k, v := range cfg.Configs {
-		expanded := utils.ExpandConfig(v)
-		if m, ok := expanded.(map[string]any); ok {
-			newCfg.Configs[k] = m
-		}
+	if len(server.Listen) > 0 {
+		return server.Listen
 	}
-	return newCfg, nil
+	if server.Addr != "" {
+		return []string{server.Addr}
+	}
+	return []string{":8080"}
 }
 
-var (
-	router       *gin.Engine
-	manager      = NewModuleManager()
-	globalRouter sync.Mutex
-)
+// 首次构建完成之前的请求返回 503，建议客户端等待的秒数
+const startupRetryAfter = 1
 
-func rebuildRouter(cfg Config) {
+// 取得处理监听 listener 上本次请求的路由引擎并登记引用：有金丝雀时按比例选择其路由。
+// 请求处理完成后必须以响应状态码调用 release；首次构建完成之前返回 nil。
+// 管理请求不登记引用：reload 等管理操作会在处理过程中替换路由并等待旧路由的引用归零，登记后只能等到 drain_timeout
+func acquireHandler(r *http.Request, listener string) (*gin.Engine, func(status int)) {
 	globalRouter.Lock()
 	defer globalRouter.Unlock()
-	router = manager.Update(cfg)
+	if router == nil {
+		return nil, func(int) {}
+	}
+	if strings.HasPrefix(r.URL.Path, routerAdmin) {
+		return router.forListener(listener), stableTraffic.record
+	}
+	if c := canary; c != nil && c.pick(r) {
+		return c.acquire(listener)
+	}
+	refs := routerRefs
+	refs.acquire()
+	return router.forListener(listener), func(status int) {
+		stableTraffic.record(status)
+		refs.release()
+	}
 }
 
-func handler() *gin.Engine {
-	globalRouter.Lock()
-	defer globalRouter.Unlock()
-	return router
+// 停止接收新连接并等待在途请求结束；超过 timeout 仍未结束时强制关闭所有连接
+func shutdownServer(srv *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Println("Graceful shutdown timed out, forcing close:", err)
+		srv.Close()
+	}
 }
 
-func main() {
-	// 设置 Gin 模式
-	devMode := os.Getenv("APP_ENV") == "dev"
+// gin 模式的优先级：server.gin_mode（debug / release / test）> APP_ENV=dev 时为 debug > release。
+// 只在启动时生效，重载不会切换模式
+func ginMode(server ServerConfig, devMode bool) string {
+	switch server.GinMode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+		return server.GinMode
+	case "":
+	default:
+		fmt.Printf("Unknown server.gin_mode %q, falling back to APP_ENV\n", server.GinMode)
+	}
 	if devMode {
-		gin.SetMode(gin.DebugMode)
-		fmt.Println("[dev mode] Gin running in DebugMode")
-	} else {
-		gin.SetMode(gin.ReleaseMode)
-		fmt.Println("Gin running in ReleaseMode")
+		return gin.DebugMode
 	}
+	return gin.ReleaseMode
+}
 
-	// 如果是 dump 模式
-	if len(os.Args) > 1 && os.Args[1] == "dump" {
-		cfg, err := loadConfig()
-		if err != nil {
-			log.Fatal("Failed to load config:", err)
-		}
-		data, _ := json.MarshalIndent(cfg, "", "  ")
-		fmt.Println(string(data))
-		return
+// 启动时的 GOMAXPROCS（通常为 CPU 数，或 GOMAXPROCS 环境变量），server.max_procs 移除后恢复为该值
+var defaultMaxProcs = runtime.GOMAXPROCS(0)
+
+// 按 server.max_procs 调整 GOMAXPROCS，值未变化时不做任何操作
+func applyMaxProcs(server ServerConfig) {
+	n := server.MaxProcs
+	if n <= 0 {
+		n = defaultMaxProcs
+	}
+	if prev := runtime.GOMAXPROCS(0); prev != n {
+		runtime.GOMAXPROCS(n)
+		fmt.Printf("GOMAXPROCS set to %d (was %d)\n", n, prev)
 	}
+}
 
-	// 正常启动 Gin 服务
-	cfg, err := loadConfig()
-	if err != nil {
-		log.Fatal(err)
+// 启动时的首次构建：构建失败，或启用了模块却没有一个初始化成功时返回错误，
+// 由 main 直接退出，避免绑定端口后以空路由对外服务
+func startupBuild(ctx context.Context, cfg Config) error {
+	if err := rebuildRouter(ctx, cfg); err != nil {
+		return err
+	}
+	if len(cfg.enabledModules()) > 0 && len(manager.ActiveModules()) == 0 {
+		return fmt.Errorf("no module started: %w", manager.initError())
 	}
-	rebuildRouter(cfg)
+	return nil
+}
 
-	// 文件监控
+// 收到 SIGHUP 时重新加载配置并重建路由，即使配置文件没有变化：${VAR} 与 ${file:...} 按当前值重新展开，
+// 用于轮换挂载为文件的密钥（或由父进程更新环境后）使新值生效，效果与 POST /admin/reload 相同
+func reloadOnSignal() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			log.Fatal(err)
+		for range hup {
+			handleSIGHUP(context.Background())
 		}
-		defer watcher.Close()
+	}()
+}
 
-		if err := watcher.Add("config.yaml"); err != nil {
-			log.Fatal(err)
-		}
+// 先重新打开 logging.file（配合 logrotate），再重载配置
+func handleSIGHUP(ctx context.Context) error {
+	fmt.Println("SIGHUP received, reopening log file and reloading config")
+	if err := logOutput.reopen(); err != nil {
+		fmt.Println("Error reopening log file:", err)
+	}
+	if err := reloadConfig(ctx); err != nil {
+		return err
+	}
+	fmt.Println("Config reloaded, modules:", manager.ActiveModules())
+	return nil
+}
 
-		// 提示 dev 模式
-		if devMode {
-			fmt.Println("[dev mode] Watching config.yaml ...")
-		} else {
-			fmt.Println("Watching config.yaml ...")
+// 监听配置来源，按最新推送的配置重建路由；-no-watch 或 server.watch_enabled: false 时不启动监听，
+// 配置只在启动时加载一次（仍可通过 /admin/reload 手动重载）。返回是否已启动监听
+func watchConfig(src ConfigSource, cfg Config, disabled bool) bool {
+	if disabled || !cfg.Server.watchEnabled() {
+		fmt.Println("Config watching disabled")
+		return false
+	}
+	updates := make(chan Config)
+	go func() {
+		// 监听失败不影响正在服务的进程，只是之后的配置变化需通过 SIGHUP 或 /admin/reload 应用
+		if err := src.Watch(updates); err != nil {
+			fmt.Println("Config watching stopped:", err)
 		}
-
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
-					fmt.Println("Config changed, reloading...")
-					newCfg, err := loadConfig()
-					if err != nil {
-						fmt.Println("Error loading config:", err)
-						continue
-					}
-					rebuildRouter(newCfg)
-				}
-			case err := <-watcher.Errors:
-				fmt.Println("Watcher error:", err)
-			}
+	}()
+	go func() {
+		var latest atomic.Pointer[Config]
+		reloads := newReloadCoalescer(func(ctx context.Context) {
+			rebuildRouter(ctx, *latest.Load())
+		})
+		reloads.minInterval = func() time.Duration { return manager.EffectiveConfig().Server.MinReloadInterval }
+		for newCfg := range updates {
+			latest.Store(&newCfg)
+			reloads.Trigger()
 		}
 	}()
+	return true
+}
 
-	// HTTP server
-	ginEngine := gin.New()
+func main() {
+	// 如果是 dump / schema / validate 模式（在打印启动信息之前处理，保证输出可直接用于管道）
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		runDump(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		runSchema()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		runValidate(os.Args[2:])
+		return
+	}
 
-	// 开发模式下启用 pprof
-	if devMode {
-		pprof.Register(ginEngine)
-		fmt.Println("[dev mode] pprof enabled at /debug/pprof")
+	devMode := os.Getenv("APP_ENV") == "dev"
+
+	sourceSpec := flag.String("source", "file", `config source: "file", "file:<path>" or "snapshot:<file or URL>"`)
+	flag.StringVar(&activeProfile, "profile", activeProfile, "config profile to apply (defaults to APP_ENV)")
+	pprofFlag := flag.Bool("pprof", false, "enable /debug/pprof regardless of APP_ENV")
+	addrFlag := flag.String("addr", "", "listen address, overrides PORT/ADDR env, server.listen and server.addr")
+	noWatchFlag := flag.Bool("no-watch", false, "load config once at startup without watching for changes")
+	configWaitFlag := flag.Duration("config-wait", 30*time.Second, "how long to wait for a missing config file at startup (0 to fail immediately)")
+	flag.Parse()
+	if activeProfile != "" {
+		fmt.Println("Using config profile:", activeProfile)
 	}
 
-	ginEngine.Any("/*path", func(c *gin.Context) {
-		handler().ServeHTTP(c.Writer, c.Request)
+	// 正常启动 Gin 服务；模块间的依赖声明有误属于编码错误，直接退出
+	if err := registry.Validate(); err != nil {
+		log.Fatal("Invalid module registry: ", err)
+	}
+	var err error
+	source, err = newConfigSource(*sourceSpec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fileSrc, ok := source.(*FileSource); ok {
+		if err := fileSrc.waitForFile(*configWaitFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	cfg, err := source.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 收到 SIGINT / SIGTERM 时所有地址同时停止接收新连接并等待请求结束；Unix socket 文件随监听关闭删除
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	err = Run(ctx, cfg, RunOptions{
+		Addrs: listenAddrs(*addrFlag, cfg.Server),
+		Pprof: devMode || *pprofFlag || cfg.Server.Pprof,
+		Mode:  ginMode(cfg.Server, devMode),
+		Started: func() {
+			watchConfig(source, cfg, *noWatchFlag)
+			reloadOnSignal()
+			go selfHeal()
+		},
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
 
-	ginEngine.Run(":8080")
+// 启动参数中由命令行与环境决定的部分
+type RunOptions struct {
+	Addrs []string // 监听地址，配置了 server.unix_socket 时忽略
+	Pprof bool     // 是否启用 pprof
+	Mode  string   // gin 运行模式，为空时使用 release
+	// 首次构建路由成功后、开始监听之前调用，main 在此启动配置监听等后台任务
+	Started func()
+	// 开始监听后以实际监听地址调用，监听 :0 时可据此得到端口
+	Listening func(addrs []net.Addr)
+}
+
+// Run 执行启动钩子后按 cfg 构建模块路由并在 opts.Addrs 上提供服务，直到 ctx 被取消或某个 server 出错：
+// 停止接收新连接并等待在途请求（最长 shutdown_timeout），再按启动逆序关闭全部模块。
+// 供 main 与 StartApp 共用；管理器与当前路由是进程级状态，同一时刻只能有一个 Run
+func Run(ctx context.Context, cfg Config, opts RunOptions) error {
+	// 设置 Gin 模式：须在构建任何路由引擎之前
+	mode := opts.Mode
+	if mode == "" {
+		mode = gin.ReleaseMode
+	}
+	gin.SetMode(mode)
+	if mode == gin.DebugMode {
+		fmt.Println("[dev mode] Gin running in DebugMode")
+	} else {
+		fmt.Println("Gin running in", mode, "mode")
+	}
+	if err := runStartupHooks(ctx, cfg.StartupHooks); err != nil {
+		return fmt.Errorf("startup failed: %w", err)
+	}
+	startLeaderElection(ctx, cfg.Server)
+	if err := startupBuild(ctx, cfg); err != nil {
+		return fmt.Errorf("startup failed: %w", err)
+	}
+	if opts.Started != nil {
+		opts.Started()
+	}
+
+	// HTTP server
+	// 每个监听名称一个入口 handler，请求交给该监听的路由；listen / addr 的监听名称为空
+	h2cEnabled := cfg.Server.HTTP2Cleartext && cfg.Server.TLS == nil
+	if h2cEnabled {
+		fmt.Println("HTTP/2 cleartext (h2c) enabled")
+	}
+	handlerFor := func(listener string) http.Handler {
+		var handler http.Handler = frontHandler(cfg.Server, opts.Pprof, listener)
+		// 明文 HTTP/2：接受 prior-knowledge 与 Upgrade 两种 h2c 连接，HTTP/1.1 客户端不受影响
+		if h2cEnabled {
+			handler = h2c.NewHandler(handler, &http2.Server{})
+		}
+		return handler
+	}
+	// 每个监听地址一个 server，共享证书；server 配置在重载时于原有监听上替换生效
+	servers, err := listenAll(cfg.Server, opts.Addrs, handlerFor)
+	if err != nil {
+		manager.ShutdownAll(0)
+		return err
+	}
+	httpServers.Store(servers)
+	defer httpServers.CompareAndSwap(servers, nil)
+	if opts.Listening != nil {
+		addrs := make([]net.Addr, len(servers.listeners))
+		for i, ln := range servers.listeners {
+			addrs[i] = ln.Addr()
+		}
+		opts.Listening(addrs)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- servers.serve() }()
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+		fmt.Println("Shutting down server...")
+		servers.shutdown(manager.EffectiveConfig().Server.shutdownTimeout())
+		err = <-served
+	}
+	// 请求已处理完毕，按启动逆序关闭全部模块
+	stopCanary(0)
+	manager.ShutdownAll(manager.EffectiveConfig().Server.drainTimeout())
+	return err
+}
+
+// 配置了 server.unix_socket 时监听 Unix socket（先删除残留的 socket 文件），否则监听 TCP 地址
+func listen(server ServerConfig, addr string) (net.Listener, error) {
+	if server.UnixSocket == "" {
+		return net.Listen("tcp", addr)
+	}
+	if info, err := os.Stat(server.UnixSocket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(server.UnixSocket); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", server.UnixSocket)
+	if err != nil {
+		return nil, err
+	}
+	if server.UnixSocketMode != "" {
+		mode, err := strconv.ParseUint(server.UnixSocketMode, 8, 32)
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("server.unix_socket_mode: invalid mode %q", server.UnixSocketMode)
+		}
+		if err := os.Chmod(server.UnixSocket, os.FileMode(mode)); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
 }
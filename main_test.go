@@ -0,0 +1,655 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 可在测试中替换内容的配置来源
+type mutableSource struct {
+	mu  sync.Mutex
+	cfg Config
+}
+
+func (s *mutableSource) Load() (Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg, nil
+}
+
+func (s *mutableSource) set(cfg Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+func (s *mutableSource) Watch(ch chan<- Config) error { return nil }
+
+// 以包级的 manager / router 运行测试，结束时关闭全部模块并恢复初始状态
+func useGlobalRouter(t testing.TB) {
+	t.Helper()
+	prevSource := source
+	reset := func() {
+		manager.ShutdownAll(0)
+		globalRouter.Lock()
+		router, routerAdmin, routerRefs = nil, "", &inflightCounter{}
+		globalRouter.Unlock()
+		manager = NewModuleManager()
+	}
+	// 先清掉之前 StartApp 等测试残留的路由，保证从"尚未构建"开始
+	reset()
+	t.Cleanup(func() {
+		reset()
+		source = prevSource
+	})
+}
+
+func TestAdminRequestsDoNotDelayDrain(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"t_a", "t_b"}}
+	cfg.Server.AdminToken = "secret"
+	cfg.Server.DrainTimeout = 3 * time.Second
+	src := &mutableSource{cfg: cfg}
+	source = src
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"reload", http.MethodPost, "/admin/reload"},
+		{"stats", http.MethodGet, "/admin/stats"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每次去掉一个模块，使重载需要排空并关闭被移除的模块
+			next := cfg
+			next.Modules = []string{"t_a"}
+			src.set(next)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			start := time.Now()
+			front.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("%s took %s, admin request waited for its own router to drain", tt.path, elapsed)
+			}
+		})
+	}
+}
+
+// 持续请求的同时反复替换路由：请求只会落到尚未关闭的模块实例上（配合 -race 运行）
+func TestRouterSwapWithInflightRequests(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"t_a", "t_b"}}
+	cfg.Server.DrainTimeout = 2 * time.Second
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+
+	var (
+		stop     atomic.Bool
+		requests atomic.Int64
+		wg       sync.WaitGroup
+	)
+	errs := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				w := httptest.NewRecorder()
+				front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t_a", nil))
+				requests.Add(1)
+				if w.Code != http.StatusOK {
+					select {
+					case errs <- w.Body.String():
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+	// 至少有请求在途后再开始替换，否则可能全部替换完成时还没有请求发出
+	eventually(t, 5*time.Second, "first request served", func() bool { return requests.Load() > 0 })
+	for i := 0; i < 20; i++ {
+		// reinit 强制替换 t_a 的实例，旧实例在排空后关闭
+		if err := rebuildRouter(context.Background(), cfg, "t_a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("request failed during router swap: %s", err)
+	}
+	if requests.Load() == 0 {
+		t.Fatal("no requests were served")
+	}
+}
+
+func TestListenAddrs(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   string
+		port   string
+		addr   string
+		server ServerConfig
+		want   []string
+	}{
+		{"default", "", "", "", ServerConfig{}, []string{":8080"}},
+		{"server.addr", "", "", "", ServerConfig{Addr: ":9000"}, []string{":9000"}},
+		{"server.listen over server.addr", "", "", "", ServerConfig{Addr: ":9000", Listen: []string{"[::1]:9001", "127.0.0.1:9001"}}, []string{"[::1]:9001", "127.0.0.1:9001"}},
+		{"ADDR env over config", "", "", "0.0.0.0:7000", ServerConfig{Addr: ":9000"}, []string{"0.0.0.0:7000"}},
+		{"PORT env over ADDR", "", "5000", "0.0.0.0:7000", ServerConfig{Addr: ":9000"}, []string{":5000"}},
+		{"flag over everything", "127.0.0.1:4000", "5000", "0.0.0.0:7000", ServerConfig{Addr: ":9000"}, []string{"127.0.0.1:4000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", tt.port)
+			t.Setenv("ADDR", tt.addr)
+			if got := listenAddrs(tt.flag, tt.server); !slices.Equal(got, tt.want) {
+				t.Errorf("listenAddrs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTP2Cleartext(t *testing.T) {
+	// prior-knowledge h2c 客户端：不经 TLS 直接以 HTTP/2 连接
+	h2cClient := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		enabled   bool
+		h2c       bool
+		wantProto string // 空表示请求应当失败
+	}{
+		{"h2c client", true, true, "HTTP/2.0"},
+		{"http/1.1 client still served", true, false, "HTTP/1.1"},
+		{"h2c disabled", false, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.HTTP2Cleartext = tt.enabled
+			app, err := StartApp(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer app.Close()
+			client := app.Client
+			if tt.h2c {
+				client = h2cClient
+			}
+			resp, err := client.Get(app.URL + "/user")
+			if tt.wantProto == "" {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("h2c request succeeded with %s while disabled", resp.Proto)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || resp.Proto != tt.wantProto {
+				t.Errorf("got %d %s, want 200 %s", resp.StatusCode, resp.Proto, tt.wantProto)
+			}
+		})
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		stale    bool // 预先留下一个残留的 socket 文件
+		wantMode os.FileMode
+		wantErr  string
+	}{
+		{"fresh socket", "", false, 0, ""},
+		{"stale socket replaced", "", true, 0, ""},
+		{"configured mode", "0600", false, 0o600, ""},
+		{"invalid mode", "rw-", false, 0, `server.unix_socket_mode: invalid mode "rw-"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sock := filepath.Join(t.TempDir(), "app.sock")
+			if tt.stale {
+				ln, err := net.Listen("unix", sock)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ln.(*net.UnixListener).SetUnlinkOnClose(false)
+				ln.Close()
+			}
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.UnixSocket = sock
+			cfg.Server.UnixSocketMode = tt.mode
+			source = &staticSource{cfg: cfg}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			listening := make(chan struct{})
+			done := make(chan error, 1)
+			go func() {
+				done <- Run(ctx, cfg, RunOptions{Listening: func([]net.Addr) { close(listening) }})
+			}()
+			select {
+			case <-listening:
+			case err := <-done:
+				if tt.wantErr == "" || err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Run = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("Run started, want error %q", tt.wantErr)
+			}
+
+			if tt.wantMode != 0 {
+				info, err := os.Stat(sock)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := info.Mode().Perm(); got != tt.wantMode {
+					t.Errorf("socket mode = %v, want %v", got, tt.wantMode)
+				}
+			}
+			client := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			}}
+			resp, err := client.Get("http://unix/user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET /user over the socket = %d, want 200", resp.StatusCode)
+			}
+
+			// 优雅关闭后删除 socket 文件
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(sock); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("socket file still present after shutdown: %v", err)
+			}
+		})
+	}
+}
+
+func TestStartupFailsFast(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	registry.Modules["t_broken"] = func() module.Module { return &flakyModule{fails: 1000} }
+	t.Cleanup(func() { delete(registry.Modules, "t_broken") })
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string // 为空表示应正常启动
+	}{
+		{"healthy config", "modules: [t_a]\n", ""},
+		{"one broken module tolerated", "modules: [t_a, t_broken]\n", ""},
+		{"no module starts", "modules: [t_broken]\n", "startup failed: no module started: "},
+		{"unknown module", "modules: [t_missing]\n", "startup failed: "},
+		{"no modules configured", "modules: []\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"config.yaml": {Data: []byte(tt.yaml)}}
+			var cfg Config
+			var err error
+			captureStderr(t, func() { cfg, err = loadConfigFS(fsys, "config.yaml") })
+			if err != nil {
+				t.Fatal(err)
+			}
+			app, err := StartApp(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				app.Close()
+				return
+			}
+			if err == nil {
+				app.Close()
+				t.Fatalf("startup succeeded, want error %q", tt.wantErr)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want prefix %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShutdownTimeoutForcesClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		hold     time.Duration // 处理器在收到关闭信号后还要运行的时间
+		wantCode int           // 0 表示连接被强制关闭
+	}{
+		{"handler finishes in time", 2 * time.Second, 50 * time.Millisecond, http.StatusOK},
+		{"forced close after deadline", 100 * time.Millisecond, 5 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mod := &blockingModule{entered: make(chan struct{}), release: make(chan struct{})}
+			registry.Modules["t_block"] = func() module.Module { return mod }
+			t.Cleanup(func() { delete(registry.Modules, "t_block") })
+			cfg := Config{Modules: []string{"t_block"}}
+			cfg.Server.ShutdownTimeout = tt.timeout
+			// 强制关闭后模块仍会等待在途请求 drain_timeout，这里缩短以免掩盖 shutdown_timeout
+			cfg.Server.DrainTimeout = 100 * time.Millisecond
+			app, err := StartApp(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			type result struct {
+				code int
+				err  error
+			}
+			done := make(chan result, 1)
+			go func() {
+				resp, err := app.Client.Get(app.URL + "/slow")
+				if err != nil {
+					done <- result{err: err}
+					return
+				}
+				resp.Body.Close()
+				done <- result{code: resp.StatusCode}
+			}()
+			<-mod.entered
+			timer := time.AfterFunc(tt.hold, func() { close(mod.release) })
+			defer func() {
+				if timer.Stop() {
+					close(mod.release)
+				}
+			}()
+
+			start := time.Now()
+			app.Close()
+			closed := time.Since(start)
+			if closed > tt.timeout+cfg.Server.DrainTimeout+time.Second {
+				t.Errorf("shutdown took %v with shutdown_timeout %v", closed, tt.timeout)
+			}
+			if mod.shutdownAt.Load() == nil {
+				t.Error("module not shut down after the server stopped")
+			}
+			res := <-done
+			if res.code != tt.wantCode {
+				t.Errorf("request = %d (%v), want %d", res.code, res.err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGinMode(t *testing.T) {
+	t.Cleanup(func() { gin.SetMode(gin.TestMode) })
+	tests := []struct {
+		name    string
+		setting string
+		devMode bool
+		want    string
+	}{
+		{"release by default", "", false, gin.ReleaseMode},
+		{"debug in dev", "", true, gin.DebugMode},
+		{"config overrides dev", gin.ReleaseMode, true, gin.ReleaseMode},
+		{"config debug outside dev", gin.DebugMode, false, gin.DebugMode},
+		{"config test mode", gin.TestMode, false, gin.TestMode},
+		{"unknown value falls back to APP_ENV", "verbose", true, gin.DebugMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.GinMode = tt.setting
+			var mode string
+			captureStdout(t, func() { mode = ginMode(cfg.Server, tt.devMode) })
+			if mode != tt.want {
+				t.Fatalf("ginMode = %q, want %q", mode, tt.want)
+			}
+
+			// Run 在构建路由引擎之前应用该模式
+			source = &staticSource{cfg: cfg}
+			ctx, cancel := context.WithCancel(context.Background())
+			applied := make(chan string, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- Run(ctx, cfg, RunOptions{
+					Addrs:     []string{"127.0.0.1:0"},
+					Mode:      mode,
+					Listening: func([]net.Addr) { applied <- gin.Mode() },
+				})
+			}()
+			select {
+			case got := <-applied:
+				if got != tt.want {
+					t.Errorf("gin.Mode() = %q, want %q", got, tt.want)
+				}
+			case err := <-done:
+				t.Fatalf("Run = %v", err)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMultipleListenAddrs(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	prevSource := source
+	t.Cleanup(func() { source = prevSource })
+	tests := []struct {
+		name  string
+		addrs []string
+	}{
+		{"single", []string{"127.0.0.1:0"}},
+		{"two ipv4 ports", []string{"127.0.0.1:0", "127.0.0.1:0"}},
+		{"ipv4 and ipv6", []string{"127.0.0.1:0", "[::1]:0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_a"}}
+			source = &staticSource{cfg: cfg}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			listening := make(chan []net.Addr, 1)
+			done := make(chan error, 1)
+			go func() {
+				done <- Run(ctx, cfg, RunOptions{Addrs: tt.addrs, Listening: func(addrs []net.Addr) { listening <- addrs }})
+			}()
+			var addrs []net.Addr
+			select {
+			case addrs = <-listening:
+			case err := <-done:
+				if strings.Contains(err.Error(), "[::1]") {
+					t.Skipf("IPv6 loopback unavailable: %v", err)
+				}
+				t.Fatalf("Run = %v", err)
+			}
+			if len(addrs) != len(tt.addrs) {
+				t.Fatalf("listening on %v, want %d addresses", addrs, len(tt.addrs))
+			}
+
+			// 每个地址都由同一个处理器提供模块路由
+			client := &http.Client{Timeout: 5 * time.Second}
+			for _, addr := range addrs {
+				resp, err := client.Get("http://" + addr.String() + "/t_a")
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK || string(body) != "t_a" {
+					t.Errorf("GET %s/t_a = %d %q", addr, resp.StatusCode, body)
+				}
+			}
+
+			// 关闭时所有地址一起停止监听
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			for _, addr := range addrs {
+				if conn, err := net.DialTimeout("tcp", addr.String(), time.Second); err == nil {
+					conn.Close()
+					t.Errorf("%s still accepting connections after shutdown", addr)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyMaxProcs(t *testing.T) {
+	t.Cleanup(func() { runtime.GOMAXPROCS(defaultMaxProcs) })
+	tests := []struct {
+		name     string
+		maxProcs int
+		want     int
+	}{
+		{"override", 1, 1},
+		{"unchanged value", 1, 1},
+		{"another override", 2, 2},
+		// 移除配置后恢复启动时的值
+		{"removed", 0, defaultMaxProcs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := runtime.GOMAXPROCS(0)
+			out := captureStdout(t, func() { applyMaxProcs(ServerConfig{MaxProcs: tt.maxProcs}) })
+			if got := runtime.GOMAXPROCS(0); got != tt.want {
+				t.Errorf("GOMAXPROCS = %d, want %d", got, tt.want)
+			}
+			if logged := out != ""; logged != (prev != tt.want) {
+				t.Errorf("output = %q after changing GOMAXPROCS from %d to %d", out, prev, tt.want)
+			}
+		})
+	}
+}
+
+// 建立连接并只发送一半请求头，返回服务端关闭该连接前经过的时间（最长等待 max）
+func headerTimeoutAfter(t *testing.T, addr string, max time.Duration) time.Duration {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	if _, err := conn.Write([]byte("GET /t_a HTTP/1.1\r\nHost: x\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(start.Add(max))
+	io.Copy(io.Discard, conn)
+	return time.Since(start)
+}
+
+func TestReloadAppliesServerSettings(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	cfg := Config{Modules: []string{"t_a"}}
+	cfg.Server.ReadHeaderTimeout = 10 * time.Second
+	cfg.Server.ShutdownTimeout = time.Second
+	app, err := StartApp(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+	addr := strings.TrimPrefix(app.URL, "http://")
+	// 请求成功说明 serve 已打印监听地址；再经 mu 同步，避免与下面的 captureStdout 竞争
+	resp, err := app.Client.Get(app.URL + "/t_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	servers := httpServers.Load()
+	servers.mu.Lock()
+	servers.mu.Unlock()
+
+	tests := []struct {
+		name         string
+		headerTO     time.Duration
+		wantReplaced bool
+		wantClosed   bool // 半个请求头的连接是否在 1s 内被关闭
+	}{
+		{"timeout shortened", 200 * time.Millisecond, true, true},
+		{"unchanged settings", 200 * time.Millisecond, false, true},
+		{"timeout lengthened", 10 * time.Second, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := cfg
+			next.Server.ReadHeaderTimeout = tt.headerTO
+			out := captureStdout(t, func() { err = rebuildRouter(context.Background(), next) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(out, "replaced servers on existing listeners"); got != tt.wantReplaced {
+				t.Errorf("servers replaced = %v, want %v (output %q)", got, tt.wantReplaced, out)
+			}
+
+			// 端口保持绑定，请求照常处理
+			resp, err := app.Client.Get(app.URL + "/t_a")
+			if err != nil {
+				t.Fatalf("GET /t_a after reload: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET /t_a after reload = %d", resp.StatusCode)
+			}
+
+			// 新连接使用新的 read_header_timeout
+			elapsed := headerTimeoutAfter(t, addr, time.Second)
+			if closed := elapsed < 900*time.Millisecond; closed != tt.wantClosed {
+				t.Errorf("half-sent request closed after %v, want closed before 1s = %v", elapsed, tt.wantClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,1205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+type ModuleManager struct {
+	active   map[string]module.Module
+	order    []string // 当前激活模块的启动顺序
+	inflight map[string]*inflightCounter
+	retired  []retiredModule                // 上次 Update 移除、等待排空后关闭的模块
+	configs  map[string]module.ModuleConfig // 各激活模块上次应用的配置，用于增量重载
+	cfg      Config                         // 上次成功应用的完整配置
+	failed   int                            // 上次 Update 中初始化失败的模块数
+	initErr  error                          // 上次 Update 中各模块的初始化错误（合并）
+	ready    atomic.Bool                    // 供 /readyz 使用，由 rebuildRouter 在重载前后切换
+	states   map[string]*ModuleState        // 各模块的历史初始化情况，模块移除后保留
+	lives    map[string]*module.Lifecycle   // 各激活实例的生命周期状态
+	workers  map[string]*workerGroup        // 各激活实例正在运行的后台任务
+	bus      *module.Bus                    // 进程内消息总线，跨重载保留
+	buses    map[string]*module.BusClient   // 各激活实例的总线客户端，实例关闭前关闭
+	logs     map[string]*logState           // 各激活实例的日志级别与格式，复用的实例随重载更新
+	limiters map[string]*concurrencyLimiter // 全局（键为空串）与各模块的并发限流器，只在 Update 中读写
+	panics   map[string]*atomic.Int64       // 各模块处理器 panic 次数，模块移除后保留
+	metrics  *httpMetrics                   // 各模块路由的请求指标，跨重载保留
+	custom   *module.MetricRegistry         // 模块通过 MetricsAware 注册的指标，跨重载保留
+	reloads  []ReloadEvent                  // 最近的重载记录，最多 server.reload_history 条
+	leader   atomic.Bool                    // 本实例是否为 leader，为 false 时不启动 ClusterSingleton 模块
+	standby  []string                       // 上次 Update 中因本实例不是 leader 而未启动的模块
+	// lock 串行化 Update / ShutdownAll 的整个过程（可能因 Init 重试耗时较长）；
+	// mu 保护上面对外可见的状态，写方只在提交时短暂持有，读方法只取 mu，不会被进行中的重载阻塞
+	lock sync.Mutex
+	mu   sync.RWMutex
+}
+
+// ModuleState 记录模块被（重新）初始化的次数与最近一次初始化错误，用于发现每次重载都失败的模块
+type ModuleState struct {
+	InitCount   int        `json:"init_count"`
+	FailCount   int        `json:"fail_count"`
+	LastInit    *time.Time `json:"last_init,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type retiredModule struct {
+	name     string
+	mod      module.Module
+	inflight *inflightCounter
+	life     *module.Lifecycle
+	workers  *workerGroup
+	bus      *module.BusClient
+}
+
+func NewModuleManager() *ModuleManager {
+	m := &ModuleManager{
+		active:   make(map[string]module.Module),
+		inflight: make(map[string]*inflightCounter),
+		configs:  make(map[string]module.ModuleConfig),
+		states:   make(map[string]*ModuleState),
+		lives:    make(map[string]*module.Lifecycle),
+		workers:  make(map[string]*workerGroup),
+		bus:      module.NewBus(),
+		buses:    make(map[string]*module.BusClient),
+		logs:     make(map[string]*logState),
+		panics:   make(map[string]*atomic.Int64),
+		metrics:  newHTTPMetrics(),
+		custom:   module.NewMetricRegistry(reservedMetrics...),
+	}
+	// 未启用 leader 选举时本实例即 leader
+	m.leader.Store(true)
+	return m
+}
+
+// 已通过依赖解析校验的模块实例名对应的工厂函数
+func factory(name string) func() module.Module {
+	f, _ := registry.Factory(name)
+	return f
+}
+
+// 别名实例（如 order@primary）的路由默认挂载在 /primary 下，避免与同类型的其他实例冲突；模块配置 prefix 可覆盖（见 modulePrefix）
+func routePrefix(name string) string {
+	return module.InstancePrefix(name)
+}
+
+// Deps() 中形如 "auth>=1.2.0" 的声明
+type depConstraint struct {
+	module string
+	dep    string
+}
+
+// 校验依赖声明中的版本约束，被依赖方需实现 module.Versioned；instances 为依赖解析时取得的实例
+func checkVersions(constraints []depConstraint, instances map[string]module.Module) error {
+	for _, c := range constraints {
+		name, constraint := module.ParseDep(c.dep)
+		if constraint == "" {
+			continue
+		}
+		verr := &module.VersionConstraintError{Module: c.module, Dep: name, Constraint: constraint}
+		v, ok := instances[name].(module.Versioned)
+		if !ok {
+			return verr
+		}
+		verr.Version = v.Version()
+		okVer, err := module.SatisfiesVersion(verr.Version, constraint)
+		if err != nil {
+			return fmt.Errorf("module %s dependency %q: %w", c.module, c.dep, err)
+		}
+		if !okVer {
+			return verr
+		}
+	}
+	return nil
+}
+
+// resolveDependencies 返回包含全部依赖的启动顺序。
+// 模块按依赖层级排序（层级 = 到最底层依赖的最长路径），同一层级内先按 Priority() 降序、再按名称字母序，
+// 因此结果与 modules 列表中的书写顺序无关；可选依赖仅在对方也被配置时参与排序
+func resolveDependencies(modNames []string) ([]string, error) {
+	ordered, _, err := resolveModules(modNames, nil)
+	return ordered, err
+}
+
+// resolveModules 与 resolveDependencies 相同，并返回读取依赖时新建的实例：existing 中已有的实例直接用于读取依赖，
+// 其余每个模块只通过工厂创建一次。Update 复用这些实例，配置检查也以它们读取 schema，不会为读取元数据额外创建实例
+func resolveModules(modNames []string, existing map[string]module.Module) ([]string, map[string]module.Module, error) {
+	configured := make(map[string]bool, len(modNames))
+	for _, name := range modNames {
+		configured[name] = true
+	}
+
+	// 收集依赖闭包
+	deps := make(map[string][]string)
+	instances := make(map[string]module.Module)
+	created := make(map[string]module.Module)
+	priority := make(map[string]int)
+	var constraints []depConstraint
+	var collect func(string) error
+	collect = func(name string) error {
+		if _, ok := deps[name]; ok {
+			return nil
+		}
+		tmp, ok := existing[name]
+		if !ok {
+			newFn, known := registry.Factory(name)
+			if !known {
+				return &module.UnknownModuleError{Name: name}
+			}
+			tmp = newFn()
+			created[name] = tmp
+		}
+		// existing 中的实例可能只用于读取过 schema，同样检查作用域
+		if err := module.CheckScope(name, tmp); err != nil {
+			return err
+		}
+		instances[name] = tmp
+		var list []string
+		for _, dep := range tmp.Deps() {
+			list = append(list, module.DepName(dep))
+			constraints = append(constraints, depConstraint{name, dep})
+		}
+		if opt, ok := tmp.(module.OptionalDeps); ok {
+			for _, dep := range opt.Optional() {
+				if configured[module.DepName(dep)] {
+					list = append(list, module.DepName(dep))
+					constraints = append(constraints, depConstraint{name, dep})
+				}
+			}
+		}
+		deps[name] = list
+		if p, ok := tmp.(module.Prioritized); ok {
+			priority[name] = p.Priority()
+		}
+		for _, dep := range list {
+			if err := collect(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, name := range modNames {
+		if err := collect(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkVersions(constraints, instances); err != nil {
+		return nil, nil, err
+	}
+
+	// 计算层级，同时检测循环依赖
+	depth := make(map[string]int, len(deps))
+	visiting := make(map[string]bool)
+	var stack []string
+	var level func(string) (int, error)
+	level = func(name string) (int, error) {
+		if d, ok := depth[name]; ok {
+			return d, nil
+		}
+		if visiting[name] {
+			for i, n := range stack {
+				if n == name {
+					path := append(append([]string(nil), stack[i:]...), name)
+					return 0, &module.DependencyCycleError{Path: path}
+				}
+			}
+		}
+		visiting[name] = true
+		stack = append(stack, name)
+		d := 0
+		for _, dep := range deps[name] {
+			dd, err := level(dep)
+			if err != nil {
+				return 0, err
+			}
+			if dd+1 > d {
+				d = dd + 1
+			}
+		}
+		visiting[name] = false
+		stack = stack[:len(stack)-1]
+		depth[name] = d
+		return d, nil
+	}
+
+	result := make([]string, 0, len(deps))
+	for name := range deps {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	for _, name := range result {
+		if _, err := level(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if depth[result[i]] != depth[result[j]] {
+			return depth[result[i]] < depth[result[j]]
+		}
+		// 同一层级内优先级高的先初始化
+		if priority[result[i]] != priority[result[j]] {
+			return priority[result[i]] > priority[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result, created, nil
+}
+
+// 当前生效配置的 server.protect_modules 中的模块若处于激活状态，不允许被本次重载移除（或禁用）。
+// 保护以当前配置为准：要移除受保护模块，须先重载去掉保护，再移除模块
+func (m *ModuleManager) checkProtected(ordered []string) error {
+	var removed []string
+	for _, name := range m.cfg.Server.ProtectModules {
+		if _, active := m.active[name]; active && !slices.Contains(ordered, name) {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		return fmt.Errorf("reload would remove protected modules %v (listed in server.protect_modules)", removed)
+	}
+	return nil
+}
+
+// server.min_active_modules 取当前配置与新配置中的较大值：被截断的配置文件可能连同该设置一起丢失。
+// 要降低下限，须先单独重载修改该值
+func (m *ModuleManager) minActiveModules(cfg Config) int {
+	return max(m.cfg.Server.MinActiveModules, cfg.Server.MinActiveModules)
+}
+
+// 新的激活模块数低于下限时拒绝重载，防止配置文件被意外截断后下线大部分模块
+func (m *ModuleManager) checkCapacity(cfg Config, active int, what string) error {
+	if floor := m.minActiveModules(cfg); active < floor {
+		fmt.Printf("Warning: reload REJECTED, only %d %s modules but server.min_active_modules is %d (truncated config?)\n", active, what, floor)
+		return fmt.Errorf("reload would leave %d %s modules, below server.min_active_modules (%d)", active, what, floor)
+	}
+	return nil
+}
+
+// 按模块配置中的 init_retries / init_backoff 重试 Init，退避时间指数增长；
+// init_timeout 限制整个初始化（含退避等待）的总时长，实现 ContextIniter 的模块在 Init 过程中同样受其约束。
+// 每次尝试前先等待 wait_for 中的外部端点可连接
+func initWithRetry(ctx context.Context, name string, mod module.Module, cfg module.ModuleConfig) error {
+	retries := cfg.GetInt("init_retries", 0)
+	backoff := module.Backoff{Base: cfg.GetDuration("init_backoff", 500*time.Millisecond)}
+
+	if timeout := cfg.GetDuration("init_timeout", 0); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := waitFor(ctx, name, cfg)
+		if err == nil {
+			err = callSafely(name, "Init", func() error {
+				if ci, ok := mod.(module.ContextIniter); ok {
+					return ci.InitContext(ctx, cfg)
+				}
+				return mod.Init(cfg)
+			})
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return &module.ModuleInitError{Module: name, Err: err}
+		}
+		delay := backoff.Delay(attempt)
+		fmt.Printf("Init module %s failed (attempt %d/%d): %v, retrying in %s\n", name, attempt+1, retries+1, err, delay)
+		if werr := module.Wait(ctx, delay); werr != nil {
+			return &module.ModuleInitError{Module: name, Err: fmt.Errorf("%w (gave up: %v)", err, werr)}
+		}
+	}
+}
+
+// 调用模块的生命周期方法，将 panic 转换为错误并打印模块名与堆栈，避免整个进程或重载协程崩溃
+func callSafely(name, phase string, fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			fmt.Printf("Module %s panicked in %s: %v\n%s", name, phase, p, debug.Stack())
+			err = fmt.Errorf("module %s: %s panicked: %v", name, phase, p)
+		}
+	}()
+	return fn()
+}
+
+// 注册单个模块的路由；gin 对冲突路由会 panic（如通过子 Group 注册的重复路径），同样转换为错误
+// 只有已初始化（或已注册过、在新路由上复用）的实例才能注册。overrides 来自模块配置 route_overrides，
+// 引用了模块未注册路由的规则视为错误
+func registerModuleRoutes(r *gin.Engine, routes *routeTable, name, prefix string, mod module.Module, life *module.Lifecycle, overrides map[string]string, handlers ...gin.HandlerFunc) error {
+	if err := life.Transition(name, module.StateRegistered); err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() {
+		if d := time.Since(start); d > slowRegisterThreshold {
+			fmt.Printf("Warning: module %s took %v in RegisterRoutes (threshold %v)\n", name, d.Round(time.Millisecond), slowRegisterThreshold)
+		}
+	}()
+	return callSafely(name, "RegisterRoutes", func() error {
+		tr := routes.tracked(name, r.Group(prefix, handlers...), overrides)
+		mod.RegisterRoutes(tr)
+		if unused := tr.unusedOverrides(); len(unused) > 0 {
+			return fmt.Errorf("module %s: route_overrides reference unregistered routes: %s", name, strings.Join(unused, ", "))
+		}
+		return nil
+	})
+}
+
+// RegisterRoutes 耗时超过该值时打印警告；路由注册期间重载无法推进，耗时操作应放在 Init 中
+var slowRegisterThreshold = 500 * time.Millisecond
+
+// 模块配置了 host 时只响应该 Host 的请求，其他 Host 返回 404；
+// 配置值不含端口时忽略请求 Host 中的端口
+func hostFilter(host string) gin.HandlerFunc {
+	withPort := strings.Contains(host, ":")
+	return func(c *gin.Context) {
+		reqHost := c.Request.Host
+		if !withPort {
+			if h, _, err := net.SplitHostPort(reqHost); err == nil {
+				reqHost = h
+			}
+		}
+		if !strings.EqualFold(reqHost, host) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Next()
+	}
+}
+
+// 模块配置了 request_timeout 时为请求设置截止时间（含依赖中间件的耗时）。处理函数须通过
+// c.Request.Context() 感知超时并返回；超时后仍未写出响应时返回 504
+func requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out after " + timeout.String()})
+		}
+	}
+}
+
+// 模块声明的全部依赖（Deps 与 Optional）的模块名，不含版本约束
+func depNames(mod module.Module) []string {
+	deps := append([]string(nil), mod.Deps()...)
+	if opt, ok := mod.(module.OptionalDeps); ok {
+		deps = append(deps, opt.Optional()...)
+	}
+	for i, dep := range deps {
+		deps[i] = module.DepName(dep)
+	}
+	return deps
+}
+
+// 从已导出的服务中取出模块所依赖的那部分，供 ServiceConsumer 注入
+func injectedFor(mod module.Module, exported map[string]map[string]any) module.Injected {
+	s := module.Injected{}
+	for _, dep := range depNames(mod) {
+		if svc, ok := exported[dep]; ok {
+			s[dep] = svc
+		}
+	}
+	return s
+}
+
+// 可复用的 ServiceConsumer / Provider 实例所依赖的、本次将重新创建的服务提供方（导出服务或在 Provide 中发布服务）；
+// 非空时该实例也需重新创建，否则会继续持有已关闭的旧实例的服务
+func replacedExporters(mod module.Module, reused map[string]bool, instances map[string]module.Module) []string {
+	_, consumer := mod.(module.ServiceConsumer)
+	_, provider := mod.(module.Provider)
+	if !consumer && !provider {
+		return nil
+	}
+	var replaced []string
+	for _, dep := range depNames(mod) {
+		inst, ok := instances[dep]
+		if !ok || reused[dep] {
+			continue
+		}
+		if _, publishes := inst.(module.Provider); publishes || module.HasCapability(inst, module.CapServices) {
+			replaced = append(replaced, dep)
+		}
+	}
+	return replaced
+}
+
+// 收集模块所依赖（含已激活的可选依赖）的模块提供的中间件，按依赖声明顺序排列
+func depMiddlewares(mod module.Module, active map[string]module.Module) []gin.HandlerFunc {
+	deps := mod.Deps()
+	if opt, ok := mod.(module.OptionalDeps); ok {
+		deps = append(append([]string(nil), deps...), opt.Optional()...)
+	}
+	var handlers []gin.HandlerFunc
+	for _, dep := range deps {
+		if p, ok := module.CapabilityOf[module.MiddlewareProvider](active[module.DepName(dep)], module.CapMiddleware); ok {
+			handlers = append(handlers, p.Middlewares()...)
+		}
+	}
+	return handlers
+}
+
+// 两份模块配置是否相同（nil 与空配置视为相同）
+func sameConfig(a, b module.ModuleConfig) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Update 按新配置构建路由；失败时返回错误，调用方应继续使用旧路由。
+// 配置未变化的已激活模块复用原实例；配置变化或在 reinit 中列出的模块重新创建并初始化
+// ctx 被取消（如有更新的配置到达）时中止本次重载并回滚已启动的模块
+//
+// 分阶段进行，任一阶段失败都不会改动当前状态：
+// 1. 解析依赖  2. 校验配置  3. 在新引擎上初始化并注册模块  4. 新模块自检（Health）
+// 全部通过后才提交状态，由调用方一次性切换路由
+func (m *ModuleManager) Update(ctx context.Context, cfg Config, reinit ...string) (_ *routerSet, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	start, before := time.Now(), maps.Clone(m.active)
+	// 首次构建没有可比较的旧配置
+	var diff *ConfigDiff
+	if !reflect.DeepEqual(m.cfg, Config{}) {
+		if d := diffConfig(m.cfg, cfg, redactPattern(cfg.Server.RedactPattern)); !d.empty() {
+			d.log()
+			diff = &d
+		}
+	}
+	defer func() { m.recordReload(start, before, diff, cfg.Server.ReloadHistory, err) }()
+
+	fmt.Println("Reload stage 1/4: resolving dependencies")
+	// 已激活的实例直接用于读取依赖，其余模块在此创建的实例在下面的初始化阶段复用
+	ordered, created, err := resolveModules(cfg.enabledModules(), m.active)
+	if err != nil {
+		return nil, fmt.Errorf("dependency resolution: %w", err)
+	}
+	known := maps.Clone(m.active)
+	maps.Copy(known, created)
+	if err := cfg.checkDisabledDeps(ordered, known); err != nil {
+		return nil, err
+	}
+	ordered, standby, err := m.leaderOnly(ordered, created)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Reload stage 2/4: validating config")
+	if err := m.checkProtected(ordered); err != nil {
+		return nil, err
+	}
+	if err := m.checkCapacity(cfg, len(ordered), "enabled"); err != nil {
+		return nil, err
+	}
+	prefixes, err := modulePrefixes(ordered, cfg.Configs)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Server.CORS.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Server.validateTrustedProxies(); err != nil {
+		return nil, err
+	}
+	if _, err := newAdminAuthenticator(cfg.Server); err != nil {
+		return nil, err
+	}
+	if problems := cfg.missingRequired(ordered, known); len(problems) > 0 {
+		if cfg.Strict {
+			return nil, errors.Join(problems...)
+		}
+		for _, p := range problems {
+			fmt.Println("Config warning:", p)
+		}
+	}
+
+	newActive := make(map[string]module.Module)
+	newInflight := make(map[string]*inflightCounter)
+	newLives := make(map[string]*module.Lifecycle)
+	newWorkers := make(map[string]*workerGroup)
+	newBuses := make(map[string]*module.BusClient)
+	newLogs := make(map[string]*logState)
+	newLimiters := make(map[string]*concurrencyLimiter)
+	globalLimiter := limiterFor(m.limiters[""], serverLimits(cfg.Server))
+	if globalLimiter != nil {
+		newLimiters[""] = globalLimiter
+	}
+	started := []string{}
+	r := newEngine(cfg)
+	routes := newRouteTable()
+	// server.listeners 中的每个监听各有一个路由，各自登记路由并处理 404
+	built := []*listenerRouter{{engine: r, routes: routes}}
+	set := &routerSet{Engine: r, listeners: make(map[string]*gin.Engine, len(cfg.Server.Listeners))}
+	for _, ln := range listenerNames(cfg.Server) {
+		lr := &listenerRouter{name: ln, engine: newEngine(cfg), routes: newRouteTable()}
+		lr.engine.Use(tagListener(ln))
+		built = append(built, lr)
+		set.listeners[ln] = lr.engine
+	}
+	// 管理端点统一挂在 server.admin_prefix 下，在每个监听上都可访问；admin_enabled: false 时全部不注册
+	if cfg.Server.adminEnabled() {
+		for _, lr := range built {
+			ops := lr.engine.Group(cfg.Server.adminPrefix())
+			registerHealthRoutes(ops, lr.routes, m)
+			registerVersionRoute(ops, lr.routes, m)
+			registerMetricsRoute(ops, lr.routes, m)
+			registerAdminRoutes(ops, lr.routes, cfg)
+		}
+	}
+	failed := 0
+	var initErrs []error
+	chainUsers := make(map[string][]string)     // 中间件链中的模块 -> 使用它的模块
+	exported := make(map[string]map[string]any) // 已激活模块导出的服务，按初始化顺序逐个加入
+
+	// 本次新启动的模块需要在失败时按启动逆序关闭（依赖方先于其依赖），复用的旧实例不受影响；
+	// 与移除的模块一样在 module_shutdown_budget 内关闭，卡住的 Shutdown 不会让重载一直无法返回
+	rollback := func() {
+		budget := newShutdownBudget(cfg.Server.moduleShutdownBudget(), len(started))
+		for i := len(started) - 1; i >= 0; i-- {
+			name := started[i]
+			if err := newLives[name].Transition(name, module.StateShutDown); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			r := retiredModule{name: name, mod: newActive[name], workers: newWorkers[name], bus: newBuses[name]}
+			if err := budget.shutdown(r, i+1); err != nil {
+				fmt.Println("Error shutting down module:", name, err)
+			} else {
+				fmt.Println("Rolled back module:", name)
+			}
+		}
+	}
+
+	// 先确定本次的模块实例：可复用的保留，其余通过工厂创建
+	force := make(map[string]bool, len(reinit))
+	for _, name := range reinit {
+		force[name] = true
+	}
+	instances := make(map[string]module.Module, len(ordered))
+	reused := make(map[string]bool, len(ordered))
+	for _, name := range ordered {
+		old, exists := m.active[name]
+		if exists && !module.IsReloadable(old) {
+			if force[name] || !sameConfig(m.configs[name], cfg.Configs[name]) {
+				fmt.Println("Config change for non-reloadable module requires a restart, keeping current instance:", name)
+			}
+			if replaced := replacedExporters(old, reused, instances); len(replaced) > 0 {
+				fmt.Printf("Services of %v are replaced but module %s is not reloadable, it keeps the previous ones until restart\n", replaced, name)
+			}
+			instances[name] = old
+			reused[name] = true
+			newLives[name] = m.lives[name]
+			continue
+		}
+		var replaced []string
+		if exists {
+			replaced = replacedExporters(old, reused, instances)
+		}
+		if exists && !force[name] && sameConfig(m.configs[name], cfg.Configs[name]) && len(replaced) == 0 {
+			instances[name] = old
+			reused[name] = true
+			newLives[name] = m.lives[name]
+			continue
+		}
+		if exists && len(replaced) > 0 {
+			fmt.Printf("Re-initializing module %s: services of %v are replaced\n", name, replaced)
+		} else if exists {
+			fmt.Println("Re-initializing module:", name)
+		}
+		if mod, ok := created[name]; ok {
+			instances[name] = mod
+			newLives[name] = &module.Lifecycle{}
+		} else if newFn, ok := registry.Factory(name); ok {
+			instances[name] = newFn()
+			newLives[name] = &module.Lifecycle{}
+		}
+	}
+
+	// Provide 阶段：所有模块按依赖顺序发布共享服务，之后才进入 Init
+	services := module.NewServiceRegistry()
+	services.Provide(module.DispatcherService, module.NewDispatcher(set.handleContext))
+	for _, name := range ordered {
+		if na, ok := instances[name].(module.NameAware); ok {
+			na.SetName(name)
+		}
+		if p, ok := instances[name].(module.Provider); ok {
+			p.Provide(services)
+		}
+	}
+
+	// 启动新模块
+	fmt.Println("Reload stage 3/4: initializing modules")
+	for _, name := range ordered {
+		if err := ctx.Err(); err != nil {
+			rollback()
+			return nil, fmt.Errorf("reload cancelled: %w", err)
+		}
+		mod, ok := instances[name]
+		if !ok {
+			continue
+		}
+		exists := reused[name]
+		if !exists {
+			modCfg := module.WithDefaults(mod, cfg.Configs[name])
+			if la, ok := mod.(module.LoggerAware); ok {
+				newLogs[name] = &logState{}
+				newLogs[name].apply(cfg, modCfg)
+				la.SetLogger(moduleLogger(name, newLogs[name]))
+			}
+			if sc, ok := mod.(module.ServiceConsumer); ok {
+				sc.Inject(injectedFor(mod, exported))
+			}
+			if ma, ok := mod.(module.MetricsAware); ok {
+				ma.SetMetrics(m.custom.For(name))
+			}
+			if ba, ok := mod.(module.BusAware); ok {
+				newBuses[name] = m.bus.Client()
+				ba.SetBus(newBuses[name])
+			}
+			err := initWithRetry(ctx, name, mod, modCfg)
+			m.recordInit(name, err)
+			if err != nil {
+				newBuses[name].Close()
+				delete(newBuses, name)
+				fmt.Println("Failed to init module:", err)
+				failed++
+				initErrs = append(initErrs, err)
+				continue
+			}
+			newLives[name].Transition(name, module.StateInitialized)
+			if g := startWorkers(name, mod); g != nil {
+				newWorkers[name] = g
+			}
+			started = append(started, name)
+		}
+		// 已存在的模块保留实例、在途计数与后台任务，仅在新路由上重新注册
+		if g, ok := m.workers[name]; ok && exists {
+			newWorkers[name] = g
+		}
+		if b, ok := m.buses[name]; ok && exists {
+			newBuses[name] = b
+		}
+		if l, ok := m.logs[name]; ok && exists {
+			newLogs[name] = l
+		}
+		newActive[name] = mod
+		if e, ok := module.CapabilityOf[module.ServiceExporter](mod, module.CapServices); ok {
+			exported[name] = e.Services()
+		}
+		counter := m.inflight[name]
+		if !exists {
+			counter = &inflightCounter{}
+		}
+		newInflight[name] = counter
+		m.mu.Lock()
+		panics := m.panics[name]
+		if panics == nil {
+			panics = &atomic.Int64{}
+			m.panics[name] = panics
+		}
+		m.mu.Unlock()
+		modCfg := module.ModuleConfig(cfg.Configs[name])
+		handlers := []gin.HandlerFunc{counter.middleware(), m.metrics.middleware(name)}
+		// 排在恢复之前，panic 时由恢复写出的错误响应同样被记录
+		if modCfg.GetBool("debug_io", false) {
+			handlers = append(handlers, debugIO(name, modCfg.GetInt("debug_io_max_bytes", 0), redactPattern(cfg.Server.RedactPattern)))
+		}
+		handlers = append(handlers, moduleRecovery(name, panics, cfg.Server.errorStack()))
+		// 全局上限由所有模块的路由共享，管理端点不受限制
+		if globalLimiter != nil {
+			handlers = append(handlers, globalLimiter.middleware())
+		}
+		if l := limiterFor(m.limiters[name], moduleLimits(modCfg)); l != nil {
+			newLimiters[name] = l
+			handlers = append(handlers, l.middleware())
+		}
+		if timeout := modCfg.GetDuration("request_timeout", 0); timeout > 0 {
+			handlers = append(handlers, requestTimeout(timeout))
+		}
+		handlers = append(handlers, depMiddlewares(mod, newActive)...)
+		chained, providers, err := chainMiddlewares(cfg.Chains, mod, modCfg.GetStringSlice("use"), instances)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		handlers = append(handlers, chained...)
+		for _, p := range providers {
+			chainUsers[p] = append(chainUsers[p], name)
+		}
+		acl, err := parseACL(modCfg)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		if acl != nil {
+			handlers = append(handlers, aclMiddleware(prefixes[name], acl))
+		}
+		if host := modCfg.GetString("host", ""); host != "" {
+			handlers = append([]gin.HandlerFunc{hostFilter(host)}, handlers...)
+		}
+		// 绑定了监听的模块只注册在该监听的路由上，其余模块注册在每个路由上
+		targets := built
+		if listener := modCfg.GetString("listener", ""); listener != "" {
+			targets = nil
+			for _, lr := range built {
+				if lr.name == listener {
+					targets = append(targets, lr)
+				}
+			}
+			if len(targets) == 0 {
+				rollback()
+				return nil, fmt.Errorf("module %s: unknown listener %q", name, listener)
+			}
+		}
+		for _, lr := range targets {
+			if err := registerModuleRoutes(lr.engine, lr.routes, name, prefixes[name], mod, newLives[name], modCfg.GetStringMap("route_overrides"), handlers...); err != nil {
+				rollback()
+				return nil, err
+			}
+			if len(lr.routes.conflicts) > 0 {
+				rollback()
+				return nil, errors.Join(lr.routes.conflicts...)
+			}
+			if unmatched := unmatchedACL(lr.routes, name, prefixes[name], acl); len(unmatched) > 0 {
+				rollback()
+				return nil, fmt.Errorf("module %s: acl references unregistered routes: %s", name, strings.Join(unmatched, ", "))
+			}
+		}
+		if !exists {
+			fmt.Println("Started module:", name)
+		}
+	}
+	if cfg.Server.listRoutesOn404() {
+		for _, lr := range built {
+			lr.engine.NoRoute(lr.routes.notFound)
+		}
+	}
+	// 初始化失败的模块被跳过，激活的模块可能少于启用的模块
+	if err := m.checkCapacity(cfg, len(newActive), "active"); err != nil {
+		rollback()
+		return nil, err
+	}
+	// 链中的模块可能在使用方之后初始化，全部初始化结束后再确认它们都已激活
+	for _, p := range ordered {
+		if _, ok := newActive[p]; !ok && len(chainUsers[p]) > 0 {
+			rollback()
+			return nil, fmt.Errorf("middleware chain module %s is not active, used by %s", p, strings.Join(chainUsers[p], ", "))
+		}
+	}
+
+	// 自检：新初始化的模块须通过 Health，复用的实例已在服务中，不再检查
+	fmt.Println("Reload stage 4/4: self-check")
+	for _, name := range started {
+		h, ok := module.CapabilityOf[module.HealthChecker](newActive[name], module.CapHealth)
+		if !ok {
+			continue
+		}
+		if err := callSafely(name, "Health", h.Health); err != nil {
+			rollback()
+			return nil, fmt.Errorf("self-check of module %s failed: %w", name, err)
+		}
+	}
+	// 预热同样只针对新初始化的模块；重载期间 /readyz 保持未就绪，直到预热结束
+	warmupModules(ctx, started, newActive, cfg.Configs)
+
+	// 提交：不再需要的模块按上一次的启动顺序逆序排队，待新路由生效并排空请求后关闭
+	m.mu.Lock()
+	for i := len(m.order) - 1; i >= 0; i-- {
+		name := m.order[i]
+		if old, ok := m.active[name]; ok && newActive[name] != old {
+			m.retired = append(m.retired, retiredModule{name: name, mod: old, inflight: m.inflight[name], life: m.lives[name], workers: m.workers[name], bus: m.buses[name]})
+		}
+	}
+
+	m.active = newActive
+	m.inflight = newInflight
+	m.lives = newLives
+	m.workers = newWorkers
+	m.buses = newBuses
+	m.logs = newLogs
+	m.limiters = newLimiters
+	m.failed = failed
+	m.initErr = errors.Join(initErrs...)
+	m.standby = standby
+	m.cfg = cfg
+	// 记录各模块实际生效的配置：复用的实例沿用原配置，日志级别与格式按新的 logging 更新
+	configs := make(map[string]module.ModuleConfig, len(newActive))
+	for name, mod := range newActive {
+		if reused[name] {
+			configs[name] = m.configs[name]
+			if l := newLogs[name]; l != nil {
+				l.apply(cfg, module.WithDefaults(mod, configs[name]))
+			}
+		} else {
+			configs[name] = cfg.Configs[name]
+		}
+	}
+	m.configs = configs
+	m.order = nil
+	for _, name := range ordered {
+		if _, ok := newActive[name]; ok {
+			m.order = append(m.order, name)
+		}
+	}
+	m.mu.Unlock()
+	m.warnDeprecated()
+	return set, nil
+}
+
+// 记录一次模块初始化的结果
+func (m *ModuleManager) recordInit(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.states[name]
+	if st == nil {
+		st = &ModuleState{}
+		m.states[name] = st
+	}
+	now := time.Now()
+	if err != nil {
+		st.FailCount++
+		st.LastError, st.LastErrorAt = err.Error(), &now
+		return
+	}
+	st.InitCount++
+	st.LastInit = &now
+}
+
+// 每次重载后对仍处于激活状态的弃用模块打印警告，提醒在移除前迁移
+func (m *ModuleManager) warnDeprecated() {
+	for _, name := range m.order {
+		if reason := module.DeprecationOf(m.active[name]); reason != "" {
+			fmt.Printf("Warning: module %s is DEPRECATED and will be removed: %s\n", name, reason)
+		}
+	}
+}
+
+// StopRetired 关闭上次 Update 移除的模块：先等待其在途请求完成（最多 drainTimeout），再调用 Shutdown；
+// 各模块的 Shutdown 共享 server.module_shutdown_budget，单个慢模块不会占用其余模块的关闭时间。
+// 应在新路由替换旧路由之后调用，确保不再有新请求进入这些模块
+func (m *ModuleManager) StopRetired(drainTimeout time.Duration) {
+	m.lock.Lock()
+	retired := m.retired
+	m.retired = nil
+	m.lock.Unlock()
+	m.mu.RLock()
+	budget := newShutdownBudget(m.cfg.Server.moduleShutdownBudget(), len(retired))
+	m.mu.RUnlock()
+
+	deadline := time.Now().Add(drainTimeout)
+	for i, r := range retired {
+		if r.inflight != nil && !r.inflight.wait(deadline) {
+			fmt.Println("Drain timeout, shutting down module with in-flight requests:", r.name)
+		}
+		if r.life != nil {
+			if err := r.life.Transition(r.name, module.StateShutDown); err != nil {
+				fmt.Println(err)
+				continue
+			}
+		}
+		if err := budget.shutdown(r, len(retired)-i); err != nil {
+			fmt.Println("Error shutting down module:", r.name, err)
+		} else {
+			fmt.Println("Stopped module:", r.name)
+		}
+	}
+}
+
+// ShutdownAll 在进程退出时按启动逆序关闭所有激活模块（连同尚未关闭的已移除模块）。
+// 生命周期状态保证每个实例的 Shutdown 至多被调用一次，重复调用 ShutdownAll 是安全的
+func (m *ModuleManager) ShutdownAll(drainTimeout time.Duration) {
+	m.lock.Lock()
+	m.mu.Lock()
+	for i := len(m.order) - 1; i >= 0; i-- {
+		name := m.order[i]
+		m.retired = append(m.retired, retiredModule{name: name, mod: m.active[name], inflight: m.inflight[name], life: m.lives[name], workers: m.workers[name], bus: m.buses[name]})
+	}
+	m.active = make(map[string]module.Module)
+	m.inflight = make(map[string]*inflightCounter)
+	m.lives = make(map[string]*module.Lifecycle)
+	m.workers = make(map[string]*workerGroup)
+	m.buses = make(map[string]*module.BusClient)
+	m.logs = make(map[string]*logState)
+	m.order = nil
+	m.mu.Unlock()
+	m.lock.Unlock()
+	m.StopRetired(drainTimeout)
+}
+
+// 上次成功的 Update 中模块初始化失败的原因，全部成功时为 nil
+func (m *ModuleManager) initError() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.initErr
+}
+
+// 上次成功的 Update 中所有模块是否都初始化成功
+func (m *ModuleManager) allInitialized() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failed == 0
+}
+
+// 上次应用的配置，以及其中启用但当前未激活（初始化失败或依赖未就绪）的模块
+func (m *ModuleManager) inactiveModules() (Config, []string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for _, name := range m.cfg.enabledModules() {
+		if _, ok := m.active[name]; !ok && !slices.Contains(m.standby, name) {
+			names = append(names, name)
+		}
+	}
+	return m.cfg, names
+}
+
+// 上次成功应用的配置（未合并默认值），用于按同一配置重建路由
+func (m *ModuleManager) appliedConfig() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cfg
+}
+
+// 本实例不是 leader 时从 ordered 中去掉 ClusterSingleton 模块，返回保留的与被去掉的模块；
+// 被去掉的模块仍被其他模块依赖时报错，与依赖已禁用的模块相同
+func (m *ModuleManager) leaderOnly(ordered []string, created map[string]module.Module) ([]string, []string, error) {
+	if m.leader.Load() {
+		return ordered, nil, nil
+	}
+	instance := func(name string) module.Module {
+		if mod, ok := m.active[name]; ok {
+			return mod
+		}
+		return created[name]
+	}
+	var kept, standby []string
+	for _, name := range ordered {
+		if module.IsClusterSingleton(instance(name)) {
+			standby = append(standby, name)
+		} else {
+			kept = append(kept, name)
+		}
+	}
+	for _, name := range kept {
+		for _, dep := range instance(name).Deps() {
+			if slices.Contains(standby, module.DepName(dep)) {
+				return nil, nil, fmt.Errorf("module %q runs on the cluster leader only but is required by %q", module.DepName(dep), name)
+			}
+		}
+	}
+	if len(standby) > 0 {
+		fmt.Println("Not the cluster leader, not starting leader-only modules:", standby)
+	}
+	return kept, standby, nil
+}
+
+// 基于上次应用的配置替换单个模块的配置块，返回新的完整配置；模块未激活时返回 false
+func (m *ModuleManager) patchedConfig(name string, values map[string]any) (Config, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.active[name]; !ok {
+		return Config{}, false
+	}
+	cfg := m.cfg
+	cfg.Configs = make(map[string]map[string]any, len(m.cfg.Configs)+1)
+	for k, v := range m.cfg.Configs {
+		cfg.Configs[k] = v
+	}
+	cfg.Configs[name] = values
+	return cfg, true
+}
+
+// 当前激活的模块实例
+func (m *ModuleManager) activeModule(name string) (module.Module, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mod, ok := m.active[name]
+	return mod, ok
+}
+
+// 返回直接或间接依赖 name 的激活模块
+func (m *ModuleManager) dependents(name string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	affected := map[string]bool{name: true}
+	var result []string
+	for _, n := range m.order {
+		for _, dep := range m.active[n].Deps() {
+			if affected[module.DepName(dep)] {
+				affected[n] = true
+				result = append(result, n)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Stats 汇总所有具备 stats 能力的激活模块的统计信息，以模块名为键；
+// 处理器发生过 panic 的模块额外包含 module_panic_total
+func (m *ModuleManager) Stats() map[string]map[string]any {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make(map[string]map[string]any)
+	for name, mod := range m.active {
+		if r, ok := module.CapabilityOf[module.StatsReporter](mod, module.CapStats); ok {
+			stats[name] = r.Stats()
+		}
+		if p := m.panics[name]; p != nil && p.Load() > 0 {
+			merged := map[string]any{"module_panic_total": p.Load()}
+			for k, v := range stats[name] {
+				merged[k] = v
+			}
+			stats[name] = merged
+		}
+	}
+	return stats
+}
+
+// Health 调用激活模块的 Health()，返回模块名 -> "ok" 或错误信息；
+// 检查可能访问外部服务，因此在锁外执行
+func (m *ModuleManager) Health() map[string]string {
+	m.mu.RLock()
+	checkers := make(map[string]module.HealthChecker)
+	for name, mod := range m.active {
+		if h, ok := module.CapabilityOf[module.HealthChecker](mod, module.CapHealth); ok {
+			checkers[name] = h
+		}
+	}
+	m.mu.RUnlock()
+
+	results := make(map[string]string, len(checkers))
+	for name, h := range checkers {
+		if err := callSafely(name, "Health", h.Health); err != nil {
+			results[name] = err.Error()
+		} else {
+			results[name] = "ok"
+		}
+	}
+	return results
+}
+
+// 模块的健康状态：Direct 为模块自身 Health() 的结果（无 health 能力视为 ok），
+// Effective 再叠加依赖：自身失败为 unhealthy，自身正常但任一依赖（含已激活的可选依赖）非 ok 时为 degraded
+type moduleHealth struct {
+	Direct     string   `json:"direct"`
+	Effective  string   `json:"effective"`
+	DegradedBy []string `json:"degraded_by,omitempty"`
+}
+
+// HealthReport 在 Health 的基础上按依赖图计算每个激活模块的有效健康状态
+func (m *ModuleManager) HealthReport() (checks map[string]string, report map[string]moduleHealth) {
+	checks = m.Health()
+	m.mu.RLock()
+	order := append([]string(nil), m.order...)
+	deps := make(map[string][]string, len(order))
+	for _, name := range order {
+		mod := m.active[name]
+		if mod == nil {
+			continue
+		}
+		for _, dep := range depNames(mod) {
+			if m.active[dep] != nil {
+				deps[name] = append(deps[name], dep)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	// order 为初始化顺序，依赖总在被依赖者之前，依次计算即可
+	report = make(map[string]moduleHealth, len(order))
+	for _, name := range order {
+		h := moduleHealth{Direct: "ok", Effective: "ok"}
+		if v, ok := checks[name]; ok && v != "ok" {
+			h.Direct, h.Effective = "unhealthy", "unhealthy"
+		}
+		for _, dep := range deps[name] {
+			if report[dep].Effective != "ok" {
+				h.DegradedBy = append(h.DegradedBy, dep)
+			}
+		}
+		if h.Effective == "ok" && len(h.DegradedBy) > 0 {
+			h.Effective = "degraded"
+		}
+		report[name] = h
+	}
+	return checks, report
+}
+
+// ModuleStates 返回各模块初始化状态的快照，active 表示当前是否激活
+func (m *ModuleManager) ModuleStates() map[string]any {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]any, len(m.states))
+	for name, st := range m.states {
+		mod, active := m.active[name]
+		var deprecated string
+		if active {
+			deprecated = module.DeprecationOf(mod)
+		}
+		result[name] = struct {
+			ModuleState
+			Active     bool   `json:"active"`
+			Deprecated string `json:"deprecated,omitempty"`
+		}{*st, active, deprecated}
+	}
+	return result
+}
+
+// ModulesByTag 返回模块配置 tags 中 k 等于 v 的激活模块（按启动顺序），
+// tags 由管理器识别，例如 tags: {tier: edge}
+func (m *ModuleManager) ModulesByTag(k, v string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for _, name := range m.order {
+		if tag, ok := m.configs[name].GetStringMap("tags")[k]; ok && tag == v {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// EffectiveConfig 返回上次成功应用的配置（含管理端点对单个模块的运行时修改），
+// 激活模块的配置已合并其声明的默认值
+func (m *ModuleManager) EffectiveConfig() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cfg := m.cfg
+	cfg.Configs = make(map[string]map[string]any, len(m.cfg.Configs))
+	for name, c := range m.cfg.Configs {
+		cfg.Configs[name] = c
+	}
+	for name, mod := range m.active {
+		if _, ok := mod.(module.Defaulter); ok {
+			cfg.Configs[name] = module.WithDefaults(mod, m.configs[name])
+		}
+	}
+	return cfg
+}
+
+// ActiveModules 返回当前激活的模块名（按启动顺序）
+func (m *ModuleManager) ActiveModules() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.order...)
+}
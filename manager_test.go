@@ -0,0 +1,1502 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 测试用模块：按工厂登记的依赖声明 Deps，Init / Shutdown 记录到共享的 events；
+// GET /<name> 在 Shutdown 之后仍被调用时返回 500
+type testModule struct {
+	name   string
+	deps   []string
+	events *testEvents
+	closed atomic.Bool
+}
+
+func (m *testModule) Deps() []string { return m.deps }
+
+func (m *testModule) Init(cfg module.ModuleConfig) error {
+	m.events.add("init " + m.name)
+	return nil
+}
+
+func (m *testModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/"+m.name, func(c *gin.Context) {
+		time.Sleep(time.Millisecond)
+		if m.closed.Load() {
+			c.String(http.StatusInternalServerError, "module already shut down")
+			return
+		}
+		c.String(http.StatusOK, m.name)
+	})
+}
+
+func (m *testModule) Shutdown() error {
+	m.closed.Store(true)
+	m.events.add("shutdown " + m.name)
+	return nil
+}
+
+type testEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *testEvents) add(ev string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, ev)
+}
+
+// 以 prefix 开头的事件，按发生顺序
+func (e *testEvents) with(prefix string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []string
+	for _, ev := range e.events {
+		if len(ev) > len(prefix) && ev[:len(prefix)] == prefix {
+			out = append(out, ev[len(prefix):])
+		}
+	}
+	return out
+}
+
+// 向注册表登记测试模块（模块名 -> 依赖），测试结束时移除；每次通过工厂创建实例记录为 "build <name>"
+func registerTestModules(t *testing.T, deps map[string][]string) *testEvents {
+	t.Helper()
+	events := &testEvents{}
+	for name, d := range deps {
+		name, d := name, d
+		registry.Modules[name] = func() module.Module {
+			events.add("build " + name)
+			return &testModule{name: name, deps: d, events: events}
+		}
+	}
+	t.Cleanup(func() {
+		for name := range deps {
+			delete(registry.Modules, name)
+		}
+	})
+	return events
+}
+
+func TestRemovedModulesStopInReverseDependencyOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		after []string
+		want  []string
+	}{
+		{"remove chain", []string{"t_keep"}, []string{"t_top", "t_mid", "t_base"}},
+		{"remove top only", []string{"t_mid", "t_keep"}, []string{"t_top"}},
+		{"remove everything", nil, []string{"t_top", "t_mid", "t_keep", "t_base"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := registerTestModules(t, map[string][]string{
+				"t_base": nil,
+				"t_mid":  {"t_base"},
+				"t_top":  {"t_mid"},
+				"t_keep": nil,
+			})
+			m := NewModuleManager()
+			ctx := context.Background()
+			if _, err := m.Update(ctx, Config{Modules: []string{"t_top", "t_mid", "t_base", "t_keep"}}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Update(ctx, Config{Modules: tt.after}); err != nil {
+				t.Fatal(err)
+			}
+			if got := events.with("shutdown "); len(got) != 0 {
+				t.Fatalf("modules shut down before StopRetired: %v", got)
+			}
+			m.StopRetired(time.Second)
+			if got := events.with("shutdown "); !slices.Equal(got, tt.want) {
+				t.Errorf("shutdown order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 重载的同时反复读取管理器状态（/admin/modules、/admin/stats 等端点的数据来源）；配合 -race 运行
+func TestConcurrentReadsDuringReload(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": {"t_a"}})
+	full := Config{Modules: []string{"t_a", "t_b"}}
+	partial := Config{Modules: []string{"t_a"}}
+	readers := []struct {
+		name string
+		read func(m *ModuleManager) []string // 返回读到的模块列表，不涉及列表的读取返回 nil
+	}{
+		{"ActiveModules", func(m *ModuleManager) []string { return m.ActiveModules() }},
+		{"Snapshot", func(m *ModuleManager) []string { return m.Snapshot().Modules }},
+		{"ModuleStates", func(m *ModuleManager) []string { m.ModuleStates(); return nil }},
+		{"Stats", func(m *ModuleManager) []string { m.Stats(); return nil }},
+		{"HealthReport", func(m *ModuleManager) []string { m.HealthReport(); return nil }},
+		{"EffectiveConfig", func(m *ModuleManager) []string { m.EffectiveConfig(); return nil }},
+	}
+	for _, r := range readers {
+		t.Run(r.name, func(t *testing.T) {
+			m := NewModuleManager()
+			ctx := context.Background()
+			if _, err := m.Update(ctx, full); err != nil {
+				t.Fatal(err)
+			}
+			defer m.ShutdownAll(0)
+
+			var stop atomic.Bool
+			var wg sync.WaitGroup
+			torn := make(chan []string, 1)
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for !stop.Load() {
+						got := r.read(m)
+						if got != nil && !slices.Equal(got, full.Modules) && !slices.Equal(got, partial.Modules) {
+							select {
+							case torn <- got:
+							default:
+							}
+						}
+					}
+				}()
+			}
+			for i := 0; i < 20; i++ {
+				next := partial
+				if i%2 == 1 {
+					next = full
+				}
+				if _, err := m.Update(ctx, next); err != nil {
+					t.Error(err)
+				}
+				m.StopRetired(time.Second)
+			}
+			stop.Store(true)
+			wg.Wait()
+			select {
+			case got := <-torn:
+				t.Errorf("read a module list from a half-applied reload: %v", got)
+			default:
+			}
+		})
+	}
+}
+
+// 前 fails 次 Init 失败，记录每次尝试的时间
+type flakyModule struct {
+	module.Base
+	fails    int
+	attempts []time.Time
+}
+
+func (m *flakyModule) Init(cfg module.ModuleConfig) error {
+	m.attempts = append(m.attempts, time.Now())
+	if len(m.attempts) <= m.fails {
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+// InitContext 阻塞到 ctx 结束
+type hangingModule struct{ module.Base }
+
+func (m *hangingModule) InitContext(ctx context.Context, cfg module.ModuleConfig) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestInitWithRetry(t *testing.T) {
+	const backoff = 10 * time.Millisecond
+	tests := []struct {
+		name         string
+		fails        int
+		retries      int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"first attempt", 0, 0, false, 1},
+		{"succeeds on attempt 3", 2, 3, false, 3},
+		{"fails after init_retries", 5, 2, true, 3},
+		{"no retries configured", 1, 0, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &flakyModule{fails: tt.fails}
+			cfg := module.ModuleConfig{"init_retries": tt.retries, "init_backoff": backoff.String()}
+			err := initWithRetry(context.Background(), "t_flaky", m, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var initErr *module.ModuleInitError
+			if err != nil && !errors.As(err, &initErr) {
+				t.Errorf("err = %T, want *module.ModuleInitError", err)
+			}
+			if len(m.attempts) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(m.attempts), tt.wantAttempts)
+			}
+			// 第 i 次重试前至少等待 backoff·2^i
+			for i := 1; i < len(m.attempts); i++ {
+				if gap, min := m.attempts[i].Sub(m.attempts[i-1]), backoff<<(i-1); gap < min {
+					t.Errorf("wait before attempt %d = %s, want >= %s", i+1, gap, min)
+				}
+			}
+		})
+	}
+}
+
+func TestInitTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  module.Module
+		cfg  module.ModuleConfig
+	}{
+		{"hung InitContext", &hangingModule{}, module.ModuleConfig{"init_timeout": "50ms"}},
+		{"backoff longer than the timeout", &flakyModule{fails: 10}, module.ModuleConfig{"init_timeout": "50ms", "init_retries": 5, "init_backoff": "1h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := initWithRetry(context.Background(), "t_slow", tt.mod, tt.cfg)
+			if err == nil {
+				t.Fatal("Init succeeded")
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Errorf("init_timeout 50ms took %s to give up", d)
+			}
+		})
+	}
+}
+
+// 在 Init 或 RegisterRoutes 中 panic 的模块
+type panicModule struct {
+	module.Base
+	phase string
+}
+
+func (m *panicModule) Init(cfg module.ModuleConfig) error {
+	if m.phase == "Init" {
+		panic("boom in Init")
+	}
+	return nil
+}
+
+func (m *panicModule) RegisterRoutes(r gin.IRouter) {
+	if m.phase == "RegisterRoutes" {
+		panic("boom in RegisterRoutes")
+	}
+	r.GET("/t_panic", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+}
+
+func TestModulePanicsAreContained(t *testing.T) {
+	tests := []struct {
+		phase   string
+		wantErr string // 重载返回的错误，为空表示重载成功、仅跳过该模块
+		served  []string
+	}{
+		{"Init", "", []string{"/t_a", "/t_b"}},
+		{"RegisterRoutes", "module t_panic: RegisterRoutes panicked: boom in RegisterRoutes", []string{"/t_a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phase, func(t *testing.T) {
+			registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+			registry.Modules["t_panic"] = func() module.Module { return &panicModule{phase: tt.phase} }
+			t.Cleanup(func() { delete(registry.Modules, "t_panic") })
+			useGlobalRouter(t)
+			if err := rebuildRouter(context.Background(), Config{Modules: []string{"t_a"}}); err != nil {
+				t.Fatal(err)
+			}
+			err := rebuildRouter(context.Background(), Config{Modules: []string{"t_a", "t_b", "t_panic"}})
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("reload error = %v, want %q", err, tt.wantErr)
+			}
+			front := frontHandler(ServerConfig{}, false, "")
+			for _, path := range append(tt.served, "/t_panic") {
+				w := httptest.NewRecorder()
+				front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				if want := slices.Contains(tt.served, path); (w.Code == http.StatusOK) != want {
+					t.Errorf("GET %s = %d, want served = %v", path, w.Code, want)
+				}
+			}
+			if slices.Contains(manager.ActiveModules(), "t_panic") {
+				t.Error("panicking module is active")
+			}
+		})
+	}
+}
+
+func TestResolveDependenciesIsDeterministic(t *testing.T) {
+	registerTestModules(t, map[string][]string{
+		"t_a": nil, "t_b": nil, "t_c": nil,
+		"t_d": {"t_a"}, "t_e": {"t_d", "t_b"},
+	})
+	tests := []struct {
+		name    string
+		modules []string
+	}{
+		{"sorted", []string{"t_a", "t_b", "t_c", "t_d", "t_e"}},
+		{"reversed", []string{"t_e", "t_d", "t_c", "t_b", "t_a"}},
+		{"shuffled", []string{"t_c", "t_e", "t_a", "t_d", "t_b"}},
+		{"dependencies pulled in", []string{"t_c", "t_e"}},
+	}
+	// 同一层级内按名称排序，层级由最长依赖链决定
+	want := []string{"t_a", "t_b", "t_c", "t_d", "t_e"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				got, err := resolveDependencies(tt.modules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("order = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+// 运行 fn 并返回其间写到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// 运行 fn 并返回其间写到标准错误的内容
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+func captureFile(t *testing.T, f **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := *f
+	*f = w
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	*f = orig
+	w.Close()
+	return <-out
+}
+
+// Reloadable 返回 reloadable 的模块，记录 Init 次数
+type pinnedModule struct {
+	module.Base
+	reloadable bool
+	inits      int
+}
+
+func (m *pinnedModule) Reloadable() bool { return m.reloadable }
+
+func (m *pinnedModule) Init(cfg module.ModuleConfig) error {
+	m.inits++
+	return nil
+}
+
+func TestNonReloadableModuleKeepsInstance(t *testing.T) {
+	tests := []struct {
+		name       string
+		reloadable bool
+		wantSame   bool
+		wantLog    string
+	}{
+		{"reloadable", true, false, "Re-initializing module: t_pinned"},
+		{"not reloadable", false, true, "Config change for non-reloadable module requires a restart, keeping current instance: t_pinned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry.Modules["t_pinned"] = func() module.Module { return &pinnedModule{reloadable: tt.reloadable} }
+			t.Cleanup(func() { delete(registry.Modules, "t_pinned") })
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"t_pinned"}, Configs: map[string]map[string]any{"t_pinned": {"port": 1}}}
+			if _, err := m.Update(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			before, _ := m.activeModule("t_pinned")
+
+			cfg.Configs = map[string]map[string]any{"t_pinned": {"port": 2}}
+			out := captureStdout(t, func() {
+				if _, err := m.Update(context.Background(), cfg); err != nil {
+					t.Error(err)
+				}
+			})
+			after, _ := m.activeModule("t_pinned")
+			if same := after == before; same != tt.wantSame {
+				t.Errorf("instance unchanged = %v, want %v", same, tt.wantSame)
+			}
+			if tt.wantSame && before.(*pinnedModule).inits != 1 {
+				t.Errorf("Init called %d times, want 1", before.(*pinnedModule).inits)
+			}
+			if !strings.Contains(out, tt.wantLog) {
+				t.Errorf("output %q does not contain %q", out, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_x": {"t_y"}, "t_y": {"t_z"}, "t_z": {"t_x"}, "t_dangling": {"t_nowhere"}})
+	registry.Modules["t_broken"] = func() module.Module { return &flakyModule{fails: 100} }
+	t.Cleanup(func() { delete(registry.Modules, "t_broken") })
+
+	tests := []struct {
+		name    string
+		modules []string
+		check   func(t *testing.T, err error)
+	}{
+		{"dependency cycle", []string{"t_x"}, func(t *testing.T, err error) {
+			var cycle *module.DependencyCycleError
+			if !errors.As(err, &cycle) || !errors.Is(err, module.ErrDependencyCycle) {
+				t.Fatalf("err = %v, want a DependencyCycleError", err)
+			}
+			if want := []string{"t_x", "t_y", "t_z", "t_x"}; !slices.Equal(cycle.Path, want) {
+				t.Errorf("cycle path = %v, want %v", cycle.Path, want)
+			}
+		}},
+		{"unknown module", []string{"t_missing"}, func(t *testing.T, err error) {
+			var unknown *module.UnknownModuleError
+			if !errors.As(err, &unknown) || unknown.Name != "t_missing" || !errors.Is(err, module.ErrUnknownModule) {
+				t.Errorf("err = %v, want UnknownModuleError for t_missing", err)
+			}
+		}},
+		{"unknown dependency", []string{"t_dangling"}, func(t *testing.T, err error) {
+			var unknown *module.UnknownModuleError
+			if !errors.As(err, &unknown) || unknown.Name != "t_nowhere" {
+				t.Errorf("err = %v, want UnknownModuleError for t_nowhere", err)
+			}
+		}},
+		{"init failure", []string{"t_broken"}, func(t *testing.T, err error) {
+			var initErr *module.ModuleInitError
+			if !errors.As(err, &initErr) || initErr.Module != "t_broken" || !errors.Is(err, module.ErrModuleInit) {
+				t.Errorf("err = %v, want ModuleInitError for t_broken", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			_, err := m.Update(context.Background(), Config{Modules: tt.modules})
+			if err == nil {
+				// 默认策略下初始化失败的模块被跳过，错误记录在 initError 中
+				err = m.initError()
+			}
+			tt.check(t, err)
+		})
+	}
+}
+
+// 声明版本的模块
+type versionedModule struct {
+	module.Base
+	version string
+	deps    []string
+}
+
+func (m *versionedModule) Version() string { return m.version }
+func (m *versionedModule) Deps() []string  { return m.deps }
+
+func TestDependencyVersionConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		dep     string // t_app 的依赖声明
+		wantErr string
+		version bool // 错误是否为 ErrVersion
+	}{
+		{"satisfied", "t_lib>=1.2.0", "", false},
+		{"exact", "t_lib==1.5.0", "", false},
+		{"unsatisfied", "t_lib>=2.0.0", "module t_app requires t_lib>=2.0.0, but t_lib is 1.5.0", true},
+		{"no version declared", "t_plain>=1.0.0", "module t_app requires t_plain>=1.0.0, but t_plain declares no version", true},
+		{"invalid constraint", "t_lib>=latest", `module t_app dependency "t_lib>=latest"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerTestModules(t, map[string][]string{"t_plain": nil})
+			registry.Modules["t_lib"] = func() module.Module { return &versionedModule{version: "1.5.0"} }
+			registry.Modules["t_app"] = func() module.Module { return &versionedModule{version: "0.1.0", deps: []string{tt.dep}} }
+			t.Cleanup(func() {
+				delete(registry.Modules, "t_lib")
+				delete(registry.Modules, "t_app")
+			})
+			_, err := resolveDependencies([]string{"t_app"})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if errors.Is(err, module.ErrVersion) != tt.version {
+				t.Errorf("errors.Is(err, ErrVersion) = %v, want %v", !tt.version, tt.version)
+			}
+		})
+	}
+}
+
+func TestModulesByTag(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_edge1": nil, "t_edge2": nil, "t_core": nil, "t_plain": nil})
+	cfg := Config{
+		Modules: []string{"t_edge1", "t_edge2", "t_core", "t_plain"},
+		Configs: map[string]map[string]any{
+			"t_edge1": {"tags": map[string]any{"tier": "edge"}},
+			"t_edge2": {"tags": map[string]any{"tier": "edge", "team": "web"}},
+			"t_core":  {"tags": map[string]any{"tier": "core"}},
+		},
+	}
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	if _, err := m.Update(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		k, v string
+		want []string
+	}{
+		{"tier", "edge", []string{"t_edge1", "t_edge2"}},
+		{"tier", "core", []string{"t_core"}},
+		{"team", "web", []string{"t_edge2"}},
+		{"tier", "missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.k+"="+tt.v, func(t *testing.T) {
+			got := m.ModulesByTag(tt.k, tt.v)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ModulesByTag(%q, %q) = %v, want %v", tt.k, tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleHostBinding(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{
+		"t_shop": {"/shop", "shop"},
+		"t_blog": {"/blog", "blog"},
+		"t_any":  {"/any", "any"},
+	})
+	useGlobalRouter(t)
+	cfg := Config{
+		Modules: []string{"t_shop", "t_blog", "t_any"},
+		Configs: map[string]map[string]any{
+			"t_shop": {"host": "shop.example.com"},
+			"t_blog": {"host": "blog.example.com:8443"},
+		},
+	}
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	h := frontHandler(cfg.Server, false, "")
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+	}{
+		{"shop.example.com", "/shop", http.StatusOK},
+		{"SHOP.example.com:8080", "/shop", http.StatusOK}, // 配置不含端口时忽略端口，不区分大小写
+		{"blog.example.com", "/shop", http.StatusNotFound},
+		{"blog.example.com:8443", "/blog", http.StatusOK},
+		{"blog.example.com:9000", "/blog", http.StatusNotFound},
+		{"shop.example.com", "/blog", http.StatusNotFound},
+		{"other.example.com", "/any", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+// 声明了 Priority 的测试模块
+type priorityModule struct {
+	*testModule
+	priority int
+}
+
+func (m *priorityModule) Priority() int { return m.priority }
+
+func TestPriorityOrdersWithinLevel(t *testing.T) {
+	events := registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_d": {"t_a"}})
+	priorities := map[string]int{"t_zlog": 10, "t_low": -1, "t_dep_first": 5}
+	deps := map[string][]string{"t_dep_first": {"t_b"}}
+	for name, p := range priorities {
+		name, p := name, p
+		registry.Modules[name] = func() module.Module {
+			return &priorityModule{testModule: &testModule{name: name, deps: deps[name], events: events}, priority: p}
+		}
+	}
+	t.Cleanup(func() {
+		for name := range priorities {
+			delete(registry.Modules, name)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		modules []string
+		want    []string
+	}{
+		// 高优先级的无依赖模块排在同层级的默认优先级模块之前，负优先级排在之后
+		{"higher priority first", []string{"t_a", "t_zlog", "t_low"}, []string{"t_zlog", "t_a", "t_low"}},
+		// 优先级不能越过依赖：t_dep_first 仍在 t_b 之后，只在其层级内排在 t_d 之前
+		{"dependencies still win", []string{"t_dep_first", "t_d"}, []string{"t_a", "t_b", "t_dep_first", "t_d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDependencies(tt.modules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+
+			// Init 按同样的顺序执行
+			events.events = nil
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			if _, err := m.Update(context.Background(), Config{Modules: tt.modules}); err != nil {
+				t.Fatal(err)
+			}
+			if inits := events.with("init "); !slices.Equal(inits, tt.want) {
+				t.Errorf("init order = %v, want %v", inits, tt.want)
+			}
+		})
+	}
+}
+
+// Health 由配置 healthy 决定，GET /t_hc 返回配置 body
+type selfCheckModule struct {
+	module.Base
+	healthy bool
+	body    string
+	closed  atomic.Bool
+}
+
+func (m *selfCheckModule) Init(cfg module.ModuleConfig) error {
+	m.healthy = cfg.GetBool("healthy", true)
+	m.body = cfg.GetString("body", "")
+	return nil
+}
+
+func (m *selfCheckModule) Health() error {
+	if !m.healthy {
+		return errors.New("backend unreachable")
+	}
+	return nil
+}
+
+func (m *selfCheckModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/t_hc", func(c *gin.Context) { c.String(http.StatusOK, m.body) })
+}
+
+func (m *selfCheckModule) Shutdown() error {
+	m.closed.Store(true)
+	return nil
+}
+
+func TestFailedSelfCheckKeepsPreviousRouter(t *testing.T) {
+	var instances []*selfCheckModule
+	registry.Modules["t_hc"] = func() module.Module {
+		m := &selfCheckModule{}
+		instances = append(instances, m)
+		return m
+	}
+	t.Cleanup(func() { delete(registry.Modules, "t_hc") })
+	useGlobalRouter(t)
+	front := frontHandler(ServerConfig{}, false, "")
+
+	tests := []struct {
+		name     string
+		healthy  bool
+		body     string
+		wantErr  string
+		wantBody string // 重载后 GET /t_hc 的响应
+	}{
+		{"healthy start", true, "v1", "", "v1"},
+		{"failing self-check", false, "v2", "self-check of module t_hc failed: backend unreachable", "v1"},
+		{"healthy again", true, "v3", "", "v3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Modules: []string{"t_hc"},
+				Configs: map[string]map[string]any{"t_hc": {"healthy": tt.healthy, "body": tt.body}},
+			}
+			err := rebuildRouter(context.Background(), cfg)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("rebuild error = %v, want %q", err, tt.wantErr)
+				}
+				// 未通过自检的新实例被关闭，旧实例继续服务
+				if rejected := instances[len(instances)-1]; !rejected.closed.Load() {
+					t.Error("instance that failed the self-check was not shut down")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t_hc", nil))
+			if w.Code != http.StatusOK || w.Body.String() != tt.wantBody {
+				t.Errorf("GET /t_hc = %d %q, want 200 %q", w.Code, w.Body, tt.wantBody)
+			}
+			if active, _ := manager.activeModule("t_hc"); active == nil || active.(*selfCheckModule).body != tt.wantBody {
+				t.Errorf("active instance serves %v, want %q", active, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestModuleAliases(t *testing.T) {
+	cfg := Config{
+		Modules: []string{"order@primary", "order@secondary"},
+		Configs: map[string]map[string]any{
+			"order@primary":   {"dsn": "memory://primary"},
+			"order@secondary": {"dsn": "memory://secondary"},
+		},
+	}
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// order 依赖 auth，两个实例共用一个 auth
+	if got := m.ActiveModules(); !slices.Equal(got, []string{"auth", "order@primary", "order@secondary"}) {
+		t.Fatalf("active modules = %v", got)
+	}
+	primary, _ := m.activeModule("order@primary")
+	secondary, _ := m.activeModule("order@secondary")
+	if primary == nil || primary == secondary {
+		t.Fatal("aliases do not get distinct instances")
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		// 别名实例默认挂载在 /<别名> 下
+		{"/primary/order", http.StatusOK, "memory://primary"},
+		{"/secondary/order", http.StatusOK, "memory://secondary"},
+		{"/order", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("GET %s = %d %s, want %d containing %q", tt.path, w.Code, w.Body, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutesRequiresInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(*module.Lifecycle)
+		wantErr bool
+	}{
+		{"uninitialized", func(*module.Lifecycle) {}, true},
+		{"initialized", func(l *module.Lifecycle) { l.Transition("t_life", module.StateInitialized) }, false},
+		{"already shut down", func(l *module.Lifecycle) {
+			l.Transition("t_life", module.StateInitialized)
+			l.Transition("t_life", module.StateShutDown)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var life module.Lifecycle
+			tt.prepare(&life)
+			mod := &testModule{name: "t_life", events: &testEvents{}}
+			r := gin.New()
+			err := registerModuleRoutes(r, newRouteTable(), "t_life", "", mod, &life, nil)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(r.Routes()) != 1 {
+					t.Errorf("routes = %v, want GET /t_life", r.Routes())
+				}
+				return
+			}
+			if !errors.Is(err, module.ErrLifecycle) {
+				t.Fatalf("err = %v, want ErrLifecycle", err)
+			}
+			// 非法转换时不调用模块的 RegisterRoutes
+			if len(r.Routes()) != 0 {
+				t.Errorf("routes registered despite lifecycle error: %v", r.Routes())
+			}
+		})
+	}
+}
+
+// 声明两个 worker 的测试模块：worker 启动与 ctx 取消都以 "<id> " 为前缀记录到 events；
+// 配置 fail 为 true 时第二个 worker 立即出错
+type workerModule struct {
+	module.Base
+	id     string
+	events *testEvents
+	fail   bool
+}
+
+func (m *workerModule) Init(cfg module.ModuleConfig) error {
+	m.fail = cfg.GetBool("fail", false)
+	m.events.add(m.id + " init")
+	return nil
+}
+
+func (m *workerModule) Workers() []module.Worker {
+	return []module.Worker{
+		module.WorkerFunc(func(ctx context.Context) error {
+			m.events.add(m.id + " start")
+			<-ctx.Done()
+			m.events.add(m.id + " cancelled")
+			return ctx.Err()
+		}),
+		module.WorkerFunc(func(ctx context.Context) error {
+			if m.fail {
+				return errors.New("queue closed")
+			}
+			<-ctx.Done()
+			return nil
+		}),
+	}
+}
+
+func (m *workerModule) Shutdown() error {
+	m.events.add(m.id + " shutdown")
+	return nil
+}
+
+// 以 id 区分实例登记 t_worker，返回记录事件的 events
+func registerWorkerModule(t *testing.T) *testEvents {
+	events := &testEvents{}
+	var n atomic.Int32
+	registry.Modules["t_worker"] = func() module.Module {
+		return &workerModule{id: fmt.Sprint(n.Add(1)), events: events}
+	}
+	t.Cleanup(func() { delete(registry.Modules, "t_worker") })
+	return events
+}
+
+func TestModuleWorkers(t *testing.T) {
+	lifecycle := []string{"init", "start", "cancelled", "shutdown"}
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m *ModuleManager)
+		want map[string][]string // 各实例的事件，按发生顺序
+	}{
+		{
+			name: "cancelled before shutdown",
+			run:  func(t *testing.T, m *ModuleManager) { m.ShutdownAll(0) },
+			want: map[string][]string{"1": lifecycle},
+		},
+		{
+			name: "restarted on reinit",
+			run: func(t *testing.T, m *ModuleManager) {
+				if _, err := m.Update(context.Background(), Config{Modules: []string{"t_worker"}}, "t_worker"); err != nil {
+					t.Fatal(err)
+				}
+				m.ShutdownAll(0)
+			},
+			want: map[string][]string{"1": lifecycle, "2": lifecycle},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := registerWorkerModule(t)
+			m := NewModuleManager()
+			if _, err := m.Update(context.Background(), Config{Modules: []string{"t_worker"}}); err != nil {
+				t.Fatal(err)
+			}
+			eventually(t, time.Second, "worker start", func() bool { return slices.Contains(events.with("1 "), "start") })
+			tt.run(t, m)
+			for id, want := range tt.want {
+				if got := events.with(id + " "); !slices.Equal(got, want) {
+					t.Errorf("instance %s events = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("failing worker cancels its siblings", func(t *testing.T) {
+		events := registerWorkerModule(t)
+		m := NewModuleManager()
+		defer m.ShutdownAll(0)
+		cfg := Config{Modules: []string{"t_worker"}, Configs: map[string]map[string]any{"t_worker": {"fail": true}}}
+		if _, err := m.Update(context.Background(), cfg); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, time.Second, "sibling cancelled", func() bool { return slices.Contains(events.with("1 "), "cancelled") })
+	})
+}
+
+// 声明了默认值的测试模块，记录 Init 收到的配置
+type defaultsModule struct {
+	module.Base
+	got module.ModuleConfig
+}
+
+func (m *defaultsModule) Defaults() module.ModuleConfig {
+	return module.ModuleConfig{"pool": 4, "mode": "fast"}
+}
+
+func (m *defaultsModule) Init(cfg module.ModuleConfig) error {
+	m.got = cfg
+	return nil
+}
+
+func TestInitReceivesDefaults(t *testing.T) {
+	registry.Modules["t_defaults"] = func() module.Module { return &defaultsModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_defaults") })
+	tests := []struct {
+		name string
+		user map[string]any
+		want module.ModuleConfig
+	}{
+		{"no user config", nil, module.ModuleConfig{"pool": 4, "mode": "fast"}},
+		{"user value wins", map[string]any{"pool": 16}, module.ModuleConfig{"pool": 16, "mode": "fast"}},
+		{"extra user key kept", map[string]any{"label": "x"}, module.ModuleConfig{"pool": 4, "mode": "fast", "label": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_defaults"}}
+			if tt.user != nil {
+				cfg.Configs = map[string]map[string]any{"t_defaults": tt.user}
+			}
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			if _, err := m.Update(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			mod, _ := m.activeModule("t_defaults")
+			if got := mod.(*defaultsModule).got; !maps.Equal(got, tt.want) {
+				t.Errorf("Init received %v, want %v", got, tt.want)
+			}
+			// 生效配置中同样包含默认值
+			if got := m.EffectiveConfig().Configs["t_defaults"]; !maps.Equal(got, tt.want) {
+				t.Errorf("effective config = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 内置模块：未配置时使用各自声明的默认值
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), Config{Modules: []string{"order", "user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{"/order": "memory://default", "/user": "Hello from user (default)"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s = %s, want it to contain %q", path, w.Body, want)
+		}
+	}
+}
+
+// 统计 Shutdown 调用次数的测试模块，第二次调用会因重复关闭 channel 而 panic
+type countingShutdownModule struct {
+	module.Base
+	done  chan struct{}
+	calls atomic.Int32
+}
+
+func (m *countingShutdownModule) Shutdown() error {
+	m.calls.Add(1)
+	close(m.done)
+	return nil
+}
+
+func TestShutdownCalledOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m *ModuleManager)
+	}{
+		{"shutdown all twice", func(t *testing.T, m *ModuleManager) {
+			m.ShutdownAll(0)
+			m.ShutdownAll(0)
+		}},
+		{"removed on reload then shutdown all", func(t *testing.T, m *ModuleManager) {
+			if _, err := m.Update(context.Background(), Config{}); err != nil {
+				t.Fatal(err)
+			}
+			m.ShutdownAll(0)
+			m.ShutdownAll(0)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instance *countingShutdownModule
+			registry.Modules["t_once"] = func() module.Module {
+				instance = &countingShutdownModule{done: make(chan struct{})}
+				return instance
+			}
+			t.Cleanup(func() { delete(registry.Modules, "t_once") })
+			m := NewModuleManager()
+			if _, err := m.Update(context.Background(), Config{Modules: []string{"t_once"}}); err != nil {
+				t.Fatal(err)
+			}
+			tt.run(t, m)
+			if n := instance.calls.Load(); n != 1 {
+				t.Errorf("Shutdown called %d times, want 1", n)
+			}
+		})
+	}
+}
+
+// RegisterRoutes 先休眠 delay 再注册 GET /t_slowreg
+type slowRegisterModule struct {
+	module.Base
+	delay time.Duration
+}
+
+func (m *slowRegisterModule) RegisterRoutes(r gin.IRouter) {
+	time.Sleep(m.delay)
+	r.GET("/t_slowreg", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+}
+
+func TestSlowRegisterRoutesWarns(t *testing.T) {
+	old := slowRegisterThreshold
+	slowRegisterThreshold = 20 * time.Millisecond
+	t.Cleanup(func() { slowRegisterThreshold = old })
+	tests := []struct {
+		name  string
+		delay time.Duration
+		warn  bool
+	}{
+		{"fast", 0, false},
+		{"slow", 50 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry.Modules["t_slowreg"] = func() module.Module { return &slowRegisterModule{delay: tt.delay} }
+			t.Cleanup(func() { delete(registry.Modules, "t_slowreg") })
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			var r *routerSet
+			var err error
+			out := captureStdout(t, func() { r, err = m.Update(context.Background(), Config{Modules: []string{"t_slowreg"}}) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			warned := strings.Contains(out, "Warning: module t_slowreg took") && strings.Contains(out, "in RegisterRoutes (threshold 20ms)")
+			if warned != tt.warn {
+				t.Errorf("warning printed = %v, want %v; output:\n%s", warned, tt.warn, out)
+			}
+			// 慢注册仍然完成
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t_slowreg", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET /t_slowreg = %d, want 200", w.Code)
+			}
+		})
+	}
+}
+
+// 以 reason 声明弃用的测试模块，reason 为空表示未弃用
+type deprecatedModule struct {
+	testModule
+	reason string
+}
+
+func (m *deprecatedModule) Deprecated() string { return m.reason }
+
+func TestDeprecatedModuleWarns(t *testing.T) {
+	tests := []struct {
+		name     string
+		reason   string
+		warnings int // 每次重载打印警告的次数
+	}{
+		{"deprecated", "use t_new instead", 1},
+		{"not deprecated", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			registry.Modules["t_old"] = func() module.Module {
+				return &deprecatedModule{testModule: testModule{name: "t_old", events: &testEvents{}}, reason: tt.reason}
+			}
+			t.Cleanup(func() { delete(registry.Modules, "t_old") })
+			cfg := Config{Modules: []string{"t_old"}}
+			cfg.Server.AdminToken = "secret"
+			warning := "Warning: module t_old is DEPRECATED and will be removed: " + tt.reason
+
+			// 每次重载都提醒，而不只是首次启动
+			for i := 0; i < 2; i++ {
+				out := captureStdout(t, func() {
+					if err := rebuildRouter(context.Background(), cfg); err != nil {
+						t.Fatal(err)
+					}
+				})
+				if got := strings.Count(out, warning); got != tt.warnings {
+					t.Errorf("reload %d: warning printed %d times, output %q", i+1, got, out)
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/modules", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			frontHandler(cfg.Server, false, "").ServeHTTP(w, req)
+			var states map[string]struct {
+				Deprecated string `json:"deprecated"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &states); err != nil {
+				t.Fatalf("GET /admin/modules = %d %s: %v", w.Code, w.Body, err)
+			}
+			if got := states["t_old"].Deprecated; got != tt.reason {
+				t.Errorf("deprecated = %q, want %q", got, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLoadBalanceAcrossInstances(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]any // 实例 -> 权重
+		want    map[string]int // dsn -> 期望命中次数
+	}{
+		{"3:1", map[string]any{"order": 3, "order@replica": 1}, map[string]int{"memory://main": 300, "memory://replica": 100}},
+		{"1:1", map[string]any{"order": 1, "order@replica": 1}, map[string]int{"memory://main": 200, "memory://replica": 200}},
+		{"single target", map[string]any{"order@replica": 5}, map[string]int{"memory://replica": 400}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{
+				Modules: []string{"order", "order@replica", "balance@orders"},
+				Configs: map[string]map[string]any{
+					"order":          {"dsn": "memory://main"},
+					"order@replica":  {"dsn": "memory://replica"},
+					"balance@orders": {"targets": tt.weights},
+				},
+			}
+			var r *routerSet
+			var err error
+			captureStdout(t, func() { r, err = m.Update(context.Background(), cfg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]int{}
+			for i := 0; i < 400; i++ {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/order", nil))
+				for dsn := range tt.want {
+					if strings.Contains(w.Body.String(), dsn) {
+						got[dsn]++
+					}
+				}
+			}
+			// 平滑加权轮询在权重总和的整数倍次请求后严格按权重分配
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("distribution = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 总线测试模块：POST /<name>/publish 发布到 orders，订阅的模块把收到的消息记录为 "<name> got <msg>"
+type busModule struct {
+	module.Base
+	name      string
+	subscribe bool
+	bus       *module.BusClient
+	events    *testEvents
+}
+
+func (m *busModule) SetBus(c *module.BusClient) { m.bus = c }
+
+func (m *busModule) Init(module.ModuleConfig) error {
+	if m.subscribe {
+		ch := m.bus.Subscribe("orders")
+		go func() {
+			for msg := range ch {
+				m.events.add(fmt.Sprintf("%s got %v", m.name, msg))
+			}
+			m.events.add(m.name + " unsubscribed")
+		}()
+	}
+	return nil
+}
+
+func (m *busModule) RegisterRoutes(r gin.IRouter) {
+	r.POST("/"+m.name+"/publish", func(c *gin.Context) {
+		m.bus.Publish("orders", c.Query("msg"))
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func TestModuleBus(t *testing.T) {
+	events := &testEvents{}
+	for name, subscribe := range map[string]bool{"t_pub": false, "t_sub": true} {
+		name, subscribe := name, subscribe
+		registry.Modules[name] = func() module.Module { return &busModule{name: name, subscribe: subscribe, events: events} }
+	}
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_pub")
+		delete(registry.Modules, "t_sub")
+	})
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		msg         string
+		want        []string // 本步新增的事件
+		subscribers int
+	}{
+		{"delivered", Config{Modules: []string{"t_pub", "t_sub"}}, "1", []string{"t_sub got 1"}, 1},
+		// 订阅方被替换：旧订阅关闭，只有新实例收到消息
+		{"subscriber reinitialized", Config{Modules: []string{"t_pub", "t_sub"}, Configs: map[string]map[string]any{"t_sub": {"v": 2}}}, "2", []string{"t_sub got 2", "t_sub unsubscribed"}, 1},
+		{"subscriber removed", Config{Modules: []string{"t_pub"}}, "3", []string{"t_sub unsubscribed"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(events.with(""))
+			r, err := m.Update(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.StopRetired(0)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/t_pub/publish?msg="+tt.msg, nil))
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("publish = %d", w.Code)
+			}
+			eventually(t, time.Second, "bus events", func() bool { return len(events.with("")) >= before+len(tt.want) })
+			time.Sleep(20 * time.Millisecond)
+			// 新旧订阅的 goroutine 之间没有先后顺序，按集合比较
+			got := events.with("")[before:]
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			if n := m.bus.Subscribers("orders"); n != tt.subscribers {
+				t.Errorf("subscribers = %d, want %d", n, tt.subscribers)
+			}
+		})
+	}
+}
+
+func TestProtectedModules(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_c": nil})
+	protected := func(modules []string, protect ...string) Config {
+		cfg := Config{Modules: modules}
+		cfg.Server.ProtectModules = protect
+		return cfg
+	}
+	disabled := protected([]string{"t_a", "t_b", "t_c"}, "t_a")
+	disabled.Configs = map[string]map[string]any{"t_a": {"enabled": false}}
+	tests := []struct {
+		name    string
+		reloads []Config // 初始配置之后依次应用
+		wantErr string   // 最后一次重载的错误
+		want    []string
+	}{
+		{"remove unprotected", []Config{protected([]string{"t_a", "t_c"}, "t_a")}, "", []string{"t_a", "t_c"}},
+		{"remove protected", []Config{protected([]string{"t_b", "t_c"}, "t_a")}, "reload would remove protected modules [t_a]", []string{"t_a", "t_b", "t_c"}},
+		{"disable protected", []Config{disabled}, "reload would remove protected modules [t_a]", []string{"t_a", "t_b", "t_c"}},
+		// 保护以当前配置为准，同一次重载里去掉保护并不能移除
+		{"drop protection in the same reload", []Config{protected([]string{"t_b", "t_c"})}, "reload would remove protected modules [t_a]", []string{"t_a", "t_b", "t_c"}},
+		{"drop protection first", []Config{protected([]string{"t_a", "t_b", "t_c"}), protected([]string{"t_b", "t_c"})}, "", []string{"t_b", "t_c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			if _, err := m.Update(context.Background(), protected([]string{"t_a", "t_b", "t_c"}, "t_a")); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			for _, cfg := range tt.reloads {
+				_, err = m.Update(context.Background(), cfg)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Update error = %v, want %q", err, tt.wantErr)
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinActiveModules(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_c": nil})
+	registry.Modules["t_broken"] = func() module.Module { return &flakyModule{fails: 1000} }
+	t.Cleanup(func() { delete(registry.Modules, "t_broken") })
+	withFloor := func(floor int, modules ...string) Config {
+		cfg := Config{Modules: modules}
+		cfg.Server.MinActiveModules = floor
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		reloads []Config // 初始配置（t_a、t_b、t_c，下限 2）之后依次应用
+		wantErr string   // 最后一次重载的错误
+		want    []string
+	}{
+		{"at the threshold", []Config{withFloor(2, "t_a", "t_b")}, "", []string{"t_a", "t_b"}},
+		{"below the threshold", []Config{withFloor(2, "t_a")},
+			"reload would leave 1 enabled modules, below server.min_active_modules (2)", []string{"t_a", "t_b", "t_c"}},
+		// 截断的配置文件连同下限一起丢失时仍以当前下限为准
+		{"truncated config without the setting", []Config{{Modules: []string{"t_a"}}},
+			"below server.min_active_modules (2)", []string{"t_a", "t_b", "t_c"}},
+		{"threshold lowered first", []Config{withFloor(0, "t_a", "t_b", "t_c"), withFloor(0, "t_a")}, "", []string{"t_a"}},
+		{"raised threshold applies to the same reload", []Config{withFloor(4, "t_a", "t_b", "t_c")},
+			"reload would leave 3 enabled modules, below server.min_active_modules (4)", []string{"t_a", "t_b", "t_c"}},
+		// 初始化失败被跳过的模块不计入
+		{"failed init not counted", []Config{withFloor(3, "t_a", "t_b", "t_broken")},
+			"reload would leave 2 active modules, below server.min_active_modules (3)", []string{"t_a", "t_b", "t_c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			if _, err := m.Update(context.Background(), withFloor(2, "t_a", "t_b", "t_c")); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			out := captureStdout(t, func() {
+				for _, cfg := range tt.reloads {
+					_, err = m.Update(context.Background(), cfg)
+				}
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Update error = %v, want %q", err, tt.wantErr)
+				}
+				if !strings.Contains(out, "reload REJECTED") {
+					t.Errorf("output = %q, want a loud rejection warning", out)
+				}
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, tt.want) {
+				t.Errorf("active modules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 依赖链中的最后一个模块：Init 或自检按设置失败
+type chainTailModule struct {
+	testModule
+	initErr, healthErr error
+}
+
+func (m *chainTailModule) Init(cfg module.ModuleConfig) error {
+	m.events.add("init " + m.name)
+	return m.initErr
+}
+
+func (m *chainTailModule) Health() error { return m.healthErr }
+
+func TestFailedReloadRollsBackStartedModules(t *testing.T) {
+	errBroken := errors.New("broken")
+	tests := []struct {
+		name         string
+		tail         *chainTailModule
+		floor        int // server.min_active_modules
+		wantErr      string
+		wantShutdown []string // 回滚时关闭的模块，按顺序
+	}{
+		// t_3 初始化失败被跳过，激活模块数低于下限而拒绝重载，t_3 未启动不需要关闭
+		{"third module fails init", &chainTailModule{initErr: errBroken}, 4,
+			"below server.min_active_modules (4)", []string{"t_2", "t_1"}},
+		{"third module fails self-check", &chainTailModule{healthErr: errBroken}, 0,
+			"self-check of module t_3 failed: broken", []string{"t_3", "t_2", "t_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// t_3 -> t_2 -> t_1 -> t_base，t_base 在重载前已激活
+			events := registerTestModules(t, map[string][]string{"t_base": nil, "t_1": {"t_base"}, "t_2": {"t_1"}})
+			registry.Modules["t_3"] = func() module.Module {
+				tt.tail.testModule = testModule{name: "t_3", deps: []string{"t_2"}, events: events}
+				return tt.tail
+			}
+			defer delete(registry.Modules, "t_3")
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), Config{Modules: []string{"t_base"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := Config{Modules: []string{"t_base", "t_1", "t_2", "t_3"}}
+			cfg.Server.MinActiveModules = tt.floor
+			_, err = m.Update(context.Background(), cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Update error = %v, want %q", err, tt.wantErr)
+			}
+			if got := events.with("shutdown "); !slices.Equal(got, tt.wantShutdown) {
+				t.Errorf("shut down during rollback = %v, want %v", got, tt.wantShutdown)
+			}
+			n := 0
+			for _, name := range events.with("init ") {
+				if name == "t_base" {
+					n++
+				}
+			}
+			if n != 1 {
+				t.Errorf("t_base initialized %d times, want 1 (reused)", n)
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, []string{"t_base"}) {
+				t.Errorf("active modules = %v, want [t_base]", got)
+			}
+			// 复用的实例未被关闭，旧路由照常服务
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t_base", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET /t_base = %d %s, want 200", w.Code, w.Body)
+			}
+		})
+	}
+}
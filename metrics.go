@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理器输出的指标名，模块不能以这些名称注册自定义指标
+var reservedMetrics = []string{
+	"http_requests_total",
+	"http_request_duration_seconds", "http_request_duration_seconds_bucket", "http_request_duration_seconds_sum", "http_request_duration_seconds_count",
+	"http_dispatch_shed_total",
+}
+
+// 请求耗时直方图的桶上界（秒）
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// 各模块路由的请求计数与耗时直方图，由管理器持有并跨重载保留，以 Prometheus 文本格式在 /metrics 输出。
+// 标签 route 为 gin 的路由模式（如 /order/:id），取值有限，不会随请求路径膨胀
+type httpMetrics struct {
+	mu     sync.Mutex
+	series map[seriesKey]*routeSeries
+}
+
+type seriesKey struct {
+	module, method, route string
+}
+
+type routeSeries struct {
+	codes   map[int]uint64
+	buckets []uint64 // 与 latencyBuckets 对应，非累计
+	count   uint64
+	sum     float64
+}
+
+func newHTTPMetrics() *httpMetrics {
+	return &httpMetrics{series: make(map[seriesKey]*routeSeries)}
+}
+
+// 记录模块 name 的请求；须排在模块的 recovery 之前，处理器 panic 时记录 500
+func (h *httpMetrics) middleware(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		h.observe(seriesKey{name, c.Request.Method, c.FullPath()}, c.Writer.Status(), time.Since(start))
+	}
+}
+
+func (h *httpMetrics) observe(key seriesKey, code int, d time.Duration) {
+	secs := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &routeSeries{codes: make(map[int]uint64), buckets: make([]uint64, len(latencyBuckets))}
+		h.series[key] = s
+	}
+	s.codes[code]++
+	s.count++
+	s.sum += secs
+	if i := sort.SearchFloat64s(latencyBuckets, secs); i < len(latencyBuckets) {
+		s.buckets[i]++
+	}
+}
+
+func (h *httpMetrics) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]seriesKey, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.module != b.module {
+			return a.module < b.module
+		}
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+
+	fmt.Fprintln(w, "# HELP http_requests_total Requests handled by module routes, by status code.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, k := range keys {
+		s := h.series[k]
+		codes := make([]int, 0, len(s.codes))
+		for code := range s.codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "http_requests_total{%s,code=\"%d\"} %d\n", k.labels(), code, s.codes[code])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Latency of requests handled by module routes.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, k := range keys {
+		s := h.series[k]
+		labels := k.labels()
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+}
+
+func (k seriesKey) labels() string {
+	return fmt.Sprintf(`module="%s",method="%s",route="%s"`, escapeLabel(k.module), escapeLabel(k.method), escapeLabel(k.route))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// /metrics 与探针一样注册在管理路由下，依次输出请求指标、max_dispatch 拒绝数与模块的自定义指标
+func registerMetricsRoute(g *gin.RouterGroup, routes *routeTable, m *ModuleManager) {
+	routes.router("manager", g).GET("/metrics", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		m.metrics.write(c.Writer)
+		fmt.Fprintln(c.Writer, "# HELP http_dispatch_shed_total Requests rejected with 503 by server.max_dispatch.")
+		fmt.Fprintln(c.Writer, "# TYPE http_dispatch_shed_total counter")
+		fmt.Fprintln(c.Writer, "http_dispatch_shed_total", dispatchShed.Load())
+		m.custom.Write(c.Writer)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	registry.Modules["t_faulty"] = func() module.Module { return &faultyModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_faulty") })
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	cfg := Config{Modules: []string{"auth", "order", "t_faulty"}}
+	r, err := m.Update(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		requests []string
+		reload   bool     // 请求之后重载一次，计数应保留
+		want     []string // /metrics 中应出现的行
+		absent   []string
+	}{
+		{"order requests counted", []string{"/order", "/order"}, false, []string{
+			`http_requests_total{module="order",method="GET",route="/order",code="200"} 2`,
+			`http_request_duration_seconds_count{module="order",method="GET",route="/order"} 2`,
+		}, nil},
+		{"counters kept across reloads", []string{"/order"}, true, []string{
+			`http_requests_total{module="order",method="GET",route="/order",code="200"} 3`,
+		}, nil},
+		{"status codes per route", []string{"/panic", "/fail", "/fail"}, false, []string{
+			`http_requests_total{module="t_faulty",method="GET",route="/panic",code="500"} 1`,
+			`http_requests_total{module="t_faulty",method="GET",route="/fail",code="409"} 2`,
+		}, nil},
+		// 未匹配任何模块路由的请求不计入
+		{"unmatched paths not counted", []string{"/missing"}, false, nil, []string{`route=""`, `/missing`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range tt.requests {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+			if tt.reload {
+				if r, err = m.Update(context.Background(), cfg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /metrics = %d", w.Code)
+			}
+			lines := strings.Split(w.Body.String(), "\n")
+			for _, want := range tt.want {
+				if !slices.Contains(lines, want) {
+					t.Errorf("/metrics is missing %s", want)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(w.Body.String(), s) {
+					t.Errorf("/metrics contains %s", s)
+				}
+			}
+		})
+	}
+}
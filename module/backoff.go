@@ -0,0 +1,42 @@
+package module
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 指数退避：第 attempt 次（从 0 开始）重试前等待 Base·2^attempt，不超过 Max（<= 0 表示不限）；
+// Jitter 为 true 时在 [d/2, d) 内随机取值，避免多个调用方同时重试
+type Backoff struct {
+	Base   time.Duration
+	Max    time.Duration
+	Jitter bool
+}
+
+// Delay 返回第 attempt 次重试前的等待时间
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 0; i < attempt && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if b.Jitter && d > 1 {
+		d = d/2 + rand.N(d/2)
+	}
+	return d
+}
+
+// Wait 等待 d，ctx 先结束时返回 ctx.Err()
+func Wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
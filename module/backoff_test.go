@@ -0,0 +1,55 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       Backoff
+		attempt int
+		want    time.Duration
+	}{
+		{"first retry", Backoff{Base: 10 * time.Millisecond}, 0, 10 * time.Millisecond},
+		{"doubles", Backoff{Base: 10 * time.Millisecond}, 1, 20 * time.Millisecond},
+		{"doubles again", Backoff{Base: 10 * time.Millisecond}, 3, 80 * time.Millisecond},
+		{"capped", Backoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}, 3, 50 * time.Millisecond},
+		{"cap does not overflow", Backoff{Base: time.Second, Max: time.Minute}, 200, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.b.Delay(tt.attempt); got != tt.want {
+			t.Errorf("%s: Delay(%d) = %s, want %s", tt.name, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Jitter: true}
+	for attempt := 0; attempt < 4; attempt++ {
+		full := Backoff{Base: b.Base}.Delay(attempt)
+		for i := 0; i < 50; i++ {
+			if d := b.Delay(attempt); d < full/2 || d >= full {
+				t.Fatalf("Delay(%d) = %s, want within [%s, %s)", attempt, d, full/2, full)
+			}
+		}
+	}
+}
+
+func TestWaitStopsOnContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := Wait(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Wait ignored the context for %s", d)
+	}
+	if err := Wait(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Wait = %v, want nil", err)
+	}
+}
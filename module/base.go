@@ -0,0 +1,90 @@
+package module
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Base 可嵌入模块结构体，为 Module 的全部方法提供空实现，模块只需覆盖用到的方法：
+//
+//	type X struct{ module.Base }
+//
+//	func (m *X) RegisterRoutes(r gin.IRouter) { r.GET("/x", ...) }
+//
+// Base 同时实现 LoggerAware，Logger() 返回管理器注入的子 logger（未注入时为 slog.Default()）。
+// 健康检查、预热、统计、中间件、配置 schema 等可选接口的存在本身会改变管理器的行为（如出现在 /healthz 中），
+// 因此 Base 不实现它们，模块按需自行实现
+type Base struct {
+	logger *slog.Logger
+}
+
+func (b *Base) Deps() []string               { return nil }
+func (b *Base) Init(cfg ModuleConfig) error  { return nil }
+func (b *Base) RegisterRoutes(r gin.IRouter) {}
+func (b *Base) Shutdown() error              { return nil }
+
+func (b *Base) SetLogger(l *slog.Logger) { b.logger = l }
+
+func (b *Base) Logger() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default()
+	}
+	return b.logger
+}
+
+// LegacyModule 是 RegisterRoutes 接收 *gin.Engine 的旧版模块接口
+type LegacyModule interface {
+	Deps() []string
+	Init(cfg ModuleConfig) error
+	RegisterRoutes(r *gin.Engine)
+	Shutdown() error
+}
+
+// Legacy 把旧版模块包装为 Module，便于逐个迁移：
+//
+//	// registry/registry.go
+//	var Modules = map[string]func() module.Module{
+//		"old": func() module.Module { return module.Legacy(&old.OldModule{}) },
+//	}
+//
+// 旧模块的路由先注册在私有的 *gin.Engine 上，再逐条挂到模块的路由组，因此前缀、listener、route_overrides
+// 等照常生效；请求在该 Engine 上按原路径分发，旧模块通过 Use 加在 Engine 上的中间件仍会执行。
+// 除 Module 之外的可选接口不会被转发，需要时应迁移为直接实现 Module：
+// 把 RegisterRoutes(r *gin.Engine) 改为 RegisterRoutes(r gin.IRouter)（函数体通常无需改动），或嵌入 Base
+func Legacy(m LegacyModule) Module {
+	return &legacyModule{LegacyModule: m}
+}
+
+type legacyModule struct {
+	LegacyModule
+}
+
+func (l *legacyModule) RegisterRoutes(r gin.IRouter) {
+	engine := gin.New()
+	l.LegacyModule.RegisterRoutes(engine)
+	for _, route := range engine.Routes() {
+		path := route.Path
+		r.Handle(route.Method, path, func(c *gin.Context) {
+			req := c.Request.Clone(c.Request.Context())
+			req.URL.Path, req.URL.RawPath = legacyPath(path, c.Params), ""
+			engine.ServeHTTP(c.Writer, req)
+		})
+	}
+}
+
+// 用请求中的参数还原旧模块注册的路径，如 /users/:id -> /users/42，使改写后的路由同样分发到原处理函数
+func legacyPath(path string, params gin.Params) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			v := params.ByName(seg[1:])
+			if seg[0] == '*' {
+				v = strings.TrimPrefix(v, "/")
+			}
+			segs[i] = v
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,78 @@
+package module_test
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 只实现 RegisterRoutes 的模块，其余方法来自 Base
+type pingModule struct{ module.Base }
+
+func (m *pingModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+}
+
+func ExampleBase() {
+	var mod module.Module = &pingModule{}
+	fmt.Println(mod.Deps(), mod.Init(nil), mod.Shutdown())
+
+	r := gin.New()
+	mod.RegisterRoutes(r.Group("/api"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+	fmt.Println(w.Code, w.Body.String())
+	// Output:
+	// [] <nil> <nil>
+	// 200 pong
+}
+
+// RegisterRoutes 接收 *gin.Engine 的旧版模块
+type oldUserModule struct{}
+
+func (oldUserModule) Deps() []string                     { return nil }
+func (oldUserModule) Init(cfg module.ModuleConfig) error { return nil }
+func (oldUserModule) Shutdown() error                    { return nil }
+
+func (oldUserModule) RegisterRoutes(r *gin.Engine) {
+	r.Use(func(c *gin.Context) { c.Header("X-Legacy", "1") })
+	r.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "user "+c.Param("id")) })
+	r.GET("/files/*path", func(c *gin.Context) { c.String(http.StatusOK, "file "+c.Param("path")) })
+}
+
+func ExampleLegacy() {
+	mod := module.Legacy(oldUserModule{})
+	r := gin.New()
+	mod.RegisterRoutes(r.Group("/api"))
+	for _, path := range []string{"/api/users/42", "/api/files/a/b.txt"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Println(w.Code, w.Body.String(), w.Header().Get("X-Legacy"))
+	}
+	// Output:
+	// 200 user 42 1
+	// 200 file /a/b.txt 1
+}
+
+func TestBaseLogger(t *testing.T) {
+	mod := &pingModule{}
+	var _ module.LoggerAware = mod
+	if mod.Logger() != slog.Default() {
+		t.Error("Logger() before SetLogger should be slog.Default()")
+	}
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mod.SetLogger(l)
+	if mod.Logger() != l {
+		t.Error("Logger() does not return the injected logger")
+	}
+}
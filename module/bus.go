@@ -0,0 +1,100 @@
+package module
+
+import "sync"
+
+// 每个订阅的缓冲消息数；订阅方处理不及、缓冲已满时新消息被丢弃，发布方不会被阻塞
+const busBuffer = 64
+
+// Bus 进程内的发布 / 订阅总线，由管理器持有并跨重载保留；模块通过 BusAware 得到各自的 BusClient
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string]map[*subscription]struct{}
+}
+
+type subscription struct {
+	topic string
+	ch    chan any
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[*subscription]struct{})}
+}
+
+// Client 创建一个客户端；关闭客户端时释放其全部订阅
+func (b *Bus) Client() *BusClient {
+	return &BusClient{bus: b}
+}
+
+// Subscribers 返回 topic 当前的订阅数
+func (b *Bus) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[topic])
+}
+
+func (b *Bus) publish(topic string, msg any) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs[topic] {
+		select {
+		case s.ch <- msg:
+		default:
+		}
+	}
+}
+
+// BusClient 是单个模块实例使用的总线句柄；管理器在该实例被移除或替换时关闭它（在 Shutdown 之前），
+// 其订阅的通道随之关闭，range 读取订阅的 goroutine 会自然退出
+type BusClient struct {
+	bus    *Bus
+	mu     sync.Mutex
+	subs   []*subscription
+	closed bool
+}
+
+// Publish 把 msg 投递给 topic 的所有订阅（含其他模块与本模块自身）
+func (c *BusClient) Publish(topic string, msg any) {
+	c.bus.publish(topic, msg)
+}
+
+// Subscribe 订阅 topic；客户端已关闭时返回已关闭的通道
+func (c *BusClient) Subscribe(topic string) <-chan any {
+	s := &subscription{topic: topic, ch: make(chan any, busBuffer)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(s.ch)
+		return s.ch
+	}
+	c.bus.mu.Lock()
+	if c.bus.subs[topic] == nil {
+		c.bus.subs[topic] = make(map[*subscription]struct{})
+	}
+	c.bus.subs[topic][s] = struct{}{}
+	c.bus.mu.Unlock()
+	c.subs = append(c.subs, s)
+	return s.ch
+}
+
+// Close 取消全部订阅并关闭其通道，可重复调用；nil 客户端上调用是安全的
+func (c *BusClient) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	for _, s := range c.subs {
+		delete(c.bus.subs[s.topic], s)
+		if len(c.bus.subs[s.topic]) == 0 {
+			delete(c.bus.subs, s.topic)
+		}
+		close(s.ch)
+	}
+	c.subs = nil
+}
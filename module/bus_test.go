@@ -0,0 +1,104 @@
+package module
+
+import (
+	"slices"
+	"testing"
+)
+
+// 读出通道中已有的全部消息，通道关闭时 closed 为 true
+func drain(ch <-chan any) (msgs []any, closed bool) {
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return msgs, true
+			}
+			msgs = append(msgs, m)
+		default:
+			return msgs, false
+		}
+	}
+}
+
+func TestBus(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(b *Bus) (got []any, closed bool)
+		want []any
+		// 期望订阅通道已关闭
+		closed bool
+		// 结束后 topic 的订阅数
+		subscribers int
+	}{
+		{
+			name: "publish reaches another client",
+			run: func(b *Bus) ([]any, bool) {
+				sub := b.Client().Subscribe("orders")
+				b.Client().Publish("orders", "created")
+				return drain(sub)
+			},
+			want:        []any{"created"},
+			subscribers: 1,
+		},
+		{
+			name: "other topics are not delivered",
+			run: func(b *Bus) ([]any, bool) {
+				sub := b.Client().Subscribe("orders")
+				b.Client().Publish("users", "created")
+				return drain(sub)
+			},
+			subscribers: 1,
+		},
+		{
+			name: "close releases subscriptions",
+			run: func(b *Bus) ([]any, bool) {
+				c := b.Client()
+				sub := c.Subscribe("orders")
+				c.Close()
+				c.Close()
+				b.Client().Publish("orders", "late")
+				return drain(sub)
+			},
+			closed: true,
+		},
+		{
+			name: "subscribe after close",
+			run: func(b *Bus) ([]any, bool) {
+				c := b.Client()
+				c.Close()
+				return drain(c.Subscribe("orders"))
+			},
+			closed: true,
+		},
+		{
+			// 缓冲已满时丢弃新消息，发布方不阻塞
+			name: "full buffer drops messages",
+			run: func(b *Bus) ([]any, bool) {
+				sub := b.Client().Subscribe("orders")
+				p := b.Client()
+				for i := 0; i < busBuffer+10; i++ {
+					p.Publish("orders", i)
+				}
+				msgs, closed := drain(sub)
+				return msgs[len(msgs)-1:], closed
+			},
+			want:        []any{busBuffer - 1},
+			subscribers: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBus()
+			got, closed := tt.run(b)
+			if !slices.Equal(got, tt.want) || closed != tt.closed {
+				t.Errorf("received %v (closed %v), want %v (closed %v)", got, closed, tt.want, tt.closed)
+			}
+			if n := b.Subscribers("orders"); n != tt.subscribers {
+				t.Errorf("subscribers = %d, want %d", n, tt.subscribers)
+			}
+		})
+	}
+	// nil 客户端上 Close 是安全的
+	var c *BusClient
+	c.Close()
+}
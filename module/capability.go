@@ -0,0 +1,56 @@
+package module
+
+import "slices"
+
+// 标准能力名：模块通过 Capabilities() 声明后，管理器才会查询对应的可选接口
+const (
+	CapHealth     = "health"     // HealthChecker：参与 /healthz 与重载自检
+	CapStats      = "stats"      // StatsReporter：参与 /admin/stats
+	CapWorkers    = "workers"    // WorkerProvider：启动后台任务
+	CapMiddleware = "middleware" // MiddlewareProvider：为依赖方的路由提供中间件
+	CapWarmup     = "warmup"     // Warmer：就绪前预热
+	CapServices   = "services"   // ServiceExporter：向依赖方注入服务
+)
+
+// 可选接口：显式声明模块支持的能力；未实现时按是否实现对应接口推断
+type Capable interface {
+	Capabilities() []string
+}
+
+// HasCapability 报告模块是否具备能力 c；实现了 Capable 的模块以声明为准
+func HasCapability(m Module, c string) bool {
+	if cp, ok := m.(Capable); ok {
+		return slices.Contains(cp.Capabilities(), c)
+	}
+	switch c {
+	case CapHealth:
+		_, ok := m.(HealthChecker)
+		return ok
+	case CapStats:
+		_, ok := m.(StatsReporter)
+		return ok
+	case CapWorkers:
+		_, ok := m.(WorkerProvider)
+		return ok
+	case CapMiddleware:
+		_, ok := m.(MiddlewareProvider)
+		return ok
+	case CapWarmup:
+		_, ok := m.(Warmer)
+		return ok
+	case CapServices:
+		_, ok := m.(ServiceExporter)
+		return ok
+	}
+	return false
+}
+
+// CapabilityOf 在模块具备能力 c 且实现了接口 T 时返回该接口
+func CapabilityOf[T any](m Module, c string) (T, bool) {
+	var zero T
+	if m == nil || !HasCapability(m, c) {
+		return zero, false
+	}
+	t, ok := m.(T)
+	return t, ok
+}
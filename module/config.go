@@ -0,0 +1,157 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetString 读取字符串配置，不存在或类型不符时返回默认值
+func (c ModuleConfig) GetString(key, def string) string {
+	if v, ok := c[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+// GetInt 读取整数配置，兼容 YAML 解析出的 int 与 JSON 解析出的 float64
+func (c ModuleConfig) GetInt(key string, def int) int {
+	switch v := c[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		if v == float64(int(v)) {
+			return int(v)
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// GetFloat 读取浮点数配置，兼容整数写法
+func (c ModuleConfig) GetFloat(key string, def float64) float64 {
+	switch v := c[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// GetBool 读取布尔配置，兼容 "true"/"false" 字符串
+func (c ModuleConfig) GetBool(key string, def bool) bool {
+	switch v := c[key].(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetDuration 读取时长配置：字符串按 time.ParseDuration 解析（如 "500ms"），数字按秒计
+func (c ModuleConfig) GetDuration(key string, def time.Duration) time.Duration {
+	switch v := c[key].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int:
+		return time.Duration(v) * time.Second
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	}
+	return def
+}
+
+// GetStringMap 读取键值对配置（如 tags），值统一转换为字符串；不存在或类型不符时返回 nil
+func (c ModuleConfig) GetStringMap(key string) map[string]string {
+	var m map[string]string
+	switch v := c[key].(type) {
+	case map[string]any:
+		m = make(map[string]string, len(v))
+		for k, val := range v {
+			m[k] = fmt.Sprint(val)
+		}
+	case map[string]string:
+		m = make(map[string]string, len(v))
+		for k, val := range v {
+			m[k] = val
+		}
+	case map[any]any: // 未经 utils.ExpandConfig 规范化的配置（如直接构造的 ModuleConfig）
+		m = make(map[string]string, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = fmt.Sprint(val)
+		}
+	}
+	return m
+}
+
+// GetStringSlice 读取字符串列表，单个字符串视为只有一项；不存在时返回 nil
+func (c ModuleConfig) GetStringSlice(key string) []string {
+	switch v := c[key].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return append([]string(nil), v...)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	}
+	return nil
+}
+
+// DecodeConfig 把配置解码到结构体 out（指针），字段按 yaml 标签匹配键名；
+// 结构体中没有的键（包括 init_retries 等由管理器处理的键）被忽略，类型不符时返回错误
+func DecodeConfig(c ModuleConfig, out any) error {
+	data, err := yaml.Marshal(map[string]any(c))
+	if err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+	return nil
+}
+
+// 可选接口：集中声明配置默认值，管理器在 Init 前把用户配置覆盖在默认值之上（仅合并顶层键）
+type Defaulter interface {
+	Defaults() ModuleConfig
+}
+
+// WithDefaults 返回用户配置覆盖模块默认值后的配置，不修改 cfg；模块未实现 Defaulter 时原样返回
+func WithDefaults(m Module, cfg ModuleConfig) ModuleConfig {
+	d, ok := m.(Defaulter)
+	if !ok {
+		return cfg
+	}
+	merged := ModuleConfig{}
+	for k, v := range d.Defaults() {
+		merged[k] = v
+	}
+	for k, v := range cfg {
+		merged[k] = v
+	}
+	return merged
+}
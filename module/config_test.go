@@ -0,0 +1,97 @@
+package module
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetters(t *testing.T) {
+	cfg := ModuleConfig{
+		"name":      "order",
+		"count":     3,
+		"count64":   int64(4),
+		"json_num":  5.0,
+		"fraction":  1.5,
+		"num_str":   "6",
+		"flag":      true,
+		"flag_str":  "false",
+		"dur":       "500ms",
+		"dur_int":   2,
+		"dur_float": 0.5,
+		"bad_dur":   "soon",
+	}
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"string", cfg.GetString("name", "x"), "order"},
+		{"string missing", cfg.GetString("missing", "x"), "x"},
+		{"string wrong type", cfg.GetString("count", "x"), "x"},
+		{"int", cfg.GetInt("count", 0), 3},
+		{"int64", cfg.GetInt("count64", 0), 4},
+		{"int from JSON float", cfg.GetInt("json_num", 0), 5},
+		{"int from fraction", cfg.GetInt("fraction", 7), 7},
+		{"int from string", cfg.GetInt("num_str", 0), 6},
+		{"int missing", cfg.GetInt("missing", 7), 7},
+		{"bool", cfg.GetBool("flag", false), true},
+		{"bool from string", cfg.GetBool("flag_str", true), false},
+		{"bool wrong type", cfg.GetBool("name", true), true},
+		{"duration string", cfg.GetDuration("dur", 0), 500 * time.Millisecond},
+		{"duration seconds", cfg.GetDuration("dur_int", 0), 2 * time.Second},
+		{"duration fractional seconds", cfg.GetDuration("dur_float", 0), 500 * time.Millisecond},
+		{"duration invalid", cfg.GetDuration("bad_dur", time.Minute), time.Minute},
+		{"duration missing", cfg.GetDuration("missing", time.Minute), time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	type target struct {
+		DSN      string        `yaml:"dsn"`
+		Pool     int           `yaml:"pool"`
+		Timeout  time.Duration `yaml:"timeout"`
+		Replicas []string      `yaml:"replicas"`
+	}
+	tests := []struct {
+		name    string
+		cfg     ModuleConfig
+		want    target
+		wantErr string
+	}{
+		{"all fields", ModuleConfig{"dsn": "memory://x", "pool": 4, "timeout": "2s", "replicas": []any{"a", "b"}},
+			target{DSN: "memory://x", Pool: 4, Timeout: 2 * time.Second, Replicas: []string{"a", "b"}}, ""},
+		// 结构体中没有的键被忽略
+		{"extra keys ignored", ModuleConfig{"dsn": "memory://x", "init_retries": 3}, target{DSN: "memory://x"}, ""},
+		{"empty config", nil, target{}, ""},
+		{"int for string", ModuleConfig{"pool": "many"}, target{}, "decode config"},
+		{"map for string", ModuleConfig{"dsn": map[string]any{"host": "db"}}, target{}, "decode config"},
+		{"bad duration", ModuleConfig{"timeout": "soon"}, target{}, "decode config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got target
+			err := DecodeConfig(tt.cfg, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeConfig error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.DSN != tt.want.DSN || got.Pool != tt.want.Pool || got.Timeout != tt.want.Timeout || !slices.Equal(got.Replicas, tt.want.Replicas) {
+				t.Errorf("DecodeConfig = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
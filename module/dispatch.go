@@ -0,0 +1,48 @@
+package module
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理器在 Provide 阶段发布的进程内转发服务名，值为 Dispatcher
+const DispatcherService = "dispatcher"
+
+// Dispatcher 把当前请求的路径改写为 path，交给本次构建中接收该请求的监听的路由重新匹配；
+// 目标路由及其中间件（在途计数、依赖中间件等）照常执行
+type Dispatcher func(c *gin.Context, path string)
+
+// 单个请求最多被转发的次数，超过时返回 508，防止转发目标互相指向造成死循环
+const maxDispatchHops = 8
+
+type dispatchHopsKey struct{}
+
+// NewDispatcher 基于 handle（通常为 engine.HandleContext）创建 Dispatcher，由管理器调用
+func NewDispatcher(handle func(*gin.Context)) Dispatcher {
+	return func(c *gin.Context, path string) {
+		hops, _ := c.Request.Context().Value(dispatchHopsKey{}).(int)
+		if hops >= maxDispatchHops {
+			c.AbortWithStatusJSON(http.StatusLoopDetected, gin.H{"error": "internal dispatch loop"})
+			return
+		}
+		// 重新匹配会重置 gin.Context 的键值，把请求 ID 放回请求头以便沿用
+		if id := RequestID(c); id != "" {
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), dispatchHopsKey{}, hops+1))
+		c.Request.URL.Path = path
+		c.Request.URL.RawPath = ""
+		handle(c)
+	}
+}
+
+// InstancePrefix 返回模块实例路由的挂载前缀：别名实例 order@replica 为 /replica，普通实例为空
+func InstancePrefix(name string) string {
+	if _, suffix, ok := strings.Cut(name, "@"); ok {
+		return "/" + suffix
+	}
+	return ""
+}
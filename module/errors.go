@@ -0,0 +1,79 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 可配合 errors.Is 判断的错误类别
+var (
+	ErrUnknownModule   = errors.New("unknown module")
+	ErrDependencyCycle = errors.New("dependency cycle")
+	ErrModuleInit      = errors.New("module init failed")
+	ErrVersion         = errors.New("dependency version not satisfied")
+	ErrScope           = errors.New("unsupported module scope")
+)
+
+// 配置或依赖中引用了未注册的模块
+type UnknownModuleError struct {
+	Name string
+}
+
+func (e *UnknownModuleError) Error() string { return "unknown module: " + e.Name }
+
+func (e *UnknownModuleError) Is(target error) bool { return target == ErrUnknownModule }
+
+// 循环依赖，Path 首尾为同一模块，如 [a b a]
+type DependencyCycleError struct {
+	Path []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+func (e *DependencyCycleError) Is(target error) bool { return target == ErrDependencyCycle }
+
+// 模块 Init 失败（含重试后仍失败），Err 为最后一次的原因
+type ModuleInitError struct {
+	Module string
+	Err    error
+}
+
+func (e *ModuleInitError) Error() string {
+	return fmt.Sprintf("init module %s: %v", e.Module, e.Err)
+}
+
+func (e *ModuleInitError) Unwrap() error { return e.Err }
+
+func (e *ModuleInitError) Is(target error) bool { return target == ErrModuleInit }
+
+// 依赖的版本不满足约束，Version 为空表示对方未实现 Versioned
+type VersionConstraintError struct {
+	Module     string
+	Dep        string
+	Constraint string
+	Version    string
+}
+
+func (e *VersionConstraintError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("module %s requires %s%s, but %s declares no version", e.Module, e.Dep, e.Constraint, e.Dep)
+	}
+	return fmt.Sprintf("module %s requires %s%s, but %s is %s", e.Module, e.Dep, e.Constraint, e.Dep, e.Version)
+}
+
+func (e *VersionConstraintError) Is(target error) bool { return target == ErrVersion }
+
+// 模块声明了管理器尚不支持的作用域
+type UnsupportedScopeError struct {
+	Module string
+	Scope  Scope
+}
+
+func (e *UnsupportedScopeError) Error() string {
+	return fmt.Sprintf("module %s declares scope %s, only %s modules are supported", e.Module, e.Scope, Singleton)
+}
+
+func (e *UnsupportedScopeError) Is(target error) bool { return target == ErrScope }
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package module
+
+import "errors"
+
+// 非 Unix 平台不支持文件锁选举，实例始终不是 leader
+func tryLockFile(path string) (func(), error) {
+	return nil, errors.New("file lock leader election is not supported on this platform")
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package module
+
+import (
+	"os"
+	"syscall"
+)
+
+// 以非阻塞方式对 path 加排他锁（文件不存在时创建），成功时返回解锁函数
+func tryLockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
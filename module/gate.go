@@ -0,0 +1,41 @@
+package module
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// When 按功能开关注册路由：cond 为 true 时原样返回 r，否则返回丢弃所有注册的路由器，
+// 例如 module.When(m.cfg.GetBool("feature_x", false), r).GET("/x", handler)
+func When(cond bool, r gin.IRouter) gin.IRouter {
+	if cond {
+		return r
+	}
+	return skipRouter{}
+}
+
+// 不注册任何路由的 gin.IRouter
+type skipRouter struct{}
+
+func (s skipRouter) Use(...gin.HandlerFunc) gin.IRoutes { return s }
+
+// Group 返回的 RouterGroup 属于一个用完即弃的引擎，其中注册的路由同样不会生效
+func (skipRouter) Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	return gin.New().Group(relativePath, handlers...)
+}
+
+func (s skipRouter) Handle(string, string, ...gin.HandlerFunc) gin.IRoutes    { return s }
+func (s skipRouter) Any(string, ...gin.HandlerFunc) gin.IRoutes               { return s }
+func (s skipRouter) GET(string, ...gin.HandlerFunc) gin.IRoutes               { return s }
+func (s skipRouter) POST(string, ...gin.HandlerFunc) gin.IRoutes              { return s }
+func (s skipRouter) DELETE(string, ...gin.HandlerFunc) gin.IRoutes            { return s }
+func (s skipRouter) PATCH(string, ...gin.HandlerFunc) gin.IRoutes             { return s }
+func (s skipRouter) PUT(string, ...gin.HandlerFunc) gin.IRoutes               { return s }
+func (s skipRouter) OPTIONS(string, ...gin.HandlerFunc) gin.IRoutes           { return s }
+func (s skipRouter) HEAD(string, ...gin.HandlerFunc) gin.IRoutes              { return s }
+func (s skipRouter) Match([]string, string, ...gin.HandlerFunc) gin.IRoutes   { return s }
+func (s skipRouter) StaticFile(string, string) gin.IRoutes                    { return s }
+func (s skipRouter) StaticFileFS(string, string, http.FileSystem) gin.IRoutes { return s }
+func (s skipRouter) Static(string, string) gin.IRoutes                        { return s }
+func (s skipRouter) StaticFS(string, http.FileSystem) gin.IRoutes             { return s }
@@ -0,0 +1,50 @@
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWhen(t *testing.T) {
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	tests := []struct {
+		name     string
+		register func(r gin.IRouter)
+		method   string
+		path     string
+	}{
+		{"GET", func(r gin.IRouter) { r.GET("/x", ok) }, http.MethodGet, "/x"},
+		{"POST", func(r gin.IRouter) { r.POST("/x", ok) }, http.MethodPost, "/x"},
+		{"Any", func(r gin.IRouter) { r.Any("/x", ok) }, http.MethodPut, "/x"},
+		{"chained", func(r gin.IRouter) { r.Use().GET("/a", ok).GET("/b", ok) }, http.MethodGet, "/b"},
+		{"group", func(r gin.IRouter) { r.Group("/v2").GET("/x", ok) }, http.MethodGet, "/v2/x"},
+	}
+	for _, tt := range tests {
+		for _, enabled := range []bool{true, false} {
+			name := tt.name + "/disabled"
+			if enabled {
+				name = tt.name + "/enabled"
+			}
+			t.Run(name, func(t *testing.T) {
+				cfg := ModuleConfig{"feature_x": enabled}
+				r := gin.New()
+				tt.register(When(cfg.GetBool("feature_x", false), r))
+				if got := len(r.Routes()) > 0; got != enabled {
+					t.Errorf("routes = %v, want registered = %v", r.Routes(), enabled)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+				want := http.StatusNotFound
+				if enabled {
+					want = http.StatusOK
+				}
+				if w.Code != want {
+					t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPError 是处理器可返回的带状态码的错误，SafeHandler 以 Code 与 Message 写出错误信封；
+// Err 为内部原因，只记录在日志中
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewHTTPError 返回状态码为 code 的错误，message 为空时使用状态码的标准文本
+func NewHTTPError(code int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// SafeHandler 把返回 error 的处理器转为 gin 处理器：返回的错误链中有 *HTTPError 时按其状态码与消息响应，
+// 其他错误与 panic 返回 500（不暴露原因）；均以请求 ID 记录日志，响应为统一的错误信封
+// {"error":{"code":...,"message":"...","request_id":"..."}}。已写出响应时只记录日志
+func SafeHandler(fn func(*gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if p := recover(); p != nil {
+				fmt.Printf("Handler panicked [%s] %s %s: %v\n%s", RequestID(c), c.Request.Method, c.Request.URL.Path, p, debug.Stack())
+				abortWithError(c, http.StatusInternalServerError, "internal server error")
+			}
+		}()
+		err := fn(c)
+		if err == nil {
+			return
+		}
+		fmt.Printf("Handler error [%s] %s %s: %v\n", RequestID(c), c.Request.Method, c.Request.URL.Path, err)
+		var he *HTTPError
+		if errors.As(err, &he) {
+			abortWithError(c, he.Code, he.Message)
+			return
+		}
+		abortWithError(c, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func abortWithError(c *gin.Context, code int, message string) {
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(code, gin.H{"error": gin.H{"code": code, "message": message, "request_id": RequestID(c)}})
+}
@@ -0,0 +1,93 @@
+package module_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 执行 fn 期间把标准输出重定向到管道，返回写出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = prev }()
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestSafeHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(*gin.Context) error
+		code    int
+		message string // 为空表示不是错误信封
+		log     string // 日志中应包含的内容
+	}{
+		{"success", func(c *gin.Context) error { c.String(http.StatusOK, "ok"); return nil }, http.StatusOK, "", ""},
+		{"http error", func(c *gin.Context) error { return module.NewHTTPError(http.StatusNotFound, "order not found") },
+			http.StatusNotFound, "order not found", "Handler error [rid-1] GET /t: 404 order not found"},
+		{"default message", func(c *gin.Context) error { return module.NewHTTPError(http.StatusConflict, "") },
+			http.StatusConflict, "Conflict", "Handler error [rid-1]"},
+		{"wrapped http error", func(c *gin.Context) error {
+			return fmt.Errorf("load order: %w", &module.HTTPError{Code: http.StatusServiceUnavailable, Message: "order data unavailable", Err: errors.New("db down")})
+		}, http.StatusServiceUnavailable, "order data unavailable", "load order: 503 order data unavailable: db down"},
+		// 其他错误与 panic 不向客户端暴露原因
+		{"plain error", func(c *gin.Context) error { return errors.New("secret detail") },
+			http.StatusInternalServerError, "internal server error", "secret detail"},
+		{"panic", func(c *gin.Context) error { panic("boom") },
+			http.StatusInternalServerError, "internal server error", "Handler panicked [rid-1] GET /t: boom"},
+		{"error after response written", func(c *gin.Context) error {
+			c.String(http.StatusAccepted, "partial")
+			return module.NewHTTPError(http.StatusBadRequest, "")
+		}, http.StatusAccepted, "", "Handler error [rid-1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set(module.RequestIDKey, "rid-1") })
+			r.GET("/t", module.SafeHandler(tt.fn))
+			w := httptest.NewRecorder()
+			out := captureStdout(t, func() { r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/t", nil)) })
+			if w.Code != tt.code {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			if tt.message != "" {
+				var body struct {
+					Error struct {
+						Code      int    `json:"code"`
+						Message   string `json:"message"`
+						RequestID string `json:"request_id"`
+					} `json:"error"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("body %s: %v", w.Body, err)
+				}
+				if body.Error.Code != tt.code || body.Error.Message != tt.message || body.Error.RequestID != "rid-1" {
+					t.Errorf("error envelope = %+v, want code %d, message %q, request ID rid-1", body.Error, tt.code, tt.message)
+				}
+				if strings.Contains(w.Body.String(), "secret detail") || strings.Contains(w.Body.String(), "boom") {
+					t.Errorf("body %s exposes the internal error", w.Body)
+				}
+			}
+			if !strings.Contains(out, tt.log) {
+				t.Errorf("log = %q, want it to contain %q", out, tt.log)
+			}
+		})
+	}
+}
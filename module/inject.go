@@ -0,0 +1,21 @@
+package module
+
+// 可选接口：Init 成功后导出服务对象，键为服务名；管理器把它们注入到依赖本模块的模块中
+type ServiceExporter interface {
+	Services() map[string]any
+}
+
+// 可选接口：在 Init 之前接收所依赖模块（Deps 与已启用的 Optional）导出的服务。
+// 导出服务的依赖在重载中被重新创建时，管理器同时重新创建本模块，使其拿到新的服务对象
+type ServiceConsumer interface {
+	Inject(s Injected)
+}
+
+// Injected 为依赖模块名 -> 服务名 -> 服务对象
+type Injected map[string]map[string]any
+
+// Service 按类型读取依赖模块 module 导出的服务 name，不存在或类型不符时返回 false
+func Service[T any](s Injected, module, name string) (T, bool) {
+	t, ok := s[module][name].(T)
+	return t, ok
+}
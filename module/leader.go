@@ -0,0 +1,67 @@
+package module
+
+import (
+	"context"
+	"time"
+)
+
+// 可选接口：返回 true 的模块在多副本部署中只应在一个实例上运行（如定时任务）。
+// 管理器只在本实例为 leader 时启动它，失去 leadership 时按移除模块的方式关闭；依赖它的模块同样只能在 leader 上启用
+type ClusterSingleton interface {
+	SingletonAcrossCluster() bool
+}
+
+// IsClusterSingleton 返回模块是否只在 leader 上运行
+func IsClusterSingleton(m Module) bool {
+	s, ok := m.(ClusterSingleton)
+	return ok && s.SingletonAcrossCluster()
+}
+
+// LeaderElector 决定本实例是否为 leader。Run 参与选举直到 ctx 取消：确定初始状态后以该状态调用 onChange，
+// 之后每次 leadership 变化时再次调用；ctx 取消时释放 leadership 并返回
+type LeaderElector interface {
+	Run(ctx context.Context, onChange func(leader bool))
+}
+
+// AlwaysLeader 始终为 leader，适用于单实例部署
+type AlwaysLeader struct{}
+
+func (AlwaysLeader) Run(ctx context.Context, onChange func(leader bool)) {
+	onChange(true)
+	<-ctx.Done()
+}
+
+const defaultLockRetry = 5 * time.Second
+
+// FileLockElector 以文件锁选举：取得 Path 上排他锁的实例成为 leader 并一直持有到 ctx 取消（或进程退出，锁由内核释放），
+// 其余实例每 Retry（默认 5s）重试一次。只适用于同一主机或锁语义可靠的共享文件系统，NFS 等网络文件系统上不可依赖
+type FileLockElector struct {
+	Path  string
+	Retry time.Duration
+}
+
+func (e *FileLockElector) Run(ctx context.Context, onChange func(leader bool)) {
+	retry := e.Retry
+	if retry <= 0 {
+		retry = defaultLockRetry
+	}
+	reported := false
+	for {
+		unlock, err := tryLockFile(e.Path)
+		if err == nil {
+			onChange(true)
+			<-ctx.Done()
+			unlock()
+			return
+		}
+		if !reported {
+			onChange(false)
+			reported = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package module_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"myapp/module"
+)
+
+// 在后台运行 elector，返回接收 leadership 变化的 channel 与停止函数
+func runElector(e module.LeaderElector) (<-chan bool, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	states := make(chan bool, 4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx, func(leader bool) { states <- leader })
+	}()
+	return states, func() { cancel(); <-done }
+}
+
+func nextState(t *testing.T, states <-chan bool, want bool) {
+	t.Helper()
+	select {
+	case got := <-states:
+		if got != want {
+			t.Fatalf("leader = %v, want %v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no leadership change, want leader = %v", want)
+	}
+}
+
+func TestFileLockElector(t *testing.T) {
+	lock := filepath.Join(t.TempDir(), "leader.lock")
+	newElector := func() module.LeaderElector { return &module.FileLockElector{Path: lock, Retry: 20 * time.Millisecond} }
+
+	first, stopFirst := runElector(newElector())
+	defer stopFirst()
+	nextState(t, first, true)
+	second, stopSecond := runElector(newElector())
+	defer stopSecond()
+
+	tests := []struct {
+		name   string
+		action func()
+		states <-chan bool
+		want   bool
+	}{
+		{"second instance follows", func() {}, second, false},
+		// leader 退出释放锁后，重试中的实例接管
+		{"takeover after release", stopFirst, second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.action()
+			nextState(t, tt.states, tt.want)
+		})
+	}
+	select {
+	case leader := <-second:
+		t.Errorf("unexpected leadership change to %v", leader)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAlwaysLeader(t *testing.T) {
+	states, stop := runElector(module.AlwaysLeader{})
+	nextState(t, states, true)
+	stop()
+}
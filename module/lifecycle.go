@@ -0,0 +1,77 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// 模块实例的生命周期：Uninitialized -> Initialized -> Registered -> ShutDown
+type LifecycleState int
+
+const (
+	StateUninitialized LifecycleState = iota
+	StateInitialized
+	StateRegistered
+	StateShutDown
+)
+
+func (s LifecycleState) String() string {
+	switch s {
+	case StateUninitialized:
+		return "uninitialized"
+	case StateInitialized:
+		return "initialized"
+	case StateRegistered:
+		return "registered"
+	case StateShutDown:
+		return "shut down"
+	}
+	return fmt.Sprintf("LifecycleState(%d)", int(s))
+}
+
+var ErrLifecycle = errors.New("invalid module lifecycle transition")
+
+// 不允许的生命周期转换，如在 Init 之前 RegisterRoutes
+type LifecycleError struct {
+	Module   string
+	From, To LifecycleState
+}
+
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("module %s: cannot move from %s to %s", e.Module, e.From, e.To)
+}
+
+func (e *LifecycleError) Is(target error) bool { return target == ErrLifecycle }
+
+// Lifecycle 记录并校验模块实例的生命周期状态，可由管理器持有或嵌入到模块中；零值为 Uninitialized
+type Lifecycle struct {
+	mu    sync.Mutex
+	state LifecycleState
+}
+
+// 允许的转换；Registered -> Registered 对应重载时复用的实例在新路由上重新注册
+var lifecycleTransitions = map[LifecycleState][]LifecycleState{
+	StateUninitialized: {StateInitialized},
+	StateInitialized:   {StateRegistered, StateShutDown},
+	StateRegistered:    {StateRegistered, StateShutDown},
+}
+
+func (l *Lifecycle) State() LifecycleState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state
+}
+
+// Transition 切换到 to 状态，不合法时返回 *LifecycleError 且状态不变
+func (l *Lifecycle) Transition(name string, to LifecycleState) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, allowed := range lifecycleTransitions[l.state] {
+		if allowed == to {
+			l.state = to
+			return nil
+		}
+	}
+	return &LifecycleError{Module: name, From: l.state, To: to}
+}
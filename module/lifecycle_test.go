@@ -0,0 +1,51 @@
+package module_test
+
+import (
+	"errors"
+	"testing"
+
+	"myapp/module"
+)
+
+func TestLifecycleTransitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []module.LifecycleState // 从 Uninitialized 依次转换
+		want  module.LifecycleState   // 最后一步失败时保持的状态
+		err   string                  // 最后一步的错误，空表示全部合法
+	}{
+		{"full lifecycle", []module.LifecycleState{module.StateInitialized, module.StateRegistered, module.StateShutDown}, module.StateShutDown, ""},
+		{"re-register on reload", []module.LifecycleState{module.StateInitialized, module.StateRegistered, module.StateRegistered}, module.StateRegistered, ""},
+		{"shutdown before register", []module.LifecycleState{module.StateInitialized, module.StateShutDown}, module.StateShutDown, ""},
+		{"register before init", []module.LifecycleState{module.StateRegistered}, module.StateUninitialized, "module t: cannot move from uninitialized to registered"},
+		{"init twice", []module.LifecycleState{module.StateInitialized, module.StateInitialized}, module.StateInitialized, "module t: cannot move from initialized to initialized"},
+		{"register after shutdown", []module.LifecycleState{module.StateInitialized, module.StateShutDown, module.StateRegistered}, module.StateShutDown, "module t: cannot move from shut down to registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l module.Lifecycle
+			var err error
+			for i, to := range tt.steps {
+				err = l.Transition("t", to)
+				if err != nil && i < len(tt.steps)-1 {
+					t.Fatalf("step %d: %v", i, err)
+				}
+			}
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				if !errors.Is(err, module.ErrLifecycle) {
+					t.Errorf("err = %v, want ErrLifecycle", err)
+				}
+			}
+			if got := l.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
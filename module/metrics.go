@@ -0,0 +1,171 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// MetricRegistry 模块自定义指标的注册表，由管理器持有并跨重载保留，在 /metrics 中以 Prometheus 文本格式输出；
+// 模块通过 MetricsAware 得到各自的 Metrics。同名指标按 module 标签区分，不同模块注册同名指标不会冲突
+type MetricRegistry struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+	reserved map[string]bool
+}
+
+type metricFamily struct {
+	kind   string // counter | gauge
+	help   string
+	series map[string]*metricValue // 模块名 -> 值
+}
+
+// NewMetricRegistry 创建注册表，reserved 为管理器自身使用的指标名，模块不能注册
+func NewMetricRegistry(reserved ...string) *MetricRegistry {
+	r := &MetricRegistry{families: make(map[string]*metricFamily), reserved: make(map[string]bool)}
+	for _, name := range reserved {
+		r.reserved[name] = true
+	}
+	return r
+}
+
+// For 返回模块 name 的指标句柄，其注册的指标都带 module="<name>" 标签
+func (r *MetricRegistry) For(name string) *Metrics {
+	return &Metrics{registry: r, module: name}
+}
+
+// 同一模块重复注册同名同类型的指标时返回已有的值，重新创建的实例接着累计；
+// 名称无效、被保留或已被注册为其他类型时返回不导出的值并打印提示，模块照常使用而不会 panic
+func (r *MetricRegistry) register(module, name, help, kind string) *metricValue {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch f := r.families[name]; {
+	case !metricNamePattern.MatchString(name):
+		fmt.Printf("Module %s: invalid metric name %q, metric is not exported\n", module, name)
+	case r.reserved[name]:
+		fmt.Printf("Module %s: metric name %s is reserved, metric is not exported\n", module, name)
+	case f != nil && f.kind != kind:
+		fmt.Printf("Module %s: metric %s is already registered as a %s, %s is not exported\n", module, name, f.kind, kind)
+	default:
+		if f == nil {
+			f = &metricFamily{kind: kind, help: help, series: make(map[string]*metricValue)}
+			r.families[name] = f
+		}
+		v := f.series[module]
+		if v == nil {
+			v = &metricValue{}
+			f.series[module] = v
+		}
+		return v
+	}
+	return &metricValue{}
+}
+
+// Write 以 Prometheus 文本格式输出全部指标，按名称与模块排序；HELP 取首次注册时的说明
+func (r *MetricRegistry) Write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := r.families[name]
+		if f.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, helpEscaper.Replace(f.help))
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+		modules := make([]string, 0, len(f.series))
+		for m := range f.series {
+			modules = append(modules, m)
+		}
+		sort.Strings(modules)
+		for _, m := range modules {
+			fmt.Fprintf(w, "%s{module=\"%s\"} %s\n", name, labelEscaper.Replace(m), strconv.FormatFloat(f.series[m].load(), 'g', -1, 64))
+		}
+	}
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// Metrics 单个模块的指标句柄；nil 句柄上注册的指标可以正常使用，只是不导出
+type Metrics struct {
+	registry *MetricRegistry
+	module   string
+}
+
+// Counter 注册只增不减的计数器，名称建议以 _total 结尾
+func (m *Metrics) Counter(name, help string) *Counter {
+	if m == nil {
+		return &Counter{v: &metricValue{}}
+	}
+	return &Counter{v: m.registry.register(m.module, name, help, "counter")}
+}
+
+// Gauge 注册可任意设置的数值
+func (m *Metrics) Gauge(name, help string) *Gauge {
+	if m == nil {
+		return &Gauge{v: &metricValue{}}
+	}
+	return &Gauge{v: m.registry.register(m.module, name, help, "gauge")}
+}
+
+// Counter 计数器，可并发使用；nil 计数器上调用是安全的
+type Counter struct {
+	v *metricValue
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add 增加 n，n 为负数时忽略
+func (c *Counter) Add(n float64) {
+	if c != nil && n > 0 {
+		c.v.add(n)
+	}
+}
+
+// Gauge 数值指标，可并发使用；nil 上调用是安全的
+type Gauge struct {
+	v *metricValue
+}
+
+func (g *Gauge) Set(n float64) {
+	if g != nil {
+		g.v.bits.Store(math.Float64bits(n))
+	}
+}
+
+func (g *Gauge) Add(n float64) {
+	if g != nil {
+		g.v.add(n)
+	}
+}
+
+type metricValue struct {
+	bits atomic.Uint64
+}
+
+func (v *metricValue) add(n float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+n)) {
+			return
+		}
+	}
+}
+
+func (v *metricValue) load() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
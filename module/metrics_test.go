@@ -0,0 +1,75 @@
+package module_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"myapp/module"
+)
+
+func TestMetricRegistry(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(r *module.MetricRegistry)
+		want   []string // 输出中应出现的行
+		absent []string
+	}{
+		{"same name in two modules", func(r *module.MetricRegistry) {
+			r.For("order").Counter("jobs_total", "Jobs processed.").Inc()
+			r.For("user").Counter("jobs_total", "Jobs processed.").Add(2)
+		}, []string{
+			"# HELP jobs_total Jobs processed.",
+			"# TYPE jobs_total counter",
+			`jobs_total{module="order"} 1`,
+			`jobs_total{module="user"} 2`,
+		}, nil},
+		// 重新创建的实例再次注册，接着累计
+		{"re-registered by a recreated instance", func(r *module.MetricRegistry) {
+			r.For("order").Counter("jobs_total", "").Inc()
+			r.For("order").Counter("jobs_total", "").Inc()
+		}, []string{`jobs_total{module="order"} 2`}, nil},
+		{"gauge", func(r *module.MetricRegistry) {
+			g := r.For("cache").Gauge("items", "Cached items.")
+			g.Set(5)
+			g.Add(-2)
+		}, []string{"# TYPE items gauge", `items{module="cache"} 3`}, nil},
+		{"reserved name", func(r *module.MetricRegistry) {
+			r.For("order").Counter("http_requests_total", "").Inc()
+		}, nil, []string{"http_requests_total"}},
+		{"registered as another type", func(r *module.MetricRegistry) {
+			r.For("order").Counter("size", "").Inc()
+			r.For("user").Gauge("size", "").Set(9)
+		}, []string{"# TYPE size counter", `size{module="order"} 1`}, []string{`module="user"`}},
+		{"invalid name", func(r *module.MetricRegistry) {
+			r.For("order").Counter("bad-name", "").Inc()
+		}, nil, []string{"bad-name"}},
+		{"nil handle", func(r *module.MetricRegistry) {
+			var m *module.Metrics
+			m.Counter("jobs_total", "").Inc()
+			m.Gauge("items", "").Set(1)
+		}, nil, []string{"jobs_total", "items"}},
+		{"escaped module label", func(r *module.MetricRegistry) {
+			r.For(`a"b`).Counter("jobs_total", "").Inc()
+		}, []string{`jobs_total{module="a\"b"} 1`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := module.NewMetricRegistry("http_requests_total")
+			captureStdout(t, func() { tt.run(r) })
+			var b strings.Builder
+			r.Write(&b)
+			lines := strings.Split(b.String(), "\n")
+			for _, want := range tt.want {
+				if !slices.Contains(lines, want) {
+					t.Errorf("output is missing %s:\n%s", want, b.String())
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(b.String(), s) {
+					t.Errorf("output contains %s:\n%s", s, b.String())
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package module
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
 
 type ModuleConfig map[string]any
 
@@ -8,6 +13,101 @@ type ModuleConfig map[string]any
 type Module interface {
 	Deps() []string               // 模块依赖哪些其他模块
 	Init(cfg ModuleConfig) error  // 模块初始化
-	RegisterRoutes(r *gin.Engine) // 注册路由
-	Shutdown() error              // 模块销毁（释放资源）
+	RegisterRoutes(r gin.IRouter) // 注册路由
+	Shutdown() error              // 模块销毁（释放资源）；管理器保证每个实例至多调用一次
+}
+
+// 可选接口：在所有模块 Init 之前按依赖顺序调用，用于发布共享服务。
+// 重载时重新创建的 Provider 视为服务被替换，依赖它的 Provider / ServiceConsumer 一并重新创建
+type Provider interface {
+	Provide(reg *ServiceRegistry)
+}
+
+// 可选接口：在 Provide 之前接收本实例在配置中的名称（如 cache@hot），同一类型启用多个实例时据此区分发布的服务
+type NameAware interface {
+	SetName(name string)
+}
+
+// 可选接口：实现时管理器调用 InitContext 代替 Init；ctx 在 init_timeout 到期或本次重载被取消时结束，
+// 连接外部服务等可能阻塞的初始化应据此返回。ctx 在 InitContext 返回后即被取消，后台任务应通过 Workers 启动
+type ContextIniter interface {
+	InitContext(ctx context.Context, cfg ModuleConfig) error
+}
+
+// 可选接口：声明可选依赖；仅当对方也在配置中启用时才参与排序，不会被自动引入
+type OptionalDeps interface {
+	Optional() []string
+}
+
+// 可选接口：在依赖顺序允许的范围内，优先级高的模块先初始化（默认 0）
+type Prioritized interface {
+	Priority() int
+}
+
+// 可选接口：返回 false 的模块激活后不参与热重载，配置变化需重启进程才能生效
+type Reloadable interface {
+	Reloadable() bool
+}
+
+// IsReloadable 未实现 Reloadable 的模块默认支持热重载
+func IsReloadable(m Module) bool {
+	if r, ok := m.(Reloadable); ok {
+		return r.Reloadable()
+	}
+	return true
+}
+
+// 可选接口：提供的中间件会应用到依赖该模块（含启用的可选依赖）的模块路由上
+type MiddlewareProvider interface {
+	Middlewares() []gin.HandlerFunc
+}
+
+// 可选接口：健康检查，返回错误表示模块（或其依赖的外部服务）不健康，由 /healthz 汇总
+type HealthChecker interface {
+	Health() error
+}
+
+// 可选接口：预热（填充缓存、预先执行热点路径等），在本次重载的全部模块初始化并注册路由后、
+// /readyz 变为就绪之前并发调用，受模块配置 warmup_timeout 限制（默认 10s）；失败只打印警告
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// 可选接口：在 Init 之前接收本实例的消息总线客户端，用于与其他模块发布 / 订阅消息而无需直接引用
+type BusAware interface {
+	SetBus(c *BusClient)
+}
+
+// 可选接口：在 Init 之前接收本实例的指标句柄，注册的指标带 module 标签并在 /metrics 中导出；
+// 同名模块的新实例得到同一组指标，数值跨重载累计
+type MetricsAware interface {
+	SetMetrics(m *Metrics)
+}
+
+// 可选接口：上报运行时统计（请求数、错误数、自定义指标），由 /admin/stats 汇总
+type StatsReporter interface {
+	Stats() map[string]any
+}
+
+// 可选接口：在 Init 之前接收按模块 log_level 配置的子 logger（带 module 字段）
+type LoggerAware interface {
+	SetLogger(l *slog.Logger)
+}
+
+// 可选接口：以 JSON Schema（对象类型）描述模块接受的配置项，由 schema 子命令汇总
+type SchemaProvider interface {
+	ConfigSchema() map[string]any
+}
+
+// 可选接口：返回非空的原因表示模块已弃用，激活期间每次重载都会打印警告并在 /admin/modules 中展示
+type Deprecatable interface {
+	Deprecated() string
+}
+
+// DeprecationOf 返回模块的弃用原因，未弃用时为空
+func DeprecationOf(m Module) string {
+	if d, ok := m.(Deprecatable); ok {
+		return d.Deprecated()
+	}
+	return ""
 }
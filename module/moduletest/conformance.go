@@ -0,0 +1,287 @@
+// Package moduletest 为模块作者提供生命周期契约的一致性检查，可在模块自己的测试中调用：
+//
+//	func TestConformance(t *testing.T) { moduletest.RunConformance(t, mymodule.New) }
+//
+// 放在单独的包中，避免 module 包（以及最终的二进制）依赖 testing
+package moduletest
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// RunConformance 按管理器的调用方式检查 factory 创建的模块：
+// 每次调用返回新实例、Deps 合法、Init 接受空配置与完整配置且不修改传入的配置、
+// RegisterRoutes 不 panic、Health（如实现）可调用、Shutdown 成功且重复调用无害、Init 启动的 goroutine 在 Shutdown 后退出。
+// full 为可选的完整配置，未提供时按 Defaults() 与 ConfigSchema() 生成；
+// 配置项需要真实取值（如已存在的目录）的模块应显式传入
+func RunConformance(t *testing.T, factory func() module.Module, full ...module.ModuleConfig) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	t.Run("factory", func(t *testing.T) {
+		a, b := factory(), factory()
+		if a == nil {
+			t.Fatal("factory returned nil")
+		}
+		// 指向零大小类型的指针可能相同，无法据此判断
+		if v := reflect.ValueOf(a); v.Kind() == reflect.Pointer && v.Elem().Type().Size() > 0 && a == b {
+			t.Error("factory returned the same instance twice; each call must create a new module")
+		}
+	})
+
+	t.Run("deps", func(t *testing.T) {
+		checkDeps(t, factory())
+	})
+
+	cases := []struct {
+		name string
+		cfg  module.ModuleConfig
+	}{{"empty config", module.ModuleConfig{}}}
+	if len(full) == 0 {
+		full = []module.ModuleConfig{fullConfig(factory())}
+	}
+	for i, cfg := range full {
+		cases = append(cases, struct {
+			name string
+			cfg  module.ModuleConfig
+		}{fmt.Sprintf("full config #%d", i+1), cfg})
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := factory()
+			cfg := module.WithDefaults(m, c.cfg)
+			if missing := missingRequired(m, cfg); len(missing) > 0 {
+				t.Skipf("config lacks required keys %v", missing)
+			}
+			runLifecycle(t, m, cfg)
+		})
+	}
+
+	t.Run("goroutine leaks", func(t *testing.T) {
+		CheckGoroutineLeaks(t, factory, full[0])
+	})
+
+	// 同时存活的两个实例各自完成生命周期：捕获 Init 写入包级变量、重复注册全局资源等问题
+	t.Run("independent instances", func(t *testing.T) {
+		cfg := full[0]
+		a, b := factory(), factory()
+		aCfg, bCfg := module.WithDefaults(a, cfg), module.WithDefaults(b, cfg)
+		prepare(a)
+		prepare(b)
+		if err := call(t, "Init", func() error { return a.Init(aCfg) }); err != nil {
+			t.Fatalf("first instance Init: %v", err)
+		}
+		if err := call(t, "Init", func() error { return b.Init(bCfg) }); err != nil {
+			t.Fatalf("second instance Init failed while the first is alive: %v", err)
+		}
+		registerRoutes(t, a)
+		registerRoutes(t, b)
+		if err := call(t, "Shutdown", a.Shutdown); err != nil {
+			t.Errorf("first instance Shutdown: %v", err)
+		}
+		if err := call(t, "Shutdown", b.Shutdown); err != nil {
+			t.Errorf("second instance Shutdown: %v", err)
+		}
+	})
+}
+
+// 完整的一次生命周期：Provide / SetLogger → Init → RegisterRoutes → Health → Shutdown × 2
+func runLifecycle(t testing.TB, m module.Module, cfg module.ModuleConfig) {
+	t.Helper()
+	prepare(m)
+	before := deepCopy(cfg)
+	if err := call(t, "Init", func() error { return m.Init(cfg) }); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if !reflect.DeepEqual(map[string]any(cfg), before) {
+		t.Errorf("Init modified the config it was given (the manager shares it with /admin/config):\nbefore %v\nafter  %v", before, cfg)
+	}
+	registerRoutes(t, m)
+	if h, ok := module.CapabilityOf[module.HealthChecker](m, module.CapHealth); ok {
+		// 依赖的外部服务可能不可用，只要求不 panic
+		if err := call(t, "Health", h.Health); err != nil {
+			t.Logf("Health: %v", err)
+		}
+	}
+	if err := call(t, "Shutdown", m.Shutdown); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := call(t, "second Shutdown", m.Shutdown); err != nil {
+		t.Errorf("second Shutdown must be a no-op, got %v", err)
+	}
+}
+
+// 与管理器一致：Init 之前发布服务并注入 logger
+func prepare(m module.Module) {
+	if p, ok := m.(module.Provider); ok {
+		p.Provide(module.NewServiceRegistry())
+	}
+	if la, ok := m.(module.LoggerAware); ok {
+		la.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+}
+
+func registerRoutes(t testing.TB, m module.Module) {
+	t.Helper()
+	engine := gin.New()
+	call(t, "RegisterRoutes", func() error {
+		m.RegisterRoutes(engine.Group("/conformance"))
+		return nil
+	})
+}
+
+// 调用 f，把 panic 记为测试失败
+func call(t testing.TB, what string, f func() error) (err error) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("%s panicked: %v", what, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
+
+func checkDeps(t testing.TB, m module.Module) {
+	t.Helper()
+	seen := map[string]bool{}
+	for _, dep := range m.Deps() {
+		name, constraint := module.ParseDep(dep)
+		if name == "" {
+			t.Errorf("Deps() contains an empty module name: %q", dep)
+			continue
+		}
+		if seen[name] {
+			t.Errorf("Deps() lists %q more than once", name)
+		}
+		seen[name] = true
+		if constraint != "" {
+			if _, err := module.SatisfiesVersion("0.0.0", constraint); err != nil {
+				t.Errorf("Deps() entry %q has an invalid version constraint: %v", dep, err)
+			}
+		}
+	}
+	if v, ok := m.(module.Versioned); ok {
+		if _, err := module.CompareVersions(v.Version(), v.Version()); err != nil {
+			t.Errorf("Version(): %v", err)
+		}
+	}
+}
+
+// 由 Defaults() 与 ConfigSchema() 的 properties 生成每个配置项都有值的配置：
+// 优先取 schema 中的 default / examples，否则按类型取示例值
+func fullConfig(m module.Module) module.ModuleConfig {
+	cfg := module.ModuleConfig{}
+	if d, ok := m.(module.Defaulter); ok {
+		for k, v := range d.Defaults() {
+			cfg[k] = v
+		}
+	}
+	sp, ok := m.(module.SchemaProvider)
+	if !ok {
+		return cfg
+	}
+	props, _ := sp.ConfigSchema()["properties"].(map[string]any)
+	for key, p := range props {
+		if _, ok := cfg[key]; ok {
+			continue
+		}
+		prop, _ := p.(map[string]any)
+		if v, ok := prop["default"]; ok {
+			cfg[key] = v
+		} else if ex, ok := prop["examples"].([]any); ok && len(ex) > 0 {
+			cfg[key] = ex[0]
+		} else if v, ok := sampleValue(prop); ok {
+			cfg[key] = v
+		}
+	}
+	return cfg
+}
+
+func sampleValue(prop map[string]any) (any, bool) {
+	if enum, ok := prop["enum"].([]string); ok && len(enum) > 0 {
+		return enum[0], true
+	}
+	if enum, ok := prop["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0], true
+	}
+	typ := prop["type"]
+	if types, ok := typ.([]string); ok && len(types) > 0 {
+		typ = types[0]
+	}
+	switch typ {
+	case "string":
+		return "conformance", true
+	case "integer", "number":
+		if min, ok := prop["minimum"]; ok {
+			return min, true
+		}
+		return 1, true
+	case "boolean":
+		return true, true
+	case "array":
+		return []any{}, true
+	case "object":
+		return map[string]any{}, true
+	}
+	return nil, false
+}
+
+// schema 中 required 但配置里缺少的键
+func missingRequired(m module.Module, cfg module.ModuleConfig) []string {
+	sp, ok := m.(module.SchemaProvider)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	switch req := sp.ConfigSchema()["required"].(type) {
+	case []string:
+		for _, k := range req {
+			if _, ok := cfg[k]; !ok {
+				missing = append(missing, k)
+			}
+		}
+	case []any:
+		for _, k := range req {
+			if s, _ := k.(string); s != "" {
+				if _, ok := cfg[s]; !ok {
+					missing = append(missing, s)
+				}
+			}
+		}
+	}
+	return missing
+}
+
+func deepCopy(v map[string]any) map[string]any {
+	out := make(map[string]any, len(v))
+	for k, val := range v {
+		out[k] = copyValue(val)
+	}
+	return out
+}
+
+func copyValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return deepCopy(val)
+	case module.ModuleConfig:
+		return module.ModuleConfig(deepCopy(val))
+	case []any:
+		out := make([]any, len(val))
+		for i, e := range val {
+			out[i] = copyValue(e)
+		}
+		return out
+	case []string:
+		return append([]string(nil), val...)
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package moduletest
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 记录失败而不让外层测试失败，用于断言检查本身能发现问题；Fatalf 通过 runtime.Goexit 结束所在的 goroutine
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+func (r *recorder) Logf(format string, args ...any) {}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// 在新 goroutine 中运行 f，Fatalf 只结束该 goroutine
+func record(t *testing.T, f func(tb testing.TB)) []string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.errs
+}
+
+type sampleModule struct {
+	module.Base
+	initErr   error
+	mutate    bool
+	panics    bool
+	shutdowns int
+	strict    bool // 第二次 Shutdown 返回错误
+}
+
+func (m *sampleModule) Init(cfg module.ModuleConfig) error {
+	if m.mutate {
+		cfg["injected"] = true
+	}
+	return m.initErr
+}
+
+func (m *sampleModule) RegisterRoutes(r gin.IRouter) {
+	if m.panics {
+		panic("route conflict")
+	}
+}
+
+func (m *sampleModule) Shutdown() error {
+	m.shutdowns++
+	if m.strict && m.shutdowns > 1 {
+		return errors.New("already closed")
+	}
+	return nil
+}
+
+func TestRunLifecycle(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  *sampleModule
+		want string // 期望的错误片段，空表示通过
+	}{
+		{"well behaved", &sampleModule{}, ""},
+		{"init mutates config", &sampleModule{mutate: true}, "Init modified the config"},
+		{"init fails", &sampleModule{initErr: errors.New("boom")}, "Init: boom"},
+		{"routes panic", &sampleModule{panics: true}, "RegisterRoutes panicked"},
+		{"second shutdown fails", &sampleModule{strict: true}, "second Shutdown must be a no-op"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := record(t, func(tb testing.TB) {
+				runLifecycle(tb, tt.mod, module.ModuleConfig{"key": "value"})
+			})
+			got := strings.Join(errs, "\n")
+			if tt.want == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected failures:\n%s", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("failures %q do not mention %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDeps(t *testing.T) {
+	tests := []struct {
+		deps []string
+		want string
+	}{
+		{[]string{"auth>=1.0.0", "cache"}, ""},
+		{[]string{"cache", "cache"}, `lists "cache" more than once`},
+		{[]string{""}, "empty module name"},
+		{[]string{"auth>=x.y"}, "invalid version constraint"},
+	}
+	for _, tt := range tests {
+		errs := record(t, func(tb testing.TB) { checkDeps(tb, depsModule(tt.deps)) })
+		got := strings.Join(errs, "\n")
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("deps %q: failures %q, want %q", tt.deps, got, tt.want)
+		}
+	}
+}
+
+type depsModule []string
+
+func (d depsModule) Deps() []string                     { return d }
+func (d depsModule) Init(cfg module.ModuleConfig) error { return nil }
+func (d depsModule) RegisterRoutes(r gin.IRouter)       {}
+func (d depsModule) Shutdown() error                    { return nil }
+
+func TestFullConfig(t *testing.T) {
+	cfg := fullConfig(schemaModule{})
+	want := module.ModuleConfig{"dsn": "memory://", "mode": "fast", "workers": 2, "debug": true, "name": "conformance"}
+	for k, v := range want {
+		if cfg[k] != v {
+			t.Errorf("%s = %v, want %v", k, cfg[k], v)
+		}
+	}
+	if missing := missingRequired(schemaModule{}, module.ModuleConfig{}); len(missing) != 1 || missing[0] != "dsn" {
+		t.Errorf("missingRequired = %v, want [dsn]", missing)
+	}
+}
+
+type schemaModule struct{ depsModule }
+
+func (schemaModule) Defaults() module.ModuleConfig { return module.ModuleConfig{"dsn": "memory://"} }
+
+func (schemaModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"dsn"},
+		"properties": map[string]any{
+			"dsn":     map[string]any{"type": "string"},
+			"mode":    map[string]any{"type": "string", "enum": []string{"fast", "safe"}},
+			"workers": map[string]any{"type": "integer", "minimum": 2},
+			"debug":   map[string]any{"type": "boolean"},
+			"name":    map[string]any{"type": "string"},
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package moduletest
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/module"
+)
+
+// 关闭后等待模块的 goroutine 退出的最长时间
+var leakWait = 2 * time.Second
+
+// CheckGoroutineLeaks 对 factory 创建的模块执行一次 Init → RegisterRoutes → Shutdown，
+// 关闭后 goroutine 数量在 leakWait 内未回到 Init 之前的水平时报告失败，并列出多出的 goroutine 调用栈。
+// 统计的是整个进程的 goroutine，调用它的测试不应与其他测试并行；RunConformance 已包含这项检查
+func CheckGoroutineLeaks(t testing.TB, factory func() module.Module, cfg module.ModuleConfig) {
+	t.Helper()
+	m := factory()
+	cfg = module.WithDefaults(m, cfg)
+	if missing := missingRequired(m, cfg); len(missing) > 0 {
+		t.Skipf("config lacks required keys %v", missing)
+	}
+	before := goroutines()
+	prepare(m)
+	if err := call(t, "Init", func() error { return m.Init(cfg) }); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	registerRoutes(t, m)
+	if err := call(t, "Shutdown", m.Shutdown); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+
+	deadline := time.Now().Add(leakWait)
+	var leaked []string
+	for {
+		leaked = leaked[:0]
+		for id, stack := range goroutines() {
+			if _, ok := before[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(leaked) > 0 {
+		t.Errorf("%d goroutine(s) started during Init are still running after Shutdown:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	}
+}
+
+// 当前全部 goroutine 的调用栈，键为 "goroutine N" 首行中的 ID
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	out := map[string]string{}
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		header, _, _ := strings.Cut(g, "\n")
+		fields := strings.Fields(header)
+		if len(fields) >= 2 && fields[0] == "goroutine" {
+			out[fields[1]] = g
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package moduletest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/module"
+)
+
+// Init 启动一个后台 goroutine；leaky 为 true 时 Shutdown 不通知它退出
+type workerModule struct {
+	module.Base
+	leaky bool
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func (m *workerModule) Init(cfg module.ModuleConfig) error {
+	stop, done := make(chan struct{}), make(chan struct{})
+	m.stop, m.done = stop, done
+	go func() {
+		defer close(done)
+		<-stop
+	}()
+	return nil
+}
+
+func (m *workerModule) Shutdown() error {
+	if m.leaky || m.stop == nil {
+		return nil
+	}
+	close(m.stop)
+	m.stop = nil
+	<-m.done
+	return nil
+}
+
+func TestCheckGoroutineLeaks(t *testing.T) {
+	defer func(d time.Duration) { leakWait = d }(leakWait)
+	leakWait = 100 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		leaky bool
+	}{
+		{"clean module", false},
+		{"leaky module", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var leaked *workerModule
+			errs := record(t, func(tb testing.TB) {
+				CheckGoroutineLeaks(tb, func() module.Module {
+					leaked = &workerModule{leaky: tt.leaky}
+					return leaked
+				}, module.ModuleConfig{})
+			})
+			if tt.leaky {
+				// 结束泄漏的 goroutine，避免影响后续用例的计数
+				defer close(leaked.stop)
+			}
+			got := strings.Join(errs, "\n")
+			if !tt.leaky {
+				if got != "" {
+					t.Errorf("clean module reported: %s", got)
+				}
+				return
+			}
+			if !strings.Contains(got, "1 goroutine(s) started during Init are still running after Shutdown") {
+				t.Fatalf("leak not reported, failures: %q", got)
+			}
+			if !strings.Contains(got, "workerModule).Init") {
+				t.Errorf("report lacks the leaked goroutine's stack:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package module
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Pool 限制同时运行的 goroutine 数量，供计算密集型模块约束自身并发
+type Pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// NewPool 创建最多同时运行 n 个任务的池，n <= 0 时取 GOMAXPROCS
+func NewPool(n int) *Pool {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &Pool{sem: make(chan struct{}, n)}
+}
+
+// PoolFromConfig 按模块配置中的 max_procs 创建池，未配置时取 GOMAXPROCS
+func PoolFromConfig(cfg ModuleConfig) *Pool {
+	return NewPool(cfg.GetInt("max_procs", 0))
+}
+
+// Size 返回池的并发上限
+func (p *Pool) Size() int {
+	return cap(p.sem)
+}
+
+// Go 在有空闲名额时启动 f，否则阻塞等待
+func (p *Pool) Go(f func()) {
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		f()
+	}()
+}
+
+// Wait 等待所有已提交的任务结束
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolFromConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ModuleConfig
+		want int
+	}{
+		{"max_procs 1", ModuleConfig{"max_procs": 1}, 1},
+		{"max_procs 3", ModuleConfig{"max_procs": 3}, 3},
+		{"unset uses GOMAXPROCS", ModuleConfig{}, runtime.GOMAXPROCS(0)},
+		{"zero uses GOMAXPROCS", ModuleConfig{"max_procs": 0}, runtime.GOMAXPROCS(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PoolFromConfig(tt.cfg)
+			if p.Size() != tt.want {
+				t.Fatalf("Size = %d, want %d", p.Size(), tt.want)
+			}
+			// 提交远多于上限的任务，同时运行的数量不超过上限，且能达到上限
+			var running, peak, done atomic.Int64
+			const tasks = 32
+			for i := 0; i < tasks; i++ {
+				p.Go(func() {
+					n := running.Add(1)
+					for {
+						old := peak.Load()
+						if n <= old || peak.CompareAndSwap(old, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					running.Add(-1)
+					done.Add(1)
+				})
+			}
+			p.Wait()
+			if done.Load() != tasks {
+				t.Errorf("%d tasks finished after Wait, want %d", done.Load(), tasks)
+			}
+			if got := peak.Load(); got > int64(tt.want) || (tt.want <= tasks && got != int64(tt.want)) {
+				t.Errorf("peak concurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
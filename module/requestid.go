@@ -0,0 +1,14 @@
+package module
+
+import "github.com/gin-gonic/gin"
+
+// 请求 ID 在 gin.Context 中的键与响应头名，由根路由的中间件设置
+const (
+	RequestIDKey    = "request_id"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// RequestID 返回当前请求的 ID（来自 X-Request-ID 请求头或自动生成），用于日志关联
+func RequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
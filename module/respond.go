@@ -0,0 +1,21 @@
+package module
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// Respond 以 200 写出 data，格式按请求的 Accept 头在 JSON 与 MessagePack 之间协商，默认 JSON
+func Respond(c *gin.Context, data any) {
+	RespondStatus(c, 200, data)
+}
+
+// RespondStatus 同 Respond，使用指定的状态码
+func RespondStatus(c *gin.Context, code int, data any) {
+	switch c.NegotiateFormat(gin.MIMEJSON, "application/msgpack", "application/x-msgpack") {
+	case "application/msgpack", "application/x-msgpack":
+		c.Render(code, render.MsgPack{Data: data})
+	default:
+		c.JSON(code, data)
+	}
+}
@@ -0,0 +1,18 @@
+package module
+
+import "github.com/gin-gonic/gin"
+
+// 认证中间件把当前用户的角色保存在 gin.Context 的该键下，供路由访问控制（模块配置 acl）读取
+const RolesKey = "roles"
+
+// SetRoles 记录当前请求已认证用户的角色
+func SetRoles(c *gin.Context, roles []string) {
+	c.Set(RolesKey, roles)
+}
+
+// Roles 返回当前请求已认证用户的角色；未经认证时为空
+func Roles(c *gin.Context) []string {
+	roles, _ := c.Get(RolesKey)
+	r, _ := roles.([]string)
+	return r
+}
@@ -0,0 +1,82 @@
+package module
+
+import "github.com/gin-gonic/gin"
+
+// RouteDoc 描述一个路由，由管理器汇总为 /admin/openapi.json 中的 OpenAPI 3 文档
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string // 缺省为模块名
+	Params      []ParamDoc
+	Response    map[string]any // 200 响应体的 JSON Schema，可为空
+}
+
+// ParamDoc 描述一个参数；In 为 path / query / header
+type ParamDoc struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      map[string]any // 缺省为 {"type": "string"}
+}
+
+// 可选接口：管理器传给 RegisterRoutes 的路由器实现该接口以收集路由文档
+type RouteDocumenter interface {
+	DocumentRoute(method, relativePath string, doc RouteDoc)
+}
+
+// RouteBuilder 注册路由并附带文档：
+//
+//	module.Route(r, http.MethodGet, "/order").Summary("查询订单").Query("id", "订单号").Handle(h)
+//
+// r 不收集文档时（如测试中直接传入的 gin 引擎）只注册路由
+type RouteBuilder struct {
+	r      gin.IRouter
+	method string
+	path   string
+	doc    RouteDoc
+}
+
+func Route(r gin.IRouter, method, relativePath string) *RouteBuilder {
+	return &RouteBuilder{r: r, method: method, path: relativePath}
+}
+
+func (b *RouteBuilder) Summary(s string) *RouteBuilder {
+	b.doc.Summary = s
+	return b
+}
+
+func (b *RouteBuilder) Description(s string) *RouteBuilder {
+	b.doc.Description = s
+	return b
+}
+
+func (b *RouteBuilder) Tags(tags ...string) *RouteBuilder {
+	b.doc.Tags = append(b.doc.Tags, tags...)
+	return b
+}
+
+func (b *RouteBuilder) Param(p ParamDoc) *RouteBuilder {
+	b.doc.Params = append(b.doc.Params, p)
+	return b
+}
+
+// Query 添加一个可选的字符串查询参数
+func (b *RouteBuilder) Query(name, description string) *RouteBuilder {
+	return b.Param(ParamDoc{Name: name, In: "query", Description: description})
+}
+
+// Response 设置 200 响应体的 JSON Schema
+func (b *RouteBuilder) Response(schema map[string]any) *RouteBuilder {
+	b.doc.Response = schema
+	return b
+}
+
+// Handle 注册路由并登记文档
+func (b *RouteBuilder) Handle(handlers ...gin.HandlerFunc) gin.IRoutes {
+	routes := b.r.Handle(b.method, b.path, handlers...)
+	if d, ok := b.r.(RouteDocumenter); ok {
+		d.DocumentRoute(b.method, b.path, b.doc)
+	}
+	return routes
+}
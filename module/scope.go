@@ -0,0 +1,46 @@
+package module
+
+import "fmt"
+
+// Scope 模块实例的作用域
+type Scope int
+
+const (
+	// Singleton 每个模块实例名只有一个实例，由全部请求共享，重载时按配置复用或替换（默认）
+	Singleton Scope = iota
+	// PerRequest 每个请求创建一个实例；尚不支持
+	PerRequest
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Singleton:
+		return "singleton"
+	case PerRequest:
+		return "per-request"
+	default:
+		return fmt.Sprintf("Scope(%d)", int(s))
+	}
+}
+
+// 可选接口：声明实例作用域，未实现时为 Singleton。目前只支持 Singleton，
+// 声明其他作用域的模块在注册校验与重载时被拒绝；单例模块的处理器并发执行，共享状态须自行同步
+type Scoped interface {
+	Scope() Scope
+}
+
+// ScopeOf 返回模块声明的作用域
+func ScopeOf(m Module) Scope {
+	if s, ok := m.(Scoped); ok {
+		return s.Scope()
+	}
+	return Singleton
+}
+
+// CheckScope 在模块声明了尚不支持的作用域时返回 *UnsupportedScopeError
+func CheckScope(name string, m Module) error {
+	if s := ScopeOf(m); s != Singleton {
+		return &UnsupportedScopeError{Module: name, Scope: s}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package module
+
+import "sync"
+
+// 服务注册表：模块在 Provide 阶段发布共享服务，依赖方在 Init 中读取
+type ServiceRegistry struct {
+	mu       sync.RWMutex
+	services map[string]any
+}
+
+func NewServiceRegistry() *ServiceRegistry {
+	return &ServiceRegistry{services: make(map[string]any)}
+}
+
+// Provide 以 name 发布服务，重复发布会覆盖
+func (r *ServiceRegistry) Provide(name string, svc any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services[name] = svc
+}
+
+func (r *ServiceRegistry) Get(name string) (any, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	svc, ok := r.services[name]
+	return svc, ok
+}
+
+// Lookup 按类型读取服务，不存在或类型不符时返回 false
+func Lookup[T any](r *ServiceRegistry, name string) (T, bool) {
+	var zero T
+	if r == nil {
+		return zero, false
+	}
+	svc, ok := r.Get(name)
+	if !ok {
+		return zero, false
+	}
+	t, ok := svc.(T)
+	return t, ok
+}
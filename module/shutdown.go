@@ -0,0 +1,27 @@
+package module
+
+import (
+	"context"
+	"sync"
+)
+
+// ShutdownOnce 可嵌入模块结构体，使 Shutdown 在被多次调用时只执行一次清理逻辑，之后返回首次的结果。
+// 管理器本身保证每个实例至多调用一次 Shutdown；直接持有模块实例的代码（测试、自定义入口）可借此避免重复关闭：
+//
+//	func (m *X) Shutdown() error { return m.Once(func() error { close(m.done); return nil }) }
+type ShutdownOnce struct {
+	once sync.Once
+	err  error
+}
+
+// Once 首次调用时执行 f 并记录其返回值
+func (s *ShutdownOnce) Once(f func() error) error {
+	s.once.Do(func() { s.err = f() })
+	return s.err
+}
+
+// 可选接口：需要感知关闭时限的模块实现 ShutdownContext，管理器以它代替 Shutdown 调用。
+// ctx 的截止时间为本模块在共享关闭预算（server.module_shutdown_budget）中可用的时间，到期后管理器不再等待
+type ContextShutdowner interface {
+	ShutdownContext(ctx context.Context) error
+}
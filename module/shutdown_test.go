@@ -0,0 +1,50 @@
+package module_test
+
+import (
+	"errors"
+	"testing"
+
+	"myapp/module"
+)
+
+// 关闭 done 的模块，重复关闭 channel 会 panic
+type closingModule struct {
+	module.Base
+	module.ShutdownOnce
+	done  chan struct{}
+	runs  int
+	fails error
+}
+
+func (m *closingModule) Shutdown() error {
+	return m.Once(func() error {
+		m.runs++
+		close(m.done)
+		return m.fails
+	})
+}
+
+func TestShutdownOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls int
+		err   error
+	}{
+		{"single call", 1, nil},
+		{"repeated calls", 3, nil},
+		{"first error returned every time", 2, errors.New("flush failed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &closingModule{done: make(chan struct{}), fails: tt.err}
+			for i := 0; i < tt.calls; i++ {
+				if err := m.Shutdown(); err != tt.err {
+					t.Errorf("call %d: err = %v, want %v", i+1, err, tt.err)
+				}
+			}
+			if m.runs != 1 {
+				t.Errorf("cleanup ran %d times, want 1", m.runs)
+			}
+		})
+	}
+}
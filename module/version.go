@@ -0,0 +1,149 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 可选接口：声明模块版本（semver），供依赖方用 "name>=1.2.0" 形式约束
+type Versioned interface {
+	Version() string
+}
+
+// Deps() 中的约束运算符，长的在前以便前缀匹配
+var constraintOps = []string{">=", "<=", "==", ">", "<", "="}
+
+// ParseDep 把 "auth>=1.2.0" 拆成模块名 "auth" 和约束 ">=1.2.0"，无约束时 constraint 为空
+func ParseDep(dep string) (name, constraint string) {
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		return strings.TrimSpace(dep[:i]), strings.TrimSpace(dep[i:])
+	}
+	return strings.TrimSpace(dep), ""
+}
+
+// DepName 返回依赖声明中的模块名
+func DepName(dep string) string {
+	name, _ := ParseDep(dep)
+	return name
+}
+
+// SatisfiesVersion 判断 version 是否满足约束，如 ">=1.2.0"
+func SatisfiesVersion(version, constraint string) (bool, error) {
+	op := "="
+	for _, o := range constraintOps {
+		if strings.HasPrefix(constraint, o) {
+			op = o
+			constraint = strings.TrimSpace(constraint[len(o):])
+			break
+		}
+	}
+	cmp, err := CompareVersions(version, constraint)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case ">=":
+		return cmp >= 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<":
+		return cmp < 0, nil
+	default:
+		return cmp == 0, nil
+	}
+}
+
+// CompareVersions 按 semver 比较两个版本，返回 -1 / 0 / 1；预发布版本低于对应正式版
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < 3; i++ {
+		if va.nums[i] != vb.nums[i] {
+			if va.nums[i] < vb.nums[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return comparePre(va.pre, vb.pre), nil
+}
+
+type semver struct {
+	nums [3]int
+	pre  []string
+}
+
+// 支持 "v" 前缀，缺省的 minor/patch 视为 0，忽略 "+build" 元数据
+func parseSemver(s string) (semver, error) {
+	var v semver
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || s == "" {
+		return v, fmt.Errorf("invalid version %q", raw)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", raw)
+		}
+		v.nums[i] = n
+	}
+	return v, nil
+}
+
+func comparePre(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case errA == nil: // 数字标识低于字母标识
+			return -1
+		case errB == nil:
+			return 1
+		case a[i] < b[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
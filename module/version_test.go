@@ -0,0 +1,48 @@
+package module
+
+import "testing"
+
+func TestParseDep(t *testing.T) {
+	tests := []struct {
+		dep, name, constraint string
+	}{
+		{"auth", "auth", ""},
+		{"auth>=1.2.0", "auth", ">=1.2.0"},
+		{"auth >= 1.2.0", "auth", ">= 1.2.0"},
+		{"cache<2", "cache", "<2"},
+		{"order@replica==1.0.0", "order@replica", "==1.0.0"},
+	}
+	for _, tt := range tests {
+		if name, constraint := ParseDep(tt.dep); name != tt.name || constraint != tt.constraint {
+			t.Errorf("ParseDep(%q) = %q, %q, want %q, %q", tt.dep, name, constraint, tt.name, tt.constraint)
+		}
+	}
+}
+
+func TestSatisfiesVersion(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+		wantErr             bool
+	}{
+		{"1.2.0", ">=1.2.0", true, false},
+		{"1.10.0", ">=1.2.0", true, false},
+		{"1.1.9", ">=1.2.0", false, false},
+		{"2.0.0", "<2.0.0", false, false},
+		{"1.9.9", "<2.0.0", true, false},
+		{"1.2.0", "1.2.0", true, false},
+		{"1.2.0", "==1.2", true, false},
+		{"v1.3.0", ">1.2.0", true, false},
+		{"1.2.0-rc.1", ">=1.2.0", false, false},
+		{"1.2.0-rc.2", ">1.2.0-rc.1", true, false},
+		{"1.2.0", "<=1.2.0", true, false},
+		{"one", ">=1.0.0", false, true},
+		{"1.0.0", ">=x", false, true},
+	}
+	for _, tt := range tests {
+		got, err := SatisfiesVersion(tt.version, tt.constraint)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("SatisfiesVersion(%q, %q) = %v, %v, want %v (err %v)", tt.version, tt.constraint, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
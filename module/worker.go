@@ -0,0 +1,18 @@
+package module
+
+import "context"
+
+// Worker 是模块的后台任务：Start 应阻塞运行直到 ctx 被取消，返回错误会停止该模块的其他 worker
+type Worker interface {
+	Start(ctx context.Context) error
+}
+
+// WorkerFunc 把普通函数适配为 Worker
+type WorkerFunc func(ctx context.Context) error
+
+func (f WorkerFunc) Start(ctx context.Context) error { return f(ctx) }
+
+// 可选接口：声明后台任务，由管理器在 Init 成功后启动，在 Shutdown 之前取消并等待其退出
+type WorkerProvider interface {
+	Workers() []Worker
+}
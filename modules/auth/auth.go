@@ -2,26 +2,134 @@ package auth
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"myapp/module"
 )
 
-type AuthModule struct{}
+// 校验 Authorization: Bearer <JWT>；通过 Middlewares() 保护依赖本模块的模块（如 order），
+// 校验通过后声明保存在 gin.Context 中，用 auth.Claims(c) 读取，其中的角色供模块配置 acl 使用。未配置密钥时不做校验
+type AuthModule struct {
+	verifier *verifier
+}
+
+// gin.Context 中保存令牌声明的键
+const ClaimsKey = "auth.claims"
+
+// 导出给依赖方的令牌校验服务名，依赖方通过 module.ServiceConsumer 取得 TokenVerifier
+const TokenVerifierService = "TokenVerifier"
+
+// TokenVerifier 校验 JWT 并返回其声明，供需要在中间件之外校验令牌的模块使用（如处理回调中的令牌）
+type TokenVerifier interface {
+	Verify(token string) (map[string]any, error)
+}
 
 func (m *AuthModule) Deps() []string { return nil }
 
+func (m *AuthModule) Version() string { return "1.0.0" }
+
+func (m *AuthModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"properties": map[string]any{
+			"algorithm":  map[string]any{"type": "string", "enum": []string{"HS256", "RS256"}},
+			"secret":     map[string]any{"type": "string", "description": "HS256 签名密钥"},
+			"public_key": map[string]any{"type": "string", "description": "RS256 公钥（PEM），可用 ${file:...} 引用"},
+			"leeway":     map[string]any{"type": "string", "description": "校验 exp / nbf 时允许的时钟偏差"},
+		},
+	}
+}
+
 func (m *AuthModule) Init(cfg module.ModuleConfig) error {
-	fmt.Println("[auth] Init")
+	alg := cfg.GetString("algorithm", "HS256")
+	secret := cfg.GetString("secret", "")
+	publicKey := cfg.GetString("public_key", "")
+	m.verifier = nil
+	// ${file:...} / ${VAR} 无法展开（文件不存在、变量未设置且无默认值）时配置中保留原文，
+	// 不能把这段可猜测的文本当作签名密钥
+	for _, kv := range [][2]string{{"secret", secret}, {"public_key", publicKey}} {
+		if strings.Contains(kv[1], "${") {
+			return fmt.Errorf("auth: %s contains an unexpanded reference; check that the referenced file or environment variable exists", kv[0])
+		}
+	}
+	if secret == "" && publicKey == "" {
+		fmt.Println("[auth] Init without signing key, authentication disabled")
+		return nil
+	}
+	v, err := newVerifier(alg, secret, publicKey, cfg.GetDuration("leeway", 0))
+	if err != nil {
+		return err
+	}
+	m.verifier = v
+	fmt.Println("[auth] Init with", alg)
 	return nil
 }
 
-func (m *AuthModule) RegisterRoutes(r *gin.Engine) {
+// 配置了密钥时导出 TokenVerifier；未启用认证时不导出，依赖方据此判断
+func (m *AuthModule) Services() map[string]any {
+	if m.verifier == nil {
+		return nil
+	}
+	return map[string]any{TokenVerifierService: m.verifier}
+}
+
+func (m *AuthModule) RegisterRoutes(r gin.IRouter) {
 	r.GET("/auth", func(c *gin.Context) {
-		c.JSON(200, gin.H{"msg": "Hello from auth module"})
+		module.Respond(c, gin.H{"msg": "Hello from auth module"})
 	})
 }
 
+func (m *AuthModule) Middlewares() []gin.HandlerFunc {
+	return []gin.HandlerFunc{m.authenticate}
+}
+
+func (m *AuthModule) authenticate(c *gin.Context) {
+	if m.verifier == nil {
+		c.Next()
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+		return
+	}
+	claims, err := m.verifier.verify(token, time.Now())
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token: " + err.Error()})
+		return
+	}
+	c.Set(ClaimsKey, claims)
+	module.SetRoles(c, claimRoles(claims))
+	c.Next()
+}
+
+// 令牌中的角色：roles 声明（字符串数组）或 role 声明（单个字符串）
+func claimRoles(claims map[string]any) []string {
+	var roles []string
+	if list, ok := claims["roles"].([]any); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	if r, ok := claims["role"].(string); ok && r != "" {
+		roles = append(roles, r)
+	}
+	return roles
+}
+
+// Claims 返回 auth 中间件校验通过后保存的令牌声明；未经校验的请求返回 nil
+func Claims(c *gin.Context) map[string]any {
+	claims, _ := c.Get(ClaimsKey)
+	m, _ := claims.(map[string]any)
+	return m
+}
+
 func (m *AuthModule) Shutdown() error {
 	fmt.Println("[auth] Shutdown")
 	return nil
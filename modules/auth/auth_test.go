@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"myapp/module"
+	"myapp/module/moduletest"
+	"myapp/utils"
+)
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New,
+		module.ModuleConfig{"algorithm": "HS256", "secret": "s3cret", "leeway": "30s"},
+	)
+}
+
+func TestInitRejectsUnexpandedKeys(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "jwt_secret")
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		wantErr string
+	}{
+		{"missing secret file", map[string]any{"secret": "${file:" + missing + "}"}, "secret contains an unexpanded reference"},
+		{"unset env var", map[string]any{"secret": "${T_JWT_SECRET_UNSET}"}, "secret contains an unexpanded reference"},
+		{"missing public key file", map[string]any{"algorithm": "RS256", "public_key": "${file:" + missing + "}"}, "public_key contains an unexpanded reference"},
+		{"expanded secret", map[string]any{"secret": "${T_JWT_SECRET_UNSET:fallback}"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 与配置加载相同：先展开引用，无法展开的保留原文
+			expanded, err := utils.ExpandConfig(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = New().Init(module.ModuleConfig(expanded.(map[string]any)))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Init: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
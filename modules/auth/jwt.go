@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 令牌校验器：只接受配置的算法，防止 alg=none 或用公钥充当 HMAC 密钥的降级攻击
+type verifier struct {
+	alg    string
+	secret []byte
+	key    *rsa.PublicKey
+	leeway time.Duration
+}
+
+func newVerifier(alg, secret, publicKey string, leeway time.Duration) (*verifier, error) {
+	v := &verifier{alg: alg, leeway: leeway}
+	switch alg {
+	case "HS256":
+		if secret == "" {
+			return nil, errors.New("auth: HS256 requires secret")
+		}
+		v.secret = []byte(secret)
+	case "RS256":
+		key, err := parseRSAPublicKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("auth: public_key: %w", err)
+		}
+		v.key = key
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q (HS256 or RS256)", alg)
+	}
+	return v, nil
+}
+
+// 接受 PKIX（BEGIN PUBLIC KEY）与 PKCS#1（BEGIN RSA PUBLIC KEY）格式
+func parseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return key, nil
+}
+
+// Verify 按当前时间校验令牌，实现 TokenVerifier
+func (v *verifier) Verify(token string) (map[string]any, error) {
+	return v.verify(token, time.Now())
+}
+
+func (v *verifier) verify(token string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if header.Alg != v.alg {
+		return nil, fmt.Errorf("unexpected algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	signed := parts[0] + "." + parts[1]
+	sum := sha256.Sum256([]byte(signed))
+	switch v.alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errors.New("invalid signature")
+		}
+	case "RS256":
+		if err := rsa.VerifyPKCS1v15(v.key, crypto.SHA256, sum[:], sig); err != nil {
+			return nil, errors.New("invalid signature")
+		}
+	}
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not yet valid")
+	}
+	return claims, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
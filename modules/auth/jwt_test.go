@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func segment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hsToken(alg string, claims map[string]any, secret string) string {
+	signed := segment(map[string]string{"alg": alg, "typ": "JWT"}) + "." + segment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHS256(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v, err := newVerifier("HS256", "s3cret", "", 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", hsToken("HS256", map[string]any{"sub": "u1", "exp": now.Unix() + 60}, "s3cret"), ""},
+		{"expired within leeway", hsToken("HS256", map[string]any{"sub": "u1", "exp": now.Unix() - 10}, "s3cret"), ""},
+		{"expired", hsToken("HS256", map[string]any{"exp": now.Unix() - 60}, "s3cret"), "token expired"},
+		{"not yet valid", hsToken("HS256", map[string]any{"nbf": now.Unix() + 60}, "s3cret"), "token not yet valid"},
+		{"wrong secret", hsToken("HS256", map[string]any{"sub": "u1"}, "other"), "invalid signature"},
+		{"alg none rejected", segment(map[string]string{"alg": "none"}) + "." + segment(map[string]any{"sub": "u1"}) + ".", `unexpected algorithm "none"`},
+		{"malformed", "abc.def", "malformed token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.verify(tt.token, now)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims["sub"] != "u1" {
+				t.Errorf("sub = %v, want u1", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pkix := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	pkcs1 := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)}))
+
+	signed := segment(map[string]string{"alg": "RS256"}) + "." + segment(map[string]any{"sub": "u1"})
+	sum := sha256.Sum256([]byte(signed))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	for name, pub := range map[string]string{"PKIX": pkix, "PKCS1": pkcs1} {
+		t.Run(name, func(t *testing.T) {
+			v, err := newVerifier("RS256", "", pub, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := v.Verify(token); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			// 用公钥充当 HMAC 密钥伪造的令牌必须被拒绝
+			if _, err := v.Verify(hsToken("HS256", map[string]any{"sub": "u1"}, pub)); err == nil {
+				t.Error("HS256 token accepted by an RS256 verifier")
+			}
+		})
+	}
+}
+
+func TestNewVerifierRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name, alg, secret, key string
+	}{
+		{"HS256 without secret", "HS256", "", ""},
+		{"RS256 without key", "RS256", "", ""},
+		{"RS256 with garbage key", "RS256", "", "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"},
+		{"unsupported algorithm", "ES256", "x", ""},
+	}
+	for _, tt := range tests {
+		if _, err := newVerifier(tt.alg, tt.secret, tt.key, 0); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package balance
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 虚拟模块：不提供自己的业务路由，把挂载前缀下的请求按权重轮询分配给同类型的多个实例。
+// 须以别名启用（如 balance@orders，挂载在 /orders 下），配置 targets 为实例名 -> 权重：
+//
+//	balance@orders:
+//	  targets: {order: 3, order@replica: 1}
+//
+// /orders/order 会被转发到 order 的 /order 或 order@replica 的 /replica/order
+type BalanceModule struct {
+	services *module.ServiceRegistry
+	dispatch module.Dispatcher
+
+	mu      sync.Mutex
+	targets []*target
+	total   int
+}
+
+type target struct {
+	name    string
+	prefix  string
+	weight  int
+	current int // 平滑加权轮询的当前权重
+	served  int64
+}
+
+func (m *BalanceModule) Deps() []string { return nil }
+
+func (m *BalanceModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"targets"},
+		"properties": map[string]any{
+			"targets": map[string]any{
+				"type":                 "object",
+				"description":          "实例名 -> 权重（正整数），实例须同时在 modules 中启用",
+				"additionalProperties": map[string]any{"type": "integer", "minimum": 1},
+			},
+		},
+	}
+}
+
+func (m *BalanceModule) Provide(reg *module.ServiceRegistry) {
+	m.services = reg
+}
+
+func (m *BalanceModule) Init(cfg module.ModuleConfig) error {
+	dispatch, ok := module.Lookup[module.Dispatcher](m.services, module.DispatcherService)
+	if !ok {
+		return fmt.Errorf("balance: dispatcher service not available")
+	}
+	m.dispatch = dispatch
+	raw, _ := cfg["targets"].(map[string]any)
+	if len(raw) == 0 {
+		return fmt.Errorf("balance: targets must list at least one instance")
+	}
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m.targets, m.total = nil, 0
+	for _, name := range names {
+		w := module.ModuleConfig(raw).GetInt(name, 0)
+		if w < 1 {
+			return fmt.Errorf("balance: weight of %q must be a positive integer", name)
+		}
+		m.targets = append(m.targets, &target{name: name, prefix: module.InstancePrefix(name), weight: w})
+		m.total += w
+	}
+	fmt.Println("[balance] Init with targets", names)
+	return nil
+}
+
+func (m *BalanceModule) RegisterRoutes(r gin.IRouter) {
+	r.Any("/*path", func(c *gin.Context) {
+		t := m.next()
+		m.dispatch(c, t.prefix+c.Param("path"))
+	})
+}
+
+// 平滑加权轮询（与 nginx 相同）：权重 3:1 时依次选中 a a b a，而不是 a a a b
+func (m *BalanceModule) next() *target {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best *target
+	for _, t := range m.targets {
+		t.current += t.weight
+		if best == nil || t.current > best.current {
+			best = t
+		}
+	}
+	best.current -= m.total
+	best.served++
+	return best
+}
+
+func (m *BalanceModule) Stats() map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	served := make(map[string]int64, len(m.targets))
+	for _, t := range m.targets {
+		served[t.name] = t.served
+	}
+	return map[string]any{"served": served}
+}
+
+func (m *BalanceModule) Shutdown() error {
+	fmt.Println("[balance] Shutdown")
+	return nil
+}
+
+func New() module.Module {
+	return &BalanceModule{}
+}
@@ -0,0 +1,99 @@
+package balance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 创建已 Init 的模块，dispatch 记录每次转发的目标路径
+func newBalance(t *testing.T, targets map[string]any) (*BalanceModule, *[]string, error) {
+	t.Helper()
+	var dispatched []string
+	reg := module.NewServiceRegistry()
+	reg.Provide(module.DispatcherService, module.Dispatcher(func(c *gin.Context, path string) {
+		dispatched = append(dispatched, path)
+		c.Status(http.StatusNoContent)
+	}))
+	m := New().(*BalanceModule)
+	m.Provide(reg)
+	return m, &dispatched, m.Init(module.ModuleConfig{"targets": targets})
+}
+
+func TestSmoothWeightedRoundRobin(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets map[string]any
+		want    []string
+	}{
+		{"3:1", map[string]any{"a": 3, "b": 1}, []string{"a", "a", "b", "a"}},
+		{"equal", map[string]any{"a": 1, "b": 1}, []string{"a", "b", "a", "b"}},
+		{"5:1:1 like nginx", map[string]any{"a": 5, "b": 1, "c": 1}, []string{"a", "a", "b", "a", "c", "a", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _, err := newBalance(t, tt.targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for range tt.want {
+				got = append(got, m.next().name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("picks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatchToInstancePrefix(t *testing.T) {
+	m, dispatched, err := newBalance(t, map[string]any{"order": 1, "order@replica": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	m.RegisterRoutes(engine.Group("/orders"))
+	for i := 0; i < 2; i++ {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/order?id=1", nil))
+	}
+	if want := []string{"/order", "/replica/order"}; !slices.Equal(*dispatched, want) {
+		t.Errorf("dispatched = %v, want %v", *dispatched, want)
+	}
+	served := m.Stats()["served"].(map[string]int64)
+	if served["order"] != 1 || served["order@replica"] != 1 {
+		t.Errorf("served = %v", served)
+	}
+}
+
+func TestInitRejectsInvalidTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets map[string]any
+		wantErr string
+	}{
+		{"no targets", nil, "at least one instance"},
+		{"zero weight", map[string]any{"a": 0}, `weight of "a"`},
+		{"non-numeric weight", map[string]any{"a": "heavy"}, `weight of "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := newBalance(t, tt.targets)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := New().Init(module.ModuleConfig{"targets": map[string]any{"a": 1}}); err == nil {
+		t.Error("Init without a dispatcher service succeeded")
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// Store 是带过期时间的内存缓存，通过 ServiceRegistry 以模块实例名（默认 "cache"，别名实例如 "cache@hot"）共享给其他模块
+type Store struct {
+	mu    sync.RWMutex
+	items map[string]item
+	ttl   time.Duration
+}
+
+type item struct {
+	value   any
+	expires time.Time
+}
+
+func NewStore() *Store {
+	return &Store{items: make(map[string]item), ttl: 5 * time.Minute}
+}
+
+func (s *Store) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	it, ok := s.items[key]
+	if !ok || time.Now().After(it.expires) {
+		return nil, false
+	}
+	return it.value, true
+}
+
+func (s *Store) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = item{value: value, expires: time.Now().Add(s.ttl)}
+}
+
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
+
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
+
+func (s *Store) setTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// 删除已过期的条目
+func (s *Store) evictExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, it := range s.items {
+		if now.After(it.expires) {
+			delete(s.items, k)
+		}
+	}
+}
+
+func (s *Store) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]item)
+}
+
+type CacheModule struct {
+	name  string
+	store *Store
+}
+
+func (m *CacheModule) Deps() []string { return nil }
+
+func (m *CacheModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"properties": map[string]any{
+			"ttl": map[string]any{"type": []string{"string", "number"}, "description": "缓存过期时间，默认 5m"},
+		},
+	}
+}
+
+func (m *CacheModule) SetName(name string) {
+	m.name = name
+}
+
+func (m *CacheModule) Provide(reg *module.ServiceRegistry) {
+	reg.Provide(m.name, m.store)
+}
+
+func (m *CacheModule) Init(cfg module.ModuleConfig) error {
+	ttl := cfg.GetDuration("ttl", 5*time.Minute)
+	m.store.setTTL(ttl)
+	fmt.Println("[cache] Init with ttl =", ttl)
+	return nil
+}
+
+// 按 ttl 周期清理过期条目，避免只写不读的键一直占用内存
+func (m *CacheModule) Workers() []module.Worker {
+	return []module.Worker{module.WorkerFunc(func(ctx context.Context) error {
+		m.store.mu.RLock()
+		interval := m.store.ttl
+		m.store.mu.RUnlock()
+		ticker := time.NewTicker(max(interval, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case now := <-ticker.C:
+				m.store.evictExpired(now)
+			}
+		}
+	})}
+}
+
+func (m *CacheModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/cache", func(c *gin.Context) {
+		module.Respond(c, gin.H{"items": m.store.Len()})
+	})
+}
+
+func (m *CacheModule) Shutdown() error {
+	m.store.clear()
+	fmt.Println("[cache] Shutdown")
+	return nil
+}
+
+func New() module.Module {
+	return &CacheModule{name: "cache", store: NewStore()}
+}
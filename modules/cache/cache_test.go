@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"myapp/module"
+	"myapp/module/moduletest"
+)
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New, module.ModuleConfig{"ttl": "1m"})
+}
+
+func TestStoreExpiry(t *testing.T) {
+	s := NewStore()
+	s.setTTL(time.Hour)
+	s.Set("live", 1)
+	s.setTTL(-time.Second)
+	s.Set("stale", 2)
+
+	tests := []struct {
+		key    string
+		want   any
+		wantOK bool
+	}{
+		{"live", 1, true},
+		{"stale", nil, false},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		if v, ok := s.Get(tt.key); v != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%q) = %v, %v; want %v, %v", tt.key, v, ok, tt.want, tt.wantOK)
+		}
+	}
+	// 过期条目在清理前仍占用空间
+	if n := s.Len(); n != 2 {
+		t.Errorf("Len before eviction = %d, want 2", n)
+	}
+	s.evictExpired(time.Now())
+	if n := s.Len(); n != 1 {
+		t.Errorf("Len after eviction = %d, want 1", n)
+	}
+	s.Delete("live")
+	if _, ok := s.Get("live"); ok {
+		t.Error("Get after Delete found the key")
+	}
+}
+
+func TestInitTTL(t *testing.T) {
+	tests := []struct {
+		cfg  module.ModuleConfig
+		want time.Duration
+	}{
+		{module.ModuleConfig{}, 5 * time.Minute},
+		{module.ModuleConfig{"ttl": "30s"}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		m := New().(*CacheModule)
+		if err := m.Init(tt.cfg); err != nil {
+			t.Fatal(err)
+		}
+		if m.store.ttl != tt.want {
+			t.Errorf("Init(%v): ttl = %s, want %s", tt.cfg, m.store.ttl, tt.want)
+		}
+	}
+}
+
+func TestProvideUsesInstanceName(t *testing.T) {
+	tests := []struct{ name string }{{"cache"}, {"cache@hot"}}
+	reg := module.NewServiceRegistry()
+	stores := map[string]*Store{}
+	for _, tt := range tests {
+		m := New().(*CacheModule)
+		m.SetName(tt.name)
+		m.Provide(reg)
+		stores[tt.name] = m.store
+	}
+	for _, tt := range tests {
+		if got, ok := module.Lookup[*Store](reg, tt.name); !ok || got != stores[tt.name] {
+			t.Errorf("Lookup(%q) = %p, want the store of instance %s", tt.name, got, tt.name)
+		}
+	}
+}
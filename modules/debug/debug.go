@@ -0,0 +1,41 @@
+package debug
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 调试模块：输出运行时信息，通常只在 dev profile 中启用
+type DebugModule struct{}
+
+func (m *DebugModule) Deps() []string { return nil }
+
+func (m *DebugModule) Init(cfg module.ModuleConfig) error {
+	fmt.Println("[debug] Init")
+	return nil
+}
+
+func (m *DebugModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/debug/info", func(c *gin.Context) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		module.Respond(c, gin.H{
+			"go_version": runtime.Version(),
+			"goroutines": runtime.NumGoroutine(),
+			"heap_alloc": mem.HeapAlloc,
+			"num_gc":     mem.NumGC,
+		})
+	})
+}
+
+func (m *DebugModule) Shutdown() error {
+	fmt.Println("[debug] Shutdown")
+	return nil
+}
+
+func New() module.Module {
+	return &DebugModule{}
+}
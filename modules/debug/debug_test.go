@@ -0,0 +1,11 @@
+package debug
+
+import (
+	"testing"
+
+	"myapp/module/moduletest"
+)
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New)
+}
@@ -2,31 +2,120 @@ package order
 
 import (
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"myapp/module"
+	"myapp/modules/auth"
+	"myapp/modules/cache"
 )
 
+// OrderConfig 是 order 模块的配置，由 module.DecodeConfig 从配置块解码
+type OrderConfig struct {
+	DSN string `yaml:"dsn"`
+}
+
 type OrderModule struct {
-	dsn string
+	cfg      OrderConfig
+	services *module.ServiceRegistry
+	cache    *cache.Store
+	tokens   auth.TokenVerifier
+	served   atomic.Int64
+	misses   *module.Counter
+	log      *slog.Logger
+}
+
+func (m *OrderModule) Deps() []string { return []string{"auth>=1.0.0"} }
+
+// 启用 cache / ratelimit 模块时在其之后初始化，并应用 ratelimit 的限流中间件
+func (m *OrderModule) Optional() []string { return []string{"cache", "ratelimit"} }
+
+func (m *OrderModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"dsn"},
+		"properties": map[string]any{
+			"dsn": map[string]any{"type": "string", "description": "订单数据库连接串"},
+		},
+	}
+}
+
+// 记录服务注册表，在 Init 中读取 cache 模块发布的存储
+func (m *OrderModule) Provide(reg *module.ServiceRegistry) {
+	m.services = reg
 }
 
-func (m *OrderModule) Deps() []string { return []string{"auth"} }
+// 由管理器在 Init 之前注入 auth 导出的令牌校验服务，auth 未配置密钥时为 nil
+func (m *OrderModule) Inject(s module.Injected) {
+	m.tokens, _ = module.Service[auth.TokenVerifier](s, "auth", auth.TokenVerifierService)
+}
+
+func (m *OrderModule) SetLogger(l *slog.Logger) {
+	m.log = l
+}
+
+func (m *OrderModule) SetMetrics(metrics *module.Metrics) {
+	m.misses = metrics.Counter("cache_misses_total", "Order lookups not served from the shared cache.")
+}
+
+func (m *OrderModule) Defaults() module.ModuleConfig {
+	return module.ModuleConfig{"dsn": "memory://default"}
+}
 
 func (m *OrderModule) Init(cfg module.ModuleConfig) error {
-	if dsn, ok := cfg["dsn"].(string); ok {
-		m.dsn = dsn
-	} else {
-		m.dsn = "memory://default"
+	if err := module.DecodeConfig(cfg, &m.cfg); err != nil {
+		return err
+	}
+	if store, ok := module.Lookup[*cache.Store](m.services, "cache"); ok {
+		m.cache = store
+		m.log.Debug("using shared cache")
 	}
-	fmt.Println("[order] Init with DSN =", m.dsn)
+	m.log.Info("init", "dsn", m.cfg.DSN, "token_verification", m.tokens != nil)
 	return nil
 }
 
-func (m *OrderModule) RegisterRoutes(r *gin.Engine) {
-	r.GET("/order", func(c *gin.Context) {
-		c.JSON(200, gin.H{"msg": "Order module using DSN: " + m.dsn})
-	})
+func (m *OrderModule) RegisterRoutes(r gin.IRouter) {
+	module.Route(r, http.MethodGet, "/order").
+		Summary("查询订单").
+		Response(map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"msg": map[string]any{"type": "string"}},
+		}).
+		Handle(module.SafeHandler(func(c *gin.Context) error {
+			msg, err := m.message()
+			if err != nil {
+				return err
+			}
+			m.served.Add(1)
+			module.Respond(c, gin.H{"msg": msg})
+			return nil
+		}))
+}
+
+func (m *OrderModule) Stats() map[string]any {
+	return map[string]any{"orders_served": m.served.Load()}
+}
+
+// 模拟一次数据查询，有共享缓存时优先读缓存；缓存由多个模块共用，值类型不符时返回 503
+func (m *OrderModule) message() (string, error) {
+	key := "order:msg:" + m.cfg.DSN
+	if m.cache != nil {
+		if v, ok := m.cache.Get(key); ok {
+			m.log.Debug("cache hit", "key", key)
+			msg, ok := v.(string)
+			if !ok {
+				return "", &module.HTTPError{Code: http.StatusServiceUnavailable, Message: "order data unavailable", Err: fmt.Errorf("cache key %s holds %T", key, v)}
+			}
+			return msg, nil
+		}
+	}
+	msg := "Order module using DSN: " + m.cfg.DSN
+	if m.cache != nil {
+		m.misses.Inc()
+		m.cache.Set(key, msg)
+	}
+	return msg, nil
 }
 
 func (m *OrderModule) Shutdown() error {
@@ -35,5 +124,5 @@ func (m *OrderModule) Shutdown() error {
 }
 
 func New() module.Module {
-	return &OrderModule{}
+	return &OrderModule{log: slog.Default()}
 }
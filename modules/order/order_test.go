@@ -0,0 +1,74 @@
+package order
+
+import (
+	"strings"
+	"testing"
+
+	"myapp/module"
+	"myapp/module/moduletest"
+	"myapp/modules/auth"
+)
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New, module.ModuleConfig{"dsn": "memory://conformance"})
+}
+
+func TestInitDecodesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     module.ModuleConfig
+		wantDSN string
+		wantErr bool
+	}{
+		{"dsn", module.ModuleConfig{"dsn": "memory://orders"}, "memory://orders", false},
+		{"defaults", (&OrderModule{}).Defaults(), "memory://default", false},
+		{"dsn of wrong type", module.ModuleConfig{"dsn": []any{"a", "b"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New().(*OrderModule)
+			err := m.Init(tt.cfg)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "decode config") {
+					t.Fatalf("Init error = %v, want a decode error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.cfg.DSN != tt.wantDSN {
+				t.Errorf("DSN = %q, want %q", m.cfg.DSN, tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestInjectAuthVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		authCfg  module.ModuleConfig
+		injected bool
+	}{
+		{"auth with secret", module.ModuleConfig{"secret": "s3cret"}, true},
+		// 未配置密钥时 auth 不导出服务
+		{"auth without key", module.ModuleConfig{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := auth.New()
+			if err := a.Init(tt.authCfg); err != nil {
+				t.Fatal(err)
+			}
+			services := a.(module.ServiceExporter).Services()
+			m := New().(*OrderModule)
+			m.Inject(module.Injected{"auth": services})
+			if got := m.tokens != nil; got != tt.injected {
+				t.Fatalf("token verifier injected = %v, want %v", got, tt.injected)
+			}
+			if tt.injected && m.tokens != services[auth.TokenVerifierService] {
+				t.Error("order received a different object than auth exported")
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 反向代理模块：按配置把路径前缀转发到外部服务
+//
+//	retries: 2              # 连接失败时的重试次数（仅 GET / HEAD / OPTIONS / PUT / DELETE 且请求体可重放），默认 0
+//	retry_backoff: 100ms    # 首次重试前的等待，之后每次翻倍（带随机抖动，最多 2s），不超过请求的时限；默认 100ms
+//	breaker_threshold: 5    # 连续失败（连接错误或 5xx）次数达到后熔断，默认 0 不熔断
+//	breaker_cooldown: 30s   # 熔断持续时间，期间返回 503；到期后只放行一个试探请求，失败则再次熔断
+//	routes:
+//	  - prefix: /github
+//	    upstream: https://api.github.com
+//	    health_path: /zen   # 可选，/healthz 检查上游时请求的路径，默认 /
+//	    retries: 0          # 可选，覆盖模块级的 retries / retry_backoff / breaker_* 配置
+type ProxyModule struct {
+	module.ShutdownOnce
+	routes    []*route
+	transport *http.Transport
+}
+
+type route struct {
+	prefix     string
+	upstream   *url.URL
+	healthPath string
+	proxy      *httputil.ReverseProxy
+}
+
+const healthTimeout = 2 * time.Second
+
+func (m *ProxyModule) Deps() []string { return nil }
+
+func (m *ProxyModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"routes"},
+		"properties": map[string]any{
+			"retries":           map[string]any{"type": "integer", "minimum": 0},
+			"retry_backoff":     map[string]any{"type": []string{"string", "number"}},
+			"breaker_threshold": map[string]any{"type": "integer", "minimum": 0},
+			"breaker_cooldown":  map[string]any{"type": []string{"string", "number"}},
+			"routes": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":     "object",
+					"required": []string{"prefix", "upstream"},
+					"properties": map[string]any{
+						"prefix":            map[string]any{"type": "string"},
+						"upstream":          map[string]any{"type": "string", "format": "uri"},
+						"health_path":       map[string]any{"type": "string"},
+						"retries":           map[string]any{"type": "integer", "minimum": 0},
+						"retry_backoff":     map[string]any{"type": []string{"string", "number"}},
+						"breaker_threshold": map[string]any{"type": "integer", "minimum": 0},
+						"breaker_cooldown":  map[string]any{"type": []string{"string", "number"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (m *ProxyModule) Init(cfg module.ModuleConfig) error {
+	m.transport = http.DefaultTransport.(*http.Transport).Clone()
+	list, _ := cfg["routes"].([]any)
+	for i, item := range list {
+		entry, _ := item.(map[string]any)
+		rc := module.ModuleConfig(entry)
+		prefix := "/" + strings.Trim(rc.GetString("prefix", ""), "/")
+		raw := rc.GetString("upstream", "")
+		if prefix == "/" || raw == "" {
+			return fmt.Errorf("proxy: routes[%d] requires prefix and upstream", i)
+		}
+		target, err := url.Parse(raw)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return fmt.Errorf("proxy: routes[%d] invalid upstream %q", i, raw)
+		}
+		rt := m.newRoute(prefix, target, rc.GetString("health_path", "/"))
+		rt.proxy.Transport = &resilientTransport{
+			next:    m.transport,
+			retries: rc.GetInt("retries", cfg.GetInt("retries", 0)),
+			backoff: module.Backoff{
+				Base:   rc.GetDuration("retry_backoff", cfg.GetDuration("retry_backoff", 100*time.Millisecond)),
+				Max:    maxRetryBackoff,
+				Jitter: true,
+			},
+			breaker: newBreaker(
+				rc.GetInt("breaker_threshold", cfg.GetInt("breaker_threshold", 0)),
+				rc.GetDuration("breaker_cooldown", cfg.GetDuration("breaker_cooldown", 30*time.Second)),
+			),
+		}
+		m.routes = append(m.routes, rt)
+	}
+	fmt.Println("[proxy] Init with", len(m.routes), "routes")
+	return nil
+}
+
+// 转发路由通配部分匹配到的路径（见 RegisterRoutes），如 /github/users -> https://api.github.com/users
+func (m *ProxyModule) newRoute(prefix string, target *url.URL, healthPath string) *route {
+	p := httputil.NewSingleHostReverseProxy(target)
+	p.Transport = m.transport
+	director := p.Director
+	p.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+	}
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(err, errCircuitOpen) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Println("[proxy] Upstream error:", target, err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return &route{prefix: prefix, upstream: target, healthPath: healthPath, proxy: p}
+}
+
+// 上游路径取 /*path 匹配到的部分，而不是从请求路径中去掉前缀：模块挂载在别名实例前缀或 prefix 下、
+// 或路由被 route_overrides 改写时，请求路径中还有这些部分
+func (m *ProxyModule) RegisterRoutes(r gin.IRouter) {
+	for _, rt := range m.routes {
+		h := func(c *gin.Context) {
+			req := c.Request.Clone(c.Request.Context())
+			req.URL.Path, req.URL.RawPath = c.Param("path"), ""
+			rt.proxy.ServeHTTP(c.Writer, req)
+		}
+		r.Any(rt.prefix, h)
+		r.Any(rt.prefix+"/*path", h)
+	}
+}
+
+// 逐个请求上游的 health_path，任一不可达或返回 5xx 即视为不健康
+func (m *ProxyModule) Health() error {
+	client := &http.Client{Transport: m.transport, Timeout: healthTimeout}
+	for _, rt := range m.routes {
+		u := *rt.upstream
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(rt.healthPath, "/")
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.String(), nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("upstream %s: %w", rt.upstream, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("upstream %s: status %d", rt.upstream, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+func (m *ProxyModule) Shutdown() error {
+	return m.Once(func() error {
+		if m.transport != nil {
+			m.transport.CloseIdleConnections()
+		}
+		fmt.Println("[proxy] Shutdown")
+		return nil
+	})
+}
+
+func New() module.Module {
+	return &ProxyModule{}
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 返回收到的请求路径的上游
+func echoUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestForwardedPath(t *testing.T) {
+	upstream := echoUpstream(t)
+	tests := []struct {
+		name     string
+		upstream string // 上游地址后缀的路径
+		mount    string // 模块路由组的前缀，如别名实例的 /primary
+		path     string
+		want     string
+	}{
+		{"root mount", "", "", "/github/users/1", "/users/1"},
+		{"prefix only", "", "", "/github", "/"},
+		{"instance prefix", "", "/primary", "/primary/github/users", "/users"},
+		{"nested mount", "", "/api/v1", "/api/v1/github/a/b", "/a/b"},
+		{"upstream base path", "/base", "/primary", "/primary/github/users", "/base/users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			cfg := module.ModuleConfig{"routes": []any{map[string]any{"prefix": "/github", "upstream": upstream.URL + tt.upstream}}}
+			if err := m.Init(cfg); err != nil {
+				t.Fatal(err)
+			}
+			defer m.Shutdown()
+			r := gin.New()
+			m.RegisterRoutes(r.Group(tt.mount))
+			// 经真实连接访问：gin 的 ResponseWriter 转发 CloseNotify，ResponseRecorder 不支持
+			front := httptest.NewServer(r)
+			defer front.Close()
+			resp, err := http.Get(front.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d", resp.StatusCode)
+			}
+			if got := string(body); got != tt.want {
+				t.Errorf("upstream path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
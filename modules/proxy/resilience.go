@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"myapp/module"
+)
+
+// 熔断期间不再转发，由 ErrorHandler 返回 503
+var errCircuitOpen = errors.New("proxy: circuit open")
+
+// 重试退避的上限
+const maxRetryBackoff = 2 * time.Second
+
+// 包装上游 Transport：连接失败时按指数退避（带抖动）重试（仅限幂等且可重放的请求），连续失败达到阈值后熔断
+type resilientTransport struct {
+	next    http.RoundTripper
+	retries int
+	backoff module.Backoff
+	breaker *breaker // 为 nil 时不熔断
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.breaker != nil && !t.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = t.next.RoundTrip(req)
+		if err == nil || attempt >= t.retries || !retryable(req) || req.Context().Err() != nil {
+			break
+		}
+		// 请求被取消或超时时不再等待，返回最后一次的错误
+		if module.Wait(req.Context(), t.backoff.Delay(attempt)) != nil {
+			break
+		}
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				break
+			}
+			req.Body = body
+		}
+	}
+	if t.breaker != nil {
+		t.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	}
+	return resp, err
+}
+
+// 只重试幂等方法，且请求体可以重放：没有请求体（ReverseProxy 对 Content-Length 为 0 的请求置 Body 为 nil），
+// 或可通过 GetBody 重新获取。连接错误时上游可能已经处理了请求，POST 等非幂等请求重放会造成重复提交
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// 连续失败（连接错误或 5xx）达到 threshold 次后熔断 cooldown；到期后进入半开状态，只放行一个试探请求，
+// 其余请求仍被拒绝：试探成功则恢复，失败则再次熔断 cooldown
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     breakerState
+	failures  int
+	openUntil time.Time
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// 试探请求尚未结束
+		return false
+	}
+	return true
+}
+
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// threshold <= 0 时不熔断
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"myapp/module"
+)
+
+// 按顺序返回预设结果的 RoundTripper，并记录调用次数
+type scriptedTransport struct {
+	mu      sync.Mutex
+	results []error // nil 表示返回 200
+	calls   int
+	at      []time.Time // 每次调用的时间
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if s.calls < len(s.results) {
+		err = s.results[s.calls]
+	}
+	s.calls++
+	s.at = append(s.at, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+var errDial = errors.New("dial: connection refused")
+
+func TestRetryOnlyIdempotentReplayableRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		body      io.Reader
+		getBody   bool
+		wantCalls int
+	}{
+		{"GET retried", http.MethodGet, nil, false, 3},
+		{"HEAD retried", http.MethodHead, nil, false, 3},
+		{"DELETE retried", http.MethodDelete, nil, false, 3},
+		{"PUT with rewindable body retried", http.MethodPut, strings.NewReader("x"), true, 3},
+		{"PUT with one-shot body not retried", http.MethodPut, strings.NewReader("x"), false, 1},
+		{"POST not retried", http.MethodPost, nil, false, 1},
+		{"POST with rewindable body not retried", http.MethodPost, strings.NewReader("x"), true, 1},
+		{"PATCH not retried", http.MethodPatch, nil, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scriptedTransport{results: []error{errDial, errDial, nil}}
+			rt := &resilientTransport{next: next, retries: 2}
+			req, _ := http.NewRequest(tt.method, "http://upstream/x", tt.body)
+			if !tt.getBody {
+				req.GetBody = nil
+			}
+			rt.RoundTrip(req)
+			if next.calls != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	const base = 20 * time.Millisecond
+	tests := []struct {
+		name      string
+		backoff   time.Duration
+		timeout   time.Duration // 请求 context 的时限，0 表示不限
+		wantCalls int
+		maxTotal  time.Duration // RoundTrip 的最长耗时
+	}{
+		// 抖动后第 i 次重试前等待 [base·2^i/2, base·2^i)
+		{"backoff doubles", base, 0, 3, time.Second},
+		{"bounded by request context", time.Second, 30 * time.Millisecond, 1, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scriptedTransport{results: []error{errDial, errDial, errDial}}
+			rt := &resilientTransport{next: next, retries: 2, backoff: module.Backoff{Base: tt.backoff, Max: maxRetryBackoff, Jitter: true}}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream/x", nil)
+			start := time.Now()
+			_, err := rt.RoundTrip(req)
+			if elapsed := time.Since(start); elapsed > tt.maxTotal {
+				t.Errorf("RoundTrip took %v, want at most %v", elapsed, tt.maxTotal)
+			}
+			if !errors.Is(err, errDial) {
+				t.Errorf("err = %v, want the last upstream error", err)
+			}
+			if next.calls != tt.wantCalls {
+				t.Fatalf("upstream calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+			for i := 1; i < len(next.at); i++ {
+				if gap, min := next.at[i].Sub(next.at[i-1]), tt.backoff<<(i-1)/2; gap < min {
+					t.Errorf("wait before retry %d = %v, want at least %v", i, gap, min)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+	tests := []struct {
+		name      string
+		probe     bool // 试探请求是否成功
+		wantAfter bool // 试探结束后是否放行新请求
+	}{
+		{"probe succeeds", true, true},
+		{"probe fails", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(2, cooldown)
+			b.record(false)
+			if !b.allow() {
+				t.Fatal("breaker opened before reaching the threshold")
+			}
+			b.record(false)
+			if b.allow() {
+				t.Fatal("breaker did not open at the threshold")
+			}
+
+			time.Sleep(cooldown + 10*time.Millisecond)
+			if !b.allow() {
+				t.Fatal("no probe admitted after the cooldown")
+			}
+			// 试探进行中，其余请求仍被拒绝
+			for i := 0; i < 3; i++ {
+				if b.allow() {
+					t.Fatal("more than one request admitted while half-open")
+				}
+			}
+			b.record(tt.probe)
+			if got := b.allow(); got != tt.wantAfter {
+				t.Errorf("allow after probe = %v, want %v", got, tt.wantAfter)
+			}
+			if !tt.probe {
+				// 再次熔断满一个 cooldown 后又只放行一个试探
+				time.Sleep(cooldown + 10*time.Millisecond)
+				if !b.allow() || b.allow() {
+					t.Error("reopened breaker should admit exactly one new probe after the cooldown")
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	if b := newBreaker(0, time.Second); b != nil {
+		t.Errorf("newBreaker(0) = %v, want nil", b)
+	}
+}
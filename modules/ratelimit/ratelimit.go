@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 按客户端 IP 的令牌桶限流；通过 Middlewares() 作用于依赖本模块的模块
+type RateLimitModule struct {
+	module.Base // 不注册路由，也没有依赖
+
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 桶容量
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// 超过该时长未访问的桶会被清理
+const idleBucketTTL = 10 * time.Minute
+
+func (m *RateLimitModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"properties": map[string]any{
+			"requests_per_second": map[string]any{"type": "number", "exclusiveMinimum": 0},
+			"burst":               map[string]any{"type": "integer", "minimum": 1},
+		},
+	}
+}
+
+func (m *RateLimitModule) Init(cfg module.ModuleConfig) error {
+	m.rate = cfg.GetFloat("requests_per_second", 10)
+	m.burst = float64(cfg.GetInt("burst", int(math.Ceil(m.rate))))
+	if m.rate <= 0 || m.burst < 1 {
+		return fmt.Errorf("ratelimit: requests_per_second must be > 0 and burst >= 1")
+	}
+	m.buckets = make(map[string]*bucket)
+	m.lastSweep = time.Now()
+	fmt.Printf("[ratelimit] Init with %.2f req/s, burst %d\n", m.rate, int(m.burst))
+	return nil
+}
+
+func (m *RateLimitModule) Middlewares() []gin.HandlerFunc {
+	return []gin.HandlerFunc{m.limit}
+}
+
+func (m *RateLimitModule) limit(c *gin.Context) {
+	ok, wait := m.take(c.ClientIP(), time.Now())
+	if !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return
+	}
+	c.Next()
+}
+
+// 取一个令牌；不足时返回需要等待的时长
+func (m *RateLimitModule) take(key string, now time.Time) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > idleBucketTTL {
+		for k, b := range m.buckets {
+			if now.Sub(b.last) > idleBucketTTL {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: m.burst, last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(m.burst, b.tokens+now.Sub(b.last).Seconds()*m.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / m.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (m *RateLimitModule) Shutdown() error {
+	fmt.Println("[ratelimit] Shutdown")
+	return nil
+}
+
+func New() module.Module {
+	return &RateLimitModule{}
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+func TestTake(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name     string
+		cfg      module.ModuleConfig
+		at       []time.Duration // 各次请求相对 start 的时间
+		want     []bool
+		lastWait time.Duration // 最后一次被拒绝时建议的等待时长
+	}{
+		{"burst then reject", module.ModuleConfig{"requests_per_second": 1, "burst": 2}, []time.Duration{0, 0, 0}, []bool{true, true, false}, time.Second},
+		{"refill over time", module.ModuleConfig{"requests_per_second": 2, "burst": 1}, []time.Duration{0, 0, 500 * time.Millisecond}, []bool{true, false, true}, 0},
+		{"partial refill", module.ModuleConfig{"requests_per_second": 4, "burst": 1}, []time.Duration{0, 100 * time.Millisecond}, []bool{true, false}, 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New().(*RateLimitModule)
+			if err := m.Init(tt.cfg); err != nil {
+				t.Fatal(err)
+			}
+			var wait time.Duration
+			for i, d := range tt.at {
+				var ok bool
+				ok, wait = m.take("10.0.0.1", start.Add(d))
+				if ok != tt.want[i] {
+					t.Fatalf("request %d: allowed = %v, want %v", i, ok, tt.want[i])
+				}
+			}
+			if wait.Round(time.Millisecond) != tt.lastWait {
+				t.Errorf("wait = %s, want %s", wait, tt.lastWait)
+			}
+		})
+	}
+}
+
+func TestHammerProtectedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		cfg      module.ModuleConfig
+		requests int
+		clients  int // 并发的客户端 IP 数，各自有独立的令牌桶
+		maxOK    int // 每个客户端最多放行的请求数（突发 + 测试期间补充的令牌）
+	}{
+		{"single client", module.ModuleConfig{"requests_per_second": 0.5, "burst": 5}, 50, 1, 6},
+		{"burst defaults to rate", module.ModuleConfig{"requests_per_second": 3}, 30, 1, 5},
+		{"clients limited independently", module.ModuleConfig{"requests_per_second": 0.5, "burst": 4}, 40, 4, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New().(*RateLimitModule)
+			if err := m.Init(tt.cfg); err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			r.GET("/protected", append(m.Middlewares(), func(c *gin.Context) { c.String(http.StatusOK, "ok") })...)
+
+			var wg sync.WaitGroup
+			ok := make([]atomic.Int64, tt.clients)
+			limited := make([]atomic.Int64, tt.clients)
+			for i := 0; i < tt.requests; i++ {
+				wg.Add(1)
+				go func(client int) {
+					defer wg.Done()
+					req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+					req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", client+1)
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+					switch w.Code {
+					case http.StatusOK:
+						ok[client].Add(1)
+					case http.StatusTooManyRequests:
+						limited[client].Add(1)
+						if w.Header().Get("Retry-After") == "" {
+							t.Error("429 without Retry-After")
+						}
+					default:
+						t.Errorf("status = %d", w.Code)
+					}
+				}(i % tt.clients)
+			}
+			wg.Wait()
+			for c := 0; c < tt.clients; c++ {
+				if n := ok[c].Load(); n < 1 || n > int64(tt.maxOK) {
+					t.Errorf("client %d: %d requests allowed, want 1..%d", c, n, tt.maxOK)
+				}
+				if limited[c].Load() == 0 {
+					t.Errorf("client %d: no 429 past the configured rate", c)
+				}
+			}
+		})
+	}
+}
+
+func TestInitRejectsInvalidConfig(t *testing.T) {
+	tests := []module.ModuleConfig{
+		{"requests_per_second": 0},
+		{"requests_per_second": 1, "burst": 0},
+	}
+	for _, cfg := range tests {
+		if err := New().Init(cfg); err == nil {
+			t.Errorf("Init(%v) succeeded, want an error", cfg)
+		}
+	}
+}
+
+// 嵌入 module.Base 后仍是完整的模块：没有依赖，RegisterRoutes 不注册任何路由
+func TestBaseDefaults(t *testing.T) {
+	m := New()
+	if deps := m.Deps(); deps != nil {
+		t.Errorf("Deps() = %v, want nil", deps)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	m.RegisterRoutes(r)
+	if routes := r.Routes(); len(routes) != 0 {
+		t.Errorf("RegisterRoutes registered %v", routes)
+	}
+	if _, ok := m.(module.MiddlewareProvider); !ok {
+		t.Error("ratelimit no longer provides middlewares")
+	}
+}
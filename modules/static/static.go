@@ -0,0 +1,105 @@
+package static
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 静态文件模块：把目录挂载到模块路由下，可用 static@docs 等别名挂载多个目录
+//
+//	route: /assets    # 默认 /static
+//	dir: ./public
+//	spa: true         # 未匹配到文件时返回 index.html（前端路由）
+type StaticModule struct {
+	route string
+	dir   string
+	spa   bool
+}
+
+func (m *StaticModule) Deps() []string { return nil }
+
+func (m *StaticModule) Defaults() module.ModuleConfig {
+	return module.ModuleConfig{"route": "/static", "spa": false}
+}
+
+func (m *StaticModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"required": []string{"dir"},
+		"properties": map[string]any{
+			"route": map[string]any{"type": "string"},
+			"dir":   map[string]any{"type": "string", "description": "静态文件目录"},
+			"spa":   map[string]any{"type": "boolean", "description": "未匹配的路径返回 index.html"},
+		},
+	}
+}
+
+func (m *StaticModule) Init(cfg module.ModuleConfig) error {
+	m.route = path.Clean("/" + cfg.GetString("route", ""))
+	m.dir = cfg.GetString("dir", "")
+	m.spa = cfg.GetBool("spa", false)
+	if m.dir == "" {
+		return fmt.Errorf("static: dir is required")
+	}
+	info, err := os.Stat(m.dir)
+	if err != nil {
+		return fmt.Errorf("static: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("static: %s is not a directory", m.dir)
+	}
+	if m.spa {
+		if _, err := os.Stat(filepath.Join(m.dir, "index.html")); err != nil {
+			return fmt.Errorf("static: spa requires index.html: %w", err)
+		}
+	}
+	fmt.Println("[static] Serving", m.dir, "at", m.route)
+	return nil
+}
+
+func (m *StaticModule) RegisterRoutes(r gin.IRouter) {
+	if !m.spa {
+		r.Static(m.route, m.dir)
+		return
+	}
+	// 不列目录；找不到文件（或是目录）时回退到 index.html
+	fs := gin.Dir(m.dir, false)
+	serve := func(c *gin.Context) {
+		name := c.Param("filepath")
+		if f, err := fs.Open(name); err == nil {
+			info, err := f.Stat()
+			f.Close()
+			if err == nil && !info.IsDir() {
+				c.FileFromFS(name, fs)
+				return
+			}
+		}
+		if strings.HasPrefix(path.Base(name), ".") || path.Ext(name) != "" && !acceptsHTML(c) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.FileFromFS("/", fs)
+	}
+	r.GET(path.Join(m.route, "/*filepath"), serve)
+	r.HEAD(path.Join(m.route, "/*filepath"), serve)
+}
+
+// 请求带扩展名的资源（如 .js）且不接受 HTML 时返回 404，避免把 index.html 当脚本返回
+func acceptsHTML(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/html")
+}
+
+func (m *StaticModule) Shutdown() error {
+	fmt.Println("[static] Shutdown")
+	return nil
+}
+
+func New() module.Module {
+	return &StaticModule{}
+}
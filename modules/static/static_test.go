@@ -0,0 +1,111 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/module/moduletest"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 含 index.html、app.js 与子目录 docs/ 的临时站点目录
+func siteDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":       "<html>index</html>",
+		"app.js":           "console.log(1)",
+		"docs/readme.html": "readme",
+	}
+	for name, body := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New,
+		module.ModuleConfig{"dir": siteDir(t)},
+		module.ModuleConfig{"dir": siteDir(t), "route": "/assets", "spa": true},
+	)
+}
+
+func TestServe(t *testing.T) {
+	dir := siteDir(t)
+	tests := []struct {
+		name     string
+		spa      bool
+		path     string
+		accept   string
+		wantCode int
+		wantBody string
+	}{
+		{"file", false, "/static/app.js", "", http.StatusOK, "console.log(1)"},
+		{"missing file", false, "/static/nope.js", "", http.StatusNotFound, ""},
+		{"spa file", true, "/static/docs/readme.html", "", http.StatusOK, "readme"},
+		{"spa client route", true, "/static/users/42", "text/html", http.StatusOK, "<html>index</html>"},
+		{"spa directory falls back to index", true, "/static/docs/", "text/html", http.StatusOK, "<html>index</html>"},
+		{"spa missing asset", true, "/static/missing.js", "*/*", http.StatusNotFound, ""},
+		{"spa missing asset from a browser", true, "/static/missing.js", "text/html", http.StatusOK, "<html>index</html>"},
+		{"spa dotfile", true, "/static/.env", "text/html", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			if err := m.Init(module.WithDefaults(m, module.ModuleConfig{"dir": dir, "spa": tt.spa})); err != nil {
+				t.Fatal(err)
+			}
+			engine := gin.New()
+			m.RegisterRoutes(engine)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestInitRejectsInvalidDir(t *testing.T) {
+	noIndex := t.TempDir()
+	file := filepath.Join(noIndex, "file.txt")
+	os.WriteFile(file, nil, 0o644)
+	tests := []struct {
+		name    string
+		cfg     module.ModuleConfig
+		wantErr string
+	}{
+		{"no dir", module.ModuleConfig{}, "dir is required"},
+		{"missing dir", module.ModuleConfig{"dir": filepath.Join(noIndex, "absent")}, "no such file"},
+		{"not a directory", module.ModuleConfig{"dir": file}, "is not a directory"},
+		{"spa without index.html", module.ModuleConfig{"dir": noIndex, "spa": true}, "spa requires index.html"},
+	}
+	for _, tt := range tests {
+		err := New().Init(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want it to mention %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -13,19 +13,27 @@ type UserModule struct {
 
 func (m *UserModule) Deps() []string { return nil }
 
-func (m *UserModule) Init(cfg module.ModuleConfig) error {
-	if g, ok := cfg["greeting"].(string); ok {
-		m.greeting = g
-	} else {
-		m.greeting = "Hello from user (default)"
+func (m *UserModule) ConfigSchema() map[string]any {
+	return map[string]any{
+		"properties": map[string]any{
+			"greeting": map[string]any{"type": "string"},
+		},
 	}
+}
+
+func (m *UserModule) Defaults() module.ModuleConfig {
+	return module.ModuleConfig{"greeting": "Hello from user (default)"}
+}
+
+func (m *UserModule) Init(cfg module.ModuleConfig) error {
+	m.greeting = cfg.GetString("greeting", "")
 	fmt.Println("[user] Init with greeting =", m.greeting)
 	return nil
 }
 
-func (m *UserModule) RegisterRoutes(r *gin.Engine) {
+func (m *UserModule) RegisterRoutes(r gin.IRouter) {
 	r.GET("/user", func(c *gin.Context) {
-		c.JSON(200, gin.H{"msg": m.greeting})
+		module.Respond(c, gin.H{"msg": m.greeting})
 	})
 }
 
@@ -0,0 +1,11 @@
+package user
+
+import (
+	"testing"
+
+	"myapp/module/moduletest"
+)
+
+func TestConformance(t *testing.T) {
+	moduletest.RunConformance(t, New)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"myapp/module"
+)
+
+// 模块通过 module.Route 登记的路由文档
+type documentedRoute struct {
+	module string
+	method string
+	path   string // 完整路径，gin 语法（:id、*path）
+	doc    module.RouteDoc
+}
+
+// 记录路由文档；路由实际注册的是改写后的方法与路径，未成功登记（冲突）的路由不记录
+func (t *trackedRouter) DocumentRoute(method, relativePath string, doc module.RouteDoc) {
+	method, relativePath = t.override(method, relativePath)
+	full := t.fullPath(relativePath)
+	if t.table.owners[method+" "+full] != t.module {
+		return
+	}
+	t.table.docs = append(t.table.docs, documentedRoute{module: t.module, method: method, path: full, doc: doc})
+}
+
+// 由已登记的路由文档生成 OpenAPI 3 文档；没有使用 module.Route 的路由不出现在文档中
+func (t *routeTable) openAPI() map[string]any {
+	paths := map[string]map[string]any{}
+	for _, r := range t.docs {
+		p, pathParams := openAPIPath(r.path)
+		op := map[string]any{
+			"operationId": r.module + "." + strings.ToLower(r.method) + operationSuffix(p),
+			"responses":   openAPIResponses(r.doc.Response),
+		}
+		if r.doc.Summary != "" {
+			op["summary"] = r.doc.Summary
+		}
+		if r.doc.Description != "" {
+			op["description"] = r.doc.Description
+		}
+		if tags := r.doc.Tags; len(tags) > 0 {
+			op["tags"] = tags
+		} else {
+			op["tags"] = []string{r.module}
+		}
+		if params := openAPIParams(r.doc.Params, pathParams); len(params) > 0 {
+			op["parameters"] = params
+		}
+		if paths[p] == nil {
+			paths[p] = map[string]any{}
+		}
+		paths[p][strings.ToLower(r.method)] = op
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "myapp", "version": Version},
+		"paths":   paths,
+	}
+}
+
+// operationId 中的路径部分：非字母数字字符替换为 _，如 /orders/{id} → _orders__id_
+func operationSuffix(p string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, p)
+}
+
+// 把 gin 路径 /orders/:id/*rest 转为 /orders/{id}/{rest}，并返回其中的路径参数名
+func openAPIPath(p string) (string, []string) {
+	segs := strings.Split(p, "/")
+	var params []string
+	for i, s := range segs {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			params = append(params, s[1:])
+			segs[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// 声明的参数在前；路径中未声明的参数按必填字符串补上，OpenAPI 要求每个路径参数都有定义
+func openAPIParams(declared []module.ParamDoc, pathParams []string) []map[string]any {
+	var out []map[string]any
+	seen := map[string]bool{}
+	for _, p := range declared {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		schema := p.Schema
+		if schema == nil {
+			schema = map[string]any{"type": "string"}
+		}
+		param := map[string]any{"name": p.Name, "in": in, "required": p.Required || in == "path", "schema": schema}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		if in == "path" {
+			seen[p.Name] = true
+		}
+		out = append(out, param)
+	}
+	sort.Strings(pathParams)
+	for _, name := range pathParams {
+		if !seen[name] {
+			out = append(out, map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+	}
+	return out
+}
+
+func openAPIResponses(schema map[string]any) map[string]any {
+	ok := map[string]any{"description": "OK"}
+	if schema != nil {
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	}
+	return map[string]any{"200": ok}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// /items/:id 用 module.Route 描述，/plain 不描述
+type documentedModule struct{ module.Base }
+
+func (m *documentedModule) RegisterRoutes(r gin.IRouter) {
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	module.Route(r, http.MethodGet, "/items/:id").Summary("查询条目").Query("fields", "返回的字段").Handle(ok)
+	r.GET("/plain", ok)
+}
+
+func TestAdminOpenAPI(t *testing.T) {
+	registry.Modules["t_doc"] = func() module.Module { return &documentedModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_doc") })
+	useGlobalRouter(t)
+	cfg := Config{Modules: []string{"order", "order@primary", "t_doc"}}
+	cfg.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/openapi.json", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	frontHandler(cfg.Server, false, "").ServeHTTP(w, req)
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string           `json:"operationId"`
+			Summary     string           `json:"summary"`
+			Tags        []string         `json:"tags"`
+			Parameters  []map[string]any `json:"parameters"`
+			Responses   map[string]struct {
+				Content map[string]any `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("GET /admin/openapi.json = %d %s: %v", w.Code, w.Body, err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+
+	tests := []struct {
+		path        string
+		operationID string // 为空表示不应出现在文档中
+		summary     string
+		tag         string
+		params      []string // 参数名
+		wantSchema  bool     // 200 响应是否带 schema
+	}{
+		{"/order", "order.get_order", "查询订单", "order", nil, true},
+		// 别名实例挂在自己的前缀下，单独成为一条路径
+		{"/primary/order", "order@primary.get_primary_order", "查询订单", "order@primary", nil, true},
+		{"/items/{id}", "t_doc.get_items__id_", "查询条目", "t_doc", []string{"fields", "id"}, false},
+		{"/plain", "", "", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			op, ok := doc.Paths[tt.path]["get"]
+			if tt.operationID == "" {
+				if ok {
+					t.Fatalf("undocumented route %s appears in the document: %+v", tt.path, op)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("path %s missing, have %v", tt.path, doc.Paths)
+			}
+			if op.OperationID != tt.operationID || op.Summary != tt.summary || len(op.Tags) != 1 || op.Tags[0] != tt.tag {
+				t.Errorf("operation = %+v, want id %q summary %q tag %q", op, tt.operationID, tt.summary, tt.tag)
+			}
+			var names []string
+			for _, p := range op.Parameters {
+				names = append(names, p["name"].(string))
+			}
+			if !slices.Equal(names, tt.params) {
+				t.Errorf("parameters = %v, want %v", names, tt.params)
+			}
+			if got := op.Responses["200"].Content != nil; got != tt.wantSchema {
+				t.Errorf("200 response content = %v, want schema = %v", op.Responses["200"].Content, tt.wantSchema)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"myapp/module"
+)
+
+// 模块路由的挂载前缀：模块配置 prefix 优先，否则为别名实例的默认前缀（见 routePrefix）
+func modulePrefix(name string, cfg module.ModuleConfig) (string, error) {
+	raw, ok := cfg["prefix"]
+	if !ok {
+		return routePrefix(name), nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("module %s: prefix must be a string", name)
+	}
+	p, err := normalizePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("module %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// 统一前缀写法：order、/order、/order/ 均为 /order，/ 与空串表示挂载在根路径（返回空串）。
+// 前缀中不允许路径参数与通配符，否则难以判断与其他模块的路由是否冲突
+func normalizePrefix(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, ":*") {
+		return "", fmt.Errorf("prefix %q: path parameters and wildcards are not allowed", s)
+	}
+	if strings.ContainsAny(s, " ?#") {
+		return "", fmt.Errorf("prefix %q: invalid character", s)
+	}
+	p := path.Clean("/" + s)
+	if p == "/" {
+		return "", nil
+	}
+	return p, nil
+}
+
+// 计算全部模块的前缀，并拒绝相互重叠的前缀：相同，或一个是另一个按路径段的前缀（如 /api 与 /api/v1）。
+// 挂载在根路径的模块不参与该检查，其路由冲突在注册时发现
+func modulePrefixes(names []string, configs map[string]map[string]any) (map[string]string, error) {
+	prefixes := make(map[string]string, len(names))
+	for _, name := range names {
+		p, err := modulePrefix(name, configs[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range names {
+			q, ok := prefixes[other]
+			if !ok || p == "" || q == "" {
+				continue
+			}
+			if p == q || strings.HasPrefix(p, q+"/") || strings.HasPrefix(q, p+"/") {
+				return nil, fmt.Errorf("module %s prefix %s overlaps module %s prefix %s", name, p, other, q)
+			}
+		}
+		prefixes[name] = p
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePrefix(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"order", "/order", ""},
+		{"/order", "/order", ""},
+		{"/order/", "/order", ""},
+		{" /api//v1/ ", "/api/v1", ""},
+		{"/api/./v1/../v2", "/api/v2", ""},
+		{"/", "", ""},
+		{"", "", ""},
+		{"/users/:id", "", "path parameters and wildcards are not allowed"},
+		{"/static/*file", "", "path parameters and wildcards are not allowed"},
+		{"/a b", "", "invalid character"},
+		{"/a?x=1", "", "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := normalizePrefix(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("normalizePrefix(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizePrefix(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModulePrefixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		configs map[string]map[string]any
+		want    map[string]string
+		wantErr string
+	}{
+		{"defaults", []string{"order", "order@primary"}, nil,
+			map[string]string{"order": "", "order@primary": "/primary"}, ""},
+		{"configured prefixes normalized", []string{"user", "order"},
+			map[string]map[string]any{"user": {"prefix": "users/"}, "order": {"prefix": "/orders"}},
+			map[string]string{"user": "/users", "order": "/orders"}, ""},
+		// 按路径段比较：/api 与 /apix 不重叠
+		{"sibling prefixes", []string{"user", "order"},
+			map[string]map[string]any{"user": {"prefix": "/api"}, "order": {"prefix": "/apix"}},
+			map[string]string{"user": "/api", "order": "/apix"}, ""},
+		{"root modules are not checked", []string{"user", "order"},
+			map[string]map[string]any{"user": {"prefix": "/"}, "order": {"prefix": "/api"}},
+			map[string]string{"user": "", "order": "/api"}, ""},
+		{"nested prefixes", []string{"user", "order"},
+			map[string]map[string]any{"user": {"prefix": "/api"}, "order": {"prefix": "/api/v1"}},
+			nil, "module order prefix /api/v1 overlaps module user prefix /api"},
+		{"same prefix written differently", []string{"user", "order"},
+			map[string]map[string]any{"user": {"prefix": "api"}, "order": {"prefix": "/api/"}},
+			nil, "module order prefix /api overlaps module user prefix /api"},
+		{"non-string prefix", []string{"user"}, map[string]map[string]any{"user": {"prefix": 1}},
+			nil, "module user: prefix must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := modulePrefixes(tt.names, tt.configs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("modulePrefixes error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("prefix of %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrefixedRoutes(t *testing.T) {
+	registerRouteModules(t, map[string][2]string{"t_pa": {"/items", "a"}, "t_pb": {"/items", "b"}})
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), Config{
+		Modules: []string{"t_pa", "t_pb"},
+		Configs: map[string]map[string]any{"t_pa": {"prefix": "a/"}, "t_pb": {"prefix": "/b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/a/items", http.StatusOK, "a"},
+		{"/b/items", http.StatusOK, "b"},
+		{"/items", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantCode || (tt.wantBody != "" && w.Body.String() != tt.wantBody) {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
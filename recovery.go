@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+)
+
+// 统一的错误响应：{"error":{"code":500,"message":"...","request_id":"...","stack":"..."}}，
+// stack 为空时省略
+func errorEnvelope(c *gin.Context, code int, message string, stack string) gin.H {
+	body := gin.H{"code": code, "message": message, "request_id": module.RequestID(c)}
+	if stack != "" {
+		body["stack"] = stack
+	}
+	return gin.H{"error": body}
+}
+
+// 替代 gin.Recovery：panic 时返回 JSON 错误信封；处理器通过 c.Error 报告错误且未写响应时同样按信封格式返回
+func recoveryMiddleware(withStack bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if p := recover(); p != nil {
+				respondPanic(c, "", p, withStack)
+			}
+		}()
+		c.Next()
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			code := c.Writer.Status()
+			if code < http.StatusBadRequest {
+				code = http.StatusInternalServerError
+			}
+			c.AbortWithStatusJSON(code, errorEnvelope(c, code, c.Errors.Last().Error(), ""))
+		}
+	}
+}
+
+// 模块路由组的恢复中间件：panic 计入该模块的 module_panic_total 并在日志中标明模块名，
+// 在全局恢复之前处理，响应格式相同
+func moduleRecovery(name string, panics *atomic.Int64, withStack bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if p := recover(); p != nil {
+				panics.Add(1)
+				respondPanic(c, name, p, withStack)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func respondPanic(c *gin.Context, name string, p any, withStack bool) {
+	stack := debug.Stack()
+	if name != "" {
+		fmt.Printf("Module %s panicked [%s] %s %s: %v\n%s", name, module.RequestID(c), c.Request.Method, c.Request.URL.Path, p, stack)
+	} else {
+		fmt.Printf("Panic recovered [%s] %s %s: %v\n%s", module.RequestID(c), c.Request.Method, c.Request.URL.Path, p, stack)
+	}
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	s := ""
+	if withStack {
+		s = string(stack)
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, errorEnvelope(c, http.StatusInternalServerError, "internal server error", s))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// /panic 触发 panic，/fail 通过 c.Error 报告 409 错误
+type faultyModule struct{ module.Base }
+
+func (m *faultyModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusConflict)
+		c.Error(errors.New("order already exists"))
+	})
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	registry.Modules["t_faulty"] = func() module.Module { return &faultyModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_faulty") })
+	tests := []struct {
+		name      string
+		path      string
+		stack     bool
+		code      int
+		message   string
+		wantStack bool
+	}{
+		{"panic", "/panic", false, http.StatusInternalServerError, "internal server error", false},
+		{"panic with stack", "/panic", true, http.StatusInternalServerError, "internal server error", true},
+		{"handler error", "/fail", false, http.StatusConflict, "order already exists", false},
+		{"handler error never has a stack", "/fail", true, http.StatusConflict, "order already exists", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"t_faulty"}}
+			cfg.Server.ErrorStack = &tt.stack
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(module.RequestIDHeader, "req-42")
+			w := httptest.NewRecorder()
+			captureStdout(t, func() { r.ServeHTTP(w, req) })
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d", w.Code, tt.code)
+			}
+			var body struct {
+				Error struct {
+					Code      int    `json:"code"`
+					Message   string `json:"message"`
+					RequestID string `json:"request_id"`
+					Stack     string `json:"stack"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", w.Body, err)
+			}
+			e := body.Error
+			if e.Code != tt.code || e.Message != tt.message || e.RequestID != "req-42" {
+				t.Errorf("envelope = %+v, want code %d, message %q, request_id req-42", e, tt.code, tt.message)
+			}
+			if (e.Stack != "") != tt.wantStack {
+				t.Errorf("stack present = %v, want %v", e.Stack != "", tt.wantStack)
+			}
+		})
+	}
+}
+
+func TestModulePanicCounter(t *testing.T) {
+	registry.Modules["t_faulty"] = func() module.Module { return &faultyModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_faulty") })
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), Config{Modules: []string{"t_faulty", "order"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		want   map[string]any // 请求后各模块的 module_panic_total，nil 表示没有该项
+	}{
+		{"/order", http.StatusOK, map[string]any{"t_faulty": nil, "order": nil}},
+		{"/panic", http.StatusInternalServerError, map[string]any{"t_faulty": int64(1), "order": nil}},
+		{"/panic", http.StatusInternalServerError, map[string]any{"t_faulty": int64(2), "order": nil}},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		out := captureStdout(t, func() { r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil)) })
+		if w.Code != tt.status {
+			t.Fatalf("step %d: GET %s = %d, want %d", i, tt.path, w.Code, tt.status)
+		}
+		if tt.status == http.StatusInternalServerError && !strings.Contains(out, "Module t_faulty panicked") {
+			t.Errorf("step %d: panic log does not name the module:\n%s", i, out)
+		}
+		stats := m.Stats()
+		for name, want := range tt.want {
+			got, ok := stats[name]["module_panic_total"]
+			if want == nil && ok || want != nil && got != want {
+				t.Errorf("step %d: %s module_panic_total = %v, want %v", i, name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package registry
+
+import "context"
+
+// 启动钩子：名称 -> 函数，在配置的 startup_hooks 中以 name 引用，于任何模块 Init 之前执行一次（重载时不再执行）。
+// 返回错误时启动中止；ctx 在进程收到退出信号或超过钩子的 timeout 时取消
+var Hooks = map[string]func(ctx context.Context) error{}
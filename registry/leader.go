@@ -0,0 +1,6 @@
+package registry
+
+import "myapp/module"
+
+// 非 nil 时用于 leader 选举，代替 server.leader_lock 的文件锁；未设置 leader_lock 时默认本实例始终为 leader
+var LeaderElector module.LeaderElector
@@ -1,15 +1,66 @@
 package registry
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"myapp/module"
 	"myapp/modules/auth"
+	"myapp/modules/balance"
+	"myapp/modules/cache"
+	"myapp/modules/debug"
 	"myapp/modules/order"
+	"myapp/modules/proxy"
+	"myapp/modules/ratelimit"
+	"myapp/modules/static"
 	"myapp/modules/user"
 )
 
 // 注册表：模块名 -> 工厂函数
 var Modules = map[string]func() module.Module{
-	"user":  user.New,
-	"auth":  auth.New,
-	"order": order.New,
+	"user":      user.New,
+	"auth":      auth.New,
+	"order":     order.New,
+	"cache":     cache.New,
+	"ratelimit": ratelimit.New,
+	"debug":     debug.New,
+	"proxy":     proxy.New,
+	"static":    static.New,
+	"balance":   balance.New,
+}
+
+// Factory 返回模块实例名对应的工厂函数；"order@primary" 形式的别名使用 order 的工厂，
+// 同一类型的多个实例各自拥有独立的配置块与实例
+func Factory(name string) (func() module.Module, bool) {
+	typ, _, _ := strings.Cut(name, "@")
+	f, ok := Modules[typ]
+	return f, ok
+}
+
+// Validate 创建每个已注册模块的临时实例，检查其 Deps() 与 Optional() 引用的模块均已注册、声明的作用域受支持
+func Validate() error {
+	names := make([]string, 0, len(Modules))
+	for name := range Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		mod := Modules[name]()
+		if err := module.CheckScope(name, mod); err != nil {
+			errs = append(errs, err)
+		}
+		deps := mod.Deps()
+		if opt, ok := mod.(module.OptionalDeps); ok {
+			deps = append(append([]string(nil), deps...), opt.Optional()...)
+		}
+		for _, dep := range deps {
+			if _, ok := Factory(module.DepName(dep)); !ok {
+				errs = append(errs, fmt.Errorf("module %q depends on unregistered module %q", name, module.DepName(dep)))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"myapp/module"
+)
+
+// 按字段声明依赖与作用域的测试模块
+type depsModule struct {
+	module.Base
+	deps, optional []string
+	scope          module.Scope
+}
+
+func (m *depsModule) Deps() []string      { return m.deps }
+func (m *depsModule) Optional() []string  { return m.optional }
+func (m *depsModule) Scope() module.Scope { return m.scope }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     *depsModule
+		wantErr string
+	}{
+		{"builtin modules only", nil, ""},
+		{"known dependency", &depsModule{deps: []string{"order"}}, ""},
+		{"version constraint on known dependency", &depsModule{deps: []string{"auth>=1.0.0"}}, ""},
+		{"unknown dependency", &depsModule{deps: []string{"billing"}}, `module "t_deps" depends on unregistered module "billing"`},
+		{"unknown optional dependency", &depsModule{optional: []string{"tracing"}}, `module "t_deps" depends on unregistered module "tracing"`},
+		{"unknown constrained dependency", &depsModule{deps: []string{"billing>=2.0.0"}}, `module "t_deps" depends on unregistered module "billing"`},
+		{"singleton scope", &depsModule{scope: module.Singleton}, ""},
+		{"per-request scope", &depsModule{scope: module.PerRequest}, "module t_deps declares scope per-request, only singleton modules are supported"},
+		{"unknown scope", &depsModule{scope: module.Scope(7)}, "module t_deps declares scope Scope(7)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mod != nil {
+				Modules["t_deps"] = func() module.Module { return tt.mod }
+				t.Cleanup(func() { delete(Modules, "t_deps") })
+			}
+			err := Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+			if tt.mod.scope != module.Singleton && !errors.Is(err, module.ErrScope) {
+				t.Errorf("Validate() = %v, want it to match module.ErrScope", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 合并重载请求：同一时刻只执行一个重载；执行期间到达的新请求会取消当前重载，
+// 并在其结束后以最新配置再执行一次，避免慢重载期间事件堆积。
+// 设置了 minInterval 时两次重载的开始时间至少相隔该值，期间到达的请求合并为一次
+type reloadCoalescer struct {
+	run         func(ctx context.Context)
+	minInterval func() time.Duration
+
+	mu      sync.Mutex
+	running bool
+	pending bool
+	cancel  context.CancelFunc
+	last    time.Time
+}
+
+func newReloadCoalescer(run func(ctx context.Context)) *reloadCoalescer {
+	return &reloadCoalescer{run: run}
+}
+
+func (c *reloadCoalescer) Trigger() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		c.pending = true
+		c.cancel()
+		return
+	}
+	c.running = true
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.loop(ctx)
+}
+
+func (c *reloadCoalescer) loop(ctx context.Context) {
+	for {
+		if wait := c.throttle(); wait > 0 {
+			fmt.Printf("Reloads requested faster than min_reload_interval, throttling for %s\n", wait.Round(time.Millisecond))
+			time.Sleep(wait)
+			// 等待期间到达的请求取消了 ctx，它们已合并到这次重载中
+			c.mu.Lock()
+			if c.pending {
+				c.pending = false
+				ctx, c.cancel = context.WithCancel(context.Background())
+			}
+			c.mu.Unlock()
+		}
+		c.mu.Lock()
+		c.last = time.Now()
+		c.mu.Unlock()
+		c.run(ctx)
+		c.cancel()
+
+		c.mu.Lock()
+		if !c.pending {
+			c.running = false
+			c.mu.Unlock()
+			return
+		}
+		c.pending = false
+		ctx, c.cancel = context.WithCancel(context.Background())
+		c.mu.Unlock()
+	}
+}
+
+// 距离允许下一次重载还需等待的时间
+func (c *reloadCoalescer) throttle() time.Duration {
+	if c.minInterval == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last.IsZero() {
+		return 0
+	}
+	return c.minInterval() - time.Since(c.last)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReloadCoalescer(t *testing.T) {
+	tests := []struct {
+		name        string
+		slow        time.Duration // 每次重载的耗时
+		minInterval time.Duration
+		triggers    [][]int // 每组内连续写入配置版本并触发，组之间等待重载结束
+		want        []int   // 完整应用的配置版本
+	}{
+		{"single change", 10 * time.Millisecond, 0, [][]int{{1}}, []int{1}},
+		{"changes during a slow reload", 300 * time.Millisecond, 0, [][]int{{1, 2, 3}}, []int{3}},
+		{"sequential changes", 10 * time.Millisecond, 0, [][]int{{1}, {2}}, []int{1, 2}},
+		{"throttled changes merged", 10 * time.Millisecond, 150 * time.Millisecond, [][]int{{1}, {2, 3}}, []int{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				latest  atomic.Int64
+				mu      sync.Mutex
+				applied []int
+				running atomic.Int32
+			)
+			c := newReloadCoalescer(func(ctx context.Context) {
+				if running.Add(1) > 1 {
+					t.Error("two reloads ran concurrently")
+				}
+				defer running.Add(-1)
+				v := int(latest.Load())
+				select {
+				case <-ctx.Done():
+				case <-time.After(tt.slow):
+					mu.Lock()
+					applied = append(applied, v)
+					mu.Unlock()
+				}
+			})
+			if tt.minInterval > 0 {
+				c.minInterval = func() time.Duration { return tt.minInterval }
+			}
+			idle := func() bool {
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				return !c.running
+			}
+			for _, group := range tt.triggers {
+				for i, v := range group {
+					latest.Store(int64(v))
+					c.Trigger()
+					if i == 0 && len(group) > 1 {
+						// 等第一次重载开始后再写入后续版本
+						time.Sleep(20 * time.Millisecond)
+					}
+				}
+				eventually(t, 2*time.Second, "reloads to finish", idle)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(applied, tt.want) {
+				t.Errorf("applied versions = %v, want %v", applied, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadReexpandsEnv(t *testing.T) {
+	useGlobalRouter(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "modules: [order]\nserver:\n  admin_token: secret\nconfigs:\n  order:\n    dsn: ${T_ROTATED_DSN}\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("T_ROTATED_DSN", "memory://v0")
+	source = &FileSource{Path: path}
+	cfg, err := source.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() { err = rebuildRouter(context.Background(), cfg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	front := frontHandler(cfg.Server, false, "")
+	dsn := func() string {
+		w := httptest.NewRecorder()
+		front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order", nil))
+		return w.Body.String()
+	}
+
+	tests := []struct {
+		name    string
+		trigger func() error
+	}{
+		{"admin reload", func() error {
+			req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			front.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				return fmt.Errorf("POST /admin/reload = %d %s", w.Code, w.Body)
+			}
+			return nil
+		}},
+		{"SIGHUP", func() error { return handleSIGHUP(context.Background()) }},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 配置文件不变，只轮换环境变量
+			want := fmt.Sprintf("memory://v%d", i+1)
+			t.Setenv("T_ROTATED_DSN", want)
+			if got := dsn(); strings.Contains(got, want) {
+				t.Fatalf("GET /order = %s before the reload", got)
+			}
+			var err error
+			captureStdout(t, func() { err = tt.trigger() })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dsn(); !strings.Contains(got, want) {
+				t.Errorf("GET /order = %s, want it to use %s", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleSIGHUP(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil})
+	tests := []struct {
+		name       string
+		modules    []string // SIGHUP 时配置来源给出的模块
+		rotate     bool     // 发送 SIGHUP 前像 logrotate 一样移走日志文件
+		wantErr    string
+		wantActive []string
+	}{
+		{"reloads config", []string{"t_a", "t_b"}, false, "", []string{"t_a", "t_b"}},
+		{"reopens rotated log file", []string{"t_a"}, true, "", []string{"t_a"}},
+		{"reload error keeps modules", []string{"t_a", "t_missing"}, true, "unknown module: t_missing", []string{"t_a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useGlobalRouter(t)
+			t.Cleanup(func() { logOutput.setPath("") })
+			logPath := filepath.Join(t.TempDir(), "app.log")
+			cfg := Config{Modules: []string{"t_a"}}
+			cfg.Logging.File = logPath
+			src := &mutableSource{cfg: cfg}
+			source = src
+			var err error
+			captureStdout(t, func() { err = rebuildRouter(context.Background(), cfg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintln(logOutput, "before")
+			if tt.rotate {
+				if err := os.Rename(logPath, logPath+".1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			next := cfg
+			next.Modules = tt.modules
+			src.set(next)
+			captureStdout(t, func() { err = handleSIGHUP(context.Background()) })
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("handleSIGHUP error = %v, want %q", err, tt.wantErr)
+			}
+			if got := manager.ActiveModules(); !slices.Equal(got, tt.wantActive) {
+				t.Errorf("active modules = %v, want %v", got, tt.wantActive)
+			}
+			// 重载失败也会重新打开日志文件
+			fmt.Fprintln(logOutput, "after")
+			want := "before\nafter\n"
+			if tt.rotate {
+				want = "after\n"
+			}
+			if data, err := os.ReadFile(logPath); err != nil || string(data) != want {
+				t.Errorf("log file = %q (%v), want %q", data, err, want)
+			}
+		})
+	}
+}
+
+func TestReloadStormThrottled(t *testing.T) {
+	tests := []struct {
+		name        string
+		minInterval time.Duration
+		triggers    int
+		every       time.Duration // 两次触发的间隔
+		throttled   bool          // 是否打印限流提示，未限流时每次变更各重载一次
+	}{
+		// 每 10ms 写一次、持续约 500ms：每 100ms 至多一次重载
+		{"storm capped to the interval", 100 * time.Millisecond, 50, 10 * time.Millisecond, true},
+		{"slower than the interval", 50 * time.Millisecond, 3, 100 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				latest atomic.Int64
+				mu     sync.Mutex
+				starts []time.Time
+				last   int64
+			)
+			c := newReloadCoalescer(func(ctx context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				starts = append(starts, time.Now())
+				last = latest.Load()
+			})
+			c.minInterval = func() time.Duration { return tt.minInterval }
+			idle := func() bool {
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				return !c.running
+			}
+			var elapsed time.Duration
+			out := captureStdout(t, func() {
+				start := time.Now()
+				for i := 1; i <= tt.triggers; i++ {
+					latest.Store(int64(i))
+					c.Trigger()
+					time.Sleep(tt.every)
+				}
+				elapsed = time.Since(start)
+				eventually(t, 2*time.Second, "reloads to finish", idle)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			// 触发持续 elapsed，最后一次触发后至多再重载一次
+			if limit := int(elapsed/tt.minInterval) + 2; tt.throttled && len(starts) > limit {
+				t.Errorf("%d reloads for %d changes over %v, want at most %d", len(starts), tt.triggers, elapsed, limit)
+			}
+			if !tt.throttled && len(starts) != tt.triggers {
+				t.Errorf("%d reloads for %d changes, want one per change", len(starts), tt.triggers)
+			}
+			for i := 1; i < len(starts); i++ {
+				// 计时精度留出少量余量
+				if gap := starts[i].Sub(starts[i-1]); gap < tt.minInterval-5*time.Millisecond {
+					t.Errorf("reload %d started %v after the previous one, want at least %v", i+1, gap, tt.minInterval)
+				}
+			}
+			if last != int64(tt.triggers) {
+				t.Errorf("last applied version = %d, want %d", last, tt.triggers)
+			}
+			if got := strings.Contains(out, "throttling for"); got != tt.throttled {
+				t.Errorf("throttling logged = %v, want %v (output: %q)", got, tt.throttled, out)
+			}
+		})
+	}
+}
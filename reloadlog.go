@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"myapp/module"
+)
+
+// 未配置 server.reload_history 时保留的重载记录条数
+const defaultReloadHistory = 20
+
+// 一次 Update 的结果，由 /admin/reloads 返回
+type ReloadEvent struct {
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration"`
+	Success  bool      `json:"success"`
+	Started  []string  `json:"started,omitempty"` // 新创建或重新初始化的实例
+	Stopped  []string  `json:"stopped,omitempty"` // 被移除或替换的实例
+	Error    string    `json:"error,omitempty"`
+	// 与上一次生效配置的差异（敏感值已隐藏）；失败的重载同样记录其尝试应用的变化
+	Diff *ConfigDiff `json:"diff,omitempty"`
+}
+
+// 追加一条重载记录，超过 size 时丢弃最旧的；调用方持有 m.lock（Update 期间），写入时另取 m.mu
+func (m *ModuleManager) recordReload(start time.Time, before map[string]module.Module, diff *ConfigDiff, size int, err error) {
+	ev := ReloadEvent{Time: start, Duration: time.Since(start).Round(time.Millisecond).String(), Success: err == nil, Diff: diff}
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		for _, name := range m.order {
+			if before[name] != m.active[name] {
+				ev.Started = append(ev.Started, name)
+			}
+		}
+		for name, mod := range before {
+			if m.active[name] != mod {
+				ev.Stopped = append(ev.Stopped, name)
+			}
+		}
+		sort.Strings(ev.Stopped)
+	}
+	if size <= 0 {
+		size = defaultReloadHistory
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloads = append(m.reloads, ev)
+	if n := len(m.reloads) - size; n > 0 {
+		m.reloads = append(m.reloads[:0:0], m.reloads[n:]...)
+	}
+}
+
+// ReloadHistory 返回最近的重载记录，按时间从旧到新
+func (m *ModuleManager) ReloadHistory() []ReloadEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]ReloadEvent(nil), m.reloads...)
+}
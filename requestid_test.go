@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// GET /rid 返回处理函数通过 module.RequestID 看到的 ID
+type requestIDModule struct{ module.Base }
+
+func (m *requestIDModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/rid", func(c *gin.Context) { c.String(http.StatusOK, module.RequestID(c)) })
+}
+
+func TestRequestID(t *testing.T) {
+	registry.Modules["t_rid"] = func() module.Module { return &requestIDModule{} }
+	t.Cleanup(func() { delete(registry.Modules, "t_rid") })
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	r, err := m.Update(context.Background(), Config{Modules: []string{"t_rid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	tests := []struct {
+		name     string
+		incoming string
+		echoed   bool // false 表示应生成新 ID
+	}{
+		{"incoming id echoed", "req-123", true},
+		{"generated when absent", "", false},
+		{"too long regenerated", strings.Repeat("x", maxRequestIDLen+1), false},
+		{"control characters regenerated", "bad\tid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/rid", nil)
+			if tt.incoming != "" {
+				req.Header.Set(module.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			id := w.Header().Get(module.RequestIDHeader)
+			if tt.echoed && id != tt.incoming {
+				t.Errorf("response id = %q, want %q", id, tt.incoming)
+			}
+			if !tt.echoed && !generated.MatchString(id) {
+				t.Errorf("response id = %q, want a generated id", id)
+			}
+			if w.Body.String() != id {
+				t.Errorf("handler saw id %q, response header has %q", w.Body, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 与 gin 的 Any 保持一致
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+// 记录本次构建中已注册的 method+path 及其所属模块，用于检测跨模块的重复路由
+type routeTable struct {
+	owners    map[string]string
+	conflicts []error
+	docs      []documentedRoute // 模块通过 module.Route 附带的文档，生成 /admin/openapi.json
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{owners: make(map[string]string)}
+}
+
+// 为模块返回一个带路由登记的 IRouter
+func (t *routeTable) router(module string, group *gin.RouterGroup) gin.IRouter {
+	return t.tracked(module, group, nil)
+}
+
+// 同 router，并按 overrides（模块配置 route_overrides）改写模块注册的路由
+func (t *routeTable) tracked(module string, group *gin.RouterGroup, overrides map[string]string) *trackedRouter {
+	return &trackedRouter{group: group, table: t, module: module, overrides: overrides, applied: make(map[string]bool)}
+}
+
+// 登记路由；已被占用时记录冲突并返回 false，调用方不再向 gin 注册以避免 panic
+func (t *routeTable) claim(module, method, fullPath string) bool {
+	key := method + " " + fullPath
+	if owner, ok := t.owners[key]; ok {
+		t.conflicts = append(t.conflicts, fmt.Errorf("route conflict: %s registered by both %q and %q", key, owner, module))
+		return false
+	}
+	t.owners[key] = module
+	return true
+}
+
+// 单条已登记的路由
+type routeEntry struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// 按所属模块分组返回已登记的路由，组内按路径、方法排序
+func (t *routeTable) byModule() map[string][]routeEntry {
+	out := make(map[string][]routeEntry)
+	for key, owner := range t.owners {
+		method, p, _ := strings.Cut(key, " ")
+		out[owner] = append(out[owner], routeEntry{Method: method, Path: p})
+	}
+	for _, entries := range out {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Path != entries[j].Path {
+				return entries[i].Path < entries[j].Path
+			}
+			return entries[i].Method < entries[j].Method
+		})
+	}
+	return out
+}
+
+// 开发模式下的 404：列出本次构建登记的全部路由，便于发现拼错的路径或未启用的模块
+func (t *routeTable) notFound(c *gin.Context) {
+	var routes []string
+	for key, owner := range t.owners {
+		routes = append(routes, key+" ("+owner+")")
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		// 按路径再按方法排序
+		mi, pi, _ := strings.Cut(routes[i], " ")
+		mj, pj, _ := strings.Cut(routes[j], " ")
+		if pi != pj {
+			return pi < pj
+		}
+		return mi < mj
+	})
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":  "no route for " + c.Request.Method + " " + c.Request.URL.Path,
+		"routes": routes,
+	})
+}
+
+// trackedRouter 包装 gin.RouterGroup，注册前先在 routeTable 中登记
+type trackedRouter struct {
+	group  *gin.RouterGroup
+	table  *routeTable
+	module string
+	// 键与值均为 "[METHOD ]path"，路径相对模块的路由组；值省略方法时沿用原方法
+	overrides map[string]string
+	applied   map[string]bool
+}
+
+// 返回改写后的方法与路径；"GET /order" 形式的键优先于只写路径的键
+func (t *trackedRouter) override(method, relativePath string) (string, string) {
+	key := method + " " + relativePath
+	to, ok := t.overrides[key]
+	if !ok {
+		key = relativePath
+		if to, ok = t.overrides[key]; !ok {
+			return method, relativePath
+		}
+	}
+	t.applied[key] = true
+	if m, p, found := strings.Cut(to, " "); found {
+		return strings.ToUpper(m), p
+	}
+	return method, to
+}
+
+// 未命中任何已注册路由的改写规则
+func (t *trackedRouter) unusedOverrides() []string {
+	var unused []string
+	for key := range t.overrides {
+		if !t.applied[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+func (t *trackedRouter) fullPath(relativePath string) string {
+	// 与 gin 的 joinPaths 规则一致：保留尾部斜杠
+	if relativePath == "" {
+		return t.group.BasePath()
+	}
+	p := path.Join(t.group.BasePath(), relativePath)
+	if relativePath[len(relativePath)-1] == '/' && p[len(p)-1] != '/' {
+		p += "/"
+	}
+	return p
+}
+
+func (t *trackedRouter) claimAll(methods []string, relativePath string) bool {
+	full := t.fullPath(relativePath)
+	ok := true
+	for _, method := range methods {
+		if !t.table.claim(t.module, method, full) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (t *trackedRouter) Use(middleware ...gin.HandlerFunc) gin.IRoutes {
+	t.group.Use(middleware...)
+	return t
+}
+
+func (t *trackedRouter) Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	return t.group.Group(relativePath, handlers...)
+}
+
+func (t *trackedRouter) Handle(method, relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	method, relativePath = t.override(method, relativePath)
+	if t.claimAll([]string{method}, relativePath) {
+		t.group.Handle(method, relativePath, handlers...)
+	}
+	return t
+}
+
+func (t *trackedRouter) Match(methods []string, relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	// 有改写规则时逐个方法注册，使每个方法可单独改写
+	if len(t.overrides) > 0 {
+		for _, method := range methods {
+			t.Handle(method, relativePath, handlers...)
+		}
+		return t
+	}
+	if t.claimAll(methods, relativePath) {
+		t.group.Match(methods, relativePath, handlers...)
+	}
+	return t
+}
+
+func (t *trackedRouter) Any(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Match(anyMethods, relativePath, handlers...)
+}
+
+func (t *trackedRouter) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+func (t *trackedRouter) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+func (t *trackedRouter) DELETE(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+func (t *trackedRouter) PATCH(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+func (t *trackedRouter) PUT(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+func (t *trackedRouter) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodOptions, relativePath, handlers...)
+}
+
+func (t *trackedRouter) HEAD(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return t.Handle(http.MethodHead, relativePath, handlers...)
+}
+
+func (t *trackedRouter) StaticFile(relativePath, filepath string) gin.IRoutes {
+	if t.claimAll([]string{http.MethodGet, http.MethodHead}, relativePath) {
+		t.group.StaticFile(relativePath, filepath)
+	}
+	return t
+}
+
+func (t *trackedRouter) StaticFileFS(relativePath, filepath string, fs http.FileSystem) gin.IRoutes {
+	if t.claimAll([]string{http.MethodGet, http.MethodHead}, relativePath) {
+		t.group.StaticFileFS(relativePath, filepath, fs)
+	}
+	return t
+}
+
+func (t *trackedRouter) Static(relativePath, root string) gin.IRoutes {
+	return t.StaticFS(relativePath, gin.Dir(root, false))
+}
+
+func (t *trackedRouter) StaticFS(relativePath string, fs http.FileSystem) gin.IRoutes {
+	if t.claimAll([]string{http.MethodGet, http.MethodHead}, path.Join(relativePath, "/*filepath")) {
+		t.group.StaticFS(relativePath, fs)
+	}
+	return t
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteTableDetectsDuplicates(t *testing.T) {
+	type reg struct {
+		module, method, path string
+	}
+	tests := []struct {
+		name      string
+		regs      []reg
+		conflicts []string // 冲突错误中应包含的片段
+	}{
+		{"distinct paths", []reg{{"a", "GET", "/a"}, {"b", "GET", "/b"}}, nil},
+		{"same path, different methods", []reg{{"a", "GET", "/x"}, {"b", "POST", "/x"}}, nil},
+		{"same route in two modules", []reg{{"a", "GET", "/x"}, {"b", "GET", "/x"}}, []string{`GET /x registered by both "a" and "b"`}},
+		{"same route twice in one module", []reg{{"a", "GET", "/x"}, {"a", "GET", "/x"}}, []string{`GET /x registered by both "a" and "a"`}},
+		{"Any overlaps a single method", []reg{{"a", "DELETE", "/x"}, {"b", "ANY", "/x"}}, []string{`DELETE /x registered by both "a" and "b"`}},
+		{"trailing slash is a different route", []reg{{"a", "GET", "/x"}, {"b", "GET", "/x/"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newRouteTable()
+			engine := gin.New()
+			for _, r := range tt.regs {
+				router := table.router(r.module, &engine.RouterGroup)
+				handler := func(c *gin.Context) {}
+				if r.method == "ANY" {
+					router.Any(r.path, handler)
+				} else {
+					router.Handle(r.method, r.path, handler)
+				}
+			}
+			if len(table.conflicts) != len(tt.conflicts) {
+				t.Fatalf("conflicts = %v, want %d", table.conflicts, len(tt.conflicts))
+			}
+			for i, want := range tt.conflicts {
+				if !strings.Contains(table.conflicts[i].Error(), want) {
+					t.Errorf("conflict %d = %v, want it to contain %q", i, table.conflicts[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateRejectsDuplicateRoutes(t *testing.T) {
+	useGlobalRouter(t)
+	registerRouteModules(t, map[string][2]string{
+		"t_first":  {"/dup", "first"},
+		"t_second": {"/dup", "second"},
+		"t_other":  {"/other", "other"},
+	})
+	// 先应用一份合法配置，冲突的重载被拒绝后应继续使用它
+	if err := rebuildRouter(context.Background(), Config{Modules: []string{"t_first", "t_other"}}); err != nil {
+		t.Fatal(err)
+	}
+	err := rebuildRouter(context.Background(), Config{Modules: []string{"t_first", "t_second"}})
+	if err == nil || !strings.Contains(err.Error(), "route conflict: GET /dup") {
+		t.Fatalf("err = %v, want a route conflict", err)
+	}
+	front := frontHandler(ServerConfig{}, false, "")
+	w := httptest.NewRecorder()
+	front.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "other" {
+		t.Errorf("GET /other after rejected reload = %d %q, want the previous router", w.Code, w.Body)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []string
+		configs map[string]map[string]any
+		owner   string
+		route   routeEntry
+	}{
+		{"order route", []string{"order"}, nil, "order", routeEntry{http.MethodGet, "/order"}},
+		{"prefixed route", []string{"order"}, map[string]map[string]any{"order": {"prefix": "/shop"}}, "order", routeEntry{http.MethodGet, "/shop/order"}},
+		{"alias instance", []string{"order@eu"}, nil, "order@eu", routeEntry{http.MethodGet, "/eu/order"}},
+		{"admin endpoints owned by manager", []string{"order"}, nil, "manager", routeEntry{http.MethodGet, "/admin/routes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: tt.modules, Configs: tt.configs}
+			cfg.Server.AdminToken = "secret"
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/admin/routes", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			var routes map[string][]routeEntry
+			if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+				t.Fatalf("GET /admin/routes = %d %s: %v", w.Code, w.Body, err)
+			}
+			if !slices.Contains(routes[tt.owner], tt.route) {
+				t.Errorf("routes of %s = %v, want %v among them", tt.owner, routes[tt.owner], tt.route)
+			}
+			// 每条路由只记在一个模块名下
+			for owner, entries := range routes {
+				if owner != tt.owner && slices.Contains(entries, tt.route) {
+					t.Errorf("%v also attributed to %s", tt.route, owner)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]any
+		wantErr   string
+		// 路径 -> 期望状态码
+		want map[string]int
+	}{
+		{"path only", map[string]any{"/order": "/v2/orders"}, "", map[string]int{"/v2/orders": http.StatusOK, "/order": http.StatusNotFound}},
+		{"method and path", map[string]any{"GET /order": "/v2/orders"}, "", map[string]int{"/v2/orders": http.StatusOK, "/order": http.StatusNotFound}},
+		{"no overrides", nil, "", map[string]int{"/order": http.StatusOK, "/v2/orders": http.StatusNotFound}},
+		{"unknown route", map[string]any{"/orders": "/v2/orders"}, "route_overrides reference unregistered routes: /orders", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"order"}}
+			if tt.overrides != nil {
+				cfg.Configs = map[string]map[string]any{"order": {"route_overrides": tt.overrides}}
+			}
+			r, err := m.Update(context.Background(), cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Update error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, code := range tt.want {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				if w.Code != code {
+					t.Errorf("GET %s = %d, want %d", path, w.Code, code)
+				}
+				// 改写后的路径仍由 order 的处理函数响应
+				if code == http.StatusOK && !strings.Contains(w.Body.String(), "memory://default") {
+					t.Errorf("GET %s = %s, want the order handler", path, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestNotFoundListsRoutes(t *testing.T) {
+	t.Cleanup(func() { gin.SetMode(gin.TestMode) })
+	on, off := true, false
+	tests := []struct {
+		name     string
+		mode     string
+		override *bool
+		wantList bool
+	}{
+		{"debug lists routes", gin.DebugMode, nil, true},
+		{"release plain 404", gin.ReleaseMode, nil, false},
+		{"enabled in release", gin.ReleaseMode, &on, true},
+		{"disabled in debug", gin.DebugMode, &off, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(tt.mode)
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.ListRoutesOn404 = tt.override
+			var r *routerSet
+			var err error
+			captureStdout(t, func() { r, err = m.Update(context.Background(), cfg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("GET /missing = %d, want 404", w.Code)
+			}
+			if got := strings.Contains(w.Body.String(), "GET /user (user)"); got != tt.wantList {
+				t.Errorf("body = %s, want routes listed = %v", w.Body, tt.wantList)
+			}
+			if !tt.wantList && strings.Contains(w.Body.String(), "/user") {
+				t.Errorf("release 404 body leaks routes: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestRedirectSettings(t *testing.T) {
+	on, off := true, false
+	// 同一个管理器依次重载，设置在每次重建路由时生效
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	tests := []struct {
+		name          string
+		trailingSlash *bool
+		fixedPath     *bool
+		path          string
+		wantCode      int
+		wantLocation  string
+	}{
+		{"trailing slash redirects by default", nil, nil, "/user/", http.StatusMovedPermanently, "/user"},
+		{"trailing slash redirect disabled", &off, nil, "/user/", http.StatusNotFound, ""},
+		{"trailing slash redirect re-enabled", &on, nil, "/user/", http.StatusMovedPermanently, "/user"},
+		{"fixed path off by default", nil, nil, "/USER", http.StatusNotFound, ""},
+		{"fixed path enabled", nil, &on, "/USER", http.StatusMovedPermanently, "/user"},
+		{"exact path unaffected", &off, &off, "/user", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Modules: []string{"user"}}
+			cfg.Server.RedirectTrailingSlash = tt.trailingSlash
+			cfg.Server.RedirectFixedPath = tt.fixedPath
+			var r *routerSet
+			var err error
+			captureStdout(t, func() { r, err = m.Update(context.Background(), cfg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantCode || w.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("GET %s = %d (Location %q), want %d (Location %q)", tt.path, w.Code, w.Header().Get("Location"), tt.wantCode, tt.wantLocation)
+			}
+		})
+	}
+}
+
+// 只含字符串（长度均小于 32）的单层 map 的 MessagePack 编码，键按给定顺序
+func msgpackFixMap(kv ...string) []byte {
+	out := []byte{0x80 | byte(len(kv)/2)}
+	for _, s := range kv {
+		out = append(out, 0xa0|byte(len(s)))
+		out = append(out, s...)
+	}
+	return out
+}
+
+func TestRespondNegotiatesFormat(t *testing.T) {
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	var r *routerSet
+	var err error
+	captureStdout(t, func() { r, err = m.Update(context.Background(), Config{Modules: []string{"user"}}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	const greeting = "Hello from user (default)"
+	tests := []struct {
+		accept   string
+		wantType string
+		wantBody []byte
+	}{
+		{"", "application/json", []byte(`{"msg":"` + greeting + `"}`)},
+		{"*/*", "application/json", []byte(`{"msg":"` + greeting + `"}`)},
+		{"application/json", "application/json", []byte(`{"msg":"` + greeting + `"}`)},
+		{"application/msgpack", "application/msgpack", msgpackFixMap("msg", greeting)},
+		{"application/x-msgpack", "application/msgpack", msgpackFixMap("msg", greeting)},
+	}
+	for _, tt := range tests {
+		t.Run("accept "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /user = %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantType)
+			}
+			if !bytes.Equal(w.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body = %q, want %q", w.Body.Bytes(), tt.wantBody)
+			}
+		})
+	}
+}
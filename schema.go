@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 模块配置中由管理器识别的通用配置项
+var managedConfigSchema = map[string]any{
+	"enabled":      map[string]any{"type": "boolean", "description": "false 时临时禁用模块"},
+	"init_retries": map[string]any{"type": "integer", "minimum": 0},
+	"init_backoff": durationSchema("Init 重试的初始退避时间"),
+	"init_timeout": durationSchema("Init（含重试）的总超时"),
+	"log_level":    map[string]any{"enum": []string{"debug", "info", "warn", "error"}},
+	"host":         map[string]any{"type": "string", "description": "只响应该 Host 的请求"},
+	"tags":         map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+	"acl": map[string]any{
+		"type":        "object",
+		"description": `路由访问控制，键为 "[METHOD ]path"（相对模块路由组），值为允许的角色（字符串或列表），由认证中间件提供角色`,
+		"additionalProperties": map[string]any{
+			"type":  []string{"string", "array"},
+			"items": map[string]any{"type": "string"},
+		},
+	},
+	"route_overrides": map[string]any{
+		"type":                 "object",
+		"description":          `改写模块注册的路由，键与值为 "[METHOD ]path"（相对模块路由组）`,
+		"additionalProperties": map[string]any{"type": "string"},
+	},
+	"wait_for":           map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Init 前等待可连接的 TCP 端点 host:port"},
+	"wait_for_timeout":   durationSchema("等待 wait_for 端点的总时长，默认 30s"),
+	"request_timeout":    durationSchema("单个请求的处理时限，超时取消请求的 context 并返回 504，默认不限制"),
+	"warmup_timeout":     durationSchema("Warmup 的时限，默认 10s"),
+	"max_inflight":       map[string]any{"type": "integer", "minimum": 0, "description": "模块路由的并发上限，0 表示不限制"},
+	"max_queue":          map[string]any{"type": "integer", "minimum": 0, "description": "达到并发上限时排队等待的请求数，默认等于 max_inflight"},
+	"queue_timeout":      durationSchema("排队等待的最长时间，默认 1s，超时返回 503"),
+	"max_procs":          map[string]any{"type": "integer", "minimum": 0, "description": "module.PoolFromConfig 创建的任务池的并发上限，默认 GOMAXPROCS"},
+	"use":                map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "按顺序应用的中间件链，链在顶层 chains 中定义"},
+	"prefix":             map[string]any{"type": "string", "description": "模块路由的挂载前缀，规范化为 /xxx 形式，不得与其他模块的前缀重叠；默认别名实例为 /<别名>、其余为根路径。balance 转发时仍按默认前缀"},
+	"listener":           map[string]any{"type": "string", "description": "只在 server.listeners 中该名称的监听上提供模块路由，其他监听返回 404；默认在全部监听上提供"},
+	"condition":          map[string]any{"type": "string", "description": "加载条件，如 APP_ENV == dev；为假时模块不加载，与 enabled: false 相同。支持 ==、!=、!、&&、|| 与括号，profile 表示当前配置 profile"},
+	"debug_io":           map[string]any{"type": "boolean", "description": "记录本模块每个请求的请求体与响应体（按 server.redact_pattern 隐藏敏感字段），仅用于排查问题"},
+	"debug_io_max_bytes": map[string]any{"type": "integer", "minimum": 0, "description": "debug_io 每个请求体 / 响应体最多记录的字节数，默认 4096"},
+}
+
+// 时长既可写成 "500ms" 也可写成秒数
+func durationSchema(desc string) map[string]any {
+	return map[string]any{"type": []string{"string", "number"}, "description": desc}
+}
+
+// schema 子命令：输出 config.yaml 的 JSON Schema，各模块的配置位于 configs.<模块名>
+func runSchema() {
+	data, _ := json.MarshalIndent(configSchema(), "", "  ")
+	fmt.Println(string(data))
+}
+
+func configSchema() map[string]any {
+	names := make([]string, 0, len(registry.Modules))
+	for name := range registry.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// 别名实例（如 order@primary）与其类型共用同一份 schema
+	configs := make(map[string]any, len(names))
+	aliases := make(map[string]any, len(names))
+	for _, name := range names {
+		schema := moduleSchema(registry.Modules[name]())
+		configs[name] = schema
+		aliases["^"+regexp.QuoteMeta(name)+"@[^@]+$"] = schema
+	}
+	modulePattern := "^(" + strings.Join(names, "|") + ")(@[^@]+)?$"
+
+	return map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "myapp config",
+		"type":    "object",
+		"properties": map[string]any{
+			"include": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"modules": map[string]any{"type": "array", "items": map[string]any{"type": "string", "pattern": modulePattern}},
+			"configs": map[string]any{"type": "object", "properties": configs, "patternProperties": aliases},
+		},
+	}
+}
+
+// 合并模块自身的 schema 与通用配置项；未实现 SchemaProvider 的模块只包含通用项
+func moduleSchema(mod module.Module) map[string]any {
+	schema := map[string]any{"type": "object"}
+	props := make(map[string]any, len(managedConfigSchema))
+	for k, v := range managedConfigSchema {
+		props[k] = v
+	}
+	if sp, ok := mod.(module.SchemaProvider); ok {
+		own := sp.ConfigSchema()
+		for k, v := range own {
+			schema[k] = v
+		}
+		ownProps, _ := own["properties"].(map[string]any)
+		for k, v := range ownProps {
+			props[k] = v
+		}
+	}
+	schema["properties"] = props
+	return schema
+}
+
+// 按 moduleSchema 检查一个模块的配置块，返回发现的问题（按键名排序）：提供 schema 的模块不得有未知键、
+// 补上 Defaults() 后须包含 required 中的键，各值须符合 type / enum / minimum / items / additionalProperties
+func validateModuleConfig(mod module.Module, values map[string]any) []string {
+	schema := moduleSchema(mod)
+	props, _ := schema["properties"].(map[string]any)
+	_, hasSchema := mod.(module.SchemaProvider)
+
+	var problems []string
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prop, ok := props[key].(map[string]any)
+		if !ok {
+			if hasSchema {
+				problems = append(problems, fmt.Sprintf("%s: unknown key", key))
+			}
+			continue
+		}
+		problems = append(problems, validateValue(key, prop, values[key])...)
+	}
+	effective := module.WithDefaults(mod, values)
+	for _, key := range schemaStrings(schema["required"]) {
+		if _, ok := effective[key]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: required", key))
+		}
+	}
+	return problems
+}
+
+func validateValue(path string, schema map[string]any, v any) []string {
+	if types := schemaStrings(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasJSONType(t, v) }) {
+		return []string{fmt.Sprintf("%s: expected %s, got %T", path, strings.Join(types, " or "), v)}
+	}
+	if enum := schemaValues(schema["enum"]); len(enum) > 0 && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return []string{fmt.Sprintf("%s: must be one of %v", path, enum)}
+	}
+	if min, ok := toFloat(schema["minimum"]); ok {
+		if f, ok := toFloat(v); ok && f < min {
+			return []string{fmt.Sprintf("%s: must be >= %v", path, schema["minimum"])}
+		}
+	}
+	var problems []string
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, e := range schemaValues(v) {
+			problems = append(problems, validateValue(fmt.Sprintf("%s[%d]", path, i), items, e)...)
+		}
+	}
+	if extra, ok := schema["additionalProperties"].(map[string]any); ok {
+		if m, ok := v.(map[string]any); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				problems = append(problems, validateValue(path+"."+k, extra, m[k])...)
+			}
+		}
+	}
+	return problems
+}
+
+// 值是否属于 JSON Schema 类型 t；JSON 解码得到的整数值 float64 也视为 integer
+func hasJSONType(t string, v any) bool {
+	switch t {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "integer":
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "array":
+		return schemaValues(v) != nil
+	case "object":
+		switch v.(type) {
+		case map[string]any, module.ModuleConfig:
+			return true
+		}
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// schema 中的字符串或字符串列表（type、required 可以是 []string 或 JSON 解码得到的 []any）
+func schemaStrings(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []any:
+		out := make([]string, 0, len(s))
+		for _, e := range s {
+			if str, ok := e.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// 列表值（[]any 或 []string）的元素，不是列表时返回 nil
+func schemaValues(v any) []any {
+	switch s := v.(type) {
+	case []any:
+		return s
+	case []string:
+		out := make([]any, len(s))
+		for i, e := range s {
+			out[i] = e
+		}
+		return out
+	}
+	return nil
+}
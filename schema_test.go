@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSchemaCommand(t *testing.T) {
+	out := captureStdout(t, runSchema)
+	var doc struct {
+		Properties struct {
+			Configs struct {
+				Properties map[string]struct {
+					Required   []string                  `json:"required"`
+					Properties map[string]map[string]any `json:"properties"`
+				} `json:"properties"`
+			} `json:"configs"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("schema output is not JSON: %v\n%s", err, out)
+	}
+	configs := doc.Properties.Configs.Properties
+
+	tests := []struct {
+		module   string
+		key      string
+		wantType any
+		required bool
+	}{
+		{"order", "dsn", "string", true},
+		// 通用配置项出现在每个模块中，但不是必填
+		{"order", "enabled", "boolean", false},
+		{"user", "max_inflight", "integer", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module+"/"+tt.key, func(t *testing.T) {
+			schema, ok := configs[tt.module]
+			if !ok {
+				t.Fatalf("no schema for module %s", tt.module)
+			}
+			prop, ok := schema.Properties[tt.key]
+			if !ok {
+				t.Fatalf("schema of %s has no %s property", tt.module, tt.key)
+			}
+			if prop["type"] != tt.wantType {
+				t.Errorf("type = %v, want %v", prop["type"], tt.wantType)
+			}
+			if got := slices.Contains(schema.Required, tt.key); got != tt.required {
+				t.Errorf("required = %v, want %v (required: %v)", got, tt.required, schema.Required)
+			}
+		})
+	}
+}
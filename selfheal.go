@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	maxSelfHealInterval = 5 * time.Minute
+	// 未开启自愈时重新检查配置的间隔，使重载后设置的 self_heal_interval 生效
+	selfHealIdleCheck = time.Minute
+)
+
+// 后台自愈：按 server.self_heal_interval 以上次应用的配置重建路由。配置未变的激活模块原样复用，
+// 只有未激活的模块重新创建并初始化，外部依赖恢复后这些模块无需修改配置即可上线
+func selfHeal() {
+	var backoff time.Duration
+	for {
+		interval := manager.EffectiveConfig().Server.SelfHealInterval
+		if interval <= 0 {
+			backoff = 0
+			time.Sleep(selfHealIdleCheck)
+			continue
+		}
+		if backoff < interval {
+			backoff = interval
+		}
+		time.Sleep(backoff)
+		if healed := reconcileModules(context.Background()); healed {
+			backoff = interval
+		} else {
+			backoff = min(backoff*2, max(maxSelfHealInterval, interval))
+		}
+	}
+}
+
+// 重试一次未激活的模块，返回之后是否所有启用的模块均已激活。
+// 在 reloadLock 内读取配置，避免用旧配置覆盖同时进行的重载
+func reconcileModules(ctx context.Context) bool {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	cfg, inactive := manager.inactiveModules()
+	if len(inactive) == 0 {
+		return true
+	}
+	fmt.Println("Self-heal: retrying inactive modules", inactive)
+	if err := rebuildLocked(ctx, cfg); err != nil {
+		return false
+	}
+	if _, still := manager.inactiveModules(); len(still) > 0 {
+		fmt.Println("Self-heal: modules still inactive", still)
+		return false
+	}
+	fmt.Println("Self-heal: all modules active")
+	return true
+}
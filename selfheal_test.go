@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 前 failures 次 Init 失败，之后成功；attempts 统计 Init 次数
+type recoveringModule struct {
+	module.Base
+	failures int64
+	attempts *atomic.Int64
+}
+
+func (m *recoveringModule) Init(module.ModuleConfig) error {
+	if m.attempts.Add(1) <= m.failures {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+func (m *recoveringModule) RegisterRoutes(r gin.IRouter) {
+	r.GET("/flaky", func(c *gin.Context) { c.String(http.StatusOK, "flaky") })
+}
+
+func TestReconcileModules(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	t.Cleanup(func() { delete(registry.Modules, "t_flaky") })
+	tests := []struct {
+		name         string
+		failures     int64
+		ticks        []bool // 每次 reconcileModules 的返回值
+		wantActive   []string
+		wantAttempts int64
+	}{
+		{"nothing to heal", 0, []bool{true}, []string{"t_a", "t_flaky"}, 1},
+		{"recovers on the first tick", 1, []bool{true}, []string{"t_a", "t_flaky"}, 2},
+		{"recovers on a later tick", 3, []bool{false, false, true, true}, []string{"t_a", "t_flaky"}, 4},
+		{"still failing", 10, []bool{false, false}, []string{"t_a"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int64
+			registry.Modules["t_flaky"] = func() module.Module { return &recoveringModule{failures: tt.failures, attempts: &attempts} }
+			useGlobalRouter(t)
+			cfg := Config{Modules: []string{"t_a", "t_flaky"}}
+			if err := rebuildRouter(context.Background(), cfg); err != nil {
+				t.Fatal(err)
+			}
+			var got []bool
+			for range tt.ticks {
+				got = append(got, reconcileModules(context.Background()))
+			}
+			if !slices.Equal(got, tt.ticks) {
+				t.Errorf("reconcile results = %v, want %v", got, tt.ticks)
+			}
+			if active := manager.ActiveModules(); !slices.Equal(active, tt.wantActive) {
+				t.Errorf("active modules = %v, want %v", active, tt.wantActive)
+			}
+			if n := attempts.Load(); n != tt.wantAttempts {
+				t.Errorf("Init attempts = %d, want %d", n, tt.wantAttempts)
+			}
+			// 已激活的模块不会因自愈重新初始化
+			manager.mu.RLock()
+			inits := manager.states["t_a"].InitCount
+			manager.mu.RUnlock()
+			if inits != 1 {
+				t.Errorf("t_a initialized %d times, want 1", inits)
+			}
+			wantCode := http.StatusNotFound
+			if slices.Contains(tt.wantActive, "t_flaky") {
+				wantCode = http.StatusOK
+			}
+			w := httptest.NewRecorder()
+			frontHandler(cfg.Server, false, "").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flaky", nil))
+			if w.Code != wantCode {
+				t.Errorf("GET /flaky = %d, want %d", w.Code, wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 每个监听地址一个 http.Server，同名监听共享同一 handler；关闭时同时停止全部 server。
+// 监听由 handoffListener 持有而不属于某个 server，重载时可以换上新 server 而不释放端口
+type serverGroup struct {
+	handlers  map[string]http.Handler // 监听名称 -> 入口 handler，listen / addr 的监听名称为空
+	listeners []*handoffListener
+	errs      chan error
+	closing   chan struct{}
+	closeOnce sync.Once
+	retiring  sync.WaitGroup // 被替换、仍在处理已有连接的旧 server
+
+	mu       sync.Mutex
+	settings httpSettings
+	tls      *tls.Config
+	servers  []*http.Server
+}
+
+// 当前 server 组，由 main 在监听后设置；重载时据此应用新的 server 配置
+var httpServers atomic.Pointer[serverGroup]
+
+// http.Server 层面的配置：与当前值不同时，重载会在原有监听上替换 server
+type httpSettings struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	certFile          string
+	keyFile           string
+}
+
+func httpSettingsOf(server ServerConfig) httpSettings {
+	s := httpSettings{
+		readTimeout:       server.ReadTimeout,
+		readHeaderTimeout: server.ReadHeaderTimeout,
+		writeTimeout:      server.WriteTimeout,
+		idleTimeout:       server.IdleTimeout,
+		maxHeaderBytes:    server.MaxHeaderBytes,
+	}
+	if server.TLS != nil {
+		s.certFile, s.keyFile = server.TLS.CertFile, server.TLS.KeyFile
+	}
+	return s
+}
+
+// 按 server.tls 创建 TLS 配置，未配置证书时返回 nil（明文 HTTP）
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg == nil || cfg.CertFile == "" {
+		return nil, nil
+	}
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	fmt.Println("HTTPS enabled with certificate", cfg.CertFile)
+	return &tls.Config{GetCertificate: reloader.GetCertificate}, nil
+}
+
+// 按地址逐个监听并创建对应的 server；任一地址监听失败时关闭已打开的监听并返回错误。
+// 配置了 server.unix_socket 时只监听该 socket。handlerFor 按监听名称创建入口 handler，每个名称调用一次
+func listenAll(server ServerConfig, addrs []string, handlerFor func(listener string) http.Handler) (*serverGroup, error) {
+	if server.UnixSocket != "" {
+		addrs = []string{server.UnixSocket}
+	}
+	tlsConfig, err := newTLSConfig(server.TLS)
+	if err != nil {
+		return nil, err
+	}
+	g := &serverGroup{
+		handlers: map[string]http.Handler{"": handlerFor("")},
+		closing:  make(chan struct{}),
+		errs:     make(chan error, len(addrs)),
+		settings: httpSettingsOf(server),
+		tls:      tlsConfig,
+	}
+	for _, addr := range addrs {
+		ln, err := listenOne(server, addr)
+		if err != nil {
+			for _, l := range g.listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		g.listeners = append(g.listeners, newHandoffListener(ln, ""))
+		g.servers = append(g.servers, g.newServer(addr, ""))
+	}
+	if server.UnixSocket == "" {
+		for _, name := range listenerNames(server) {
+			addr := server.Listeners[name]
+			ln, err := listenOne(server, addr)
+			if err != nil {
+				for _, l := range g.listeners {
+					l.Close()
+				}
+				return nil, fmt.Errorf("listener %s: %w", name, err)
+			}
+			g.handlers[name] = handlerFor(name)
+			g.listeners = append(g.listeners, newHandoffListener(ln, name))
+			g.servers = append(g.servers, g.newServer(addr, name))
+		}
+	}
+	return g, nil
+}
+
+func listenOne(server ServerConfig, addr string) (net.Listener, error) {
+	if server.UnixSocket == "" {
+		// IPv6 地址须带方括号，如 [::1]:8080；裸地址给出明确提示而不是 "too many colons"
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			if net.ParseIP(addr) != nil {
+				return nil, fmt.Errorf("listen address %q: IPv6 addresses need brackets and a port, e.g. [%s]:8080", addr, addr)
+			}
+			return nil, fmt.Errorf("listen address %q: %w", addr, err)
+		}
+	}
+	return listen(server, addr)
+}
+
+// 按当前 settings 与 TLS 配置创建 server，调用方持有 mu（或 g 尚未发布）；
+// name 为具名监听的名称，server 使用该监听的 handler，请求只会到达该监听的路由
+func (g *serverGroup) newServer(addr, name string) *http.Server {
+	s := g.settings
+	return &http.Server{
+		Addr:              addr,
+		Handler:           g.handlers[name],
+		TLSConfig:         g.tls,
+		MaxHeaderBytes:    s.maxHeaderBytes,
+		ReadTimeout:       s.readTimeout,
+		ReadHeaderTimeout: s.readHeaderTimeout,
+		WriteTimeout:      s.writeTimeout,
+		IdleTimeout:       s.idleTimeout,
+	}
+}
+
+// 在 ln 上启动 srv；非 ErrServerClosed 的错误交给 serve 处理
+func (g *serverGroup) start(srv *http.Server, ln *handoffListener) {
+	tlsEnabled := srv.TLSConfig != nil
+	go func() {
+		var err error
+		if tlsEnabled {
+			err = srv.ServeTLS(ln.view(), "", "")
+		} else {
+			err = srv.Serve(ln.view())
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case g.errs <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// 在各自的监听上启动全部 server，直到开始关闭；任一 server 出错时整体关闭并返回该错误
+func (g *serverGroup) serve() error {
+	g.mu.Lock()
+	for i, srv := range g.servers {
+		fmt.Println("Listening on", g.listeners[i].Addr())
+		g.start(srv, g.listeners[i])
+	}
+	g.mu.Unlock()
+	select {
+	case err := <-g.errs:
+		go g.shutdown(0)
+		return err
+	case <-g.closing:
+		return nil
+	}
+}
+
+// 应用重载后的 server 配置：超时、max_header_bytes 或证书路径变化时，在原有监听上启动新 server，
+// 旧 server 停止接收新连接并在后台处理完已有连接（最长 shutdown_timeout）。端口始终保持绑定
+func (g *serverGroup) apply(server ServerConfig) {
+	s := httpSettingsOf(server)
+	g.mu.Lock()
+	select {
+	case <-g.closing:
+		g.mu.Unlock()
+		return
+	default:
+	}
+	if s == g.settings {
+		g.mu.Unlock()
+		return
+	}
+	tlsConfig, err := newTLSConfig(server.TLS)
+	if err != nil {
+		g.mu.Unlock()
+		fmt.Println("Keeping current HTTP servers:", err)
+		return
+	}
+	g.settings, g.tls = s, tlsConfig
+	old := g.servers
+	g.servers = nil
+	for i, ln := range g.listeners {
+		srv := g.newServer(old[i].Addr, ln.name)
+		g.servers = append(g.servers, srv)
+		g.start(srv, ln)
+	}
+	g.retiring.Add(1)
+	g.mu.Unlock()
+	fmt.Println("HTTP server settings changed, replaced servers on existing listeners")
+
+	go func() {
+		defer g.retiring.Done()
+		shutdownServers(old, server.shutdownTimeout())
+	}()
+}
+
+// 关闭监听并对全部 server 做优雅关闭，各 server 并行等待，最长 timeout
+func (g *serverGroup) shutdown(timeout time.Duration) {
+	g.closeOnce.Do(func() { close(g.closing) })
+	g.mu.Lock()
+	servers := g.servers
+	g.mu.Unlock()
+	for _, ln := range g.listeners {
+		ln.Close()
+	}
+	shutdownServers(servers, timeout)
+	g.retiring.Wait()
+}
+
+func shutdownServers(servers []*http.Server, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			shutdownServer(srv, timeout)
+		}(srv)
+	}
+	wg.Wait()
+}
+
+// 由 server 组持有的监听：单独的 goroutine 接受连接，交给当前在其 view 上 Accept 的 server。
+// server 关闭时只关闭自己的 view，底层监听在 Close 时才释放
+type handoffListener struct {
+	net.Listener
+	name      string // server.listeners 中的名称，listen / addr 的监听为空
+	conns     chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newHandoffListener(ln net.Listener, name string) *handoffListener {
+	h := &handoffListener{Listener: ln, name: name, conns: make(chan acceptResult), done: make(chan struct{})}
+	go h.acceptLoop()
+	return h
+}
+
+// 临时错误（如 EMFILE）同样转交给 server，由 http.Server 退避重试；底层监听关闭后退出
+func (h *handoffListener) acceptLoop() {
+	for {
+		conn, err := h.Listener.Accept()
+		select {
+		case h.conns <- acceptResult{conn, err}:
+		case <-h.done:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+	}
+}
+
+func (h *handoffListener) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.done)
+		h.closeErr = h.Listener.Close()
+	})
+	return h.closeErr
+}
+
+func (h *handoffListener) view() net.Listener {
+	return &listenerView{h: h, closed: make(chan struct{})}
+}
+
+// 交给一个 server 使用的监听视图，Close 只让该 server 停止 Accept
+type listenerView struct {
+	h         *handoffListener
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (v *listenerView) Accept() (net.Conn, error) {
+	select {
+	case r := <-v.h.conns:
+		return r.conn, r.err
+	case <-v.closed:
+		return nil, net.ErrClosed
+	case <-v.h.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (v *listenerView) Close() error {
+	v.closeOnce.Do(func() { close(v.closed) })
+	return nil
+}
+
+func (v *listenerView) Addr() net.Addr { return v.h.Addr() }
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"myapp/module"
+	"myapp/registry"
+)
+
+// 测试用服务提供方：Provide 以实例名发布一个新的 token，Shutdown 后 token 失效
+type svcToken struct {
+	owner string
+	dead  atomic.Bool
+}
+
+type svcProvider struct {
+	module.Base
+	name   string
+	token  *svcToken
+	events *testEvents
+}
+
+func (m *svcProvider) SetName(name string) { m.name = name }
+
+func (m *svcProvider) Provide(reg *module.ServiceRegistry) {
+	m.events.add("provide " + m.name)
+	m.token = &svcToken{owner: m.name}
+	reg.Provide(m.name, m.token)
+}
+
+func (m *svcProvider) Init(cfg module.ModuleConfig) error {
+	m.events.add("init " + m.name)
+	return nil
+}
+
+func (m *svcProvider) Shutdown() error {
+	m.token.dead.Store(true)
+	return nil
+}
+
+// 测试用服务使用方：在 Init 中读取依赖发布的 token
+type svcUser struct {
+	module.Base
+	dep      string
+	services *module.ServiceRegistry
+	token    *svcToken
+	events   *testEvents
+}
+
+func (m *svcUser) Deps() []string { return []string{m.dep} }
+
+func (m *svcUser) Provide(reg *module.ServiceRegistry) { m.services = reg }
+
+func (m *svcUser) Init(cfg module.ModuleConfig) error {
+	m.events.add("init user")
+	m.token, _ = module.Lookup[*svcToken](m.services, m.dep)
+	return nil
+}
+
+// 登记 t_prov、t_prov@b 两个提供方实例与依赖 dep 的 t_user，返回共享的 events 与最近创建的使用方
+func registerServiceModules(t *testing.T, dep string) (*testEvents, func() *svcUser) {
+	t.Helper()
+	events := &testEvents{}
+	var last atomic.Pointer[svcUser]
+	registry.Modules["t_prov"] = func() module.Module { return &svcProvider{events: events} }
+	registry.Modules["t_user"] = func() module.Module {
+		u := &svcUser{dep: dep, events: events}
+		last.Store(u)
+		return u
+	}
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_prov")
+		delete(registry.Modules, "t_user")
+	})
+	return events, last.Load
+}
+
+func TestProvideRunsBeforeInit(t *testing.T) {
+	events, user := registerServiceModules(t, "t_prov@b")
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	if _, err := m.Update(context.Background(), Config{Modules: []string{"t_prov", "t_prov@b", "t_user"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := events.with("")
+	lastProvide, firstInit := -1, len(got)
+	for i, ev := range got {
+		switch ev[:4] {
+		case "prov":
+			lastProvide = i
+		case "init":
+			firstInit = min(firstInit, i)
+		}
+	}
+	if lastProvide > firstInit {
+		t.Errorf("a module was initialized before every Provide ran: %v", got)
+	}
+	// 同一类型的两个实例以各自的实例名发布，互不覆盖
+	if tok := user().token; tok == nil || tok.owner != "t_prov@b" {
+		t.Errorf("user got token %+v, want the one published by t_prov@b", tok)
+	}
+}
+
+func TestRecreatedProviderRebuildsDependents(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(cfg *Config) // 在第二次 Update 之前修改配置
+		reinit      []string
+		wantRebuilt bool
+	}{
+		{"provider config changed", func(cfg *Config) { cfg.Configs["t_prov"] = map[string]any{"ttl": "2m"} }, nil, true},
+		{"provider reinit", func(*Config) {}, []string{"t_prov"}, true},
+		{"unrelated provider changed", func(cfg *Config) { cfg.Configs["t_prov@b"] = map[string]any{"ttl": "2m"} }, nil, false},
+		{"nothing changed", func(*Config) {}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, user := registerServiceModules(t, "t_prov")
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			ctx := context.Background()
+			cfg := Config{
+				Modules: []string{"t_prov", "t_prov@b", "t_user"},
+				Configs: map[string]map[string]any{"t_prov": {"ttl": "1m"}},
+			}
+			if _, err := m.Update(ctx, cfg); err != nil {
+				t.Fatal(err)
+			}
+			first := user()
+			next := cfg
+			next.Configs = map[string]map[string]any{"t_prov": {"ttl": "1m"}}
+			tt.change(&next)
+			if _, err := m.Update(ctx, next, tt.reinit...); err != nil {
+				t.Fatal(err)
+			}
+			m.StopRetired(0)
+			if rebuilt := user() != first; rebuilt != tt.wantRebuilt {
+				t.Fatalf("user rebuilt = %v, want %v", rebuilt, tt.wantRebuilt)
+			}
+			if tok := user().token; tok == nil || tok.dead.Load() {
+				t.Errorf("user holds token %+v from a shut down provider", tok)
+			}
+		})
+	}
+}
+
+// 复现：修改 cache 的 ttl 后，复用的 order 不能继续写入已关闭的旧 Store
+func TestOrderUsesRecreatedCache(t *testing.T) {
+	m := NewModuleManager()
+	defer m.ShutdownAll(0)
+	ctx := context.Background()
+	cfg := Config{
+		Modules: []string{"auth", "cache", "order"},
+		Configs: map[string]map[string]any{"cache": {"ttl": "1m"}, "order": {"dsn": "memory://t"}},
+	}
+	cacheItems := func(set *routerSet) float64 {
+		t.Helper()
+		w := httptest.NewRecorder()
+		set.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("/order: %d %s", w.Code, w.Body)
+		}
+		w = httptest.NewRecorder()
+		set.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cache", nil))
+		var body struct {
+			Items float64 `json:"items"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Items
+	}
+	for i, ttl := range []string{"1m", "2m", "3m"} {
+		cfg.Configs = map[string]map[string]any{"cache": {"ttl": ttl}, "order": {"dsn": "memory://t"}}
+		set, err := m.Update(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.StopRetired(0)
+		if got := cacheItems(set); got != 1 {
+			t.Errorf("update %d (ttl %s): /cache items = %v after /order, want 1 (order wrote to a stale store)", i+1, ttl, got)
+		}
+	}
+	if got := m.ActiveModules(); !slices.Equal(got, []string{"auth", "cache", "order"}) {
+		t.Errorf("active = %v", got)
+	}
+}
+
+// 测试用服务导出方：Init 时按配置 label 创建 token，label 为空时不导出
+type svcExporter struct {
+	module.Base
+	token *svcToken
+}
+
+func (m *svcExporter) Init(cfg module.ModuleConfig) error {
+	if label := cfg.GetString("label", ""); label != "" {
+		m.token = &svcToken{owner: label}
+	}
+	return nil
+}
+
+func (m *svcExporter) Services() map[string]any {
+	if m.token == nil {
+		return nil
+	}
+	return map[string]any{"token": m.token}
+}
+
+// 测试用注入方：记录 Init 之前注入的服务
+type svcConsumer struct {
+	module.Base
+	dep   string
+	token *svcToken
+}
+
+func (m *svcConsumer) Deps() []string { return []string{m.dep} }
+
+func (m *svcConsumer) Inject(s module.Injected) {
+	m.token, _ = module.Service[*svcToken](s, "t_exp", "token")
+}
+
+func TestInjectExportedServices(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil})
+	registry.Modules["t_exp"] = func() module.Module { return &svcExporter{} }
+	t.Cleanup(func() {
+		delete(registry.Modules, "t_exp")
+		delete(registry.Modules, "t_cons")
+	})
+	tests := []struct {
+		name   string
+		dep    string
+		labels []string // 每次 Update 时 t_exp 的 label
+		want   string   // 最后 t_cons 收到的 token，空表示未注入
+	}{
+		{"injects dependency service", "t_exp", []string{"v1"}, "v1"},
+		// t_exp 重新创建后 t_cons 一并重新创建，拿到新的服务对象
+		{"recreated exporter re-injects", "t_exp", []string{"v1", "v2"}, "v2"},
+		{"nothing exported", "t_exp", []string{""}, ""},
+		{"only dependencies are injected", "t_a", []string{"v1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry.Modules["t_cons"] = func() module.Module { return &svcConsumer{dep: tt.dep} }
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			for _, label := range tt.labels {
+				cfg := Config{
+					Modules: []string{"t_a", "t_exp", "t_cons"},
+					Configs: map[string]map[string]any{"t_exp": {"label": label}},
+				}
+				if _, err := m.Update(context.Background(), cfg); err != nil {
+					t.Fatal(err)
+				}
+				m.StopRetired(0)
+			}
+			m.mu.RLock()
+			consumer := m.active["t_cons"].(*svcConsumer)
+			exporter := m.active["t_exp"].(*svcExporter)
+			m.mu.RUnlock()
+			var got string
+			if consumer.token != nil {
+				got = consumer.token.owner
+				if consumer.token != exporter.token {
+					t.Error("t_cons holds a service object the active t_exp no longer exports")
+				}
+			}
+			if got != tt.want {
+				t.Errorf("injected token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"myapp/module"
+)
+
+const defaultModuleShutdownBudget = 30 * time.Second
+
+// 一批模块共享的关闭预算：每个模块至少保证 total/n（公平份额），前面的模块未用完的时间顺延给后面的模块。
+// 轮到某个模块时，可用时间为剩余预算减去为其后每个模块保留的公平份额
+type shutdownBudget struct {
+	total time.Duration
+	fair  time.Duration
+	spent time.Duration
+}
+
+func newShutdownBudget(total time.Duration, n int) *shutdownBudget {
+	b := &shutdownBudget{total: total}
+	if n > 0 {
+		b.fair = total / time.Duration(n)
+	}
+	return b
+}
+
+// 还剩 remaining 个模块（含当前）时当前模块的可用时间
+func (b *shutdownBudget) allot(remaining int) time.Duration {
+	return b.total - b.spent - time.Duration(remaining-1)*b.fair
+}
+
+// 在预算内关闭模块：依次停止后台任务、关闭总线客户端、调用 ShutdownContext（或 Shutdown）。
+// 超出可用时间时不再等待，返回错误，继续关闭其余模块；超出公平份额时打印提示
+func (b *shutdownBudget) shutdown(r retiredModule, remaining int) error {
+	allotted := b.allot(remaining)
+	ctx, cancel := context.WithTimeout(context.Background(), allotted)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		r.workers.stop()
+		r.bus.Close()
+		if s, ok := r.mod.(module.ContextShutdowner); ok {
+			done <- s.ShutdownContext(ctx)
+		} else {
+			done <- r.mod.Shutdown()
+		}
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("shutdown did not finish within %s", allotted.Round(time.Millisecond))
+	}
+	elapsed := min(time.Since(start), allotted)
+	b.spent += elapsed
+	if elapsed > b.fair {
+		fmt.Printf("Module %s took %s to shut down, over its fair share of %s\n", r.name, elapsed.Round(time.Millisecond), b.fair.Round(time.Millisecond))
+	}
+	return err
+}
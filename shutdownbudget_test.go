@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"myapp/module"
+)
+
+// ShutdownContext 记录收到的剩余时限，然后耗时 delay；cooperative 为 true 时在 ctx 到期后提前返回
+type budgetModule struct {
+	module.Base
+	delay       time.Duration
+	cooperative bool
+	allotted    atomic.Int64 // 关闭超时后仍在运行的 ShutdownContext 可能晚于读取
+}
+
+func (m *budgetModule) ShutdownContext(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		m.allotted.Store(int64(time.Until(deadline)))
+	}
+	if !m.cooperative {
+		time.Sleep(m.delay)
+		return nil
+	}
+	select {
+	case <-time.After(m.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestShutdownBudget(t *testing.T) {
+	const slow = 2 * time.Second
+	tests := []struct {
+		name     string
+		total    time.Duration
+		modules  []*budgetModule // 按关闭顺序
+		allotted []time.Duration // 每个模块收到的时限（允许略少）
+		failed   []bool          // 是否超时
+		overFair []bool          // 是否因超出公平份额打印提示
+	}{
+		{"slow first module", 400 * time.Millisecond,
+			[]*budgetModule{{delay: slow}, {}},
+			[]time.Duration{200 * time.Millisecond, 200 * time.Millisecond}, []bool{true, false}, []bool{false, false}},
+		{"cooperative slow first module", 400 * time.Millisecond,
+			[]*budgetModule{{delay: slow, cooperative: true}, {}},
+			[]time.Duration{200 * time.Millisecond, 200 * time.Millisecond}, []bool{true, false}, []bool{false, false}},
+		// 前面的模块未用完的时间顺延给后面的模块
+		{"unused time carries over", 400 * time.Millisecond,
+			[]*budgetModule{{}, {delay: slow}},
+			[]time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, []bool{false, true}, []bool{false, true}},
+		{"three modules", 300 * time.Millisecond,
+			[]*budgetModule{{delay: slow}, {}, {}},
+			[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond}, []bool{true, false, false}, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := newShutdownBudget(tt.total, len(tt.modules))
+			var failed []bool
+			out := captureStdout(t, func() {
+				for i, mod := range tt.modules {
+					err := budget.shutdown(retiredModule{name: "m" + strconv.Itoa(i), mod: mod}, len(tt.modules)-i)
+					failed = append(failed, err != nil)
+				}
+			})
+			for i, mod := range tt.modules {
+				got, want := time.Duration(mod.allotted.Load()), tt.allotted[i]
+				if got <= 0 || got > want || got < want-60*time.Millisecond {
+					t.Errorf("module %d deadline = %v, want about %v", i, got, want)
+				}
+				if failed[i] != tt.failed[i] {
+					t.Errorf("module %d failed = %v, want %v", i, failed[i], tt.failed[i])
+				}
+				if got := strings.Contains(out, "Module m"+strconv.Itoa(i)+" took"); got != tt.overFair[i] {
+					t.Errorf("module %d fair share warning = %v, want %v (output: %q)", i, got, tt.overFair[i], out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStateSnapshotRoundTrip(t *testing.T) {
+	useGlobalRouter(t)
+	cfg := Config{
+		Modules: []string{"order", "order@primary", "user"},
+		Configs: map[string]map[string]any{"order": {"dsn": "memory://exported"}},
+	}
+	cfg.Server.AdminToken = "secret"
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	exported := manager.ActiveModules()
+
+	// 旧进程的 /admin/state
+	old := httptest.NewServer(frontHandler(cfg.Server, false, ""))
+	defer old.Close()
+	req, _ := http.NewRequest(http.MethodGet, old.URL+"/admin/state", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /admin/state = %d %s (%v)", resp.StatusCode, body, err)
+	}
+	file := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(file, body, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		loc     string
+		token   string
+		wantErr string
+	}{
+		{"from file", file, "", ""},
+		{"from URL", old.URL + "/admin/state", "secret", ""},
+		{"URL with wrong token", old.URL + "/admin/state", "wrong", "401"},
+		{"missing file", filepath.Join(t.TempDir(), "none.json"), "", "config file not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SNAPSHOT_TOKEN", tt.token)
+			src, err := newConfigSource("snapshot:" + tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var imported Config
+			captureStdout(t, func() { imported, err = src.Load() })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 新进程按快照启动，得到相同的激活模块与配置
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			captureStdout(t, func() { _, err = m.Update(context.Background(), imported) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.ActiveModules(); !slices.Equal(got, exported) {
+				t.Errorf("active modules = %v, want %v", got, exported)
+			}
+			if got := m.EffectiveConfig().Configs["order"]["dsn"]; got != "memory://exported" {
+				t.Errorf("order dsn = %v, want memory://exported", got)
+			}
+		})
+	}
+}
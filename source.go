@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 配置来源：文件之外还可以实现 Consul / etcd / HTTP 等远程来源
+type ConfigSource interface {
+	Load() (Config, error)
+	// Watch 在配置变化时把新配置推送到 ch，阻塞直到出错
+	Watch(ch chan<- Config) error
+}
+
+// 按 -source 参数创建配置来源："file"、"file:<path>" 或 "snapshot:<文件或 URL>"
+func newConfigSource(spec string) (ConfigSource, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "file":
+		if arg == "" {
+			arg = configFile
+		}
+		return &FileSource{Path: arg}, nil
+	case "snapshot":
+		if arg == "" {
+			return nil, fmt.Errorf("snapshot source needs a file or URL: snapshot:<location>")
+		}
+		return &SnapshotSource{Location: arg}, nil
+	default:
+		return nil, fmt.Errorf("unknown config source: %s", spec)
+	}
+}
+
+// FileSource 从本地 YAML 文件加载配置，并用 fsnotify 监听该文件及 server.watch 中的额外路径
+type FileSource struct {
+	Path string
+
+	last atomic.Pointer[Config] // 最近一次成功加载的配置
+}
+
+func (s *FileSource) Load() (Config, error) {
+	cfg, err := loadConfigFrom(s.Path)
+	if err == nil {
+		s.last.Store(&cfg)
+	}
+	return cfg, err
+}
+
+// 启动时配置文件可能还没写好（如由 init 容器稍后生成）：不存在时轮询等待，最长 timeout，
+// timeout <= 0 时不等待
+func (s *FileSource) waitForFile(timeout time.Duration) error {
+	_, err := os.Stat(s.Path)
+	if err == nil || timeout <= 0 || !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	fmt.Printf("Config file %s not found, waiting up to %s for it to appear...\n", s.Path, timeout)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(missingFilePollInterval)
+		if _, err := os.Stat(s.Path); err == nil {
+			fmt.Println("Config file appeared:", s.Path)
+			return nil
+		}
+	}
+	return fmt.Errorf("config file %s did not appear within %s", s.Path, timeout)
+}
+
+func (s *FileSource) Watch(ch chan<- Config) error {
+	// 开始监听时文件可能正被改坏：沿用最近一次成功加载的配置确定监听路径，之后的修改照常触发重载
+	cfg, err := s.Load()
+	if err != nil {
+		fmt.Println("Error loading config, watching with the last good config:", err)
+		if last := s.last.Load(); last != nil {
+			cfg = *last
+		}
+	}
+	watched := append([]string{s.Path, filepath.Join(moduleConfigDir(s.Path, cfg), "*.yaml")}, cfg.Server.Watch...)
+	fmt.Println("Watching", strings.Join(watched, ", "), "...")
+
+	return watchPaths(watched, cfg.Server.WatchDebounce, func() {
+		fmt.Println("Config changed, reloading...")
+		newCfg, err := s.Load()
+		if err != nil {
+			fmt.Println("Error loading config:", err)
+			return
+		}
+		ch <- newCfg
+	})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 内存中的配置来源：Watch 把 pushes 中的配置逐个推送出去
+type pushSource struct {
+	initial Config
+	pushes  chan Config
+}
+
+func (s *pushSource) Load() (Config, error) { return s.initial, nil }
+
+func (s *pushSource) Watch(ch chan<- Config) error {
+	for cfg := range s.pushes {
+		ch <- cfg
+	}
+	return nil
+}
+
+func TestWatchConfigAppliesPushedConfigs(t *testing.T) {
+	events := registerTestModules(t, map[string][]string{"t_a": nil, "t_b": nil, "t_c": nil})
+	useGlobalRouter(t)
+	src := &pushSource{initial: Config{Modules: []string{"t_a"}}, pushes: make(chan Config)}
+	defer close(src.pushes)
+	cfg, _ := src.Load()
+	if err := rebuildRouter(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !watchConfig(src, cfg, false) {
+		t.Fatal("watching disabled")
+	}
+
+	tests := []struct {
+		push []string
+		want []string // 推送后激活的模块
+	}{
+		{[]string{"t_a", "t_b"}, []string{"t_a", "t_b"}},
+		{[]string{"t_c"}, []string{"t_c"}},
+	}
+	for _, tt := range tests {
+		src.pushes <- Config{Modules: tt.push}
+		eventually(t, 2*time.Second, "reload to "+tt.push[len(tt.push)-1], func() bool {
+			return slices.Equal(manager.ActiveModules(), tt.want)
+		})
+	}
+	if inits := events.with("init "); !slices.Equal(inits, []string{"t_a", "t_b", "t_c"}) {
+		t.Errorf("inits = %v, want each pushed config applied once", inits)
+	}
+}
+
+func TestNewConfigSource(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ConfigSource
+		wantErr bool
+	}{
+		{"file", &FileSource{Path: configFile}, false},
+		{"file:/etc/app/config.yaml", &FileSource{Path: "/etc/app/config.yaml"}, false},
+		{"snapshot:state.json", &SnapshotSource{Location: "state.json"}, false},
+		{"snapshot:", nil, true},
+		{"consul:kv/app", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := newConfigSource(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch want := tt.want.(type) {
+			case *FileSource:
+				if fs, ok := got.(*FileSource); !ok || fs.Path != want.Path {
+					t.Errorf("source = %#v, want FileSource %s", got, want.Path)
+				}
+			case *SnapshotSource:
+				if ss, ok := got.(*SnapshotSource); !ok || ss.Location != want.Location {
+					t.Errorf("source = %#v, want SnapshotSource %s", got, want.Location)
+				}
+			}
+		})
+	}
+}
+
+// 记录 Watch 是否被调用的配置来源，Watch 阻塞到 stop 关闭
+type recordingSource struct {
+	watched chan struct{}
+	stop    chan struct{}
+}
+
+func (s *recordingSource) Load() (Config, error) { return Config{}, nil }
+
+func (s *recordingSource) Watch(ch chan<- Config) error {
+	close(s.watched)
+	<-s.stop
+	return nil
+}
+
+func TestWatchConfigDisabled(t *testing.T) {
+	off, on := false, true
+	tests := []struct {
+		name    string
+		noWatch bool // -no-watch
+		enabled *bool
+		want    bool
+	}{
+		{"watching by default", false, nil, true},
+		{"explicitly enabled", false, &on, true},
+		{"no-watch flag", true, nil, false},
+		{"watch_enabled false", false, &off, false},
+		{"flag wins over config", true, &on, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &recordingSource{watched: make(chan struct{}), stop: make(chan struct{})}
+			defer close(src.stop)
+			var cfg Config
+			cfg.Server.WatchEnabled = tt.enabled
+			var started bool
+			captureStdout(t, func() { started = watchConfig(src, cfg, tt.noWatch) })
+			if started != tt.want {
+				t.Fatalf("watchConfig = %v, want %v", started, tt.want)
+			}
+			select {
+			case <-src.watched:
+				if !tt.want {
+					t.Error("Watch called although watching is disabled")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.want {
+					t.Error("Watch not called")
+				}
+			}
+		})
+	}
+}
+
+func TestWaitForConfigFile(t *testing.T) {
+	prevSource := source
+	t.Cleanup(func() { source = prevSource })
+	tests := []struct {
+		name    string
+		delay   time.Duration // 文件延迟创建的时长，<0 表示始终不创建
+		timeout time.Duration
+		wantErr error // Load 的错误，nil 表示服务应正常启动
+		waitErr string
+	}{
+		{"already present", 0, time.Second, nil, ""},
+		{"created shortly after startup", 300 * time.Millisecond, 5 * time.Second, nil, ""},
+		{"never created", -1, 700 * time.Millisecond, nil, "did not appear within 700ms"},
+		{"waiting disabled", -1, 0, ErrConfigNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			create := func() { os.WriteFile(path, []byte("modules: [user]\n"), 0o644) }
+			switch {
+			case tt.delay == 0:
+				create()
+			case tt.delay > 0:
+				timer := time.AfterFunc(tt.delay, create)
+				t.Cleanup(func() { timer.Stop() })
+			}
+
+			src := &FileSource{Path: path}
+			var err error
+			captureStdout(t, func() { err = src.waitForFile(tt.timeout) })
+			if tt.waitErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.waitErr) {
+					t.Fatalf("waitForFile = %v, want %q", err, tt.waitErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := src.Load()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 文件出现后服务照常启动
+			app, err := StartApp(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer app.Close()
+			resp, err := app.Client.Get(app.URL + "/user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET /user = %d, want 200", resp.StatusCode)
+			}
+		})
+	}
+}
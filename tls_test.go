@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 生成 CN 为 cn 的自签名证书，返回 PEM 编码的证书与私钥
+func selfSignedCert(t *testing.T, cn string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// 写入证书文件并把修改时间推后 step 秒，保证与上一次写入的时间不同
+func writeCertFiles(t *testing.T, certFile, keyFile string, cert, key []byte, step int) {
+	t.Helper()
+	mod := time.Now().Add(time.Duration(step) * time.Second)
+	for path, data := range map[string][]byte{certFile: cert, keyFile: key} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCertReloaderServesRenewedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert, key := selfSignedCert(t, "first")
+	writeCertFiles(t, certFile, keyFile, cert, key, 0)
+	tlsCfg, err := newTLSConfig(&TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: tlsCfg,
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	// 每次新建连接，确保重新握手
+	servedCN := func() string {
+		t.Helper()
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	second, secondKey := selfSignedCert(t, "second")
+	steps := []struct {
+		name      string
+		cert, key []byte
+		want      string
+	}{
+		{"initial cert", nil, nil, "first"},
+		{"renewed cert", second, secondKey, "second"},
+		{"broken files keep the last good cert", []byte("not a cert"), []byte("not a key"), "second"},
+		{"mismatched key keeps the last good cert", cert, secondKey, "second"},
+		{"recovers once the files are valid again", cert, key, "first"},
+	}
+	for i, step := range steps {
+		if step.cert != nil {
+			writeCertFiles(t, certFile, keyFile, step.cert, step.key, i)
+		}
+		if got := servedCN(); got != step.want {
+			t.Errorf("%s: served CN = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestNewCertReloaderRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if _, err := newCertReloader(certFile, keyFile); err == nil {
+		t.Error("missing files accepted")
+	}
+	writeCertFiles(t, certFile, keyFile, []byte("x"), []byte("y"), 0)
+	if _, err := newCertReloader(certFile, keyFile); err == nil {
+		t.Error("invalid PEM accepted")
+	}
+}
@@ -1,23 +1,45 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
-// ${VAR} 或 ${VAR:default}
-var envPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)(?::([^}]+))?\}`)
+// ${VAR} 或 ${VAR:default}；$${VAR} 为转义，原样输出 ${VAR}
+// ${file:/path} 或 ${file:/path:default} 读取文件内容（如 Docker/K8s secret）
+// ${VAR:int:8080} / ${VAR:bool:false} 为带类型的引用（默认值可省略），取值须能解析为声明的类型，见 ExpandConfig
+var envPattern = regexp.MustCompile(`\$(\$)?\{([A-Za-z0-9_]+)(?::([^}]+))?\}`)
 
+// ExpandEnv 单次扫描完成替换：转义结果不会被再次展开。
+// 匹配自左向右进行，因此 $$${VAR} 中第一个 $ 保持原样，其后的 $${VAR} 作为转义，得到 $${VAR}
 func ExpandEnv(s string) string {
 	return envPattern.ReplaceAllStringFunc(s, func(m string) string {
 		groups := envPattern.FindStringSubmatch(m)
-		if len(groups) < 2 {
+		if len(groups) < 3 {
 			return m
 		}
-		key := groups[1]
+		if groups[1] != "" {
+			return m[1:]
+		}
+		key := groups[2]
 		def := ""
-		if len(groups) > 2 {
-			def = groups[2]
+		if len(groups) > 3 {
+			def = groups[3]
+		}
+		if key == "file" {
+			return expandFile(m, def)
+		}
+		// 带类型的引用取值无效时保留原文；ExpandConfig 中会报错
+		if kind, typedDef, ok := typedSpec(def); ok {
+			v, err := expandTyped(key, kind, typedDef)
+			if err != nil {
+				return m
+			}
+			return fmt.Sprint(v)
 		}
 		if val := os.Getenv(key); val != "" {
 			return val
@@ -29,24 +51,158 @@ func ExpandEnv(s string) string {
 	})
 }
 
-// 递归展开配置
-func ExpandConfig(v any) any {
+// 解析 ${VAR:<type>[:default]} 中冒号之后的部分，type 为 int 或 bool 时返回类型与默认值
+func typedSpec(spec string) (kind, def string, ok bool) {
+	kind, def, _ = strings.Cut(spec, ":")
+	if kind == "int" || kind == "bool" {
+		return kind, def, true
+	}
+	return "", "", false
+}
+
+// 取环境变量（为空时取默认值）并按 kind 解析；变量未设置且无默认值、或取值无法解析时返回错误
+func expandTyped(key, kind, def string) (any, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		val = def
+	}
+	if val == "" {
+		return nil, fmt.Errorf("${%s:%s}: %s is not set and has no default", key, kind, key)
+	}
+	var v any
+	var err error
+	switch kind {
+	case "int":
+		v, err = strconv.Atoi(strings.TrimSpace(val))
+	case "bool":
+		v, err = strconv.ParseBool(strings.TrimSpace(val))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("${%s:%s}: %q is not a valid %s", key, kind, val, kind)
+	}
+	return v, nil
+}
+
+// 展开配置中的字符串值：整个值恰为一个带类型的引用时得到 int / bool，而不是字符串；
+// 带类型的引用取值无效时返回错误，其余引用按 ExpandEnv 展开
+func expandString(s string) (any, error) {
+	matches := envPattern.FindAllStringSubmatch(s, -1)
+	for _, groups := range matches {
+		if groups[1] != "" || groups[2] == "file" {
+			continue
+		}
+		kind, def, ok := typedSpec(groups[3])
+		if !ok {
+			continue
+		}
+		v, err := expandTyped(groups[2], kind, def)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 1 && groups[0] == s {
+			return v, nil
+		}
+	}
+	return ExpandEnv(s), nil
+}
+
+// 读取文件内容并去掉首尾空白；文件不可读时使用默认值，没有默认值则保留原文
+func expandFile(m, spec string) string {
+	path, def, hasDef := strings.Cut(spec, ":")
+	if path == "" {
+		return m
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if hasDef {
+		return def
+	}
+	return m
+}
+
+// 递归展开配置，总是返回新的 map / slice，带类型的引用得到对应类型的值；避免锚点（&/*）或合并（<<）共享的结构相互影响；
+// 非字符串键的 map（yaml.v3 解码为 map[any]any）统一转换为字符串键。
+// 带类型的引用取值无效时返回错误，错误信息带出错的键路径（如 db.hosts[0]: ...）
+func ExpandConfig(v any) (any, error) {
+	return ExpandConfigDepth(v, 0)
+}
+
+// 嵌套层数超过 ExpandConfigDepth 的限制
+var ErrMaxDepth = errors.New("config nesting too deep")
+
+// ExpandConfigDepth 同 ExpandConfig，但 map / slice 嵌套超过 maxDepth 层时返回 ErrMaxDepth；maxDepth <= 0 表示不限制
+func ExpandConfigDepth(v any, maxDepth int) (any, error) {
+	return expandValue(v, 0, maxDepth)
+}
+
+func expandValue(v any, depth, maxDepth int) (any, error) {
+	switch v.(type) {
+	case map[string]any, map[any]any, []any:
+		if maxDepth > 0 && depth >= maxDepth {
+			return nil, fmt.Errorf("%w: more than %d levels", ErrMaxDepth, maxDepth)
+		}
+	}
 	switch val := v.(type) {
 	case string:
-		return ExpandEnv(val)
+		return expandString(val)
 	case map[string]any:
-		newMap := make(map[string]any)
+		newMap := make(map[string]any, len(val))
+		for k, v2 := range val {
+			e, err := expandValue(v2, depth+1, maxDepth)
+			if err != nil {
+				return nil, atKey(k, err)
+			}
+			newMap[k] = e
+		}
+		return newMap, nil
+	case map[any]any:
+		newMap := make(map[string]any, len(val))
 		for k, v2 := range val {
-			newMap[k] = ExpandConfig(v2)
+			e, err := expandValue(v2, depth+1, maxDepth)
+			if err != nil {
+				return nil, atKey(fmt.Sprint(k), err)
+			}
+			newMap[fmt.Sprint(k)] = e
 		}
-		return newMap
+		return newMap, nil
 	case []any:
 		newSlice := make([]any, len(val))
 		for i, v2 := range val {
-			newSlice[i] = ExpandConfig(v2)
+			e, err := expandValue(v2, depth+1, maxDepth)
+			if err != nil {
+				return nil, atKey(fmt.Sprintf("[%d]", i), err)
+			}
+			newSlice[i] = e
 		}
-		return newSlice
+		return newSlice, nil
+	case int, int64, uint64, float64, bool, nil:
+		// 标量保持原类型，不转换为字符串
+		return v, nil
 	default:
-		return v
+		return v, nil
+	}
+}
+
+// 展开失败的位置：path 为出错值的键路径，如 db.hosts[0]
+type keyError struct {
+	path string
+	err  error
+}
+
+func (e *keyError) Error() string { return e.path + ": " + e.err.Error() }
+func (e *keyError) Unwrap() error { return e.err }
+
+// 在错误的键路径前加上一级 key（数组下标形如 [0]）
+func atKey(key string, err error) error {
+	var ke *keyError
+	if !errors.As(err, &ke) {
+		return &keyError{path: key, err: err}
+	}
+	if strings.HasPrefix(ke.path, "[") {
+		ke.path = key + ke.path
+	} else {
+		ke.path = key + "." + ke.path
 	}
+	return ke
 }
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("T_HOST", "db.local")
+	t.Setenv("T_PORT", "5432")
+	tests := []struct {
+		in, want string
+	}{
+		{"${T_HOST}", "db.local"},
+		{"${T_MISSING:fallback}", "fallback"},
+		{"${T_MISSING}", "${T_MISSING}"},
+		{"$${T_HOST}", "${T_HOST}"},
+		{"$$${T_HOST}", "$${T_HOST}"},
+		{"${T_HOST}:${T_PORT:int}", "db.local:5432"},
+		{"${T_BAD:int:x}", "${T_BAD:int:x}"},
+	}
+	for _, tt := range tests {
+		if got := ExpandEnv(tt.in); got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandEnvEscapes(t *testing.T) {
+	t.Setenv("T_NAME", "app")
+	tests := []struct {
+		name, in, want string
+	}{
+		{"normal", "${T_NAME}", "app"},
+		{"escaped", "$${T_NAME}", "${T_NAME}"},
+		{"escaped with default", "$${T_MISSING:x}", "${T_MISSING:x}"},
+		{"double escaped", "$$${T_NAME}", "$${T_NAME}"},
+		{"escape then reference", "$${T_NAME}-${T_NAME}", "${T_NAME}-app"},
+		{"escaped result not expanded again", "$${T_NAME}$${T_NAME}", "${T_NAME}${T_NAME}"},
+		{"lone dollars untouched", "$$ and $T_NAME", "$$ and $T_NAME"},
+		{"template for another system", "Hello {{.Name}} from $${HOSTNAME}", "Hello {{.Name}} from ${HOSTNAME}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandEnv(tt.in); got != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("  s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	tests := []struct {
+		name, in, want string
+	}{
+		{"contents trimmed", "${file:" + secret + "}", "s3cret"},
+		{"default ignored when file exists", "${file:" + secret + ":fallback}", "s3cret"},
+		{"missing file uses default", "${file:" + missing + ":fallback}", "fallback"},
+		{"missing file keeps literal", "${file:" + missing + "}", "${file:" + missing + "}"},
+		{"inside a longer value", "postgres://app:${file:" + secret + "}@db/app", "postgres://app:s3cret@db/app"},
+		{"escaped", "$${file:" + secret + "}", "${file:" + secret + "}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandEnv(tt.in); got != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	// ExpandConfig 递归展开配置树中任意位置的文件引用
+	got, err := ExpandConfig(map[string]any{"db": map[string]any{"hosts": []any{"${file:" + secret + "}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"db": map[string]any{"hosts": []any{"s3cret"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandConfig = %#v, want %#v", got, want)
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	t.Setenv("T_PORT", "8080")
+	t.Setenv("T_DEBUG", "true")
+	t.Setenv("T_WORD", "many")
+	tests := []struct {
+		name    string
+		in      any
+		want    any
+		wantErr string
+	}{
+		{
+			name: "typed values",
+			in:   map[string]any{"port": "${T_PORT:int}", "debug": "${T_DEBUG:bool}", "retries": "${T_UNSET:int:3}"},
+			want: map[string]any{"port": 8080, "debug": true, "retries": 3},
+		},
+		{
+			name: "typed reference inside a longer string stays a string",
+			in:   map[string]any{"addr": ":${T_PORT:int}"},
+			want: map[string]any{"addr": ":8080"},
+		},
+		{
+			name: "non-string keys normalized",
+			in:   map[any]any{1: "${T_WORD}", "list": []any{"${T_PORT:int}", 2}},
+			want: map[string]any{"1": "many", "list": []any{8080, 2}},
+		},
+		{
+			name:    "invalid int reports the key",
+			in:      map[string]any{"ok": "x", "db": map[string]any{"pool": "${T_WORD:int}"}},
+			wantErr: `db.pool: ${T_WORD:int}: "many" is not a valid int`,
+		},
+		{
+			name:    "invalid bool inside a list",
+			in:      map[string]any{"flags": []any{true, "${T_WORD:bool}"}},
+			wantErr: `flags[1]: ${T_WORD:bool}: "many" is not a valid bool`,
+		},
+		{
+			name:    "unset without default",
+			in:      map[string]any{"port": "${T_UNSET:int}"},
+			wantErr: "port: ${T_UNSET:int}: T_UNSET is not set and has no default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandConfig(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandConfigDepth(t *testing.T) {
+	nested := map[string]any{"a": map[string]any{"b": []any{map[string]any{"c": 1}}}}
+	if _, err := ExpandConfigDepth(nested, 4); err != nil {
+		t.Errorf("depth 4: %v", err)
+	}
+	_, err := ExpandConfigDepth(nested, 3)
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Fatalf("depth 3: err = %v, want ErrMaxDepth", err)
+	}
+	if want := "a.b[0]: config nesting too deep: more than 3 levels"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestExpandConfigAnchors(t *testing.T) {
+	t.Setenv("T_DB_HOST", "db.local")
+	src := `
+shared: &db
+  host: ${T_DB_HOST}
+  port: 5432
+  ssl: true
+  ratio: 0.5
+  replicas:
+    - {host: r1, port: 5433}
+configs:
+  order:
+    db: *db
+  user:
+    db: *db
+`
+	var raw any
+	if err := yaml.Unmarshal([]byte(src), &raw); err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := ExpandConfig(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := expanded.(map[string]any)["configs"].(map[string]any)
+
+	want := map[string]any{
+		"host":     "db.local",
+		"port":     5432,
+		"ssl":      true,
+		"ratio":    0.5,
+		"replicas": []any{map[string]any{"host": "r1", "port": 5433}},
+	}
+	for _, name := range []string{"order", "user"} {
+		t.Run(name, func(t *testing.T) {
+			db := configs[name].(map[string]any)["db"]
+			if !reflect.DeepEqual(db, want) {
+				t.Errorf("db = %#v, want %#v", db, want)
+			}
+		})
+	}
+
+	// 展开后两个模块各自持有独立的副本
+	configs["order"].(map[string]any)["db"].(map[string]any)["host"] = "changed"
+	if host := configs["user"].(map[string]any)["db"].(map[string]any)["host"]; host != "db.local" {
+		t.Errorf("user db host = %v after changing order's copy", host)
+	}
+}
+
+func TestExpandConfigNonStringKeys(t *testing.T) {
+	t.Setenv("T_HOST", "db.local")
+	t.Setenv("T_PORT", "5432")
+	tests := []struct {
+		name string
+		src  string
+		want any
+	}{
+		{
+			name: "int keys",
+			src:  "shards:\n  1: ${T_HOST}\n  2: {port: \"${T_PORT:int}\"}\n",
+			want: map[string]any{"shards": map[string]any{"1": "db.local", "2": map[string]any{"port": 5432}}},
+		},
+		{
+			name: "bool keys nested in a list",
+			src:  "flags:\n  - true: ${T_HOST}\n    false: [\"${T_PORT}\"]\n",
+			want: map[string]any{"flags": []any{map[string]any{"true": "db.local", "false": []any{"5432"}}}},
+		},
+		{
+			name: "mixed keys several levels deep",
+			src:  "configs:\n  order:\n    pools:\n      10: {dsn: \"postgres://${T_HOST}:${T_PORT}\"}\n      name: main\n",
+			want: map[string]any{"configs": map[string]any{"order": map[string]any{"pools": map[string]any{"10": map[string]any{"dsn": "postgres://db.local:5432"}, "name": "main"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw any
+			if err := yaml.Unmarshal([]byte(tt.src), &raw); err != nil {
+				t.Fatal(err)
+			}
+			// 确认输入中确实含有 map[any]any
+			if !strings.Contains(fmt.Sprintf("%#v", raw), "map[interface {}]interface {}") {
+				t.Fatalf("decoded %#v, want a map[any]any inside", raw)
+			}
+			got, err := ExpandConfig(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ${config.<module>.<key>} 引用另一模块配置块中的值，key 可用 . 进入嵌套 map；$${config...} 为转义
+var refPattern = regexp.MustCompile(`\$(\$)?\{config\.([^.}]+)\.([^}]+)\}`)
+
+// 配置引用无法解析：目标不存在或存在循环引用
+var ErrConfigRef = errors.New("invalid config reference")
+
+// ExpandRefs 在环境变量展开之后单独执行，原地替换 configs 中的 ${config.<module>.<key>} 引用。
+// 整个字符串就是一个引用时保留目标值的原始类型（数字、列表等），否则按文本拼接
+func ExpandRefs(configs map[string]map[string]any) error {
+	r := &refResolver{configs: configs, stack: map[string]bool{}}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resolved := make(map[string]map[string]any, len(configs))
+	for _, name := range names {
+		v, err := r.resolve(configs[name], "configs."+name)
+		if err != nil {
+			return err
+		}
+		resolved[name], _ = v.(map[string]any)
+	}
+	for name, m := range resolved {
+		configs[name] = m
+	}
+	return nil
+}
+
+type refResolver struct {
+	configs map[string]map[string]any
+	stack   map[string]bool // 正在解析的引用链，用于检测循环
+}
+
+func (r *refResolver) resolve(v any, at string) (any, error) {
+	switch val := v.(type) {
+	case string:
+		return r.resolveString(val, at)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, v2 := range val {
+			e, err := r.resolve(v2, at+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = e
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, v2 := range val {
+			e, err := r.resolve(v2, fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (r *refResolver) resolveString(s, at string) (any, error) {
+	if !strings.Contains(s, "{config.") {
+		return s, nil
+	}
+	// 整个值就是一个引用：返回目标的原始类型
+	if loc := refPattern.FindStringSubmatchIndex(s); loc != nil && loc[0] == 0 && loc[1] == len(s) && loc[2] < 0 {
+		return r.lookup(s[loc[4]:loc[5]], s[loc[6]:loc[7]], at)
+	}
+	var firstErr error
+	out := refPattern.ReplaceAllStringFunc(s, func(m string) string {
+		groups := refPattern.FindStringSubmatch(m)
+		if groups[1] != "" {
+			return m[1:]
+		}
+		v, err := r.lookup(groups[2], groups[3], at)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return m
+		}
+		return fmt.Sprint(v)
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
+
+// 取出并解析 <module>.<key> 指向的值
+func (r *refResolver) lookup(name, key, at string) (any, error) {
+	ref := name + "." + key
+	if r.stack[ref] {
+		return nil, fmt.Errorf("%s: %w: cycle through ${config.%s}", at, ErrConfigRef, ref)
+	}
+	var v any = r.configs[name]
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			v = nil
+			break
+		}
+		if v, ok = m[part]; !ok {
+			break
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%s: %w: ${config.%s} not found", at, ErrConfigRef, ref)
+	}
+	r.stack[ref] = true
+	defer delete(r.stack, ref)
+	return r.resolve(v, "configs."+ref)
+}
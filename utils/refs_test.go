@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs map[string]map[string]any
+		want    map[string]map[string]any
+		wantErr string
+	}{
+		{
+			name: "typed and interpolated",
+			configs: map[string]map[string]any{
+				"db":    {"port": 5432, "host": "db.local", "hosts": []any{"a", "b"}},
+				"order": {"port": "${config.db.port}", "dsn": "pg://${config.db.host}:${config.db.port}", "hosts": "${config.db.hosts}"},
+			},
+			want: map[string]map[string]any{
+				"db":    {"port": 5432, "host": "db.local", "hosts": []any{"a", "b"}},
+				"order": {"port": 5432, "dsn": "pg://db.local:5432", "hosts": []any{"a", "b"}},
+			},
+		},
+		{
+			name: "nested key and chained reference",
+			configs: map[string]map[string]any{
+				"a": {"pool": map[string]any{"size": 8}},
+				"b": {"size": "${config.a.pool.size}"},
+				"c": {"list": []any{"${config.b.size}"}},
+			},
+			want: map[string]map[string]any{
+				"a": {"pool": map[string]any{"size": 8}},
+				"b": {"size": 8},
+				"c": {"list": []any{8}},
+			},
+		},
+		{
+			name:    "escaped",
+			configs: map[string]map[string]any{"a": {"x": "$${config.b.y}"}},
+			want:    map[string]map[string]any{"a": {"x": "${config.b.y}"}},
+		},
+		{
+			name:    "missing target",
+			configs: map[string]map[string]any{"a": {"x": "${config.b.y}"}},
+			wantErr: "configs.a.x: invalid config reference: ${config.b.y} not found",
+		},
+		{
+			name: "cycle",
+			configs: map[string]map[string]any{
+				"a": {"x": "${config.b.y}"},
+				"b": {"y": "v-${config.a.x}"},
+			},
+			wantErr: "cycle through ${config.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ExpandRefs(tt.configs)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrConfigRef) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want ErrConfigRef mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.configs, tt.want) {
+				t.Errorf("got %v, want %v", tt.configs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"myapp/registry"
+)
+
+// validate 子命令：检查模块注册表的依赖声明，以及配置文件的解析、展开与一致性，有问题时以状态码 1 退出；
+// --format=json 时每个问题以一行 JSON 对象写到 stderr
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	path := fs.String("config", configFile, "config file to validate")
+	format := fs.String("format", "text", "error output format: text, json")
+	fs.StringVar(&activeProfile, "profile", activeProfile, "config profile to apply (defaults to APP_ENV)")
+	fs.Parse(args)
+	jsonErrors = *format == "json"
+
+	var problems []error
+	if err := registry.Validate(); err != nil {
+		problems = append(problems, splitErrors(err)...)
+	}
+	cfg, err := readRawConfig(*path)
+	if err == nil {
+		cfg, err = expandConfig(cfg)
+	}
+	if err != nil {
+		problems = append(problems, splitErrors(err)...)
+	} else {
+		problems = append(problems, cfg.Validate()...)
+	}
+	if len(problems) > 0 {
+		reportErrors(problems, false)
+		os.Exit(1)
+	}
+	fmt.Println(*path, "OK")
+}
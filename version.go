@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 构建信息，由 -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildTime=..." 注入
+var (
+	Version   = "dev"
+	Commit    string
+	BuildTime string
+)
+
+// /version 返回构建信息与当前激活的模块，由 manager 在每次重建路由时注册
+func registerVersionRoute(g *gin.RouterGroup, routes *routeTable, m *ModuleManager) {
+	routes.router("manager", g).GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"version":    Version,
+			"commit":     Commit,
+			"build_time": BuildTime,
+			"modules":    m.ActiveModules(),
+		})
+	})
+}
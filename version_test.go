@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	registerTestModules(t, map[string][]string{"t_a": nil, "t_b": {"t_a"}})
+	oldVersion, oldCommit, oldBuildTime := Version, Commit, BuildTime
+	Version, Commit, BuildTime = "1.4.2", "abc1234", "2026-01-02T03:04:05Z"
+	t.Cleanup(func() { Version, Commit, BuildTime = oldVersion, oldCommit, oldBuildTime })
+
+	disabled := false
+	tests := []struct {
+		name   string
+		server ServerConfig
+		path   string
+		status int
+	}{
+		{"default prefix", ServerConfig{}, "/version", http.StatusOK},
+		{"admin prefix", ServerConfig{AdminPrefix: "/ops"}, "/ops/version", http.StatusOK},
+		{"admin disabled", ServerConfig{AdminEnabled: &disabled}, "/version", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			r, err := m.Update(context.Background(), Config{Modules: []string{"t_b"}, Server: tt.server})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got struct {
+				Version   string   `json:"version"`
+				Commit    string   `json:"commit"`
+				BuildTime string   `json:"build_time"`
+				Modules   []string `json:"modules"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != "1.4.2" || got.Commit != "abc1234" || got.BuildTime != "2026-01-02T03:04:05Z" {
+				t.Errorf("build info = %+v", got)
+			}
+			if !slices.Equal(got.Modules, []string{"t_a", "t_b"}) {
+				t.Errorf("modules = %v, want [t_a t_b]", got.Modules)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"myapp/module"
+)
+
+// 两次连接尝试之间的间隔
+var waitForInterval = 200 * time.Millisecond
+
+// 按模块配置 wait_for 依次等待外部 TCP 端点（host:port）可连接，与模块依赖无关；
+// wait_for_timeout 限制总等待时长（默认 30s），超时返回最后一次连接错误
+func waitFor(ctx context.Context, name string, cfg module.ModuleConfig) error {
+	endpoints := cfg.GetStringSlice("wait_for")
+	if len(endpoints) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.GetDuration("wait_for_timeout", 30*time.Second))
+	defer cancel()
+
+	var d net.Dialer
+	for _, addr := range endpoints {
+		for logged := false; ; {
+			dialCtx, dialCancel := context.WithTimeout(ctx, time.Second)
+			conn, err := d.DialContext(dialCtx, "tcp", addr)
+			dialCancel()
+			if err == nil {
+				conn.Close()
+				break
+			}
+			if !logged {
+				fmt.Printf("Module %s waiting for %s: %v\n", name, addr, err)
+				logged = true
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("wait_for %s: %w", addr, err)
+			case <-time.After(waitForInterval):
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForEndpoints(t *testing.T) {
+	prev := waitForInterval
+	waitForInterval = 20 * time.Millisecond
+	t.Cleanup(func() { waitForInterval = prev })
+
+	tests := []struct {
+		name    string
+		delay   time.Duration // 监听器延迟启动的时长，<0 表示始终不启动
+		timeout string
+		wantOK  bool
+	}{
+		{"already up", 0, "2s", true},
+		{"comes up later", 200 * time.Millisecond, "2s", true},
+		{"never up", -1, "150ms", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 先占用再释放一个端口，之后按 delay 在该端口上启动监听
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+			if tt.delay >= 0 {
+				timer := time.AfterFunc(tt.delay, func() {
+					if l, err := net.Listen("tcp", addr); err == nil {
+						t.Cleanup(func() { l.Close() })
+					}
+				})
+				t.Cleanup(func() { timer.Stop() })
+			}
+
+			events := registerTestModules(t, map[string][]string{"t_wait": nil})
+			m := NewModuleManager()
+			defer m.ShutdownAll(0)
+			cfg := Config{
+				Modules: []string{"t_wait"},
+				Configs: map[string]map[string]any{"t_wait": {"wait_for": []any{addr}, "wait_for_timeout": tt.timeout}},
+			}
+			start := time.Now()
+			var updateErr error
+			out := captureStdout(t, func() { _, updateErr = m.Update(context.Background(), cfg) })
+			if updateErr != nil {
+				t.Fatal(updateErr)
+			}
+
+			inited := slices.Contains(events.with("init "), "t_wait")
+			if _, active := m.activeModule("t_wait"); active != tt.wantOK || inited != tt.wantOK {
+				t.Fatalf("active = %v, inited = %v, want %v (output %q)", active, inited, tt.wantOK, out)
+			}
+			if tt.wantOK {
+				// Init 在端点可连接之后才执行
+				if elapsed := time.Since(start); elapsed < tt.delay {
+					t.Errorf("Update returned after %v, before the endpoint came up at %v", elapsed, tt.delay)
+				}
+				return
+			}
+			if !strings.Contains(out, "Module t_wait waiting for "+addr) {
+				t.Errorf("output = %q, want a waiting message", out)
+			}
+			m.mu.RLock()
+			st := *m.states["t_wait"]
+			m.mu.RUnlock()
+			if st.FailCount != 1 || !strings.Contains(st.LastError, "wait_for "+addr) {
+				t.Errorf("state = %+v, want one wait_for failure", st)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"myapp/module"
+)
+
+const defaultWarmupTimeout = 10 * time.Second
+
+// 并发预热本次新初始化的模块，全部结束（或各自超时）后返回；预热失败不影响重载，只打印警告
+func warmupModules(ctx context.Context, names []string, active map[string]module.Module, configs map[string]map[string]any) {
+	var wg sync.WaitGroup
+	for _, name := range names {
+		w, ok := module.CapabilityOf[module.Warmer](active[name], module.CapWarmup)
+		if !ok {
+			continue
+		}
+		timeout := module.ModuleConfig(configs[name]).GetDuration("warmup_timeout", defaultWarmupTimeout)
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			if err := callSafely(name, "Warmup", func() error { return w.Warmup(ctx) }); err != nil {
+				fmt.Printf("Warning: warmup of module %s failed: %v\n", name, err)
+				return
+			}
+			fmt.Printf("Module %s warmed up in %v\n", name, time.Since(start).Round(time.Millisecond))
+		}(name)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	defaultWatchDebounce    = 200 * time.Millisecond
+	missingFilePollInterval = 500 * time.Millisecond
+
+	watcherRestartBackoff    = 100 * time.Millisecond
+	maxWatcherRestartBackoff = 30 * time.Second
+)
+
+// fsnotify 的最小接口，便于替换底层实现
+type fsWatcher interface {
+	Add(name string) error
+	Remove(name string) error
+	Close() error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+type fsnotifyWatcher struct {
+	w *fsnotify.Watcher
+}
+
+func (f fsnotifyWatcher) Add(name string) error         { return f.w.Add(name) }
+func (f fsnotifyWatcher) Remove(name string) error      { return f.w.Remove(name) }
+func (f fsnotifyWatcher) Close() error                  { return f.w.Close() }
+func (f fsnotifyWatcher) Events() <-chan fsnotify.Event { return f.w.Events }
+func (f fsnotifyWatcher) Errors() <-chan error          { return f.w.Errors }
+
+var newFSWatcher = func() (fsWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return fsnotifyWatcher{w}, nil
+}
+
+// 监听一组文件、目录或通配符（如 config.d/*.yaml），事件经过防抖合并后调用 onChange；
+// 监听目录时，目录下新建的文件同样会触发重载
+func watchPaths(paths []string, debounce time.Duration, onChange func()) error {
+	explicit := make(map[string]bool)
+	files := make(map[string]bool) // 直接监听的文件，被删除或替换后需要重新添加
+	var globs []string
+	var targets []string // 实际添加到 watcher 的路径
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if strings.ContainsAny(p, "*?[") {
+			// 通配符：监听所在目录，事件按模式过滤
+			targets = append(targets, filepath.Dir(p))
+			globs = append(globs, p)
+			continue
+		}
+		targets = append(targets, p)
+		explicit[p] = true
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			files[p] = true
+		}
+	}
+
+	// 文件被删除或重命名（原子替换、k8s ConfigMap 的符号链接切换）后 inotify 监听随之失效：
+	// 继续使用最后一次成功的配置，轮询直到文件重新出现，再恢复监听并触发重载
+	missing := make(map[string]bool)
+
+	// 创建 watcher 并添加所有路径；重建时暂不存在的文件交给轮询恢复
+	start := func(restart bool) (fsWatcher, error) {
+		w, err := newFSWatcher()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			if err := w.Add(t); err != nil {
+				if restart && files[t] {
+					missing[t] = true
+					continue
+				}
+				// 尚不存在的路径（额外监听的文件或目录、通配符所在的 config.d 等）轮询等待其出现，而不是整体失败；
+				// 重建时同样如此，否则缺少的目录会让重建无限重试
+				if errors.Is(err, fs.ErrNotExist) {
+					if !missing[t] {
+						fmt.Println("Watched path does not exist yet, waiting for it to appear:", t)
+					}
+					missing[t] = true
+					continue
+				}
+				w.Close()
+				return nil, err
+			}
+			delete(missing, t)
+		}
+		return w, nil
+	}
+	watcher, err := start(false)
+	if err != nil {
+		return err
+	}
+	defer func() { watcher.Close() }()
+
+	// watcher 出错或通道被关闭后按指数退避重建，重建成功后触发一次重载以补上期间漏掉的变更
+	restart := func(cause error) {
+		fmt.Println("Watcher failed, restarting:", cause)
+		watcher.Close()
+		backoff := watcherRestartBackoff
+		for {
+			time.Sleep(backoff)
+			w, err := start(true)
+			if err == nil {
+				watcher = w
+				fmt.Println("Watcher re-established")
+				return
+			}
+			fmt.Printf("Watcher restart failed: %v, retrying in %s\n", err, backoff*2)
+			backoff = min(backoff*2, maxWatcherRestartBackoff)
+		}
+	}
+
+	matches := func(name string) bool {
+		name = filepath.Clean(name)
+		if explicit[name] || explicit[filepath.Dir(name)] {
+			return true
+		}
+		for _, g := range globs {
+			if ok, _ := filepath.Match(g, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+	fire := make(chan struct{}, 1)
+	var timer *time.Timer
+	schedule := func() {
+		if timer == nil {
+			timer = time.AfterFunc(debounce, func() {
+				select {
+				case fire <- struct{}{}:
+				default:
+				}
+			})
+		} else {
+			timer.Reset(debounce)
+		}
+	}
+
+	poll := time.NewTicker(missingFilePollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events():
+			if !ok {
+				restart(errors.New("event channel closed"))
+				schedule()
+				continue
+			}
+			name := filepath.Clean(event.Name)
+			if files[name] && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				fmt.Println("Watched file removed, waiting for it to reappear:", name)
+				watcher.Remove(name)
+				missing[name] = true
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 || !matches(name) {
+				continue
+			}
+			schedule()
+		case <-poll.C:
+			for name := range missing {
+				info, err := os.Stat(name)
+				if err != nil {
+					continue
+				}
+				if err := watcher.Add(name); err != nil {
+					fmt.Println("Failed to re-watch file:", name, err)
+					continue
+				}
+				delete(missing, name)
+				if !info.IsDir() {
+					files[name] = true
+				}
+				fmt.Println("Watched file recreated:", name)
+				schedule()
+			}
+		case <-fire:
+			onChange()
+		case err, ok := <-watcher.Errors():
+			if !ok {
+				err = errors.New("error channel closed")
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// 事件队列溢出只会丢失事件，watcher 仍可用：直接重载一次
+				fmt.Println("Watcher event overflow, reloading")
+				schedule()
+				continue
+			}
+			restart(err)
+			schedule()
+		}
+	}
+}
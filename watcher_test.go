@@ -0,0 +1,370 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 包装真实 watcher，可向 Errors 注入错误以触发重建
+type faultyWatcher struct {
+	fsWatcher
+	errs chan error
+}
+
+func (f *faultyWatcher) Errors() <-chan error { return f.errs }
+
+// 替换 newFSWatcher，返回已创建的 watcher（按创建顺序）
+func trackWatchers(t *testing.T) func() []*faultyWatcher {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		watchers []*faultyWatcher
+	)
+	prev := newFSWatcher
+	newFSWatcher = func() (fsWatcher, error) {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+		fw := &faultyWatcher{fsWatcher: fsnotifyWatcher{w}, errs: make(chan error, 1)}
+		mu.Lock()
+		watchers = append(watchers, fw)
+		mu.Unlock()
+		return fw, nil
+	}
+	t.Cleanup(func() { newFSWatcher = prev })
+	return func() []*faultyWatcher {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*faultyWatcher(nil), watchers...)
+	}
+}
+
+// 等待 cond 成立，最长 timeout
+func eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchPathsMatchesAndDebounces(t *testing.T) {
+	write := func(path string) error { return os.WriteFile(path, []byte(time.Now().String()), 0o644) }
+	tests := []struct {
+		name    string
+		watch   []string // 相对临时目录
+		change  func(dir string) error
+		changes int // 防抖结束后的重载次数
+	}{
+		{
+			name:    "watched file",
+			watch:   []string{"config.yaml"},
+			change:  func(dir string) error { return write(filepath.Join(dir, "config.yaml")) },
+			changes: 1,
+		},
+		{
+			name:    "new file in watched directory",
+			watch:   []string{"config.yaml", "extra"},
+			change:  func(dir string) error { return write(filepath.Join(dir, "extra", "new.yaml")) },
+			changes: 1,
+		},
+		{
+			name:    "file matching glob",
+			watch:   []string{"config.yaml", "extra/*.yaml"},
+			change:  func(dir string) error { return write(filepath.Join(dir, "extra", "a.yaml")) },
+			changes: 1,
+		},
+		{
+			name:    "file not matching glob",
+			watch:   []string{"config.yaml", "extra/*.yaml"},
+			change:  func(dir string) error { return write(filepath.Join(dir, "extra", "a.txt")) },
+			changes: 0,
+		},
+		{
+			name:  "burst of writes coalesced",
+			watch: []string{"config.yaml", "extra/*.yaml"},
+			change: func(dir string) error {
+				for i := 0; i < 5; i++ {
+					if err := write(filepath.Join(dir, "config.yaml")); err != nil {
+						return err
+					}
+					if err := write(filepath.Join(dir, "extra", "a.yaml")); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			changes: 1,
+		},
+	}
+	const debounce = 100 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watchers := trackWatchers(t)
+			dir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(dir, "extra"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := write(filepath.Join(dir, "config.yaml")); err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, p := range tt.watch {
+				paths = append(paths, filepath.Join(dir, p))
+			}
+			var (
+				mu      sync.Mutex
+				changes int
+			)
+			go watchPaths(paths, debounce, func() {
+				mu.Lock()
+				changes++
+				mu.Unlock()
+			})
+			eventually(t, time.Second, "watcher start", func() bool { return len(watchers()) == 1 })
+			if err := tt.change(dir); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(5 * debounce)
+			mu.Lock()
+			defer mu.Unlock()
+			if changes != tt.changes {
+				t.Errorf("reloads = %d, want %d", changes, tt.changes)
+			}
+		})
+	}
+}
+
+func TestWatchedFileRecreated(t *testing.T) {
+	tests := []struct {
+		name    string
+		remove  func(path string) error
+		restore func(path string) error
+	}{
+		{
+			name:    "deleted then recreated",
+			remove:  os.Remove,
+			restore: func(path string) error { return os.WriteFile(path, []byte("modules: [order]\n"), 0o644) },
+		},
+		{
+			name:   "renamed away then replaced atomically",
+			remove: func(path string) error { return os.Rename(path, path+".old") },
+			restore: func(path string) error {
+				if err := os.WriteFile(path+".tmp", []byte("modules: [order]\n"), 0o644); err != nil {
+					return err
+				}
+				return os.Rename(path+".tmp", path)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watchers := trackWatchers(t)
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte("modules: [user]\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var (
+				mu      sync.Mutex
+				changes int
+			)
+			count := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return changes
+			}
+			go watchPaths([]string{path}, 20*time.Millisecond, func() {
+				mu.Lock()
+				changes++
+				mu.Unlock()
+			})
+			eventually(t, time.Second, "watcher start", func() bool { return len(watchers()) == 1 })
+
+			if err := tt.remove(path); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			if n := count(); n != 0 {
+				t.Fatalf("%d reloads while the file was missing, want 0", n)
+			}
+			if err := tt.restore(path); err != nil {
+				t.Fatal(err)
+			}
+			eventually(t, 3*time.Second, "reload after the file reappeared", func() bool { return count() >= 1 })
+
+			// 恢复监听后，之后的修改照常触发重载
+			time.Sleep(100 * time.Millisecond)
+			before := count()
+			if err := os.WriteFile(path, []byte("modules: [auth]\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			eventually(t, 2*time.Second, "reload after editing the recreated file", func() bool { return count() > before })
+		})
+	}
+}
+
+func TestWatcherRestartWithMissingPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		watch   func(dir string) string // 除 config.yaml 外监听的、一开始不存在的路径
+		appear  func(dir string) error  // 让该路径出现并产生一次变更
+		changes int                     // appear 之后至少触发的重载次数
+	}{
+		{
+			name:  "missing glob directory",
+			watch: func(dir string) string { return filepath.Join(dir, "config.d", "*.yaml") },
+			appear: func(dir string) error {
+				if err := os.Mkdir(filepath.Join(dir, "config.d"), 0o755); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, "config.d", "a.yaml"), []byte("a: 1\n"), 0o644)
+			},
+			changes: 1,
+		},
+		{
+			name:  "missing extra file",
+			watch: func(dir string) string { return filepath.Join(dir, "extra.yaml") },
+			appear: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "extra.yaml"), []byte("x: 1\n"), 0o644)
+			},
+			changes: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watchers := trackWatchers(t)
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(cfgPath, []byte("modules: []\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var (
+				mu      sync.Mutex
+				changes int
+			)
+			count := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return changes
+			}
+			go watchPaths([]string{cfgPath, tt.watch(dir)}, 20*time.Millisecond, func() {
+				mu.Lock()
+				changes++
+				mu.Unlock()
+			})
+			eventually(t, time.Second, "watcher start", func() bool { return len(watchers()) == 1 })
+
+			// 注入错误使 watcher 重建；缺少的路径不应让重建失败
+			watchers()[0].errs <- os.ErrClosed
+			eventually(t, 2*time.Second, "watcher restart", func() bool { return len(watchers()) >= 2 })
+			eventually(t, time.Second, "reload after restart", func() bool { return count() >= 1 })
+			time.Sleep(3 * watcherRestartBackoff)
+			if n := len(watchers()); n != 2 {
+				t.Fatalf("watcher was created %d times, want 2 (restart kept failing)", n)
+			}
+
+			before := count()
+			if err := tt.appear(dir); err != nil {
+				t.Fatal(err)
+			}
+			eventually(t, 3*time.Second, "reload after path appeared", func() bool { return count() >= before+tt.changes })
+
+			before = count()
+			if err := os.WriteFile(cfgPath, []byte("modules: [user]\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			eventually(t, 2*time.Second, "reload after config change", func() bool { return count() > before })
+		})
+	}
+}
+
+func TestFileSourceWatchKeepsLastGoodConfig(t *testing.T) {
+	trackWatchers(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("modules: [user]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := &FileSource{Path: path}
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("modules: [user\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	updates := make(chan Config, 1)
+	errs := make(chan error, 1)
+	go func() { errs <- src.Watch(updates) }()
+	select {
+	case err := <-errs:
+		t.Fatalf("Watch returned on a broken config: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := os.WriteFile(path, []byte("modules: [order]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-updates:
+		if len(cfg.Modules) != 1 || cfg.Modules[0] != "order" {
+			t.Errorf("reloaded modules = %v, want [order]", cfg.Modules)
+		}
+	case err := <-errs:
+		t.Fatalf("Watch returned: %v", err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no config update after fixing the file")
+	}
+}
+
+func TestFileSourceWatchesModuleConfigDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool // 开始监听前 config.d/order.yaml 是否已存在
+	}{
+		{"sidecar rewritten", true},
+		{"sidecar created", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trackWatchers(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.yaml")
+			sidecar := filepath.Join(dir, "config.d", "order.yaml")
+			if err := os.WriteFile(path, []byte("modules: [order]\nserver:\n  watch_debounce: 50ms\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Dir(sidecar), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				if err := os.WriteFile(sidecar, []byte("dsn: memory://v1\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			src := &FileSource{Path: path}
+			updates := make(chan Config, 1)
+			go src.Watch(updates)
+			// 等待监听建立
+			time.Sleep(200 * time.Millisecond)
+			if err := os.WriteFile(sidecar, []byte("dsn: memory://v2\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case cfg := <-updates:
+				if got := cfg.Configs["order"]["dsn"]; got != "memory://v2" {
+					t.Errorf("reloaded dsn = %v, want memory://v2", got)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("no config update after changing the module config file")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"myapp/module"
+)
+
+// 一个模块的全部 worker：共享同一个 ctx，任一 worker 出错即取消其余 worker（与 errgroup 相同）
+type workerGroup struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// 启动模块声明的 worker；未声明 workers 能力或未实现 WorkerProvider 时返回 nil
+func startWorkers(name string, mod module.Module) *workerGroup {
+	wp, ok := module.CapabilityOf[module.WorkerProvider](mod, module.CapWorkers)
+	if !ok {
+		return nil
+	}
+	workers := wp.Workers()
+	if len(workers) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &workerGroup{cancel: cancel}
+	for i, w := range workers {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			err := callSafely(name, "Worker", func() error { return w.Start(ctx) })
+			if err != nil && !errors.Is(err, context.Canceled) {
+				fmt.Printf("Worker %d of module %s failed: %v\n", i, name, err)
+				cancel()
+			}
+		}()
+	}
+	return g
+}
+
+// 取消并等待所有 worker 退出
+func (g *workerGroup) stop() {
+	if g == nil {
+		return
+	}
+	g.cancel()
+	g.wg.Wait()
+}